@@ -6,7 +6,9 @@ import (
 
 	"msp-admin-service/conf"
 	"msp-admin-service/service/delete_old_audit_worker"
+	"msp-admin-service/service/expired_role_worker"
 	"msp-admin-service/service/inactive_worker"
+	"msp-admin-service/service/recertification_worker"
 	"msp-admin-service/service/session_worker"
 
 	"github.com/pkg/errors"
@@ -17,18 +19,20 @@ import (
 	"github.com/txix-open/isp-kit/dbrx"
 	"github.com/txix-open/isp-kit/dbx"
 	"github.com/txix-open/isp-kit/grpc"
+	"github.com/txix-open/isp-kit/http"
 	"github.com/txix-open/isp-kit/http/httpcli"
 	"github.com/txix-open/isp-kit/http/httpclix"
 	"github.com/txix-open/isp-kit/log"
 )
 
 type Assembly struct {
-	boot     *bootstrap.Bootstrap
-	db       *dbrx.Client
-	server   *grpc.Server
-	httpCli  *httpcli.Client
-	logger   *log.Adapter
-	bgjobCli *bgjobx.Client
+	boot       *bootstrap.Bootstrap
+	db         *dbrx.Client
+	server     *grpc.Server
+	scimServer *http.Server
+	httpCli    *httpcli.Client
+	logger     *log.Adapter
+	bgjobCli   *bgjobx.Client
 }
 
 func New(boot *bootstrap.Bootstrap) (*Assembly, error) {
@@ -38,12 +42,13 @@ func New(boot *bootstrap.Bootstrap) (*Assembly, error) {
 	db := dbrx.New(logger, dbx.WithMigrationRunner(boot.MigrationsDir, logger))
 	bgjobCli := bgjobx.NewClient(db, logger)
 	return &Assembly{
-		boot:     boot,
-		db:       db,
-		server:   server,
-		logger:   logger,
-		httpCli:  httpCli,
-		bgjobCli: bgjobCli,
+		boot:       boot,
+		db:         db,
+		server:     server,
+		scimServer: http.NewServer(logger),
+		logger:     logger,
+		httpCli:    httpCli,
+		bgjobCli:   bgjobCli,
 	}, nil
 }
 
@@ -67,7 +72,13 @@ func (a *Assembly) ReceiveConfig(ctx context.Context, remoteConfig []byte) error
 	locator := NewLocator(a.logger, a.httpCli, a.db)
 	config := locator.Config(ctx, newCfg, time.Minute)
 
+	err = config.RoleConfig.Reconcile(ctx)
+	if err != nil {
+		a.logger.Fatal(ctx, errors.WithMessage(err, "reconcile config roles"))
+	}
+
 	a.server.Upgrade(config.Handler)
+	a.scimServer.Upgrade(config.ScimHandler)
 
 	err = a.bgjobCli.Upgrade(a.boot.App.Context(), config.BgJobCfg)
 	if err != nil {
@@ -86,6 +97,14 @@ func (a *Assembly) ReceiveConfig(ctx context.Context, remoteConfig []byte) error
 	if err != nil {
 		a.logger.Fatal(ctx, errors.WithMessage(err, "seed expire session worker"))
 	}
+	err = expired_role_worker.EnqueueSeedJob(ctx, a.bgjobCli)
+	if err != nil {
+		a.logger.Fatal(ctx, errors.WithMessage(err, "seed expired user role worker"))
+	}
+	err = recertification_worker.EnqueueSeedJob(ctx, a.bgjobCli)
+	if err != nil {
+		a.logger.Fatal(ctx, errors.WithMessage(err, "seed recertification worker"))
+	}
 
 	return nil
 }
@@ -101,6 +120,17 @@ func (a *Assembly) Runners() []app.Runner {
 			}
 			return nil
 		}),
+		app.RunnerFunc(func(ctx context.Context) error {
+			address := a.boot.App.Config().Optional().String("scimBindingAddress", "")
+			if address == "" {
+				return nil
+			}
+			err := a.scimServer.ListenAndServe(address)
+			if err != nil {
+				return errors.WithMessage(err, "listen and serve scim http server")
+			}
+			return nil
+		}),
 		app.RunnerFunc(func(ctx context.Context) error {
 			err := a.boot.ClusterCli.Run(ctx, eventHandler)
 			if err != nil {
@@ -118,6 +148,9 @@ func (a *Assembly) Closers() []app.Closer {
 			a.server.Shutdown()
 			return nil
 		}),
+		app.CloserFunc(func() error {
+			return a.scimServer.Shutdown(context.Background())
+		}),
 		app.CloserFunc(func() error {
 			a.bgjobCli.Close()
 			return nil
@@ -2,6 +2,7 @@ package assembly
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"msp-admin-service/conf"
@@ -9,8 +10,13 @@ import (
 	"msp-admin-service/repository"
 	"msp-admin-service/routes"
 	"msp-admin-service/service"
+	"msp-admin-service/service/access_restriction"
 	"msp-admin-service/service/delete_old_audit_worker"
+	"msp-admin-service/service/expired_role_worker"
 	"msp-admin-service/service/inactive_worker"
+	"msp-admin-service/service/password"
+	"msp-admin-service/service/recertification_worker"
+	"msp-admin-service/service/role_hierarchy"
 	"msp-admin-service/service/secure"
 	"msp-admin-service/service/session_worker"
 	"msp-admin-service/transaction"
@@ -43,8 +49,10 @@ func NewLocator(logger log.Logger, httpCli *httpcli.Client, db DB) Locator {
 }
 
 type Config struct {
-	Handler  isp.BackendServiceServer
-	BgJobCfg []bgjobx.WorkerConfig
+	Handler     isp.BackendServiceServer
+	ScimHandler http.Handler
+	BgJobCfg    []bgjobx.WorkerConfig
+	RoleConfig  service.RoleConfig
 }
 
 //nolint:funlen
@@ -54,17 +62,38 @@ func (l Locator) Config(
 	jobPollInterval time.Duration,
 ) Config {
 	sudirRepo := repository.NewSudir(l.httpCli, cfg.SudirAuth)
+	ldapRepo := repository.NewLdap(cfg.Ldap)
 	roleRepo := repository.NewRole(l.db)
 	userRepo := repository.NewUser(l.db)
 	tokenRepo := repository.NewToken(l.db)
 	auditRepo := repository.NewAudit(l.db)
 	auditEventRepo := repository.NewAuditEvent(l.db)
 	userRoleRepo := repository.NewUserRole(l.db)
+	externalGroupRuleRepo := repository.NewExternalGroupRule(l.db)
+	webauthnRepo := repository.NewWebauthn(l.db)
+	accessRequestRepo := repository.NewAccessRequest(l.db)
+	pendingChangeRepo := repository.NewPendingChange(l.db)
+	recertificationRepo := repository.NewRecertification(l.db)
+	permissionRepo := repository.NewPermission(l.db)
+	roleVersionRepo := repository.NewRoleVersion(l.db)
 
 	auditService := service.NewAudit(ctx, l.logger, auditRepo, auditEventRepo, cfg.Audit.EventSettings)
 	tokenService := service.NewToken(tokenRepo, cfg.ExpireSec)
 	sudirService := service.NewSudir(cfg.SudirAuth, sudirRepo)
-	secureService := secure.NewService(tokenRepo, userRoleRepo)
+	ldapService := service.NewLdap(cfg.Ldap, ldapRepo)
+	magicLinkService := service.NewMagicLink(cfg.MagicLink, newMailSender(cfg.MagicLink))
+	webauthnService := service.NewWebauthn(cfg.Webauthn, userRepo, webauthnRepo, auditService)
+	accessChecker := access_restriction.NewChecker(cfg.AccessRestrictions)
+	roleResolver := role_hierarchy.NewResolver(roleRepo)
+	secureService := secure.NewService(
+		tokenRepo, userRoleRepo, roleResolver, accessChecker, auditService,
+		cfg.AccessRestrictions.CheckOnAuthorize,
+	)
+	passwordHasher := password.NewHasher(cfg.PasswordHashing)
+	changeGate := service.NewChangeGate(cfg.FourEyes, pendingChangeRepo, auditService)
+	sodService := service.NewSeparationOfDuties(cfg.SeparationOfDuties, roleRepo, userRepo, userRoleRepo, roleResolver)
+	accessReportService := service.NewAccessReport(roleRepo, userRepo, userRoleRepo)
+	permissionsService := service.NewPermission(permissionRepo, cfg.Permissions)
 
 	txManager := transaction.NewManager(l.db)
 
@@ -72,21 +101,40 @@ func (l Locator) Config(
 		userRepo,
 		userRoleRepo,
 		roleRepo,
+		roleResolver,
+		permissionsService,
 		tokenRepo,
 		auditService,
 		txManager,
 		tokenService,
+		passwordHasher,
+		changeGate,
+		sodService,
 		cfg.IdleTimeoutMs,
 		l.logger,
 	)
 	authService := service.NewAuth(
-		userRepo, txManager, tokenService, sudirService, auditService, l.logger,
+		userRepo, txManager, tokenService, passwordHasher, sudirService, ldapService, magicLinkService, webauthnService, accessChecker,
+		sodService, auditService,
+		l.logger,
 		cfg.AntiBruteforce.DelayLoginRequestInSec,
 		cfg.AntiBruteforce.MaxInFlightLoginRequests,
 	)
-	roleService := service.NewRole(roleRepo, auditService)
-
-	permissionsService := service.NewPermission(cfg.Permissions)
+	roleService := service.NewRole(
+		roleRepo, roleVersionRepo, userRepo, userRoleRepo, txManager, permissionsService, changeGate, sodService, auditService,
+		cfg.RoleTemplates,
+	)
+	roleConfigService := service.NewRoleConfig(cfg.Roles, txManager, auditService, l.logger)
+
+	externalGroupRuleService := service.NewExternalGroupRule(externalGroupRuleRepo, roleRepo, auditService)
+	accessRequestService := service.NewAccessRequest(accessRequestRepo, roleRepo, userRoleRepo, roleResolver, sodService, txManager, auditService)
+	pendingChangeService := service.NewPendingChange(pendingChangeRepo, txManager, roleService, userService, auditService)
+	recertificationService := service.NewRecertification(recertificationRepo, tokenRepo, txManager, auditService, l.logger)
+	scimService := service.NewScim(userService, roleService)
+	impersonationService := service.NewImpersonation(
+		userRepo, userRoleRepo, roleResolver, permissionsService, tokenRepo, tokenService, auditService,
+		cfg.ImpersonationExpireSec,
+	)
 
 	userController := controller.NewUser(userService)
 	customizationController := controller.NewCustomization(cfg.UiDesign)
@@ -96,18 +144,36 @@ func (l Locator) Config(
 	auditController := controller.NewAudit(auditService)
 	roleController := controller.NewRole(roleService)
 	permissionController := controller.NewPermissions(permissionsService)
-
+	externalGroupRuleController := controller.NewExternalGroupRule(externalGroupRuleService)
+	accessRequestController := controller.NewAccessRequest(accessRequestService)
+	pendingChangeController := controller.NewPendingChange(pendingChangeService)
+	recertificationController := controller.NewRecertification(recertificationService)
+	scimController := controller.NewScim(scimService, l.logger)
+	impersonationController := controller.NewImpersonation(impersonationService, l.logger)
+	webauthnController := controller.NewWebauthn(webauthnService)
+	sodController := controller.NewSeparationOfDuties(sodService)
+	accessReportController := controller.NewAccessReport(accessReportService)
+
+	clientIpMiddleware := controller.ClientIpMiddleware(cfg.AccessRestrictions.TrustedProxies, cfg.AccessRestrictions.TrustRealIp)
 	handler := routes.Handler(
-		endpoint.DefaultWrapper(l.logger),
+		endpoint.DefaultWrapper(l.logger, clientIpMiddleware, impersonationController.Middleware()),
 		routes.Controllers{
-			User:          userController,
-			Customization: customizationController,
-			Auth:          authController,
-			Secure:        secureController,
-			Session:       sessionController,
-			Audit:         auditController,
-			Role:          roleController,
-			Permissions:   permissionController,
+			User:               userController,
+			Customization:      customizationController,
+			Auth:               authController,
+			Secure:             secureController,
+			Session:            sessionController,
+			Audit:              auditController,
+			Role:               roleController,
+			Permissions:        permissionController,
+			ExternalGroupRule:  externalGroupRuleController,
+			AccessRequest:      accessRequestController,
+			PendingChange:      pendingChangeController,
+			Recertification:    recertificationController,
+			Impersonation:      impersonationController,
+			Webauthn:           webauthnController,
+			SeparationOfDuties: sodController,
+			AccessReport:       accessReportController,
 		},
 	)
 
@@ -120,9 +186,13 @@ func (l Locator) Config(
 	)
 	deleteOldAuditWorker := delete_old_audit_worker.NewService(l.logger, auditRepo, cfg.Audit.AuditTTl)
 	expireSessionWorker := session_worker.NewExpireSessionWorker(l.logger, txManager)
+	expiredRoleWorker := expired_role_worker.NewExpiredRoleWorker(l.logger, userRoleRepo, auditService)
+	recertificationWorker := recertification_worker.NewRecertificationWorker(l.logger, recertificationService)
 
 	return Config{
-		Handler: handler,
+		Handler:     handler,
+		ScimHandler: routes.ScimHandler(scimController, cfg.Scim),
+		RoleConfig:  roleConfigService,
 		BgJobCfg: []bgjobx.WorkerConfig{{
 			Queue:        delete_old_audit_worker.QueueName,
 			Concurrency:  1,
@@ -138,6 +208,23 @@ func (l Locator) Config(
 			Concurrency:  1,
 			PollInterval: jobPollInterval,
 			Handle:       expireSessionWorker,
+		}, {
+			Queue:        expired_role_worker.QueueName,
+			Concurrency:  1,
+			PollInterval: jobPollInterval,
+			Handle:       expiredRoleWorker,
+		}, {
+			Queue:        recertification_worker.QueueName,
+			Concurrency:  1,
+			PollInterval: jobPollInterval,
+			Handle:       recertificationWorker,
 		}},
 	}
 }
+
+func newMailSender(cfg *conf.MagicLink) repository.Smtp {
+	if cfg == nil {
+		return repository.NewSmtp(nil)
+	}
+	return repository.NewSmtp(&cfg.Smtp)
+}
@@ -18,17 +18,29 @@ func init() { //
 }
 
 type Remote struct {
-	Audit     Audit
-	Database  dbx.Config
-	ExpireSec int      `validate:"required" schema:"Время жизни токена в секундах,in seconds"`
-	UiDesign  UIDesign `schema:"Кастомизация интерфейса"`
+	Audit                  Audit
+	Database               dbx.Config
+	ExpireSec              int      `validate:"required" schema:"Время жизни токена в секундах,in seconds"`
+	ImpersonationExpireSec int      `schema:"Время жизни токена входа от имени пользователя,в секундах, по умолчанию 900"`
+	UiDesign               UIDesign `schema:"Кастомизация интерфейса"`
 	//nolint:lll
 	IdleTimeoutMs       int                 `schema:"Время бездействия пользователя,в милисекундах, после указанного времени пользователь будет разлогирован из интерфейса в браузере, по умолчанию отключено"`
 	SudirAuth           *SudirAuth          `schema:"СУДИР авторизация"`
+	Ldap                *Ldap               `schema:"LDAP авторизация"`
+	Scim                *Scim               `schema:"SCIM 2.0 провизионирование пользователей и групп"`
+	MagicLink           *MagicLink          `schema:"Вход по ссылке из письма для локальных пользователей"`
+	Webauthn            *Webauthn           `schema:"Вход по ключам доступа WebAuthn (passkey)"`
 	LogLevel            log.Level           `schemaGen:"logLevel" schema:"Уровень логирования"`
 	AntiBruteforce      AntiBruteforce      `schema:"Настройки антибрут для admin login"`
+	PasswordHashing     PasswordHashing     `schema:"Хеширование паролей"`
+	AccessRestrictions  AccessRestrictions  `schema:"Ограничения ролей по сетям и времени входа"`
+	FourEyes            FourEyes            `schema:"Подтверждение изменений вторым администратором"`
+	SeparationOfDuties  []SodConstraint     `validate:"dive" schema:"Разделение обязанностей,наборы взаимоисключающих ролей или разрешений"`
+	RoleTemplates       RoleTemplates       `schema:"Шаблоны ролей"`
 	BlockInactiveWorker BlockInactiveWorker `validate:"required" schema:"Блокировка неактивных УЗ"`
 	Permissions         []Permission        `schema:"Список разрешений"`
+	//nolint:lll
+	Roles []Role `validate:"dive" schema:"Роли из конфигурации,создаются и обновляются при получении конфигурации, изменение этих ролей через интерфейс запрещено"`
 }
 
 type Audit struct {
@@ -58,6 +70,88 @@ type SudirAuth struct {
 	RedirectURI  string `validate:"required"`
 }
 
+type Ldap struct {
+	Url                string `validate:"required" schema:"Адрес сервера,пример ldaps://ldap.example.com:636 или ldap://ldap.example.com:389"`
+	StartTls           bool   `schema:"Использовать StartTLS для ldap://"`
+	InsecureSkipVerify bool   `schema:"Не проверять сертификат сервера"`
+	RootCa             string `schema:"Сертификат УЦ,в формате PEM, по умолчанию используются системные сертификаты"`
+	//nolint:lll
+	BindDnTemplate     string `validate:"required" schema:"Шаблон DN для bind,%s заменяется логином пользователя, пример uid=%s,ou=people,dc=example,dc=com или %s@corp.local"`
+	BaseDn             string `validate:"required" schema:"Базовый DN поиска пользователя"`
+	UserFilter         string `validate:"required" schema:"Фильтр поиска пользователя,%s заменяется логином пользователя, пример (sAMAccountName=%s)"`
+	EmailAttribute     string `schema:"Атрибут email,по умолчанию mail"`
+	FirstNameAttribute string `schema:"Атрибут имени,по умолчанию givenName"`
+	LastNameAttribute  string `schema:"Атрибут фамилии,по умолчанию sn"`
+	FullNameAttribute  string `schema:"Атрибут ФИО,по умолчанию displayName"`
+	TimeoutInSec       int    `schema:"Таймаут запросов,в секундах, по умолчанию 10"`
+}
+
+type Scim struct {
+	BearerToken string `validate:"required" schema:"Токен доступа,передается провайдером в заголовке Authorization: Bearer"`
+}
+
+type MagicLink struct {
+	UrlTemplate        string `validate:"required" schema:"Шаблон ссылки,%s заменяется токеном, пример https://admin.example.com/login?magicLink=%s"`
+	ExpireSec          int    `schema:"Время жизни ссылки,в секундах, по умолчанию 600"`
+	MaxRequestsPerHour int    `schema:"Количество запросов ссылок на email в час,по умолчанию 5"`
+	Smtp               Smtp   `validate:"required" schema:"Настройки почтового сервера"`
+}
+
+type Webauthn struct {
+	RpId              string   `validate:"required" schema:"Идентификатор проверяющей стороны,домен панели администрирования, пример admin.example.com"`
+	RpDisplayName     string   `schema:"Отображаемое название проверяющей стороны,по умолчанию совпадает с идентификатором"`
+	RpOrigins         []string `validate:"required" schema:"Разрешенные источники,пример https://admin.example.com"`
+	CeremonyExpireSec int      `schema:"Время на подтверждение ключом,в секундах, по умолчанию 300"`
+}
+
+type PasswordHashing struct {
+	Algorithm  string   `schema:"Алгоритм хеширования паролей,argon2id или bcrypt, по умолчанию argon2id"`
+	Argon2id   Argon2id `schema:"Параметры argon2id"`
+	BcryptCost int      `schema:"Стоимость bcrypt,по умолчанию 12"`
+}
+
+type AccessRestrictions struct {
+	Timezone         string `schema:"Часовой пояс интервалов времени ролей,пример Europe/Moscow, по умолчанию UTC"`
+	CheckOnAuthorize bool   `schema:"Проверять ограничения при каждой проверке разрешения,по умолчанию только при входе"`
+	//nolint:lll
+	TrustedProxies int `validate:"min=0" schema:"Количество доверенных прокси,адрес клиента берется из X-Forwarded-For перед адресами, добавленными доверенными прокси, по умолчанию 1"`
+	//nolint:lll
+	TrustRealIp bool `schema:"Доверять заголовку X-Real-IP,включать только если шлюз всегда перезаписывает заголовок, по умолчанию адрес берется из X-Forwarded-For"`
+}
+
+type FourEyes struct {
+	//nolint:lll
+	Operations      []string `validate:"dive,oneof=role_update role_delete role_import user_delete privileged_role_grant" schema:"Операции,требующие подтверждения: role_update, role_delete, role_import, user_delete, privileged_role_grant"`
+	PrivilegedRoles []string `schema:"Привилегированные роли,названия ролей, назначение которых требует подтверждения при privileged_role_grant"`
+	ExpireSec       int      `schema:"Время ожидания подтверждения,в секундах, по умолчанию 86400"`
+}
+
+type SodConstraint struct {
+	Name string `validate:"required" schema:"Название ограничения"`
+	//nolint:lll
+	Roles []string `validate:"required_without=Permissions,omitempty,min=2" schema:"Взаимоисключающие роли,названия ролей, пользователю может быть назначено не более одной из них"`
+	//nolint:lll
+	Permissions []string `validate:"required_without=Roles,omitempty,min=2" schema:"Взаимоисключающие разрешения,ключи разрешений, пользователю может быть выдано не более одного из них с учетом всех ролей, шаблонов и наследования"`
+}
+
+type RoleTemplates struct {
+	ReadOnlySuffixes []string `schema:"Суффиксы разрешений для чтения,шаблон read_only оставляет разрешения с этими суффиксами, по умолчанию _view"`
+}
+
+type Argon2id struct {
+	Iterations  int `schema:"Количество итераций,по умолчанию 2"`
+	MemoryKib   int `schema:"Объем памяти,в КиБ, по умолчанию 19456"`
+	Parallelism int `schema:"Количество потоков,по умолчанию 1"`
+}
+
+type Smtp struct {
+	Host     string `validate:"required" schema:"Адрес почтового сервера"`
+	Port     int    `validate:"required" schema:"Порт почтового сервера"`
+	Username string `schema:"Имя пользователя"`
+	Password string `schema:"Пароль"`
+	From     string `validate:"required" schema:"Адрес отправителя"`
+}
+
 type AntiBruteforce struct {
 	MaxInFlightLoginRequests int `validate:"required" schema:"Количество одновременных запросов /login"`
 	DelayLoginRequestInSec   int `validate:"required" schema:"Задержка выполнения /login"`
@@ -72,3 +166,9 @@ type Permission struct {
 	Key  string
 	Name string
 }
+
+type Role struct {
+	Name          string   `validate:"required" schema:"Название"`
+	ExternalGroup string   `schema:"Группа ЕСК"`
+	Permissions   []string `schema:"Разрешения"`
+}
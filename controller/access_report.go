@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"msp-admin-service/domain"
+)
+
+type accessReportService interface {
+	UsersByPermission(ctx context.Context, req domain.PermissionReportRequest) ([]domain.UserAccess, error)
+	UserPermissions(ctx context.Context, req domain.UserPermissionsReportRequest) (*domain.UserPermissionsReport, error)
+	RolesByPermission(ctx context.Context, req domain.PermissionReportRequest) ([]domain.RoleAccess, error)
+	UnusedRoles(ctx context.Context) ([]domain.RoleAccess, error)
+	InactiveUsers(ctx context.Context, req domain.InactiveUsersReportRequest) ([]domain.UserAccess, error)
+	Csv(ctx context.Context, req domain.AccessReportCsvRequest) (*domain.AccessReportFile, error)
+}
+
+type AccessReport struct {
+	service accessReportService
+}
+
+func NewAccessReport(service accessReportService) AccessReport {
+	return AccessReport{
+		service: service,
+	}
+}
+
+// UsersByPermission
+// @Tags accessReport
+// @Summary Пользователи с разрешением
+// @Description Пользователи, которым действующие роли, с учетом родительских ролей и запретов, дают разрешение `permission`
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.PermissionReportRequest true "Тело запроса"
+// @Success 200 {array} domain.UserAccess
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /report/users_by_permission [POST]
+func (c AccessReport) UsersByPermission(ctx context.Context, req domain.PermissionReportRequest) ([]domain.UserAccess, error) {
+	result, err := c.service.UsersByPermission(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get users by permission")
+	}
+	return result, nil
+}
+
+// UserPermissions
+// @Tags accessReport
+// @Summary Разрешения пользователя
+// @Description Итоговые разрешения пользователя после объединения его действующих ролей
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.UserPermissionsReportRequest true "Тело запроса"
+// @Success 200 {object} domain.UserPermissionsReport
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 404 {object} domain.GrpcError "Пользователь не найден"
+// @Failure 500 {object} domain.GrpcError
+// @Router /report/user_permissions [POST]
+func (c AccessReport) UserPermissions(ctx context.Context, req domain.UserPermissionsReportRequest) (*domain.UserPermissionsReport, error) {
+	result, err := c.service.UserPermissions(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "user not found")
+	case err != nil:
+		return nil, errors.WithMessage(err, "get user permissions")
+	default:
+		return result, nil
+	}
+}
+
+// RolesByPermission
+// @Tags accessReport
+// @Summary Роли с разрешением
+// @Description Роли, которые дают разрешение `permission` сами или через родительские роли, с количеством пользователей
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.PermissionReportRequest true "Тело запроса"
+// @Success 200 {array} domain.RoleAccess
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /report/roles_by_permission [POST]
+func (c AccessReport) RolesByPermission(ctx context.Context, req domain.PermissionReportRequest) ([]domain.RoleAccess, error) {
+	result, err := c.service.RolesByPermission(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get roles by permission")
+	}
+	return result, nil
+}
+
+// UnusedRoles
+// @Tags accessReport
+// @Summary Неиспользуемые роли
+// @Description Роли, которые не назначены ни одному пользователю
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Success 200 {array} domain.RoleAccess
+// @Failure 500 {object} domain.GrpcError
+// @Router /report/unused_roles [POST]
+func (c AccessReport) UnusedRoles(ctx context.Context) ([]domain.RoleAccess, error) {
+	result, err := c.service.UnusedRoles(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get unused roles")
+	}
+	return result, nil
+}
+
+// InactiveUsers
+// @Tags accessReport
+// @Summary Неактивные пользователи с ролями
+// @Description Незаблокированные пользователи с действующими ролями, которые не были активны `days` дней
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.InactiveUsersReportRequest true "Тело запроса"
+// @Success 200 {array} domain.UserAccess
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /report/inactive_users [POST]
+func (c AccessReport) InactiveUsers(ctx context.Context, req domain.InactiveUsersReportRequest) ([]domain.UserAccess, error) {
+	result, err := c.service.InactiveUsers(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get inactive users")
+	}
+	return result, nil
+}
+
+// Csv
+// @Tags accessReport
+// @Summary Отчет по доступу в CSV
+// @Description Отчет `report` в формате CSV, параметры отчета передаются в `permission`, `userId` и `days`
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.AccessReportCsvRequest true "Тело запроса"
+// @Success 200 {object} domain.AccessReportFile
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса или параметры отчета"
+// @Failure 404 {object} domain.GrpcError "Пользователь не найден"
+// @Failure 500 {object} domain.GrpcError
+// @Router /report/csv [POST]
+func (c AccessReport) Csv(ctx context.Context, req domain.AccessReportCsvRequest) (*domain.AccessReportFile, error) {
+	result, err := c.service.Csv(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrInvalid):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "user not found")
+	case err != nil:
+		return nil, errors.WithMessage(err, "get access report csv")
+	default:
+		return result, nil
+	}
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"msp-admin-service/domain"
+)
+
+type accessRequestService interface {
+	All(ctx context.Context, req domain.AccessRequestPageRequest, adminId int64) (*domain.AccessRequestResponse, error)
+	Create(ctx context.Context, req domain.CreateAccessRequestRequest, userId int64) (*domain.AccessRequest, error)
+	Cancel(ctx context.Context, req domain.CancelAccessRequestRequest, userId int64) (*domain.AccessRequest, error)
+	Approve(ctx context.Context, req domain.ApproveAccessRequestRequest, adminId int64) (*domain.AccessRequest, error)
+	Reject(ctx context.Context, req domain.RejectAccessRequestRequest, adminId int64) (*domain.AccessRequest, error)
+}
+
+type AccessRequest struct {
+	service accessRequestService
+}
+
+func NewAccessRequest(service accessRequestService) AccessRequest {
+	return AccessRequest{
+		service: service,
+	}
+}
+
+// All
+// @Tags accessRequest
+// @Summary Список запросов доступа
+// @Description С разрешением `access_request_approve` доступны все запросы, иначе собственные и на роли, где пользователь согласующий
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.AccessRequestPageRequest true "Тело запроса"
+// @Success 200 {object} domain.AccessRequestResponse
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /access_request/all [POST]
+func (c AccessRequest) All(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.AccessRequestPageRequest,
+) (*domain.AccessRequestResponse, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.service.All(ctx, req, adminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get access requests")
+	}
+	return result, nil
+}
+
+// Create
+// @Tags accessRequest
+// @Summary Запросить роль
+// @Description Создать запрос текущего пользователя на получение роли с обоснованием, роль может быть запрошена на срок
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.CreateAccessRequestRequest true "Тело запроса"
+// @Success 200 {object} domain.AccessRequest
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса или срок действия роли"
+// @Failure 404 {object} domain.GrpcError "Роль с указанным id не существует"
+// @Failure 409 {object} domain.GrpcError "Роль уже назначена или запрос на роль ожидает рассмотрения"
+// @Failure 500 {object} domain.GrpcError
+// @Router /access_request/create [POST]
+func (c AccessRequest) Create(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.CreateAccessRequestRequest,
+) (*domain.AccessRequest, error) {
+	userId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.service.Create(ctx, req, userId)
+	switch {
+	case errors.Is(err, domain.ErrInvalidRolePeriod):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "role not found")
+	case errors.Is(err, domain.ErrAlreadyExists):
+		return nil, status.Error(codes.AlreadyExists, "role is already granted or requested")
+	case err != nil:
+		return nil, errors.WithMessage(err, "create access request")
+	default:
+		return result, nil
+	}
+}
+
+// Cancel
+// @Tags accessRequest
+// @Summary Отменить запрос доступа
+// @Description Отменить собственный запрос, ожидающий рассмотрения
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.CancelAccessRequestRequest true "Тело запроса"
+// @Success 200 {object} domain.AccessRequest
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 403 {object} domain.GrpcError "Запрос создан другим пользователем"
+// @Failure 404 {object} domain.GrpcError "Запрос с указанным id не существует"
+// @Failure 412 {object} domain.GrpcError "Запрос уже рассмотрен"
+// @Failure 500 {object} domain.GrpcError
+// @Router /access_request/cancel [POST]
+func (c AccessRequest) Cancel(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.CancelAccessRequestRequest,
+) (*domain.AccessRequest, error) {
+	userId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.service.Cancel(ctx, req, userId)
+	if err != nil {
+		return nil, c.reviewError(err, "cancel access request")
+	}
+	return result, nil
+}
+
+// Approve
+// @Tags accessRequest
+// @Summary Одобрить запрос доступа
+// @Description Одобрить запрос и назначить роль пользователю, срок действия роли берется из запроса или из поля `validUntil`
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.ApproveAccessRequestRequest true "Тело запроса"
+// @Success 200 {object} domain.AccessRequest
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса или срок действия роли"
+// @Failure 403 {object} domain.GrpcError "Пользователь не является согласующим или рассматривает собственный запрос"
+// @Failure 404 {object} domain.GrpcError "Запрос с указанным id не существует"
+// @Failure 412 {object} domain.GrpcError "Запрос уже рассмотрен или роль нарушает разделение обязанностей"
+// @Failure 500 {object} domain.GrpcError
+// @Router /access_request/approve [POST]
+func (c AccessRequest) Approve(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.ApproveAccessRequestRequest,
+) (*domain.AccessRequest, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.service.Approve(ctx, req, adminId)
+	if err != nil {
+		return nil, c.reviewError(err, "approve access request")
+	}
+	return result, nil
+}
+
+// Reject
+// @Tags accessRequest
+// @Summary Отклонить запрос доступа
+// @Description Отклонить запрос с комментарием
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RejectAccessRequestRequest true "Тело запроса"
+// @Success 200 {object} domain.AccessRequest
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 403 {object} domain.GrpcError "Пользователь не является согласующим или рассматривает собственный запрос"
+// @Failure 404 {object} domain.GrpcError "Запрос с указанным id не существует"
+// @Failure 412 {object} domain.GrpcError "Запрос уже рассмотрен"
+// @Failure 500 {object} domain.GrpcError
+// @Router /access_request/reject [POST]
+func (c AccessRequest) Reject(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.RejectAccessRequestRequest,
+) (*domain.AccessRequest, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.service.Reject(ctx, req, adminId)
+	if err != nil {
+		return nil, c.reviewError(err, "reject access request")
+	}
+	return result, nil
+}
+
+func (c AccessRequest) reviewError(err error, message string) error {
+	sodErr := domain.SodViolationError{}
+	switch {
+	case errors.As(err, &sodErr):
+		return sodViolationError(sodErr)
+	case errors.Is(err, domain.ErrInvalidRolePeriod):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrAccessRequestDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		return status.Error(codes.NotFound, "access request not found")
+	case errors.Is(err, domain.ErrAccessRequestNotPending):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return errors.WithMessage(err, message)
+	}
+}
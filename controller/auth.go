@@ -17,6 +17,11 @@ import (
 type authService interface {
 	Login(ctx context.Context, request domain.LoginRequest) (*domain.LoginResponse, error)
 	LoginWithSudir(ctx context.Context, request domain.LoginSudirRequest) (*domain.LoginResponse, error)
+	LoginWithLdap(ctx context.Context, request domain.LoginLdapRequest) (*domain.LoginResponse, error)
+	RequestMagicLink(ctx context.Context, request domain.MagicLinkRequest) error
+	LoginWithMagicLink(ctx context.Context, request domain.LoginMagicLinkRequest) (*domain.LoginResponse, error)
+	BeginWebauthnLogin(ctx context.Context, request domain.WebauthnBeginRequest) (*domain.WebauthnChallenge, error)
+	FinishWebauthnLogin(ctx context.Context, request domain.WebauthnFinishRequest) (*domain.LoginResponse, error)
 	Logout(ctx context.Context, adminId int64, request *domain.LogoutRequest) error
 }
 
@@ -86,13 +91,14 @@ func (a Auth) LogoutWithReason(ctx context.Context, authData grpc.AuthData, logo
 // Login
 // @Tags auth
 // @Summary Авторизация по логину и паролю
-// @Description Авторизация с получением токена администратора
+// @Description Авторизация с получением токена администратора, при наличии ключей доступа возвращается challenge для подтверждения
 // @Accept json
 // @Produce json
 // @Param body body domain.LoginRequest true "Тело запроса"
 // @Success 200 {object} domain.LoginResponse
 // @Failure 400 {object} domain.GrpcError
 // @Failure 401 {object} domain.GrpcError "Данные для авторизации не верны"
+// @Failure 403 {object} domain.GrpcError "Вход ограничен разрешенными сетями или временем роли"
 // @Failure 500 {object} domain.GrpcError
 // @Router /auth/login [POST]
 func (a Auth) Login(ctx context.Context, authRequest domain.LoginRequest) (*domain.LoginResponse, error) {
@@ -106,6 +112,8 @@ func (a Auth) Login(ctx context.Context, authRequest domain.LoginRequest) (*doma
 		return nil, status.Error(codes.Unauthenticated, "invalid credential")
 	case errors.Is(err, domain.ErrTooManyLoginRequests):
 		return nil, status.Error(codes.ResourceExhausted, "too many requests")
+	case errors.Is(err, domain.ErrAccessRestricted):
+		return nil, status.Error(codes.PermissionDenied, "access is restricted by role")
 	case err != nil:
 		return nil, errors.WithMessage(err, "login")
 	default:
@@ -122,17 +130,22 @@ func (a Auth) Login(ctx context.Context, authRequest domain.LoginRequest) (*doma
 // @Param body body domain.LoginSudirRequest true "Тело запроса"
 // @Success 200 {object} domain.LoginResponse
 // @Failure 401 {object} domain.GrpcError "Некорректный код для авторизации"
-// @Failure 412 {object} domain.GrpcError "Авторизация СУДИР не настроена на сервере"
+// @Failure 403 {object} domain.GrpcError "Вход ограничен разрешенными сетями или временем роли"
+// @Failure 412 {object} domain.GrpcError "Авторизация СУДИР не настроена на сервере или роли нарушают разделение обязанностей"
 // @Failure 500 {object} domain.GrpcError
 // @Router /auth/login_with_sudir [POST]
 func (a Auth) LoginWithSudir(ctx context.Context, request domain.LoginSudirRequest) (*domain.LoginResponse, error) {
 	auth, err := a.authService.LoginWithSudir(ctx, request)
-
+	sodErr := domain.SodViolationError{}
 	switch {
+	case errors.As(err, &sodErr):
+		return nil, sodViolationError(sodErr)
 	case errors.Is(err, domain.ErrSudirAuthIsMissed):
 		return nil, status.Error(codes.FailedPrecondition, "sudir auth is not configured")
 	case errors.Is(err, domain.ErrUnauthenticated):
 		return nil, status.Error(codes.Unauthenticated, "invalid code")
+	case errors.Is(err, domain.ErrAccessRestricted):
+		return nil, status.Error(codes.PermissionDenied, "access is restricted by role")
 	case err != nil:
 		return nil, errors.WithMessage(err, "login with sudir")
 	default:
@@ -140,6 +153,171 @@ func (a Auth) LoginWithSudir(ctx context.Context, request domain.LoginSudirReque
 	}
 }
 
+// LoginWithLdap
+// @Tags auth
+// @Summary Авторизация по логину и паролю из LDAP
+// @Description Авторизация в каталоге LDAP с получением токена администратора
+// @Accept json
+// @Produce json
+// @Param body body domain.LoginLdapRequest true "Тело запроса"
+// @Success 200 {object} domain.LoginResponse
+// @Failure 401 {object} domain.GrpcError "Данные для авторизации не верны"
+// @Failure 403 {object} domain.GrpcError "Вход ограничен разрешенными сетями или временем роли"
+// @Failure 412 {object} domain.GrpcError "Авторизация LDAP не настроена на сервере или роли нарушают разделение обязанностей"
+// @Failure 429 {object} domain.GrpcError "Слишком много запросов"
+// @Failure 500 {object} domain.GrpcError
+// @Router /auth/login_with_ldap [POST]
+func (a Auth) LoginWithLdap(ctx context.Context, request domain.LoginLdapRequest) (*domain.LoginResponse, error) {
+	auth, err := a.authService.LoginWithLdap(ctx, request)
+	sodErr := domain.SodViolationError{}
+	switch {
+	case errors.As(err, &sodErr):
+		return nil, sodViolationError(sodErr)
+	case errors.Is(err, domain.ErrLdapAuthIsMissed):
+		return nil, status.Error(codes.FailedPrecondition, "ldap auth is not configured")
+	case errors.Is(err, domain.ErrUnauthenticated):
+		a.logger.Error(ctx, err.Error())
+		return nil, status.Error(codes.Unauthenticated, "invalid credential")
+	case errors.Is(err, domain.ErrTooManyLoginRequests):
+		return nil, status.Error(codes.ResourceExhausted, "too many requests")
+	case errors.Is(err, domain.ErrAccessRestricted):
+		return nil, status.Error(codes.PermissionDenied, "access is restricted by role")
+	case err != nil:
+		return nil, errors.WithMessage(err, "login with ldap")
+	default:
+		return auth, nil
+	}
+}
+
+// RequestMagicLink
+// @Tags auth
+// @Summary Запрос ссылки для входа
+// @Description Отправка одноразовой ссылки для входа на email локального пользователя, ответ не раскрывает существование пользователя
+// @Accept json
+// @Produce json
+// @Param body body domain.MagicLinkRequest true "Тело запроса"
+// @Success 200
+// @Failure 400 {object} domain.GrpcError
+// @Failure 412 {object} domain.GrpcError "Вход по ссылке не настроен на сервере"
+// @Failure 429 {object} domain.GrpcError "Слишком много запросов"
+// @Failure 500 {object} domain.GrpcError
+// @Router /auth/request_magic_link [POST]
+func (a Auth) RequestMagicLink(ctx context.Context, request domain.MagicLinkRequest) error {
+	err := a.authService.RequestMagicLink(ctx, request)
+
+	switch {
+	case errors.Is(err, domain.ErrMagicLinkIsMissed):
+		return status.Error(codes.FailedPrecondition, "magic link auth is not configured")
+	case errors.Is(err, domain.ErrTooManyLoginRequests):
+		return status.Error(codes.ResourceExhausted, "too many requests")
+	case err != nil:
+		return errors.WithMessage(err, "request magic link")
+	default:
+		return nil
+	}
+}
+
+// LoginWithMagicLink
+// @Tags auth
+// @Summary Авторизация по ссылке из письма
+// @Description Обмен одноразового токена из ссылки на токен администратора
+// @Accept json
+// @Produce json
+// @Param body body domain.LoginMagicLinkRequest true "Тело запроса"
+// @Success 200 {object} domain.LoginResponse
+// @Failure 400 {object} domain.GrpcError
+// @Failure 401 {object} domain.GrpcError "Ссылка недействительна"
+// @Failure 403 {object} domain.GrpcError "Вход ограничен разрешенными сетями или временем роли"
+// @Failure 412 {object} domain.GrpcError "Вход по ссылке не настроен на сервере"
+// @Failure 429 {object} domain.GrpcError "Слишком много запросов"
+// @Failure 500 {object} domain.GrpcError
+// @Router /auth/login_with_magic_link [POST]
+func (a Auth) LoginWithMagicLink(ctx context.Context, request domain.LoginMagicLinkRequest) (*domain.LoginResponse, error) {
+	auth, err := a.authService.LoginWithMagicLink(ctx, request)
+
+	switch {
+	case errors.Is(err, domain.ErrMagicLinkIsMissed):
+		return nil, status.Error(codes.FailedPrecondition, "magic link auth is not configured")
+	case errors.Is(err, domain.ErrSudirAuthorization):
+		return nil, status.Error(codes.InvalidArgument, "magic link auth is not available")
+	case errors.Is(err, domain.ErrUnauthenticated):
+		a.logger.Error(ctx, err.Error())
+		return nil, status.Error(codes.Unauthenticated, "invalid magic link")
+	case errors.Is(err, domain.ErrTooManyLoginRequests):
+		return nil, status.Error(codes.ResourceExhausted, "too many requests")
+	case errors.Is(err, domain.ErrAccessRestricted):
+		return nil, status.Error(codes.PermissionDenied, "access is restricted by role")
+	case err != nil:
+		return nil, errors.WithMessage(err, "login with magic link")
+	default:
+		return auth, nil
+	}
+}
+
+// BeginWebauthnLogin
+// @Tags auth
+// @Summary Начало авторизации по ключу доступа
+// @Description Получение параметров для navigator.credentials.get(), без email принимается любой ключ доступа (passkey)
+// @Accept json
+// @Produce json
+// @Param body body domain.WebauthnBeginRequest true "Тело запроса"
+// @Success 200 {object} domain.WebauthnChallenge
+// @Failure 412 {object} domain.GrpcError "Вход по ключам доступа не настроен на сервере"
+// @Failure 429 {object} domain.GrpcError "Слишком много запросов"
+// @Failure 500 {object} domain.GrpcError
+// @Router /auth/webauthn/begin [POST]
+func (a Auth) BeginWebauthnLogin(ctx context.Context, request domain.WebauthnBeginRequest) (*domain.WebauthnChallenge, error) {
+	challenge, err := a.authService.BeginWebauthnLogin(ctx, request)
+
+	switch {
+	case errors.Is(err, domain.ErrWebauthnIsMissed):
+		return nil, status.Error(codes.FailedPrecondition, "webauthn auth is not configured")
+	case errors.Is(err, domain.ErrTooManyLoginRequests):
+		return nil, status.Error(codes.ResourceExhausted, "too many requests")
+	case err != nil:
+		return nil, errors.WithMessage(err, "begin webauthn login")
+	default:
+		return challenge, nil
+	}
+}
+
+// FinishWebauthnLogin
+// @Tags auth
+// @Summary Авторизация по ключу доступа
+// @Description Проверка ответа navigator.credentials.get() с получением токена, также подтверждает вход по логину и паролю
+// @Accept json
+// @Produce json
+// @Param body body domain.WebauthnFinishRequest true "Тело запроса"
+// @Success 200 {object} domain.LoginResponse
+// @Failure 400 {object} domain.GrpcError
+// @Failure 401 {object} domain.GrpcError "Ключ доступа не прошел проверку"
+// @Failure 403 {object} domain.GrpcError "Вход ограничен разрешенными сетями или временем роли"
+// @Failure 412 {object} domain.GrpcError "Вход по ключам доступа не настроен на сервере"
+// @Failure 429 {object} domain.GrpcError "Слишком много запросов"
+// @Failure 500 {object} domain.GrpcError
+// @Router /auth/webauthn/finish [POST]
+func (a Auth) FinishWebauthnLogin(ctx context.Context, request domain.WebauthnFinishRequest) (*domain.LoginResponse, error) {
+	auth, err := a.authService.FinishWebauthnLogin(ctx, request)
+
+	switch {
+	case errors.Is(err, domain.ErrWebauthnIsMissed):
+		return nil, status.Error(codes.FailedPrecondition, "webauthn auth is not configured")
+	case errors.Is(err, domain.ErrSudirAuthorization):
+		return nil, status.Error(codes.InvalidArgument, "webauthn auth is not available")
+	case errors.Is(err, domain.ErrUnauthenticated):
+		a.logger.Error(ctx, err.Error())
+		return nil, status.Error(codes.Unauthenticated, "invalid credential")
+	case errors.Is(err, domain.ErrTooManyLoginRequests):
+		return nil, status.Error(codes.ResourceExhausted, "too many requests")
+	case errors.Is(err, domain.ErrAccessRestricted):
+		return nil, status.Error(codes.PermissionDenied, "access is restricted by role")
+	case err != nil:
+		return nil, errors.WithMessage(err, "finish webauthn login")
+	default:
+		return auth, nil
+	}
+}
+
 func getAdminId(authData grpc.AuthData) (int64, error) {
 	token, err := grpc.StringFromMd(domain.AdminAuthIdHeader, metadata.MD(authData))
 	if err != nil {
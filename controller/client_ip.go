@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"msp-admin-service/domain"
+
+	"github.com/txix-open/isp-kit/grpc"
+	"github.com/txix-open/isp-kit/grpc/isp"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	forwardedForHeader = "x-forwarded-for"
+	realIpHeader       = "x-real-ip"
+)
+
+// ClientIpMiddleware puts address of the end user, passed by the gateway, into request context,
+// X-Real-IP is used only if trustRealIp is set, because clients can pass it through the gateway, which does not overwrite it
+func ClientIpMiddleware(trustedProxies int, trustRealIp bool) grpc.Middleware {
+	if trustedProxies < 1 {
+		trustedProxies = 1
+	}
+	return func(next grpc.HandlerFunc) grpc.HandlerFunc {
+		return func(ctx context.Context, message *isp.Message) (*isp.Message, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			clientIp := clientIpFromMd(md, trustedProxies, trustRealIp)
+			if clientIp != "" {
+				ctx = domain.ContextWithClientIp(ctx, clientIp)
+			}
+			return next(ctx, message)
+		}
+	}
+}
+
+// clientIpFromMd prefers the address set by the gateway in X-Real-IP, if trustRealIp is set,
+// otherwise the address appended to X-Forwarded-For by the farthest of trustedProxies proxies is taken,
+// addresses to the left of it are passed by the client and can not be trusted
+func clientIpFromMd(md metadata.MD, trustedProxies int, trustRealIp bool) string {
+	realIp := md.Get(realIpHeader)
+	if trustRealIp && len(realIp) > 0 && strings.TrimSpace(realIp[0]) != "" {
+		return strings.TrimSpace(realIp[0])
+	}
+
+	addresses := make([]string, 0)
+	for _, forwardedFor := range md.Get(forwardedForHeader) {
+		for address := range strings.SplitSeq(forwardedFor, ",") {
+			addresses = append(addresses, strings.TrimSpace(address))
+		}
+	}
+	if len(addresses) < trustedProxies {
+		return ""
+	}
+	return addresses[len(addresses)-trustedProxies]
+}
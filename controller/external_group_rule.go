@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"msp-admin-service/domain"
+)
+
+type externalGroupRuleService interface {
+	All(ctx context.Context) ([]domain.ExternalGroupRule, error)
+	Create(ctx context.Context, req domain.CreateExternalGroupRuleRequest, adminId int64) (*domain.ExternalGroupRule, error)
+	Update(ctx context.Context, req domain.UpdateExternalGroupRuleRequest, adminId int64) (*domain.ExternalGroupRule, error)
+	Delete(ctx context.Context, req domain.DeleteExternalGroupRuleRequest, adminId int64) error
+	DryRun(ctx context.Context, req domain.ExternalGroupDryRunRequest) (*domain.ExternalGroupDryRunResponse, error)
+}
+
+type ExternalGroupRule struct {
+	service externalGroupRuleService
+}
+
+func NewExternalGroupRule(service externalGroupRuleService) ExternalGroupRule {
+	return ExternalGroupRule{
+		service: service,
+	}
+}
+
+// All
+// @Tags externalGroupRule
+// @Summary Список правил сопоставления внешних групп
+// @Description Получить список правил сопоставления внешних групп с ролями в порядке применения
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Success 200 {array} domain.ExternalGroupRule
+// @Failure 500 {object} domain.GrpcError
+// @Router /external_group_rule/all [POST]
+func (c ExternalGroupRule) All(ctx context.Context) ([]domain.ExternalGroupRule, error) {
+	rules, err := c.service.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get external group rules")
+	}
+	return rules, nil
+}
+
+// Create
+// @Tags externalGroupRule
+// @Summary Создать правило сопоставления внешних групп
+// @Description Создать правило сопоставления внешних групп с ролью
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.CreateExternalGroupRuleRequest true "Тело запроса"
+// @Success 200 {object} domain.ExternalGroupRule
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 404 {object} domain.GrpcError "Роль с указанным id не существует"
+// @Failure 500 {object} domain.GrpcError
+// @Router /external_group_rule/create [POST]
+func (c ExternalGroupRule) Create(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.CreateExternalGroupRuleRequest,
+) (*domain.ExternalGroupRule, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := c.service.Create(ctx, req, adminId)
+	switch {
+	case errors.Is(err, domain.ErrInvalid):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "role not found")
+	case err != nil:
+		return nil, errors.WithMessage(err, "create external group rule")
+	default:
+		return rule, nil
+	}
+}
+
+// Update
+// @Tags externalGroupRule
+// @Summary Обновить правило сопоставления внешних групп
+// @Description Обновить существующее правило сопоставления внешних групп с ролью
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.UpdateExternalGroupRuleRequest true "Тело запроса"
+// @Success 200 {object} domain.ExternalGroupRule
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 404 {object} domain.GrpcError "Правило или роль с указанным id не существует"
+// @Failure 500 {object} domain.GrpcError
+// @Router /external_group_rule/update [POST]
+func (c ExternalGroupRule) Update(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.UpdateExternalGroupRuleRequest,
+) (*domain.ExternalGroupRule, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := c.service.Update(ctx, req, adminId)
+	switch {
+	case errors.Is(err, domain.ErrInvalid):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "rule or role not found")
+	case err != nil:
+		return nil, errors.WithMessage(err, "update external group rule")
+	default:
+		return rule, nil
+	}
+}
+
+// Delete
+// @Tags externalGroupRule
+// @Summary Удалить правило сопоставления внешних групп
+// @Description Удалить существующее правило сопоставления внешних групп с ролью
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.DeleteExternalGroupRuleRequest true "Тело запроса"
+// @Success 200
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /external_group_rule/delete [POST]
+func (c ExternalGroupRule) Delete(ctx context.Context, authData grpc.AuthData, req domain.DeleteExternalGroupRuleRequest) error {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return err
+	}
+
+	err = c.service.Delete(ctx, req, adminId)
+	if err != nil {
+		return errors.WithMessage(err, "delete external group rule")
+	}
+	return nil
+}
+
+// DryRun
+// @Tags externalGroupRule
+// @Summary Проверить сопоставление внешних групп
+// @Description Возвращает роли, которые получит пользователь с указанным набором внешних групп
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.ExternalGroupDryRunRequest true "Тело запроса"
+// @Success 200 {object} domain.ExternalGroupDryRunResponse
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /external_group_rule/dry_run [POST]
+func (c ExternalGroupRule) DryRun(ctx context.Context, req domain.ExternalGroupDryRunRequest) (*domain.ExternalGroupDryRunResponse, error) {
+	result, err := c.service.DryRun(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "dry run external group rules")
+	}
+	return result, nil
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+
+	"msp-admin-service/domain"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc"
+	"github.com/txix-open/isp-kit/grpc/isp"
+	"github.com/txix-open/isp-kit/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type impersonationService interface {
+	Impersonate(ctx context.Context, adminId int64, req domain.ImpersonateRequest) (*domain.ImpersonateResponse, error)
+	End(ctx context.Context, token string) error
+	ImpersonatorId(ctx context.Context, token string, userId int64) (int64, bool, error)
+}
+
+type Impersonation struct {
+	service impersonationService
+	logger  log.Logger
+}
+
+func NewImpersonation(service impersonationService, logger log.Logger) Impersonation {
+	return Impersonation{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Impersonate
+// @Tags auth
+// @Summary Вход от имени пользователя
+// @Description Выпуск ограниченного по времени токена пользователя, действия по токену попадают в аудит с указанием администратора
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.ImpersonateRequest true "Тело запроса"
+// @Success 200 {object} domain.ImpersonateResponse
+// @Failure 400 {object} domain.GrpcError
+// @Failure 403 {object} domain.GrpcError "Вход от имени пользователя запрещен"
+// @Failure 404 {object} domain.GrpcError "Пользователь не найден"
+// @Failure 500 {object} domain.GrpcError
+// @Router /auth/impersonate [POST]
+func (c Impersonation) Impersonate(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.ImpersonateRequest,
+) (*domain.ImpersonateResponse, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.service.Impersonate(ctx, adminId, req)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, domain.ErrImpersonationDenied):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		return nil, errors.WithMessage(err, "impersonate")
+	default:
+		return resp, nil
+	}
+}
+
+// EndImpersonation
+// @Tags auth
+// @Summary Завершение входа от имени пользователя
+// @Description Отзыв токена, выпущенного методом /auth/impersonate
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен входа от имени пользователя"
+// @Success 200
+// @Failure 400 {object} domain.GrpcError
+// @Failure 412 {object} domain.GrpcError "Токен не является токеном входа от имени пользователя"
+// @Failure 500 {object} domain.GrpcError
+// @Router /auth/end_impersonation [POST]
+func (c Impersonation) EndImpersonation(ctx context.Context, authData grpc.AuthData) error {
+	token, err := grpc.StringFromMd(domain.AdminAuthHeaderName, metadata.MD(authData))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = c.service.End(ctx, token)
+	switch {
+	case errors.Is(err, domain.ErrTokenNotFound):
+		return status.Error(codes.NotFound, "token not found")
+	case errors.Is(err, domain.ErrNotImpersonated):
+		return status.Error(codes.FailedPrecondition, "session is not an impersonation")
+	case err != nil:
+		return errors.WithMessage(err, "end impersonation")
+	default:
+		return nil
+	}
+}
+
+// Middleware puts impersonator id into request context, so audit records show the real actor,
+// only requests authenticated by the gateway are checked, the request is treated as not impersonated,
+// if the token can not be checked
+func (c Impersonation) Middleware() grpc.Middleware {
+	return func(next grpc.HandlerFunc) grpc.HandlerFunc {
+		return func(ctx context.Context, message *isp.Message) (*isp.Message, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			token, err := grpc.StringFromMd(domain.AdminAuthHeaderName, md)
+			if err != nil || token == "" {
+				return next(ctx, message)
+			}
+			adminId, err := getAdminId(grpc.AuthData(md))
+			if err != nil {
+				return next(ctx, message)
+			}
+
+			impersonatorId, ok, err := c.service.ImpersonatorId(ctx, token, adminId)
+			if err != nil {
+				c.logger.Warn(ctx, "resolve impersonator", log.Int64("adminId", adminId), log.Any("error", err))
+			}
+			if ok {
+				ctx = domain.ContextWithImpersonator(ctx, impersonatorId)
+			}
+			return next(ctx, message)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"github.com/txix-open/isp-kit/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"msp-admin-service/domain"
+)
+
+type pendingChangeService interface {
+	All(ctx context.Context, req domain.PendingChangePageRequest) (*domain.PendingChangeResponse, error)
+	Approve(ctx context.Context, req domain.ReviewPendingChangeRequest, adminId int64) (*domain.PendingChange, error)
+	Reject(ctx context.Context, req domain.ReviewPendingChangeRequest, adminId int64) (*domain.PendingChange, error)
+}
+
+type PendingChange struct {
+	service pendingChangeService
+}
+
+func NewPendingChange(service pendingChangeService) PendingChange {
+	return PendingChange{
+		service: service,
+	}
+}
+
+// All
+// @Tags pendingChange
+// @Summary Список изменений, требующих подтверждения
+// @Description Неподтвержденные в срок изменения переводятся в статус `EXPIRED`
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.PendingChangePageRequest true "Тело запроса"
+// @Success 200 {object} domain.PendingChangeResponse
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /pending_change/all [POST]
+func (c PendingChange) All(ctx context.Context, req domain.PendingChangePageRequest) (*domain.PendingChangeResponse, error) {
+	result, err := c.service.All(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get pending changes")
+	}
+	return result, nil
+}
+
+// Approve
+// @Tags pendingChange
+// @Summary Подтвердить изменение
+// @Description Подтвердить и применить изменение от имени его автора, изменение должен подтвердить другой администратор
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.ReviewPendingChangeRequest true "Тело запроса"
+// @Success 200 {object} domain.PendingChange
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 403 {object} domain.GrpcError "Администратор подтверждает собственное изменение"
+// @Failure 404 {object} domain.GrpcError "Изменение или изменяемая сущность не существует"
+// @Failure 409 {object} domain.GrpcError "Сущность изменена после версии, на которой основано изменение (код 1005)"
+// @Failure 412 {object} domain.GrpcError "Изменение уже рассмотрено, истек срок подтверждения или роли нарушают разделение обязанностей"
+// @Failure 500 {object} domain.GrpcError
+// @Router /pending_change/approve [POST]
+func (c PendingChange) Approve(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.ReviewPendingChangeRequest,
+) (*domain.PendingChange, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.service.Approve(ctx, req, adminId)
+	if err != nil {
+		return nil, c.reviewError(err, "approve pending change")
+	}
+	return result, nil
+}
+
+// Reject
+// @Tags pendingChange
+// @Summary Отклонить изменение
+// @Description Отклонить изменение, ожидающее подтверждения
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.ReviewPendingChangeRequest true "Тело запроса"
+// @Success 200 {object} domain.PendingChange
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 403 {object} domain.GrpcError "Администратор отклоняет собственное изменение"
+// @Failure 404 {object} domain.GrpcError "Изменение не существует"
+// @Failure 412 {object} domain.GrpcError "Изменение уже рассмотрено или истек срок подтверждения"
+// @Failure 500 {object} domain.GrpcError
+// @Router /pending_change/reject [POST]
+func (c PendingChange) Reject(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.ReviewPendingChangeRequest,
+) (*domain.PendingChange, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.service.Reject(ctx, req, adminId)
+	if err != nil {
+		return nil, c.reviewError(err, "reject pending change")
+	}
+	return result, nil
+}
+
+func (c PendingChange) reviewError(err error, message string) error {
+	conflictErr := domain.VersionConflictError{}
+	sodErr := domain.SodViolationError{}
+	switch {
+	case errors.As(err, &conflictErr):
+		return versionConflictError(conflictErr)
+	case errors.As(err, &sodErr):
+		return sodViolationError(sodErr)
+	case errors.Is(err, domain.ErrPendingChangeDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		return status.Error(codes.NotFound, "pending change or changed entity not found")
+	case errors.Is(err, domain.ErrPendingChangeNotPending), errors.Is(err, domain.ErrRoleInUse):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return errors.WithMessage(err, message)
+	}
+}
+
+func approvalRequiredError(err domain.PendingChangeError) error {
+	return apierrors.New(codes.FailedPrecondition, domain.ErrCodeApprovalRequired, "change requires approval", err).
+		WithDetails(map[string]any{"pendingChangeId": err.Id}).
+		WithLogLevel(log.InfoLevel)
+}
@@ -3,11 +3,15 @@ package controller
 import (
 	"context"
 
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"msp-admin-service/domain"
 )
 
 type permissionsService interface {
-	All(ctx context.Context) []domain.Permission
+	Groups(ctx context.Context) ([]domain.PermissionGroup, error)
+	Register(ctx context.Context, req domain.RegisterPermissionsRequest) error
 }
 
 type Permissions struct {
@@ -23,11 +27,39 @@ func NewPermissions(permissionsService permissionsService) Permissions {
 // GetPermissions
 // @Tags user
 // @Summary Получить все разрашения
-// @Description Получить все разрашения
+// @Description Получить каталог разрешений, сгруппированный по категориям
 // @Accept json
 // @Produce json
-// @Success 200 {array} domain.Permission
+// @Success 200 {array} domain.PermissionGroup
+// @Failure 500 {object} domain.GrpcError
 // @Router /user/get_permissions [POST]
-func (u Permissions) GetPermissions(ctx context.Context) []domain.Permission {
-	return u.permissionsService.All(ctx)
+func (u Permissions) GetPermissions(ctx context.Context) ([]domain.PermissionGroup, error) {
+	result, err := u.permissionsService.Groups(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get permissions")
+	}
+	return result, nil
+}
+
+// Register
+// @Tags permission
+// @Summary Зарегистрировать разрешения модуля
+// @Description Добавить или обновить разрешения модуля `module` и исключить из каталога разрешения `deprecatedKeys`
+// @Accept json
+// @Produce json
+// @Param body body domain.RegisterPermissionsRequest true "Тело запроса"
+// @Success 200
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса или ключ разрешения является шаблоном"
+// @Failure 500 {object} domain.GrpcError
+// @Router /permission/register [POST]
+func (u Permissions) Register(ctx context.Context, req domain.RegisterPermissionsRequest) error {
+	err := u.permissionsService.Register(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrInvalidPermission):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return errors.WithMessage(err, "register permissions")
+	default:
+		return nil
+	}
 }
@@ -0,0 +1,239 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"msp-admin-service/domain"
+)
+
+type recertificationService interface {
+	Start(ctx context.Context, req domain.StartRecertificationRequest, adminId int64) (*domain.RecertificationCampaign, error)
+	All(ctx context.Context, req domain.RecertificationCampaignPageRequest) (*domain.RecertificationCampaignResponse, error)
+	Get(ctx context.Context, req domain.RecertificationIdRequest) (*domain.RecertificationCampaign, error)
+	Close(ctx context.Context, req domain.RecertificationIdRequest, adminId int64) (*domain.RecertificationCampaign, error)
+	Report(ctx context.Context, req domain.RecertificationIdRequest) (*domain.RecertificationReport, error)
+	Items(ctx context.Context, req domain.RecertificationItemPageRequest, adminId int64) (*domain.RecertificationItemResponse, error)
+	Decide(ctx context.Context, req domain.DecideRecertificationRequest, adminId int64) error
+}
+
+type Recertification struct {
+	service recertificationService
+}
+
+func NewRecertification(service recertificationService) Recertification {
+	return Recertification{
+		service: service,
+	}
+}
+
+// Start
+// @Tags recertification
+// @Summary Начать пересмотр доступа
+// @Description Создать пересмотр назначений ролей пользователям `userIds` и ролям `roleIds` с проверяющими и сроком
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.StartRecertificationRequest true "Тело запроса"
+// @Success 200 {object} domain.RecertificationCampaign
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса, срок или нет назначений ролей для пересмотра"
+// @Failure 500 {object} domain.GrpcError
+// @Router /recertification/start [POST]
+func (c Recertification) Start(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.StartRecertificationRequest,
+) (*domain.RecertificationCampaign, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.service.Start(ctx, req, adminId)
+	switch {
+	case errors.Is(err, domain.ErrRecertificationDeadline), errors.Is(err, domain.ErrRecertificationScope):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, errors.WithMessage(err, "start recertification")
+	default:
+		return result, nil
+	}
+}
+
+// All
+// @Tags recertification
+// @Summary Список пересмотров доступа
+// @Description Список пересмотров с прогрессом рассмотрения
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RecertificationCampaignPageRequest true "Тело запроса"
+// @Success 200 {object} domain.RecertificationCampaignResponse
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /recertification/all [POST]
+func (c Recertification) All(
+	ctx context.Context,
+	req domain.RecertificationCampaignPageRequest,
+) (*domain.RecertificationCampaignResponse, error) {
+	result, err := c.service.All(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get recertifications")
+	}
+	return result, nil
+}
+
+// Get
+// @Tags recertification
+// @Summary Получить пересмотр доступа
+// @Description Получить пересмотр с прогрессом рассмотрения
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RecertificationIdRequest true "Тело запроса"
+// @Success 200 {object} domain.RecertificationCampaign
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 404 {object} domain.GrpcError "Пересмотр с указанным id не существует"
+// @Failure 500 {object} domain.GrpcError
+// @Router /recertification/get [POST]
+func (c Recertification) Get(ctx context.Context, req domain.RecertificationIdRequest) (*domain.RecertificationCampaign, error) {
+	result, err := c.service.Get(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "recertification not found")
+	case err != nil:
+		return nil, errors.WithMessage(err, "get recertification")
+	default:
+		return result, nil
+	}
+}
+
+// Close
+// @Tags recertification
+// @Summary Завершить пересмотр доступа
+// @Description Завершить пересмотр до срока и отозвать роли с решением `REVOKE`, все назначения должны быть рассмотрены
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RecertificationIdRequest true "Тело запроса"
+// @Success 200 {object} domain.RecertificationCampaign
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 404 {object} domain.GrpcError "Пересмотр с указанным id не существует"
+// @Failure 412 {object} domain.GrpcError "Пересмотр уже завершен или есть нерассмотренные назначения"
+// @Failure 500 {object} domain.GrpcError
+// @Router /recertification/close [POST]
+func (c Recertification) Close(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.RecertificationIdRequest,
+) (*domain.RecertificationCampaign, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.service.Close(ctx, req, adminId)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "recertification not found")
+	case errors.Is(err, domain.ErrRecertificationClosed), errors.Is(err, domain.ErrRecertificationUndone):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, errors.WithMessage(err, "close recertification")
+	default:
+		return result, nil
+	}
+}
+
+// Report
+// @Tags recertification
+// @Summary Отчет по пересмотру доступа
+// @Description Решения по назначениям ролей в формате CSV
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RecertificationIdRequest true "Тело запроса"
+// @Success 200 {object} domain.RecertificationReport
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 404 {object} domain.GrpcError "Пересмотр с указанным id не существует"
+// @Failure 500 {object} domain.GrpcError
+// @Router /recertification/report [POST]
+func (c Recertification) Report(ctx context.Context, req domain.RecertificationIdRequest) (*domain.RecertificationReport, error) {
+	result, err := c.service.Report(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "recertification not found")
+	case err != nil:
+		return nil, errors.WithMessage(err, "get recertification report")
+	default:
+		return result, nil
+	}
+}
+
+// Items
+// @Tags recertification
+// @Summary Назначения ролей на пересмотре
+// @Description Назначения ролей пересмотров, где текущий пользователь проверяющий, с последней активностью пользователя
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RecertificationItemPageRequest true "Тело запроса"
+// @Success 200 {object} domain.RecertificationItemResponse
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /recertification/items [POST]
+func (c Recertification) Items(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.RecertificationItemPageRequest,
+) (*domain.RecertificationItemResponse, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.service.Items(ctx, req, adminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get recertification items")
+	}
+	return result, nil
+}
+
+// Decide
+// @Tags recertification
+// @Summary Рассмотреть назначения ролей
+// @Description Сохранить или отозвать роли, решение применяется при завершении пересмотра и может быть изменено до него
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.DecideRecertificationRequest true "Тело запроса"
+// @Success 200
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 403 {object} domain.GrpcError "Пользователь не является проверяющим или рассматривает собственную роль"
+// @Failure 404 {object} domain.GrpcError "Назначение с указанным id не существует"
+// @Failure 412 {object} domain.GrpcError "Пересмотр завершен"
+// @Failure 500 {object} domain.GrpcError
+// @Router /recertification/decide [POST]
+func (c Recertification) Decide(ctx context.Context, authData grpc.AuthData, req domain.DecideRecertificationRequest) error {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return err
+	}
+
+	err = c.service.Decide(ctx, req, adminId)
+	switch {
+	case errors.Is(err, domain.ErrRecertificationDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		return status.Error(codes.NotFound, "recertification item not found")
+	case errors.Is(err, domain.ErrRecertificationClosed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return errors.WithMessage(err, "decide recertification items")
+	default:
+		return nil
+	}
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/txix-open/isp-kit/grpc"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"github.com/txix-open/isp-kit/log"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"msp-admin-service/domain"
@@ -15,6 +17,20 @@ type roleService interface {
 	Create(ctx context.Context, req domain.CreateRoleRequest, adminId int64) (*domain.Role, error)
 	Update(ctx context.Context, req domain.UpdateRoleRequest, adminId int64) (*domain.Role, error)
 	Delete(ctx context.Context, req domain.DeleteRoleRequest, adminId int64) error
+	DeletePreview(ctx context.Context, req domain.RoleDeletePreviewRequest) (*domain.RoleDeletePreview, error)
+	UnknownPermissions(ctx context.Context) ([]domain.RoleUnknownPermissions, error)
+	RemoveUnknownPermissions(
+		ctx context.Context,
+		req domain.RemoveUnknownPermissionsRequest,
+		adminId int64,
+	) ([]domain.RoleUnknownPermissions, error)
+	Versions(ctx context.Context, req domain.RoleVersionsRequest) ([]domain.RoleVersion, error)
+	VersionDiff(ctx context.Context, req domain.RoleVersionDiffRequest) (*domain.RoleVersionDiff, error)
+	Rollback(ctx context.Context, req domain.RollbackRoleRequest, adminId int64) (*domain.Role, error)
+	Export(ctx context.Context, req domain.RbacExportRequest) (*domain.RbacExportResponse, error)
+	Import(ctx context.Context, req domain.RbacImportRequest, adminId int64) (*domain.RbacImportResponse, error)
+	Clone(ctx context.Context, req domain.CloneRoleRequest, adminId int64) (*domain.Role, error)
+	Templates(ctx context.Context) ([]domain.RoleTemplate, error)
 }
 
 type Role struct {
@@ -56,7 +72,7 @@ func (u Role) All(ctx context.Context) ([]domain.Role, error) {
 // @Param X-AUTH-ADMIN header string true "Токен администратора"
 // @Param body body domain.CreateRoleRequest true "Тело запроса"
 // @Success 200 {object} domain.User
-// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса, разрешение, разрешение вне каталога (код 1003) или родительская роль"
 // @Failure 409 {object} domain.GrpcError "Роль с указанным именем уже существует"
 // @Failure 500 {object} domain.GrpcError
 // @Router /role/create [POST]
@@ -67,9 +83,14 @@ func (u Role) CreateRole(ctx context.Context, authData grpc.AuthData, req domain
 	}
 
 	role, err := u.roleService.Create(ctx, req, adminId)
+	unknownErr := domain.UnknownPermissionsError{}
 	switch {
+	case errors.As(err, &unknownErr):
+		return nil, unknownPermissionsError(unknownErr)
 	case errors.Is(err, domain.ErrAlreadyExists):
 		return nil, status.Error(codes.AlreadyExists, "role with current name already exists")
+	case errors.Is(err, domain.ErrInvalidRoleParent), errors.Is(err, domain.ErrInvalidPermission):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	case err != nil:
 		return nil, errors.WithMessage(err, "create role")
 	default:
@@ -77,6 +98,62 @@ func (u Role) CreateRole(ctx context.Context, authData grpc.AuthData, req domain
 	}
 }
 
+// Clone
+// @Tags role
+// @Summary Копировать роль
+// @Description Создать роль с разрешениями и ограничениями существующей роли, группа ЕСК копируется только при `copyExternalGroup`
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.CloneRoleRequest true "Тело запроса"
+// @Success 200 {object} domain.Role
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса, разрешение или разрешение вне каталога (код 1003)"
+// @Failure 404 {object} domain.GrpcError "Роль с указанным id не существует"
+// @Failure 409 {object} domain.GrpcError "Роль с указанным именем уже существует"
+// @Failure 500 {object} domain.GrpcError
+// @Router /role/clone [POST]
+func (u Role) Clone(ctx context.Context, authData grpc.AuthData, req domain.CloneRoleRequest) (*domain.Role, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := u.roleService.Clone(ctx, req, adminId)
+	unknownErr := domain.UnknownPermissionsError{}
+	switch {
+	case errors.As(err, &unknownErr):
+		return nil, unknownPermissionsError(unknownErr)
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "role not found")
+	case errors.Is(err, domain.ErrAlreadyExists):
+		return nil, status.Error(codes.AlreadyExists, "role with current name already exists")
+	case errors.Is(err, domain.ErrInvalidRoleParent), errors.Is(err, domain.ErrInvalidPermission):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, errors.WithMessage(err, "clone role")
+	default:
+		return role, nil
+	}
+}
+
+// Templates
+// @Tags role
+// @Summary Шаблоны ролей
+// @Description Получить шаблоны ролей только для чтения, созданные из существующих ролей
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Success 200 {array} domain.RoleTemplate
+// @Failure 500 {object} domain.GrpcError
+// @Router /role/templates [POST]
+func (u Role) Templates(ctx context.Context) ([]domain.RoleTemplate, error) {
+	templates, err := u.roleService.Templates(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get role templates")
+	}
+	return templates, nil
+}
+
 // UpdateRole
 // @Tags role
 // @Summary Обновить роль
@@ -86,8 +163,10 @@ func (u Role) CreateRole(ctx context.Context, authData grpc.AuthData, req domain
 // @Param X-AUTH-ADMIN header string true "Токен администратора"
 // @Param body body domain.UpdateRoleRequest true "Тело запроса"
 // @Success 200 {object} domain.Role
+// @Failure 400 {object} domain.GrpcError "Невалидное разрешение, разрешение вне каталога (код 1003) или родительская роль"
 // @Failure 404 {object} domain.GrpcError "Роль с указанным id не существует"
-// @Failure 409 {object} domain.GrpcError "Роль с указанным именем уже существует"
+// @Failure 409 {object} domain.GrpcError "Роль с указанным именем уже существует или роль изменена после версии `version` (код 1005)"
+// @Failure 412 {object} domain.GrpcError "Изменение ожидает подтверждения вторым администратором или роль управляется конфигурацией"
 // @Failure 500 {object} domain.GrpcError
 // @Router /role/update [POST]
 func (u Role) UpdateRole(ctx context.Context, authData grpc.AuthData, req domain.UpdateRoleRequest) (*domain.Role, error) {
@@ -97,11 +176,24 @@ func (u Role) UpdateRole(ctx context.Context, authData grpc.AuthData, req domain
 	}
 
 	result, err := u.roleService.Update(ctx, req, adminId)
+	pendingErr := domain.PendingChangeError{}
+	unknownErr := domain.UnknownPermissionsError{}
+	conflictErr := domain.VersionConflictError{}
 	switch {
+	case errors.As(err, &pendingErr):
+		return nil, approvalRequiredError(pendingErr)
+	case errors.As(err, &unknownErr):
+		return nil, unknownPermissionsError(unknownErr)
+	case errors.As(err, &conflictErr):
+		return nil, versionConflictError(conflictErr)
 	case errors.Is(err, domain.ErrNotFound):
 		return nil, status.Error(codes.NotFound, "role not found")
+	case errors.Is(err, domain.ErrConfigManagedRole):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
 	case errors.Is(err, domain.ErrAlreadyExists):
 		return nil, status.Error(codes.AlreadyExists, "role with current name already exists")
+	case errors.Is(err, domain.ErrInvalidRoleParent), errors.Is(err, domain.ErrInvalidPermission):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	case err != nil:
 		return nil, errors.WithMessage(err, "update role")
 	default:
@@ -112,13 +204,15 @@ func (u Role) UpdateRole(ctx context.Context, authData grpc.AuthData, req domain
 // DeleteRole
 // @Tags role
 // @Summary Удалить роль
-// @Description Удалить существующую роль
+// @Description Удалить роль, роль пользователей удаляется только с переназначением `reassignToRoleId` или признаком `force`
 // @Accept json
 // @Produce json
 // @Param X-AUTH-ADMIN header string true "Токен администратора"
 // @Param body body domain.DeleteRoleRequest true "Тело запроса"
 // @Success 200
-// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса или роль для переназначения"
+// @Failure 404 {object} domain.GrpcError "Роль с указанным id не существует"
+// @Failure 412 {object} domain.GrpcError "Ожидает подтверждения, роль из конфигурации, назначена пользователям или нарушено разделение обязанностей"
 // @Failure 500 {object} domain.GrpcError
 // @Router /role/delete [POST]
 func (u Role) DeleteRole(ctx context.Context, authData grpc.AuthData, req domain.DeleteRoleRequest) error {
@@ -128,9 +222,255 @@ func (u Role) DeleteRole(ctx context.Context, authData grpc.AuthData, req domain
 	}
 
 	err = u.roleService.Delete(ctx, req, adminId)
-	if err != nil {
+	pendingErr := domain.PendingChangeError{}
+	sodErr := domain.SodViolationError{}
+	switch {
+	case errors.As(err, &pendingErr):
+		return approvalRequiredError(pendingErr)
+	case errors.As(err, &sodErr):
+		return sodViolationError(sodErr)
+	case errors.Is(err, domain.ErrNotFound):
+		return status.Error(codes.NotFound, "role not found")
+	case errors.Is(err, domain.ErrConfigManagedRole), errors.Is(err, domain.ErrRoleInUse):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, domain.ErrInvalidReassignRole):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
 		return errors.WithMessage(err, "delete")
+	default:
+		return nil
+	}
+}
+
+// DeletePreview
+// @Tags role
+// @Summary Пользователи удаляемой роли
+// @Description Получить количество и список пользователей, которым назначена роль
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RoleDeletePreviewRequest true "Тело запроса"
+// @Success 200 {object} domain.RoleDeletePreview
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 404 {object} domain.GrpcError "Роль с указанным id не существует"
+// @Failure 500 {object} domain.GrpcError
+// @Router /role/delete_preview [POST]
+func (u Role) DeletePreview(ctx context.Context, req domain.RoleDeletePreviewRequest) (*domain.RoleDeletePreview, error) {
+	result, err := u.roleService.DeletePreview(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "role not found")
+	case err != nil:
+		return nil, errors.WithMessage(err, "get role delete preview")
+	default:
+		return result, nil
+	}
+}
+
+// UnknownPermissions
+// @Tags role
+// @Summary Роли с разрешениями вне каталога
+// @Description Список ролей с разрешениями и ограничениями по ресурсам, которых нет в каталоге разрешений
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Success 200 {array} domain.RoleUnknownPermissions
+// @Failure 500 {object} domain.GrpcError
+// @Router /role/unknown_permissions [POST]
+func (u Role) UnknownPermissions(ctx context.Context) ([]domain.RoleUnknownPermissions, error) {
+	result, err := u.roleService.UnknownPermissions(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get roles with unknown permissions")
+	}
+	return result, nil
+}
+
+// RemoveUnknownPermissions
+// @Tags role
+// @Summary Удалить разрешения вне каталога
+// @Description Удалить из ролей `roleIds` (из всех, если не указаны) разрешения, которых нет в каталоге, неизменяемые роли пропускаются
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RemoveUnknownPermissionsRequest true "Тело запроса"
+// @Success 200 {array} domain.RoleUnknownPermissions
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /role/remove_unknown_permissions [POST]
+func (u Role) RemoveUnknownPermissions(
+	ctx context.Context,
+	authData grpc.AuthData,
+	req domain.RemoveUnknownPermissionsRequest,
+) ([]domain.RoleUnknownPermissions, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	result, err := u.roleService.RemoveUnknownPermissions(ctx, req, adminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "remove unknown permissions")
+	}
+	return result, nil
+}
+
+// Versions
+// @Tags role
+// @Summary История изменений роли
+// @Description Версии роли с состоянием после изменения, автором и причиной, начиная с последней
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RoleVersionsRequest true "Тело запроса"
+// @Success 200 {array} domain.RoleVersion
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /role/versions [POST]
+func (u Role) Versions(ctx context.Context, req domain.RoleVersionsRequest) ([]domain.RoleVersion, error) {
+	result, err := u.roleService.Versions(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get role versions")
+	}
+	return result, nil
+}
+
+// VersionDiff
+// @Tags role
+// @Summary Сравнить версии роли
+// @Description Изменения роли между версиями `fromVersion` и `toVersion`
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RoleVersionDiffRequest true "Тело запроса"
+// @Success 200 {object} domain.RoleVersionDiff
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 404 {object} domain.GrpcError "Версия роли не существует"
+// @Failure 500 {object} domain.GrpcError
+// @Router /role/version_diff [POST]
+func (u Role) VersionDiff(ctx context.Context, req domain.RoleVersionDiffRequest) (*domain.RoleVersionDiff, error) {
+	result, err := u.roleService.VersionDiff(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "role version not found")
+	case err != nil:
+		return nil, errors.WithMessage(err, "get role version diff")
+	default:
+		return result, nil
+	}
+}
+
+// Rollback
+// @Tags role
+// @Summary Откатить роль к версии
+// @Description Восстановить состояние существующей роли из версии `version`, откат сохраняется как новая версия
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RollbackRoleRequest true "Тело запроса"
+// @Success 200 {object} domain.Role
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса, разрешения версии вне каталога или родительская роль"
+// @Failure 404 {object} domain.GrpcError "Роль или версия не существует"
+// @Failure 409 {object} domain.GrpcError "Роль с именем из версии уже существует"
+// @Failure 412 {object} domain.GrpcError "Изменение ожидает подтверждения вторым администратором или роль управляется конфигурацией"
+// @Failure 500 {object} domain.GrpcError
+// @Router /role/rollback [POST]
+func (u Role) Rollback(ctx context.Context, authData grpc.AuthData, req domain.RollbackRoleRequest) (*domain.Role, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := u.roleService.Rollback(ctx, req, adminId)
+	pendingErr := domain.PendingChangeError{}
+	unknownErr := domain.UnknownPermissionsError{}
+	switch {
+	case errors.As(err, &pendingErr):
+		return nil, approvalRequiredError(pendingErr)
+	case errors.As(err, &unknownErr):
+		return nil, unknownPermissionsError(unknownErr)
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "role or role version not found")
+	case errors.Is(err, domain.ErrConfigManagedRole):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, domain.ErrAlreadyExists):
+		return nil, status.Error(codes.AlreadyExists, "role with current name already exists")
+	case errors.Is(err, domain.ErrInvalidRoleParent), errors.Is(err, domain.ErrInvalidPermission):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, errors.WithMessage(err, "rollback role")
+	default:
+		return result, nil
+	}
+}
+
+// Export
+// @Tags role
+// @Summary Экспорт конфигурации ролей
+// @Description Документ с ролями, разрешениями, группами ЕСК и признаками ролей в формате `json` или `yaml`, родители указываются по названию
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RbacExportRequest true "Тело запроса"
+// @Success 200 {object} domain.RbacExportResponse
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /role/export [POST]
+func (u Role) Export(ctx context.Context, req domain.RbacExportRequest) (*domain.RbacExportResponse, error) {
+	result, err := u.roleService.Export(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "export roles")
+	}
+	return result, nil
+}
+
+// Import
+// @Tags role
+// @Summary Импорт конфигурации ролей
+// @Description Роли сопоставляются по названию, отсутствующие в документе роли удаляются, изменения применяются в одной транзакции
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RbacImportRequest true "Тело запроса"
+// @Success 200 {object} domain.RbacImportResponse
+// @Failure 400 {object} domain.GrpcError "Невалидный документ, разрешения вне каталога или родительские роли"
+// @Failure 412 {object} domain.GrpcError "Изменение неизменяемых ролей или удаление назначенных ролей без `force`, изменение ролей из конфигурации"
+// @Failure 500 {object} domain.GrpcError
+// @Router /role/import [POST]
+func (u Role) Import(ctx context.Context, authData grpc.AuthData, req domain.RbacImportRequest) (*domain.RbacImportResponse, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := u.roleService.Import(ctx, req, adminId)
+	pendingErr := domain.PendingChangeError{}
+	unknownErr := domain.UnknownPermissionsError{}
+	switch {
+	case errors.As(err, &pendingErr):
+		return nil, approvalRequiredError(pendingErr)
+	case errors.As(err, &unknownErr):
+		return nil, unknownPermissionsError(unknownErr)
+	case errors.Is(err, domain.ErrInvalidRbacDocument),
+		errors.Is(err, domain.ErrInvalidRoleParent),
+		errors.Is(err, domain.ErrInvalidPermission):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrImmutableRole), errors.Is(err, domain.ErrConfigManagedRole), errors.Is(err, domain.ErrRoleInUse):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, errors.WithMessage(err, "import roles")
+	default:
+		return result, nil
+	}
+}
+
+func unknownPermissionsError(err domain.UnknownPermissionsError) error {
+	return apierrors.New(codes.InvalidArgument, domain.ErrCodeUnknownPermissions, "unknown permissions", err).
+		WithDetails(map[string]any{"unknownPermissions": err.Keys}).
+		WithLogLevel(log.InfoLevel)
+}
+
+func versionConflictError(err domain.VersionConflictError) error {
+	return apierrors.New(codes.Aborted, domain.ErrCodeVersionConflict, "entity was changed by another request", err).
+		WithDetails(map[string]any{"current": err.Current}).
+		WithLogLevel(log.InfoLevel)
 }
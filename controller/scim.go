@@ -0,0 +1,403 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/json"
+	"github.com/txix-open/isp-kit/log"
+	"msp-admin-service/domain"
+)
+
+// @title msp-admin-service SCIM
+// @version 1.0.0
+// @description SCIM 2.0 API (RFC 7644) для провайдеров учетных записей, обслуживается отдельным http сервером на scimBindingAddress
+
+// @license.name GNU GPL v3.0
+
+// @BasePath /scim/v2
+
+const scimContentType = "application/scim+json"
+
+type scimService interface {
+	ListUsers(ctx context.Context, req domain.ScimListRequest) (*domain.ScimListResponse, error)
+	GetUser(ctx context.Context, id string) (*domain.ScimUser, error)
+	CreateUser(ctx context.Context, req domain.ScimUser) (*domain.ScimUser, error)
+	ReplaceUser(ctx context.Context, id string, req domain.ScimUser) (*domain.ScimUser, error)
+	PatchUser(ctx context.Context, id string, req domain.ScimPatchRequest) (*domain.ScimUser, error)
+	DeleteUser(ctx context.Context, id string) error
+	ListGroups(ctx context.Context, req domain.ScimListRequest) (*domain.ScimListResponse, error)
+	GetGroup(ctx context.Context, id string) (*domain.ScimGroup, error)
+	CreateGroup(ctx context.Context, req domain.ScimGroup) (*domain.ScimGroup, error)
+	ReplaceGroup(ctx context.Context, id string, req domain.ScimGroup) (*domain.ScimGroup, error)
+	PatchGroup(ctx context.Context, id string, req domain.ScimPatchRequest) (*domain.ScimGroup, error)
+	DeleteGroup(ctx context.Context, id string) error
+}
+
+// Scim serves SCIM 2.0 REST API (RFC 7644), it is not exposed through grpc
+type Scim struct {
+	scimService scimService
+	logger      log.Logger
+}
+
+func NewScim(scimService scimService, logger log.Logger) Scim {
+	return Scim{
+		scimService: scimService,
+		logger:      logger,
+	}
+}
+
+// ListUsers
+// @Tags scim
+// @Summary Список пользователей
+// @Description Получить пользователей с фильтром и постраничным выводом
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param filter query string false "Фильтр, например userName eq \"user@example.com\""
+// @Param startIndex query int false "Номер первого элемента, начиная с 1"
+// @Param count query int false "Количество элементов"
+// @Success 200 {object} domain.ScimListResponse
+// @Failure 400 {object} domain.ScimErrorResponse "Невалидное тело запроса, фильтр или нарушение разделения обязанностей"
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Users [GET]
+func (c Scim) ListUsers(w http.ResponseWriter, r *http.Request) {
+	resp, err := c.scimService.ListUsers(r.Context(), scimListRequest(r))
+	c.write(w, r, http.StatusOK, resp, err)
+}
+
+// GetUser
+// @Tags scim
+// @Summary Получить пользователя
+// @Description Получить пользователя по идентификатору
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param id path string true "Идентификатор"
+// @Success 200 {object} domain.ScimUser
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 404 {object} domain.ScimErrorResponse "Ресурс не найден"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Users/{id} [GET]
+func (c Scim) GetUser(w http.ResponseWriter, r *http.Request) {
+	resp, err := c.scimService.GetUser(r.Context(), r.PathValue("id"))
+	c.write(w, r, http.StatusOK, resp, err)
+}
+
+// CreateUser
+// @Tags scim
+// @Summary Создать пользователя
+// @Description Создать пользователя, неактивный пользователь создается заблокированным
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param body body domain.ScimUser true "Тело запроса"
+// @Success 201 {object} domain.ScimUser
+// @Success 202 {object} domain.ScimErrorResponse "Изменение ожидает подтверждения второго администратора"
+// @Failure 400 {object} domain.ScimErrorResponse "Невалидное тело запроса, фильтр или нарушение разделения обязанностей"
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 409 {object} domain.ScimErrorResponse "Ресурс с указанным именем уже существует"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Users [POST]
+func (c Scim) CreateUser(w http.ResponseWriter, r *http.Request) {
+	req := domain.ScimUser{}
+	if !c.readBody(w, r, &req) {
+		return
+	}
+	resp, err := c.scimService.CreateUser(r.Context(), req)
+	c.write(w, r, http.StatusCreated, resp, err)
+}
+
+// ReplaceUser
+// @Tags scim
+// @Summary Заменить пользователя
+// @Description Заменить данные пользователя, active=false блокирует пользователя
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param id path string true "Идентификатор"
+// @Param body body domain.ScimUser true "Тело запроса"
+// @Success 200 {object} domain.ScimUser
+// @Success 202 {object} domain.ScimErrorResponse "Изменение ожидает подтверждения второго администратора"
+// @Failure 400 {object} domain.ScimErrorResponse "Невалидное тело запроса, фильтр или нарушение разделения обязанностей"
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 404 {object} domain.ScimErrorResponse "Ресурс не найден"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Users/{id} [PUT]
+func (c Scim) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	req := domain.ScimUser{}
+	if !c.readBody(w, r, &req) {
+		return
+	}
+	resp, err := c.scimService.ReplaceUser(r.Context(), r.PathValue("id"), req)
+	c.write(w, r, http.StatusOK, resp, err)
+}
+
+// PatchUser
+// @Tags scim
+// @Summary Изменить пользователя
+// @Description Изменить атрибуты пользователя операциями add, replace, remove
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param id path string true "Идентификатор"
+// @Param body body domain.ScimPatchRequest true "Тело запроса"
+// @Success 200 {object} domain.ScimUser
+// @Success 202 {object} domain.ScimErrorResponse "Изменение ожидает подтверждения второго администратора"
+// @Failure 400 {object} domain.ScimErrorResponse "Невалидное тело запроса, фильтр или нарушение разделения обязанностей"
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 404 {object} domain.ScimErrorResponse "Ресурс не найден"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Users/{id} [PATCH]
+func (c Scim) PatchUser(w http.ResponseWriter, r *http.Request) {
+	req := domain.ScimPatchRequest{}
+	if !c.readBody(w, r, &req) {
+		return
+	}
+	resp, err := c.scimService.PatchUser(r.Context(), r.PathValue("id"), req)
+	c.write(w, r, http.StatusOK, resp, err)
+}
+
+// DeleteUser
+// @Tags scim
+// @Summary Удалить пользователя
+// @Description Удалить пользователя
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param id path string true "Идентификатор"
+// @Success 204
+// @Success 202 {object} domain.ScimErrorResponse "Изменение ожидает подтверждения второго администратора"
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 404 {object} domain.ScimErrorResponse "Ресурс не найден"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Users/{id} [DELETE]
+func (c Scim) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	err := c.scimService.DeleteUser(r.Context(), r.PathValue("id"))
+	c.write(w, r, http.StatusNoContent, nil, err)
+}
+
+// ListGroups
+// @Tags scim
+// @Summary Список групп
+// @Description Получить роли как группы с участниками, с фильтром и постраничным выводом
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param filter query string false "Фильтр, например userName eq \"user@example.com\""
+// @Param startIndex query int false "Номер первого элемента, начиная с 1"
+// @Param count query int false "Количество элементов"
+// @Success 200 {object} domain.ScimListResponse
+// @Failure 400 {object} domain.ScimErrorResponse "Невалидное тело запроса, фильтр или нарушение разделения обязанностей"
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Groups [GET]
+func (c Scim) ListGroups(w http.ResponseWriter, r *http.Request) {
+	resp, err := c.scimService.ListGroups(r.Context(), scimListRequest(r))
+	c.write(w, r, http.StatusOK, resp, err)
+}
+
+// GetGroup
+// @Tags scim
+// @Summary Получить группу
+// @Description Получить роль как группу с участниками
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param id path string true "Идентификатор"
+// @Success 200 {object} domain.ScimGroup
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 404 {object} domain.ScimErrorResponse "Ресурс не найден"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Groups/{id} [GET]
+func (c Scim) GetGroup(w http.ResponseWriter, r *http.Request) {
+	resp, err := c.scimService.GetGroup(r.Context(), r.PathValue("id"))
+	c.write(w, r, http.StatusOK, resp, err)
+}
+
+// CreateGroup
+// @Tags scim
+// @Summary Создать группу
+// @Description Создать роль и назначить ее участникам группы
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param body body domain.ScimGroup true "Тело запроса"
+// @Success 201 {object} domain.ScimGroup
+// @Success 202 {object} domain.ScimErrorResponse "Изменение ожидает подтверждения второго администратора"
+// @Failure 400 {object} domain.ScimErrorResponse "Невалидное тело запроса, фильтр или нарушение разделения обязанностей"
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 409 {object} domain.ScimErrorResponse "Ресурс с указанным именем уже существует"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Groups [POST]
+func (c Scim) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	req := domain.ScimGroup{}
+	if !c.readBody(w, r, &req) {
+		return
+	}
+	resp, err := c.scimService.CreateGroup(r.Context(), req)
+	c.write(w, r, http.StatusCreated, resp, err)
+}
+
+// ReplaceGroup
+// @Tags scim
+// @Summary Заменить группу
+// @Description Переименовать роль и заменить список ее участников
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param id path string true "Идентификатор"
+// @Param body body domain.ScimGroup true "Тело запроса"
+// @Success 200 {object} domain.ScimGroup
+// @Success 202 {object} domain.ScimErrorResponse "Изменение ожидает подтверждения второго администратора"
+// @Failure 400 {object} domain.ScimErrorResponse "Невалидное тело запроса, фильтр или нарушение разделения обязанностей"
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 404 {object} domain.ScimErrorResponse "Ресурс не найден"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Groups/{id} [PUT]
+func (c Scim) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	req := domain.ScimGroup{}
+	if !c.readBody(w, r, &req) {
+		return
+	}
+	resp, err := c.scimService.ReplaceGroup(r.Context(), r.PathValue("id"), req)
+	c.write(w, r, http.StatusOK, resp, err)
+}
+
+// PatchGroup
+// @Tags scim
+// @Summary Изменить группу
+// @Description Изменить название роли или участников операциями add, replace, remove
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param id path string true "Идентификатор"
+// @Param body body domain.ScimPatchRequest true "Тело запроса"
+// @Success 200 {object} domain.ScimGroup
+// @Success 202 {object} domain.ScimErrorResponse "Изменение ожидает подтверждения второго администратора"
+// @Failure 400 {object} domain.ScimErrorResponse "Невалидное тело запроса, фильтр или нарушение разделения обязанностей"
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 404 {object} domain.ScimErrorResponse "Ресурс не найден"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Groups/{id} [PATCH]
+func (c Scim) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	req := domain.ScimPatchRequest{}
+	if !c.readBody(w, r, &req) {
+		return
+	}
+	resp, err := c.scimService.PatchGroup(r.Context(), r.PathValue("id"), req)
+	c.write(w, r, http.StatusOK, resp, err)
+}
+
+// DeleteGroup
+// @Tags scim
+// @Summary Удалить группу
+// @Description Удалить роль, роль отзывается у всех пользователей
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Param id path string true "Идентификатор"
+// @Success 204
+// @Success 202 {object} domain.ScimErrorResponse "Изменение ожидает подтверждения второго администратора"
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 404 {object} domain.ScimErrorResponse "Ресурс не найден"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /Groups/{id} [DELETE]
+func (c Scim) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	err := c.scimService.DeleteGroup(r.Context(), r.PathValue("id"))
+	c.write(w, r, http.StatusNoContent, nil, err)
+}
+
+// ServiceProviderConfig
+// @Tags scim
+// @Summary Возможности сервиса
+// @Description Получить поддерживаемые возможности SCIM
+// @Produce application/scim+json
+// @Param Authorization header string true "Bearer и токен из настройки scim.bearerToken"
+// @Success 200 {object} map[string]any
+// @Failure 401 {object} domain.ScimErrorResponse "Неверный токен"
+// @Failure 500 {object} domain.ScimErrorResponse
+// @Router /ServiceProviderConfig [GET]
+func (c Scim) ServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	c.write(w, r, http.StatusOK, map[string]any{
+		"schemas":        []string{domain.ScimSchemaProviderConfig},
+		"patch":          map[string]any{"supported": true},
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": 1000},
+		"changePassword": map[string]any{"supported": false},
+		"sort":           map[string]any{"supported": false},
+		"etag":           map[string]any{"supported": false},
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "Authentication with static bearer token",
+		}},
+	}, nil)
+}
+
+func (c Scim) readBody(w http.ResponseWriter, r *http.Request, req any) bool {
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		c.write(w, r, 0, nil, domain.ScimError{
+			Status:   http.StatusBadRequest,
+			ScimType: domain.ScimErrorTypeInvalidValue,
+			Detail:   "invalid json body",
+		})
+		return false
+	}
+	return true
+}
+
+func (c Scim) write(w http.ResponseWriter, r *http.Request, statusCode int, resp any, err error) {
+	if err != nil {
+		scimErr := domain.ScimError{}
+		sodErr := domain.SodViolationError{}
+		pendingErr := domain.PendingChangeError{}
+		switch {
+		case errors.As(err, &scimErr):
+		case errors.As(err, &pendingErr):
+			scimErr = domain.ScimError{Status: http.StatusAccepted, Detail: pendingErr.Error()}
+		case errors.As(err, &sodErr):
+			scimErr = domain.ScimError{
+				Status:   http.StatusBadRequest,
+				ScimType: domain.ScimErrorTypeInvalidValue,
+				Detail:   sodErr.Error(),
+			}
+		case errors.Is(err, domain.ErrNotFound):
+			scimErr = domain.ScimError{Status: http.StatusNotFound, Detail: "resource not found"}
+		case errors.Is(err, domain.ErrAlreadyExists):
+			scimErr = domain.ScimError{
+				Status:   http.StatusConflict,
+				ScimType: domain.ScimErrorTypeUniqueness,
+				Detail:   "resource already exists",
+			}
+		default:
+			c.logger.Error(r.Context(), "scim request", log.String("path", r.URL.Path), log.Any("error", err))
+			scimErr = domain.ScimError{Status: http.StatusInternalServerError, Detail: "internal error"}
+		}
+
+		statusCode = scimErr.Status
+		resp = domain.ScimErrorResponse{
+			Schemas:  []string{domain.ScimSchemaError},
+			Status:   strconv.Itoa(scimErr.Status),
+			ScimType: scimErr.ScimType,
+			Detail:   scimErr.Detail,
+		}
+	}
+
+	if statusCode == http.StatusNoContent {
+		w.WriteHeader(statusCode)
+		return
+	}
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func scimListRequest(r *http.Request) domain.ScimListRequest {
+	query := r.URL.Query()
+	startIndex, _ := strconv.Atoi(query.Get("startIndex"))
+	count, _ := strconv.Atoi(query.Get("count"))
+	return domain.ScimListRequest{
+		Filter:     query.Get("filter"),
+		StartIndex: startIndex,
+		Count:      count,
+	}
+}
@@ -10,7 +10,7 @@ import (
 
 type SecureService interface {
 	Authenticate(ctx context.Context, token string) (int64, error)
-	Authorize(ctx context.Context, adminId int, permission string) (bool, error)
+	Authorize(ctx context.Context, adminId int, permission string, resource *domain.ResourceRef) (*domain.SecureAuthzResponse, error)
 }
 
 type Secure struct {
@@ -62,7 +62,7 @@ func (s Secure) Authenticate(ctx context.Context, req domain.SecureAuthRequest)
 // Authorize
 // @Tags secure
 // @Summary Метод авторизации для администратора
-// @Description Проверяет наличие у администратора необходимого разрешения
+// @Description Проверяет наличие у администратора разрешения с учетом ограничений ролей по сетям, времени и ресурсам `resource`
 // @Accept json
 // @Produce json
 // @Param body body domain.SecureAuthzRequest true "Тело запроса"
@@ -70,11 +70,13 @@ func (s Secure) Authenticate(ctx context.Context, req domain.SecureAuthRequest)
 // @Failure 500 {object} domain.GrpcError
 // @Router /secure/authorize [POST]
 func (s Secure) Authorize(ctx context.Context, req domain.SecureAuthzRequest) (*domain.SecureAuthzResponse, error) {
-	ok, err := s.service.Authorize(ctx, req.AdminId, req.Permission)
+	if req.ClientIp != "" {
+		ctx = domain.ContextWithClientIp(ctx, req.ClientIp)
+	}
+
+	result, err := s.service.Authorize(ctx, req.AdminId, req.Permission, req.Resource)
 	if err != nil {
 		return nil, apierrors.NewInternalServiceError(err)
 	}
-	return &domain.SecureAuthzResponse{
-		Authorized: ok,
-	}, nil
+	return result, nil
 }
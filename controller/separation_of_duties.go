@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"github.com/txix-open/isp-kit/log"
+	"google.golang.org/grpc/codes"
+	"msp-admin-service/domain"
+)
+
+type separationOfDutiesService interface {
+	Violations(ctx context.Context, req domain.SodViolationsRequest) (*domain.SodViolationsResponse, error)
+}
+
+type SeparationOfDuties struct {
+	service separationOfDutiesService
+}
+
+func NewSeparationOfDuties(service separationOfDutiesService) SeparationOfDuties {
+	return SeparationOfDuties{
+		service: service,
+	}
+}
+
+// Violations
+// @Tags separationOfDuties
+// @Summary Нарушения разделения обязанностей
+// @Description Список пользователей, которым назначено несколько взаимоисключающих ролей, например после добавления ограничения
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.SodViolationsRequest true "Тело запроса"
+// @Success 200 {object} domain.SodViolationsResponse
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 500 {object} domain.GrpcError
+// @Router /separation_of_duties/violations [POST]
+func (c SeparationOfDuties) Violations(ctx context.Context, req domain.SodViolationsRequest) (*domain.SodViolationsResponse, error) {
+	result, err := c.service.Violations(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get separation of duties violations")
+	}
+	return result, nil
+}
+
+func sodViolationError(err domain.SodViolationError) error {
+	return apierrors.New(codes.FailedPrecondition, domain.ErrCodeSodViolation, "separation of duties violation", err).
+		WithDetails(map[string]any{"constraint": err.Constraint, "roles": err.Roles, "permissions": err.Permissions}).
+		WithLogLevel(log.InfoLevel)
+}
@@ -19,6 +19,7 @@ type userService interface {
 	GetAllUsers(ctx context.Context) (*domain.UsersResponse, error)
 	CreateUser(ctx context.Context, req domain.CreateUserRequest, adminId int64) (*domain.User, error)
 	UpdateUser(ctx context.Context, req domain.UpdateUserRequest, adminId int64) (*domain.User, error)
+	GrantRole(ctx context.Context, req domain.GrantRoleRequest, adminId int64) (*domain.User, error)
 	DeleteUsers(ctx context.Context, ids []int64, adminId int64) (int, error)
 	Block(ctx context.Context, adminId int64, userId int) error
 	GetById(ctx context.Context, userId int) (*domain.User, error)
@@ -125,6 +126,7 @@ func (u User) GetAllUsers(ctx context.Context) (*domain.UsersResponse, error) {
 // @Success 200 {object} domain.User
 // @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
 // @Failure 409 {object} domain.GrpcError "Пользователь с указанным email уже существует"
+// @Failure 412 {object} domain.GrpcError "Назначение привилегированной роли ожидает подтверждения или роли нарушают разделение обязанностей"
 // @Failure 500 {object} domain.GrpcError
 // @Router /user/create_user [POST]
 func (u User) CreateUser(ctx context.Context, authData grpc.AuthData, req domain.CreateUserRequest) (*domain.User, error) {
@@ -134,7 +136,13 @@ func (u User) CreateUser(ctx context.Context, authData grpc.AuthData, req domain
 	}
 
 	user, err := u.userService.CreateUser(ctx, req, adminId)
+	pendingErr := domain.PendingChangeError{}
+	sodErr := domain.SodViolationError{}
 	switch {
+	case errors.As(err, &pendingErr):
+		return nil, approvalRequiredError(pendingErr)
+	case errors.As(err, &sodErr):
+		return nil, sodViolationError(sodErr)
 	case errors.Is(err, domain.ErrAlreadyExists):
 		return nil, status.Error(codes.AlreadyExists, "user with the same email already exists")
 	case err != nil:
@@ -153,9 +161,10 @@ func (u User) CreateUser(ctx context.Context, authData grpc.AuthData, req domain
 // @Param X-AUTH-ADMIN header string true "Токен администратора"
 // @Param body body domain.UpdateUserRequest true "Тело запроса"
 // @Success 200 {object} domain.User
-// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса или срок действия роли"
 // @Failure 404 {object} domain.GrpcError "Пользователь с указанным id не существует"
-// @Failure 409 {object} domain.GrpcError "Пользователь с указанным email уже существует"
+// @Failure 409 {object} domain.GrpcError "Пользователь с указанным email уже существует или пользователь изменен после версии `version` (код 1005)"
+// @Failure 412 {object} domain.GrpcError "Назначение привилегированной роли ожидает подтверждения или роли нарушают разделение обязанностей"
 // @Failure 500 {object} domain.GrpcError
 // @Router /user/update_user [POST]
 func (u User) UpdateUser(ctx context.Context, authData grpc.AuthData, req domain.UpdateUserRequest) (*domain.User, error) {
@@ -165,9 +174,20 @@ func (u User) UpdateUser(ctx context.Context, authData grpc.AuthData, req domain
 	}
 
 	result, err := u.userService.UpdateUser(ctx, req, adminId)
+	pendingErr := domain.PendingChangeError{}
+	sodErr := domain.SodViolationError{}
+	conflictErr := domain.VersionConflictError{}
 	switch {
+	case errors.As(err, &pendingErr):
+		return nil, approvalRequiredError(pendingErr)
+	case errors.As(err, &sodErr):
+		return nil, sodViolationError(sodErr)
+	case errors.As(err, &conflictErr):
+		return nil, versionConflictError(conflictErr)
 	case errors.Is(err, domain.ErrNotFound):
 		return nil, status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, domain.ErrInvalidRolePeriod):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	case errors.Is(err, domain.ErrInvalid):
 		return nil, status.Error(codes.InvalidArgument, "user modification is not available")
 	case errors.Is(err, domain.ErrAlreadyExists):
@@ -179,6 +199,45 @@ func (u User) UpdateUser(ctx context.Context, authData grpc.AuthData, req domain
 	}
 }
 
+// GrantRole
+// @Tags user
+// @Summary Назначить роль пользователю
+// @Description Назначить роль пользователю бессрочно или на срок, повторное назначение заменяет срок действия роли
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.GrantRoleRequest true "Тело запроса"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса или срок действия роли"
+// @Failure 404 {object} domain.GrpcError "Пользователь или роль не существует"
+// @Failure 412 {object} domain.GrpcError "Назначение привилегированной роли ожидает подтверждения или роли нарушают разделение обязанностей"
+// @Failure 500 {object} domain.GrpcError
+// @Router /user/grant_role [POST]
+func (u User) GrantRole(ctx context.Context, authData grpc.AuthData, req domain.GrantRoleRequest) (*domain.User, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := u.userService.GrantRole(ctx, req, adminId)
+	pendingErr := domain.PendingChangeError{}
+	sodErr := domain.SodViolationError{}
+	switch {
+	case errors.As(err, &pendingErr):
+		return nil, approvalRequiredError(pendingErr)
+	case errors.As(err, &sodErr):
+		return nil, sodViolationError(sodErr)
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "user or role not found")
+	case errors.Is(err, domain.ErrInvalidRolePeriod):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, errors.WithMessage(err, "grant role")
+	default:
+		return result, nil
+	}
+}
+
 // DeleteUser
 // @Tags user
 // @Summary Удалить пользователя
@@ -189,6 +248,7 @@ func (u User) UpdateUser(ctx context.Context, authData grpc.AuthData, req domain
 // @Param body body domain.IdentitiesRequest true "Тело запроса"
 // @Success 200 {object} domain.DeleteResponse
 // @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 412 {object} domain.GrpcError "Удаление ожидает подтверждения вторым администратором"
 // @Failure 500 {object} domain.GrpcError
 // @Router /user/delete_user [POST]
 func (u User) DeleteUser(ctx context.Context, authData grpc.AuthData, identities domain.IdentitiesRequest) (*domain.DeleteResponse, error) {
@@ -198,11 +258,15 @@ func (u User) DeleteUser(ctx context.Context, authData grpc.AuthData, identities
 	}
 
 	deletedCount, err := u.userService.DeleteUsers(ctx, identities.Ids, adminId)
-	if err != nil {
+	pendingErr := domain.PendingChangeError{}
+	switch {
+	case errors.As(err, &pendingErr):
+		return nil, approvalRequiredError(pendingErr)
+	case err != nil:
 		return nil, errors.WithMessage(err, "delete")
+	default:
+		return &domain.DeleteResponse{Deleted: deletedCount}, nil
 	}
-
-	return &domain.DeleteResponse{Deleted: deletedCount}, nil
 }
 
 // Block
@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"context"
+
+	"msp-admin-service/domain"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type webauthnService interface {
+	BeginRegistration(ctx context.Context, adminId int64) (*domain.WebauthnChallenge, error)
+	FinishRegistration(
+		ctx context.Context,
+		adminId int64,
+		request domain.WebauthnFinishRegistrationRequest,
+	) (*domain.WebauthnCredential, error)
+	Credentials(ctx context.Context, adminId int64) ([]domain.WebauthnCredential, error)
+	Rename(ctx context.Context, adminId int64, request domain.RenameWebauthnCredentialRequest) (*domain.WebauthnCredential, error)
+	Delete(ctx context.Context, adminId int64, request domain.DeleteWebauthnCredentialRequest) error
+}
+
+type Webauthn struct {
+	service webauthnService
+}
+
+func NewWebauthn(service webauthnService) Webauthn {
+	return Webauthn{
+		service: service,
+	}
+}
+
+// BeginRegistration
+// @Tags webauthn
+// @Summary Начало регистрации ключа доступа
+// @Description Получение параметров для navigator.credentials.create() для текущего пользователя
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Success 200 {object} domain.WebauthnChallenge
+// @Failure 400 {object} domain.GrpcError "Ключи доступа недоступны для пользователей СУДИР и LDAP"
+// @Failure 403 {object} domain.GrpcError "Регистрация ключа запрещена при входе от имени пользователя"
+// @Failure 412 {object} domain.GrpcError "Вход по ключам доступа не настроен на сервере"
+// @Failure 500 {object} domain.GrpcError
+// @Router /user/webauthn/begin_registration [POST]
+func (c Webauthn) BeginRegistration(ctx context.Context, authData grpc.AuthData) (*domain.WebauthnChallenge, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := c.service.BeginRegistration(ctx, adminId)
+	switch {
+	case errors.Is(err, domain.ErrWebauthnIsMissed):
+		return nil, status.Error(codes.FailedPrecondition, "webauthn auth is not configured")
+	case errors.Is(err, domain.ErrSudirAuthorization):
+		return nil, status.Error(codes.InvalidArgument, "webauthn auth is not available")
+	case errors.Is(err, domain.ErrImpersonationDenied):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		return nil, errors.WithMessage(err, "begin webauthn registration")
+	default:
+		return challenge, nil
+	}
+}
+
+// FinishRegistration
+// @Tags webauthn
+// @Summary Регистрация ключа доступа
+// @Description Проверка ответа navigator.credentials.create() и сохранение ключа доступа текущего пользователя
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.WebauthnFinishRegistrationRequest true "Тело запроса"
+// @Success 200 {object} domain.WebauthnCredential
+// @Failure 400 {object} domain.GrpcError "Ответ ключа доступа не прошел проверку"
+// @Failure 409 {object} domain.GrpcError "Ключ доступа уже зарегистрирован"
+// @Failure 412 {object} domain.GrpcError "Вход по ключам доступа не настроен на сервере"
+// @Failure 500 {object} domain.GrpcError
+// @Router /user/webauthn/finish_registration [POST]
+func (c Webauthn) FinishRegistration(
+	ctx context.Context,
+	authData grpc.AuthData,
+	request domain.WebauthnFinishRegistrationRequest,
+) (*domain.WebauthnCredential, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := c.service.FinishRegistration(ctx, adminId, request)
+	switch {
+	case errors.Is(err, domain.ErrWebauthnIsMissed):
+		return nil, status.Error(codes.FailedPrecondition, "webauthn auth is not configured")
+	case errors.Is(err, domain.ErrInvalid):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrAlreadyExists):
+		return nil, status.Error(codes.AlreadyExists, "credential already registered")
+	case err != nil:
+		return nil, errors.WithMessage(err, "finish webauthn registration")
+	default:
+		return credential, nil
+	}
+}
+
+// Credentials
+// @Tags webauthn
+// @Summary Список ключей доступа
+// @Description Получить список ключей доступа текущего пользователя
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Success 200 {array} domain.WebauthnCredential
+// @Failure 500 {object} domain.GrpcError
+// @Router /user/webauthn/credentials [POST]
+func (c Webauthn) Credentials(ctx context.Context, authData grpc.AuthData) ([]domain.WebauthnCredential, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := c.service.Credentials(ctx, adminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get webauthn credentials")
+	}
+	return credentials, nil
+}
+
+// RenameCredential
+// @Tags webauthn
+// @Summary Переименовать ключ доступа
+// @Description Изменить название ключа доступа текущего пользователя
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.RenameWebauthnCredentialRequest true "Тело запроса"
+// @Success 200 {object} domain.WebauthnCredential
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 404 {object} domain.GrpcError "Ключ доступа не найден"
+// @Failure 500 {object} domain.GrpcError
+// @Router /user/webauthn/rename_credential [POST]
+func (c Webauthn) RenameCredential(
+	ctx context.Context,
+	authData grpc.AuthData,
+	request domain.RenameWebauthnCredentialRequest,
+) (*domain.WebauthnCredential, error) {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := c.service.Rename(ctx, adminId, request)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "credential not found")
+	case err != nil:
+		return nil, errors.WithMessage(err, "rename webauthn credential")
+	default:
+		return credential, nil
+	}
+}
+
+// DeleteCredential
+// @Tags webauthn
+// @Summary Удалить ключ доступа
+// @Description Удалить ключ доступа текущего пользователя
+// @Accept json
+// @Produce json
+// @Param X-AUTH-ADMIN header string true "Токен администратора"
+// @Param body body domain.DeleteWebauthnCredentialRequest true "Тело запроса"
+// @Success 200
+// @Failure 400 {object} domain.GrpcError "Невалидное тело запроса"
+// @Failure 404 {object} domain.GrpcError "Ключ доступа не найден"
+// @Failure 500 {object} domain.GrpcError
+// @Router /user/webauthn/delete_credential [POST]
+func (c Webauthn) DeleteCredential(
+	ctx context.Context,
+	authData grpc.AuthData,
+	request domain.DeleteWebauthnCredentialRequest,
+) error {
+	adminId, err := getAdminId(authData)
+	if err != nil {
+		return err
+	}
+
+	err = c.service.Delete(ctx, adminId, request)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return status.Error(codes.NotFound, "credential not found")
+	case err != nil:
+		return errors.WithMessage(err, "delete webauthn credential")
+	default:
+		return nil
+	}
+}
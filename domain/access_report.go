@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	AccessReportUsersByPermission = "users_by_permission"
+	AccessReportUserPermissions   = "user_permissions"
+	AccessReportRolesByPermission = "roles_by_permission"
+	AccessReportUnusedRoles       = "unused_roles"
+	AccessReportInactiveUsers     = "inactive_users"
+)
+
+type PermissionReportRequest struct {
+	Permission string `validate:"required"`
+}
+
+type UserPermissionsReportRequest struct {
+	UserId int64 `validate:"required"`
+}
+
+// InactiveUsersReportRequest Days is the period without activity of the user
+type InactiveUsersReportRequest struct {
+	Days int `validate:"required,min=1"`
+}
+
+// AccessReportCsvRequest parameters of the report are taken from Permission, UserId and Days
+type AccessReportCsvRequest struct {
+	Report     string `validate:"required,oneof=users_by_permission user_permissions roles_by_permission unused_roles inactive_users"`
+	Permission string
+	UserId     int64
+	Days       int
+}
+
+// UserAccess Roles are active roles of the user, which grant the permission for users_by_permission report
+type UserAccess struct {
+	UserId       int64
+	Email        string
+	FullName     string
+	Blocked      bool
+	LastActiveAt time.Time
+	Roles        []string
+}
+
+// RoleAccess Inherited means the permission is granted only by parent roles
+type RoleAccess struct {
+	RoleId     int
+	Name       string
+	Inherited  bool
+	UsersCount int
+}
+
+type UserPermissionsReport struct {
+	UserId      int64
+	Email       string
+	FullName    string
+	Roles       []string
+	Permissions []string
+}
+
+type AccessReportFile struct {
+	FileName string
+	Content  string
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"time"
+)
+
+type AccessRequest struct {
+	Id            int
+	UserId        int64
+	RoleId        int
+	Justification string
+	Status        string
+	ValidUntil    *time.Time
+	ReviewerId    *int64
+	ReviewComment string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type CreateAccessRequestRequest struct {
+	RoleId        int    `validate:"required"`
+	Justification string `validate:"required"`
+	ValidUntil    *time.Time
+}
+
+type CancelAccessRequestRequest struct {
+	Id int `validate:"required"`
+}
+
+// ApproveAccessRequestRequest ValidUntil overrides the period from the request, the role is permanent if both are empty
+type ApproveAccessRequestRequest struct {
+	Id         int `validate:"required"`
+	ValidUntil *time.Time
+	Comment    string
+}
+
+type RejectAccessRequestRequest struct {
+	Id      int `validate:"required"`
+	Comment string
+}
+
+type AccessRequestPageRequest struct {
+	LimitOffestParams
+
+	Query *AccessRequestQuery
+}
+
+type AccessRequestQuery struct {
+	UserId    []int64
+	RoleId    []int
+	Status    []string `validate:"dive,oneof=PENDING APPROVED REJECTED CANCELLED"`
+	CreatedAt *DateFromToParams
+}
+
+type AccessRequestResponse struct {
+	TotalCount int
+	Items      []AccessRequest
+}
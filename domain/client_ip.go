@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"context"
+)
+
+type clientIpKey struct{}
+
+func ContextWithClientIp(ctx context.Context, clientIp string) context.Context {
+	return context.WithValue(ctx, clientIpKey{}, clientIp)
+}
+
+// ClientIpFromContext returns address of the end user, empty string if it is unknown
+func ClientIpFromContext(ctx context.Context) string {
+	clientIp, _ := ctx.Value(clientIpKey{}).(string)
+	return clientIp
+}
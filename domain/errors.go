@@ -2,27 +2,56 @@ package domain
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 )
 
 const (
-	ErrCodeInvalidPassword = 1001
+	ErrCodeInvalidPassword    = 1001
+	ErrCodeApprovalRequired   = 1002
+	ErrCodeUnknownPermissions = 1003
+	ErrCodeSodViolation       = 1004
+	ErrCodeVersionConflict    = 1005
 )
 
 var (
-	ErrNotFound             = errors.New("not found")
-	ErrUnauthenticated      = errors.New("authentication failure")
-	ErrSudirAuthorization   = errors.New("User is authorized only with SUDIR")
-	ErrSudirAuthIsMissed    = errors.New("SUDIR authorization is not configured on the server")
-	ErrInvalid              = errors.New("entity is invalid")
-	ErrAlreadyExists        = errors.New("already exists")
-	ErrTokenExpired         = errors.New("token expired")
-	ErrTokenNotFound        = errors.New("token not found")
-	ErrTooManyLoginRequests = errors.New("too many login requests")
-	ErrUserIsBlocked        = errors.New("user is blocked")
-	ErrNoActionRequired     = errors.New("no action required")
-	ErrInvalidPassword      = errors.New("invalid password")
+	ErrNotFound                = errors.New("not found")
+	ErrUnauthenticated         = errors.New("authentication failure")
+	ErrSudirAuthorization      = errors.New("User is authorized only with SUDIR")
+	ErrSudirAuthIsMissed       = errors.New("SUDIR authorization is not configured on the server")
+	ErrLdapAuthIsMissed        = errors.New("LDAP authorization is not configured on the server")
+	ErrMagicLinkIsMissed       = errors.New("magic link authorization is not configured on the server")
+	ErrWebauthnIsMissed        = errors.New("webauthn authorization is not configured on the server")
+	ErrInvalid                 = errors.New("entity is invalid")
+	ErrAlreadyExists           = errors.New("already exists")
+	ErrTokenExpired            = errors.New("token expired")
+	ErrTokenNotFound           = errors.New("token not found")
+	ErrTooManyLoginRequests    = errors.New("too many login requests")
+	ErrUserIsBlocked           = errors.New("user is blocked")
+	ErrNoActionRequired        = errors.New("no action required")
+	ErrInvalidPassword         = errors.New("invalid password")
+	ErrImpersonationDenied     = errors.New("impersonation is not allowed")
+	ErrNotImpersonated         = errors.New("session is not an impersonation")
+	ErrAccessRestricted        = errors.New("access is restricted by role")
+	ErrInvalidRolePeriod       = errors.New("role validity period is invalid")
+	ErrAccessRequestNotPending = errors.New("access request is already resolved")
+	ErrAccessRequestDenied     = errors.New("access request review is not allowed")
+	ErrPendingChangeNotPending = errors.New("pending change is already resolved or expired")
+	ErrPendingChangeDenied     = errors.New("pending change review is not allowed")
+	ErrRecertificationScope    = errors.New("recertification campaign has no role links to review")
+	ErrRecertificationDeadline = errors.New("recertification deadline must be in the future")
+	ErrRecertificationClosed   = errors.New("recertification campaign is closed")
+	ErrRecertificationUndone   = errors.New("recertification campaign has not reviewed items")
+	ErrRecertificationDenied   = errors.New("recertification review is not allowed")
+	ErrInvalidRoleParent       = errors.New("role parent is invalid")
+	ErrInvalidPermission       = errors.New("role permission is invalid")
+	ErrInvalidRbacDocument     = errors.New("rbac document is invalid")
+	ErrImmutableRole           = errors.New("role is immutable")
+	ErrConfigManagedRole       = errors.New("role is managed by config")
+	ErrRoleInUse               = errors.New("role is assigned to users")
+	ErrInvalidReassignRole     = errors.New("role for reassignment is invalid")
+	ErrVersionConflict         = errors.New("entity was changed by another request")
 )
 
 type UnknownAuditEventError struct {
@@ -32,3 +61,50 @@ type UnknownAuditEventError struct {
 func (e UnknownAuditEventError) Error() string {
 	return fmt.Sprintf("unknown audit event: %s", e.Event)
 }
+
+// PendingChangeError means the change is not applied and waits for approval of the second admin
+type PendingChangeError struct {
+	Id int
+}
+
+func (e PendingChangeError) Error() string {
+	return fmt.Sprintf("change requires approval, pending change id: %d", e.Id)
+}
+
+// UnknownPermissionsError means the role has permissions, which do not match any permission of the catalog
+type UnknownPermissionsError struct {
+	Keys []string
+}
+
+func (e UnknownPermissionsError) Error() string {
+	return fmt.Sprintf("unknown permissions: %s", strings.Join(e.Keys, ", "))
+}
+
+// SodViolationError means roles of the user violate the separation of duties constraint,
+// Permissions are set for constraints over permissions
+type SodViolationError struct {
+	Constraint  string
+	Roles       []string
+	Permissions []string
+}
+
+func (e SodViolationError) Error() string {
+	if len(e.Permissions) > 0 {
+		return fmt.Sprintf("separation of duties constraint '%s' is violated by permissions: %s of roles: %s",
+			e.Constraint, strings.Join(e.Permissions, ", "), strings.Join(e.Roles, ", "))
+	}
+	return fmt.Sprintf("separation of duties constraint '%s' is violated by roles: %s", e.Constraint, strings.Join(e.Roles, ", "))
+}
+
+// VersionConflictError means the entity was changed after the version in the request, Current is the current state
+type VersionConflictError struct {
+	Current any
+}
+
+func (e VersionConflictError) Error() string {
+	return ErrVersionConflict.Error()
+}
+
+func (e VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
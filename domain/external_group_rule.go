@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"time"
+)
+
+type ExternalGroupRule struct {
+	Id        int
+	Pattern   string
+	MatchType string
+	RoleId    int
+	Priority  int
+	Terminal  bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type CreateExternalGroupRuleRequest struct {
+	Pattern   string
+	MatchType string `validate:"required,oneof=EXACT PREFIX REGEX DEFAULT"`
+	RoleId    int    `validate:"required"`
+	Priority  int
+	Terminal  bool
+}
+
+type UpdateExternalGroupRuleRequest struct {
+	Id        int `validate:"required"`
+	Pattern   string
+	MatchType string `validate:"required,oneof=EXACT PREFIX REGEX DEFAULT"`
+	RoleId    int    `validate:"required"`
+	Priority  int
+	Terminal  bool
+}
+
+type DeleteExternalGroupRuleRequest struct {
+	Id int `validate:"required"`
+}
+
+type ExternalGroupDryRunRequest struct {
+	Groups []string
+}
+
+type ExternalGroupDryRunResponse struct {
+	RoleIds      []int
+	MatchedRules []ExternalGroupRule
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+)
+
+type impersonatorIdKey struct{}
+
+type ImpersonateRequest struct {
+	UserId int64 `validate:"required"`
+}
+
+type ImpersonateResponse struct {
+	Token      string
+	Expired    string
+	HeaderName string
+	UserId     int64
+}
+
+// ContextWithImpersonator marks request as made by impersonatorId on behalf of the token owner
+func ContextWithImpersonator(ctx context.Context, impersonatorId int64) context.Context {
+	return context.WithValue(ctx, impersonatorIdKey{}, impersonatorId)
+}
+
+func ImpersonatorFromContext(ctx context.Context) (int64, bool) {
+	impersonatorId, ok := ctx.Value(impersonatorIdKey{}).(int64)
+	return impersonatorId, ok
+}
@@ -14,6 +14,19 @@ type LoginRequest struct {
 	Password string ` validate:"required"`
 }
 
+type LoginLdapRequest struct {
+	Login    string `validate:"required"`
+	Password string `validate:"required"`
+}
+
+type MagicLinkRequest struct {
+	Email string `validate:"required"`
+}
+
+type LoginMagicLinkRequest struct {
+	Token string `validate:"required"`
+}
+
 type LoginSudirRequest struct {
 	AuthCode string `validate:"required"`
 }
@@ -23,4 +36,6 @@ type LoginResponse struct {
 	Token      string
 	Expired    string `json:",omitempty"`
 	HeaderName string
+	// Webauthn is set instead of token, when user has to confirm login with passkey via admin/auth/webauthn/finish
+	Webauthn *WebauthnChallenge `json:",omitempty"`
 }
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type PendingChange struct {
+	Id         int
+	Operation  string
+	Payload    json.RawMessage `swaggertype:"object"`
+	Diff       string
+	Status     string
+	CreatedBy  int64
+	ReviewerId *int64
+	ExpiredAt  time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type PendingChangePageRequest struct {
+	LimitOffestParams
+
+	Query *PendingChangeQuery
+}
+
+type PendingChangeQuery struct {
+	Operation []string
+	Status    []string `validate:"dive,oneof=PENDING APPROVED REJECTED EXPIRED"`
+	CreatedBy []int64
+}
+
+type PendingChangeResponse struct {
+	TotalCount int
+	Items      []PendingChange
+}
+
+type ReviewPendingChangeRequest struct {
+	Id int `validate:"required"`
+}
+
+// CreateUserChange is the payload of the pending user creation, the password is saved as the hash only
+type CreateUserChange struct {
+	CreateUserRequest
+
+	PasswordHash string
+}
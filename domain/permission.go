@@ -1,6 +1,28 @@
 package domain
 
 type Permission struct {
-	Key  string
-	Name string
+	Key         string
+	Name        string
+	Description string
+	Category    string
+	Module      string
+}
+
+type PermissionGroup struct {
+	Category    string
+	Permissions []Permission
+}
+
+type RegisterPermissionsRequest struct {
+	Module      string                 `validate:"required"`
+	Permissions []RegisteredPermission `validate:"dive"`
+	// DeprecatedKeys are removed from the catalog, only permissions of the module are affected
+	DeprecatedKeys []string
+}
+
+type RegisteredPermission struct {
+	Key         string `validate:"required"`
+	Name        string `validate:"required"`
+	Description string
+	Category    string
 }
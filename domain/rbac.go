@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	RbacFormatJson = "json"
+	RbacFormatYaml = "yaml"
+
+	// RbacDocumentVersion is the version of the RbacDocument format, documents of other versions are not imported
+	RbacDocumentVersion = 1
+)
+
+// RbacDocument is the exported configuration of roles, roles are matched by name on import
+type RbacDocument struct {
+	Version    int
+	ExportedAt time.Time
+	Roles      []RbacRole `validate:"dive"`
+}
+
+// RbacRole is the role of RbacDocument, approvers are not exported, because users differ between stands
+type RbacRole struct {
+	Name          string `validate:"required"`
+	ExternalGroup string
+	Permissions   []string
+	AllowedCidrs  []string     `validate:"dive,cidr"`
+	TimeWindows   []TimeWindow `validate:"dive"`
+	// ParentNames are names of parent roles, parents must be in the same document
+	ParentNames    []string
+	ResourceScopes []ResourceScope `validate:"dive"`
+	Immutable      bool
+	Exclusive      bool
+}
+
+type RbacExportRequest struct {
+	// Format is json by default
+	Format string `validate:"omitempty,oneof=json yaml"`
+}
+
+type RbacExportResponse struct {
+	Format  string
+	Content string
+}
+
+type RbacImportRequest struct {
+	// Format is json by default
+	Format        string `validate:"omitempty,oneof=json yaml"`
+	Content       string `validate:"required"`
+	ChangeMessage string
+	// DryRun returns changes without applying them
+	DryRun bool
+	// Force allows to change and delete immutable roles and to delete roles held by users
+	Force bool
+}
+
+type RbacImportResponse struct {
+	DryRun  bool
+	Changes []RbacRoleChange
+}
+
+// RbacRoleChange is the change of the role by import, Operation is CREATE, UPDATE or DELETE,
+// Users lose the deleted role, Users is null, if no user holds the role
+type RbacRoleChange struct {
+	RoleName  string
+	Operation string
+	Immutable bool
+	Fields    []RoleFieldChange
+	Users     []RoleHolder
+}
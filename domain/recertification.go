@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"time"
+)
+
+type RecertificationCampaign struct {
+	Id          int
+	Name        string
+	UserIds     []int64
+	RoleIds     []int
+	ReviewerIds []int64
+	Status      string
+	Deadline    time.Time
+	CreatedBy   *int64
+	ClosedAt    *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Progress    RecertificationProgress
+}
+
+// RecertificationProgress Revoked includes items revoked automatically after the deadline
+type RecertificationProgress struct {
+	Total    int
+	Reviewed int
+	Kept     int
+	Revoked  int
+}
+
+type RecertificationItem struct {
+	Id           int
+	CampaignId   int
+	UserId       int64
+	UserEmail    string
+	RoleId       int
+	RoleName     string
+	LastActiveAt *time.Time
+	Decision     *string
+	Comment      string
+	ReviewerId   *int64
+	DecidedAt    *time.Time
+}
+
+// StartRecertificationRequest role links of UserIds and RoleIds are reviewed, links matching both lists if both are set
+type StartRecertificationRequest struct {
+	Name        string `validate:"required"`
+	UserIds     []int64
+	RoleIds     []int
+	ReviewerIds []int64   `validate:"required,min=1"`
+	Deadline    time.Time `validate:"required"`
+}
+
+type RecertificationIdRequest struct {
+	Id int `validate:"required"`
+}
+
+type RecertificationCampaignPageRequest struct {
+	LimitOffestParams
+
+	Query *RecertificationCampaignQuery
+}
+
+type RecertificationCampaignQuery struct {
+	Status []string `validate:"dive,oneof=ACTIVE CLOSED"`
+}
+
+type RecertificationCampaignResponse struct {
+	TotalCount int
+	Items      []RecertificationCampaign
+}
+
+type RecertificationItemPageRequest struct {
+	LimitOffestParams
+
+	Query *RecertificationItemQuery
+}
+
+// RecertificationItemQuery Undecided returns only not reviewed items
+type RecertificationItemQuery struct {
+	CampaignId []int
+	UserId     []int64
+	RoleId     []int
+	Undecided  bool
+}
+
+type RecertificationItemResponse struct {
+	TotalCount int
+	Items      []RecertificationItem
+}
+
+// DecideRecertificationRequest decision may be changed until the campaign is closed
+type DecideRecertificationRequest struct {
+	Ids      []int  `validate:"required,min=1"`
+	Decision string `validate:"required,oneof=KEEP REVOKE"`
+	Comment  string
+}
+
+type RecertificationReport struct {
+	FileName string
+	Content  string
+}
@@ -10,10 +10,19 @@ type Role struct {
 	ExternalGroup string
 	ChangeMessage string
 	Permissions   []string
-	Immutable     bool
-	Exclusive     bool
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	// InheritedPermissions are permissions of parent roles, which the role does not have itself
+	InheritedPermissions []string
+	AllowedCidrs         []string
+	TimeWindows          []TimeWindow
+	ApproverIds          []int64
+	ParentIds            []int
+	ResourceScopes       []ResourceScope
+	Immutable            bool
+	Exclusive            bool
+	ConfigManaged        bool
+	Version              int
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
 }
 
 type CreateRoleRequest struct {
@@ -21,6 +30,12 @@ type CreateRoleRequest struct {
 	ExternalGroup string
 	ChangeMessage string
 	Permissions   []string
+	AllowedCidrs  []string     `validate:"dive,cidr"`
+	TimeWindows   []TimeWindow `validate:"dive"`
+	ApproverIds   []int64
+	ParentIds     []int
+	// ResourceScopes limit permissions of the role to listed resources
+	ResourceScopes []ResourceScope `validate:"dive"`
 }
 
 type UpdateRoleRequest struct {
@@ -29,8 +44,143 @@ type UpdateRoleRequest struct {
 	ExternalGroup string
 	ChangeMessage string
 	Permissions   []string
+	AllowedCidrs  []string     `validate:"dive,cidr"`
+	TimeWindows   []TimeWindow `validate:"dive"`
+	ApproverIds   []int64
+	ParentIds     []int
+	// ResourceScopes limit permissions of the role to listed resources
+	ResourceScopes []ResourceScope `validate:"dive"`
+	// Version is the version of the role, which the change is based on, it is not checked if omitted
+	Version int
 }
 
+// DeleteRoleRequest the role assigned to users is deleted only with ReassignToRoleId or Force,
+// ReassignToRoleId grants the role to its users instead of the deleted one, Force revokes it from users
 type DeleteRoleRequest struct {
-	Id int
+	Id               int
+	ReassignToRoleId int
+	Force            bool
+}
+
+type RoleDeletePreviewRequest struct {
+	Id int `validate:"required"`
+}
+
+type RoleDeletePreview struct {
+	RoleId     int
+	RoleName   string
+	UsersCount int
+	Users      []RoleHolder
+}
+
+type RoleHolder struct {
+	Id       int64
+	Email    string
+	FullName string
+}
+
+// TimeWindow is a daily interval, when role is usable,
+// To less than From means the interval passes midnight, equal From and To mean the whole day
+type TimeWindow struct {
+	Weekdays []int  `validate:"dive,min=1,max=7"`
+	From     string `validate:"required,datetime=15:04"`
+	To       string `validate:"required,datetime=15:04"`
+}
+
+// ResourceScope limits permissions matched by Permission to resources of ResourceType with ResourceIds
+type ResourceScope struct {
+	Permission   string   `validate:"required"`
+	ResourceType string   `validate:"required"`
+	ResourceIds  []string `validate:"required,min=1"`
+}
+
+type RoleUnknownPermissions struct {
+	RoleId        int
+	RoleName      string
+	Immutable     bool
+	ConfigManaged bool
+	Permissions   []string
+}
+
+type RemoveUnknownPermissionsRequest struct {
+	// RoleIds limit the cleanup, all roles are cleaned if empty
+	RoleIds []int
+}
+
+type RoleVersion struct {
+	Id            int
+	RoleId        int
+	Version       int
+	Operation     string
+	Snapshot      RoleSnapshot
+	ChangedBy     *int64
+	ChangeMessage string
+	CreatedAt     time.Time
+}
+
+type RoleSnapshot struct {
+	Name           string
+	ExternalGroup  string
+	Permissions    []string
+	AllowedCidrs   []string
+	TimeWindows    []TimeWindow
+	ApproverIds    []int64
+	ParentIds      []int
+	ResourceScopes []ResourceScope
+}
+
+type RoleVersionsRequest struct {
+	RoleId int `validate:"required"`
+}
+
+type RoleVersionDiffRequest struct {
+	RoleId      int `validate:"required"`
+	FromVersion int `validate:"required"`
+	ToVersion   int `validate:"required"`
+}
+
+type RoleVersionDiff struct {
+	RoleId             int
+	FromVersion        int
+	ToVersion          int
+	AddedPermissions   []string
+	RemovedPermissions []string
+	Changes            []RoleFieldChange
+}
+
+// RoleFieldChange is a changed field of the role snapshot
+type RoleFieldChange struct {
+	Field string
+	From  any
+	To    any
+}
+
+type RollbackRoleRequest struct {
+	RoleId        int `validate:"required"`
+	Version       int `validate:"required"`
+	ChangeMessage string
+}
+
+const (
+	RoleTemplateReadOnly = "read_only"
+)
+
+// CloneRoleRequest Template filters permissions of the source role, Permissions are added to permissions of the clone
+type CloneRoleRequest struct {
+	Id          int    `validate:"required"`
+	Name        string `validate:"required"`
+	Template    string `validate:"omitempty,oneof=read_only"`
+	Permissions []string
+	// CopyExternalGroup copies the external group of the source role, so members of the group get both roles on next login
+	CopyExternalGroup bool
+	ChangeMessage     string
+}
+
+// RoleTemplate is the role generated from the existing role, Name is the suggested name of the new role
+type RoleTemplate struct {
+	Template       string
+	SourceRoleId   int
+	SourceRoleName string
+	Name           string
+	Permissions    []string
 }
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/txix-open/isp-kit/json"
+)
+
+const (
+	ScimSchemaUser           = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimSchemaGroup          = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ScimSchemaListResponse   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimSchemaPatchOp        = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ScimSchemaError          = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ScimSchemaProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	ScimErrorTypeInvalidFilter = "invalidFilter"
+	ScimErrorTypeInvalidValue  = "invalidValue"
+	ScimErrorTypeInvalidPath   = "invalidPath"
+	ScimErrorTypeUniqueness    = "uniqueness"
+	ScimErrorTypeMutability    = "mutability"
+)
+
+// nolint:tagliatelle
+type ScimUser struct {
+	Schemas     []string         `json:"schemas"`
+	Id          string           `json:"id,omitempty"`
+	ExternalId  string           `json:"externalId,omitempty"`
+	UserName    string           `json:"userName"`
+	Name        *ScimName        `json:"name,omitempty"`
+	DisplayName string           `json:"displayName,omitempty"`
+	Active      *bool            `json:"active,omitempty"`
+	Password    string           `json:"password,omitempty"`
+	Emails      []ScimMultiValue `json:"emails,omitempty"`
+	Groups      []ScimMultiValue `json:"groups,omitempty"`
+	Meta        *ScimMeta        `json:"meta,omitempty"`
+}
+
+// nolint:tagliatelle
+type ScimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// nolint:tagliatelle
+type ScimMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// nolint:tagliatelle
+type ScimMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// nolint:tagliatelle
+type ScimGroup struct {
+	Schemas     []string         `json:"schemas"`
+	Id          string           `json:"id,omitempty"`
+	ExternalId  string           `json:"externalId,omitempty"`
+	DisplayName string           `json:"displayName"`
+	Members     []ScimMultiValue `json:"members,omitempty"`
+	Meta        *ScimMeta        `json:"meta,omitempty"`
+}
+
+type ScimListRequest struct {
+	Filter     string
+	StartIndex int
+	Count      int
+}
+
+// nolint:tagliatelle
+type ScimListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+// nolint:tagliatelle
+type ScimPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []ScimPatchOperation `json:"Operations"`
+}
+
+// nolint:tagliatelle
+type ScimPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value" swaggertype:"object"`
+}
+
+// nolint:tagliatelle,errname
+type ScimErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+type ScimError struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+func (e ScimError) Error() string {
+	return fmt.Sprintf("scim error %d %s: %s", e.Status, e.ScimType, e.Detail)
+}
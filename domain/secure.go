@@ -13,8 +13,20 @@ type SecureAuthResponse struct {
 type SecureAuthzRequest struct {
 	AdminId    int
 	Permission string
+	// ClientIp is address of the end user, used to check role network restrictions
+	ClientIp string
+	// Resource is checked against resource scopes of roles, without it scoped permission is authorized
+	// and the caller filters resources by Scopes
+	Resource *ResourceRef
+}
+
+type ResourceRef struct {
+	Type string `validate:"required"`
+	Id   string `validate:"required"`
 }
 
 type SecureAuthzResponse struct {
 	Authorized bool
+	// Scopes limit the permission to listed resources, empty if the permission is granted for all resources
+	Scopes []ResourceScope
 }
@@ -0,0 +1,20 @@
+package domain
+
+// SodViolationsRequest Constraint filters violations by the name of the constraint, all constraints are checked if it is empty
+type SodViolationsRequest struct {
+	Constraint string
+}
+
+// SodViolation Permissions are set for constraints over permissions, Roles are roles of the user, which grant them
+type SodViolation struct {
+	UserId      int64
+	Email       string
+	FullName    string
+	Constraint  string
+	Roles       []string
+	Permissions []string
+}
+
+type SodViolationsResponse struct {
+	Items []SodViolation
+}
@@ -7,6 +7,7 @@ import (
 type User struct {
 	Id                   int64
 	Roles                []int
+	TemporaryRoles       []TemporaryRole
 	FirstName            string
 	LastName             string
 	FullName             string
@@ -14,6 +15,7 @@ type User struct {
 	Description          string
 	Blocked              bool
 	LastSessionCreatedAt *time.Time
+	Version              int
 	UpdatedAt            time.Time
 	CreatedAt            time.Time
 }
@@ -39,15 +41,17 @@ type UsersPageRequest struct {
 }
 
 type AdminUserShort struct {
-	FirstName     string
-	LastName      string
-	FullName      string
-	Email         string `validate:"required"`
-	Role          string
-	Roles         []int
-	RoleNames     []string
-	Permissions   []string
-	IdleTimeoutMs int
+	FirstName   string
+	LastName    string
+	FullName    string
+	Email       string `validate:"required"`
+	Role        string
+	Roles       []int
+	RoleNames   []string
+	Permissions []string
+	// ResourceScopes limit permissions to listed resources, permissions without scope are granted for all resources
+	ResourceScopes []ResourceScope
+	IdleTimeoutMs  int
 }
 
 type CreateUserRequest struct {
@@ -60,13 +64,31 @@ type CreateUserRequest struct {
 }
 
 type UpdateUserRequest struct {
-	Id          int64 `validate:"required"`
-	Roles       []int
-	FirstName   string
-	LastName    string
-	Email       string
-	Description string
-	Blocked     bool
+	Id    int64 `validate:"required"`
+	Roles []int
+	// TemporaryRoles replaces validity periods of user roles, periods of kept roles are not changed if it is omitted
+	TemporaryRoles []TemporaryRole `validate:"dive"`
+	FirstName      string
+	LastName       string
+	Email          string
+	Description    string
+	Blocked        bool
+	// Version is the version of the user, which the change is based on, it is not checked if omitted
+	Version int
+}
+
+// TemporaryRole is a role of the user, that is active only within the validity period
+type TemporaryRole struct {
+	RoleId     int `validate:"required"`
+	ValidFrom  *time.Time
+	ValidUntil *time.Time
+}
+
+type GrantRoleRequest struct {
+	UserId     int64 `validate:"required"`
+	RoleId     int   `validate:"required"`
+	ValidFrom  *time.Time
+	ValidUntil *time.Time
 }
 
 type DeleteResponse struct {
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebauthnBeginRequest struct {
+	Email string
+}
+
+// WebauthnChallenge contains ceremony id and options for navigator.credentials.get()/create()
+type WebauthnChallenge struct {
+	SessionId string
+	Options   json.RawMessage `swaggertype:"object"`
+}
+
+type WebauthnFinishRequest struct {
+	SessionId  string          `validate:"required"`
+	Credential json.RawMessage `validate:"required" swaggertype:"object"`
+}
+
+type WebauthnFinishRegistrationRequest struct {
+	SessionId  string          `validate:"required"`
+	Name       string          `validate:"required,max=255"`
+	Credential json.RawMessage `validate:"required" swaggertype:"object"`
+}
+
+type WebauthnCredential struct {
+	Id         int
+	Name       string
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type RenameWebauthnCredentialRequest struct {
+	Id   int    `validate:"required"`
+	Name string `validate:"required,max=255"`
+}
+
+type DeleteWebauthnCredentialRequest struct {
+	Id int `validate:"required"`
+}
@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	AccessRequestPending   = "PENDING"
+	AccessRequestApproved  = "APPROVED"
+	AccessRequestRejected  = "REJECTED"
+	AccessRequestCancelled = "CANCELLED"
+)
+
+type AccessRequest struct {
+	Id            int
+	UserId        int64
+	RoleId        int
+	Justification string
+	Status        string
+	ValidUntil    *time.Time
+	ReviewerId    *int64
+	ReviewComment string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// AccessRequestScope limits requests visible to the user: own requests and requests for RoleIds
+type AccessRequestScope struct {
+	UserId  int64
+	RoleIds []int
+}
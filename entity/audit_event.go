@@ -1,14 +1,21 @@
 package entity
 
 const (
-	EventSuccessLogin        = "success_login"
-	EventErrorLogin          = "error_login"
-	EventSuccessLogout       = "success_logout"
-	EventRoleChanged         = "role_changed"
-	EventUserChanged         = "user_changed"
-	EventUserPasswordChanged = "success_change_password"
-	EventErrorPasswordChange = "error_change_password"
-	EventUserBlocked         = "user_blocked"
+	EventSuccessLogin         = "success_login"
+	EventErrorLogin           = "error_login"
+	EventSuccessLogout        = "success_logout"
+	EventRoleChanged          = "role_changed"
+	EventUserChanged          = "user_changed"
+	EventUserPasswordChanged  = "success_change_password"
+	EventErrorPasswordChange  = "error_change_password"
+	EventUserBlocked          = "user_blocked"
+	EventUserImpersonated     = "user_impersonated"
+	EventMagicLinkRequested   = "magic_link_requested"
+	EventWebauthnChanged      = "webauthn_credential_changed"
+	EventAccessRestricted     = "access_restricted"
+	EventAccessRequestChanged = "access_request_changed"
+	EventPendingChange        = "pending_change"
+	EventRecertification      = "recertification"
 )
 
 type AuditEvent struct {
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	GroupMatchExact   = "EXACT"
+	GroupMatchPrefix  = "PREFIX"
+	GroupMatchRegex   = "REGEX"
+	GroupMatchDefault = "DEFAULT"
+)
+
+type ExternalGroupRule struct {
+	Id        int
+	Pattern   string
+	MatchType string
+	RoleId    int
+	Priority  int
+	Terminal  bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
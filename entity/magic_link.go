@@ -0,0 +1,14 @@
+package entity
+
+import (
+	"time"
+)
+
+type MagicLink struct {
+	Id        int
+	UserId    int64
+	TokenHash string
+	ExpiredAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
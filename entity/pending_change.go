@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	ChangeRoleUpdate    = "role_update"
+	ChangeRoleRollback  = "role_rollback"
+	ChangeRoleDelete    = "role_delete"
+	ChangeRoleImport    = "role_import"
+	ChangeUserDelete    = "user_delete"
+	ChangeUserGrantRole = "user_grant_role"
+	ChangeUserUpdate    = "user_update"
+	ChangeUserCreate    = "user_create"
+)
+
+const (
+	PendingChangePending  = "PENDING"
+	PendingChangeApproved = "APPROVED"
+	PendingChangeRejected = "REJECTED"
+	PendingChangeExpired  = "EXPIRED"
+)
+
+// PendingChange is an administrative change, which is applied after approval of the second admin,
+// Payload is the original request of the operation
+type PendingChange struct {
+	Id         int
+	Operation  string
+	Payload    json.RawMessage
+	Diff       string
+	Status     string
+	CreatedBy  int64
+	ReviewerId *int64
+	ExpiredAt  time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+)
+
+// Permission is a catalog entry registered by Module
+type Permission struct {
+	Key         string
+	Name        string
+	Description string
+	Category    string
+	Module      string
+	Deprecated  bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	RecertificationActive = "ACTIVE"
+	RecertificationClosed = "CLOSED"
+
+	RecertificationKeep       = "KEEP"
+	RecertificationRevoke     = "REVOKE"
+	RecertificationAutoRevoke = "AUTO_REVOKE"
+)
+
+// RecertificationCampaign reviews role links of UserIds and RoleIds, links matching both lists are reviewed if both are set
+type RecertificationCampaign struct {
+	Id          int
+	Name        string
+	UserIds     IdList
+	RoleIds     RoleIdList
+	ReviewerIds IdList
+	Status      string
+	Deadline    time.Time
+	CreatedBy   *int64
+	ClosedAt    *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// RecertificationItem is a role link under review, empty Decision means the link is not reviewed yet
+type RecertificationItem struct {
+	Id           int
+	CampaignId   int
+	UserId       int64
+	RoleId       int
+	LastActiveAt *time.Time
+	Decision     *string
+	Comment      string
+	ReviewerId   *int64
+	DecidedAt    *time.Time
+}
+
+// RecertificationItemView is an item with user email and role name
+type RecertificationItemView struct {
+	RecertificationItem
+
+	UserEmail string
+	RoleName  string
+}
+
+type RecertificationProgress struct {
+	CampaignId int
+	Total      int
+	Kept       int
+	Revoked    int
+}
@@ -13,14 +13,21 @@ const (
 )
 
 type Role struct {
-	Id            int
-	Name          string
-	ExternalGroup string
-	Permissions   PermList
-	Immutable     bool
-	Exclusive     bool
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	Id             int
+	Name           string
+	ExternalGroup  string
+	Permissions    PermList
+	Immutable      bool
+	Exclusive      bool
+	ConfigManaged  bool
+	AllowedCidrs   CidrList
+	TimeWindows    TimeWindowList
+	ApproverIds    IdList
+	ParentIds      RoleIdList
+	ResourceScopes ResourceScopeList
+	Version        int
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
 
 type PermList []string
@@ -34,3 +41,97 @@ func (p *PermList) Value() (driver.Value, error) {
 	bytes, err := json.Marshal(p)
 	return driver.Value(bytes), err
 }
+
+// CidrList is a list of networks in CIDR notation, role is usable only from these networks if not empty
+type CidrList []string
+
+// nolint
+func (l *CidrList) Scan(src any) error {
+	return json.Unmarshal(src.([]byte), l)
+}
+
+func (l CidrList) Value() (driver.Value, error) {
+	if l == nil {
+		l = CidrList{}
+	}
+	bytes, err := json.Marshal(l)
+	return driver.Value(bytes), err
+}
+
+// TimeWindow is a daily interval [From, To) in HH:MM, Weekdays are ISO numbers 1 (monday) - 7 (sunday), empty means every day
+type TimeWindow struct {
+	Weekdays []int
+	From     string
+	To       string
+}
+
+// TimeWindowList is a list of intervals, role is usable only within one of them if not empty
+type TimeWindowList []TimeWindow
+
+// nolint
+func (l *TimeWindowList) Scan(src any) error {
+	return json.Unmarshal(src.([]byte), l)
+}
+
+func (l TimeWindowList) Value() (driver.Value, error) {
+	if l == nil {
+		l = TimeWindowList{}
+	}
+	bytes, err := json.Marshal(l)
+	return driver.Value(bytes), err
+}
+
+// IdList is a list of user identities
+type IdList []int64
+
+// nolint
+func (l *IdList) Scan(src any) error {
+	return json.Unmarshal(src.([]byte), l)
+}
+
+func (l IdList) Value() (driver.Value, error) {
+	if l == nil {
+		l = IdList{}
+	}
+	bytes, err := json.Marshal(l)
+	return driver.Value(bytes), err
+}
+
+// ResourceScope limits grants of permissions matched by Permission to resources of ResourceType with ResourceIds
+type ResourceScope struct {
+	Permission   string
+	ResourceType string
+	ResourceIds  []string
+}
+
+// ResourceScopeList is a list of scopes, permissions without scope are granted for all resources
+type ResourceScopeList []ResourceScope
+
+// nolint
+func (l *ResourceScopeList) Scan(src any) error {
+	return json.Unmarshal(src.([]byte), l)
+}
+
+func (l ResourceScopeList) Value() (driver.Value, error) {
+	if l == nil {
+		l = ResourceScopeList{}
+	}
+	bytes, err := json.Marshal(l)
+	return driver.Value(bytes), err
+}
+
+// RoleIdList is a list of role identities
+type RoleIdList []int
+
+// nolint
+func (l *RoleIdList) Scan(src any) error {
+	return json.Unmarshal(src.([]byte), l)
+}
+
+func (l RoleIdList) Value() (driver.Value, error) {
+	if l == nil {
+		l = RoleIdList{}
+	}
+	bytes, err := json.Marshal(l)
+	return driver.Value(bytes), err
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/txix-open/isp-kit/json"
+)
+
+const (
+	RoleVersionCreate   = "CREATE"
+	RoleVersionUpdate   = "UPDATE"
+	RoleVersionDelete   = "DELETE"
+	RoleVersionRollback = "ROLLBACK"
+)
+
+// RoleVersion is the state of the role after the operation, the state before deletion for RoleVersionDelete
+type RoleVersion struct {
+	Id            int
+	RoleId        int
+	Version       int
+	Operation     string
+	Snapshot      RoleSnapshot
+	ChangedBy     *int64
+	ChangeMessage string
+	CreatedAt     time.Time
+}
+
+type RoleSnapshot struct {
+	Name           string
+	ExternalGroup  string
+	Permissions    []string
+	AllowedCidrs   []string
+	TimeWindows    []TimeWindow
+	ApproverIds    []int64
+	ParentIds      []int
+	ResourceScopes []ResourceScope
+}
+
+// nolint
+func (s *RoleSnapshot) Scan(src any) error {
+	return json.Unmarshal(src.([]byte), s)
+}
+
+func (s RoleSnapshot) Value() (driver.Value, error) {
+	bytes, err := json.Marshal(s)
+	return driver.Value(bytes), err
+}
@@ -11,11 +11,12 @@ const (
 )
 
 type Token struct {
-	Id        int
-	Token     string
-	UserId    int64
-	Status    string
-	ExpiredAt time.Time
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Id             int
+	Token          string
+	UserId         int64
+	ImpersonatorId *int64
+	Status         string
+	ExpiredAt      time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
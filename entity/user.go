@@ -13,6 +13,7 @@ type User struct {
 	Password             string
 	Blocked              bool
 	LastActiveAt         time.Time
+	Version              int
 	UpdatedAt            time.Time
 	CreatedAt            time.Time
 	LastSessionCreatedAt *time.Time
@@ -28,10 +29,20 @@ type SudirUser struct {
 	FullName    string
 }
 
+// UpdateUser Version is the expected version of the user, it is not checked if zero
 type UpdateUser struct {
 	FirstName   string
 	LastName    string
 	FullName    string
 	Email       string
 	Description string
+	Version     int
+}
+
+type LdapUser struct {
+	Email     string
+	FirstName string
+	LastName  string
+	FullName  string
+	Groups    []string
 }
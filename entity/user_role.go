@@ -1,6 +1,12 @@
 package entity
 
+import (
+	"time"
+)
+
 type UserRole struct {
-	UserId int
-	RoleId int
+	UserId     int
+	RoleId     int
+	ValidFrom  *time.Time
+	ValidUntil *time.Time
 }
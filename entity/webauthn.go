@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	WebauthnCeremonyRegistration = "registration"
+	WebauthnCeremonyLogin        = "login"
+	WebauthnCeremonySecondFactor = "second_factor"
+)
+
+type WebauthnCredential struct {
+	Id           int
+	UserId       int64
+	CredentialId []byte
+	Name         string
+	Credential   json.RawMessage
+	LastUsedAt   *time.Time
+	CreatedAt    time.Time
+}
+
+type WebauthnSession struct {
+	Id        string
+	UserId    *int64
+	Ceremony  string
+	Data      json.RawMessage
+	ExpiredAt time.Time
+	CreatedAt time.Time
+}
@@ -21,8 +21,9 @@ var version = "1.0.0"
 // @host localhost:9000
 // @BasePath /api/admin
 
-//go:generate swag init --parseDependency
-//go:generate rm -f docs/swagger.json docs/docs.go
+//go:generate swag init --parseDependency --tags !scim
+//go:generate swag init --parseDependency --tags scim -g controller/scim.go --instanceName scim
+//go:generate rm -f docs/swagger.json docs/docs.go docs/scim_swagger.json docs/scim_docs.go
 func main() {
 	boot := bootstrap.New(version, conf.Remote{}, routes.EndpointDescriptors(), cluster.GrpcTransport)
 	app := boot.App
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE external_group_rules
+(
+    id         SERIAL PRIMARY KEY,
+    pattern    TEXT      NOT NULL DEFAULT '',
+    match_type TEXT      NOT NULL,
+    role_id    INTEGER   NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    priority   INTEGER   NOT NULL DEFAULT 0,
+    terminal   BOOL      NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
+
+-- +goose Down
+DROP TABLE external_group_rules;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE tokens
+    ADD COLUMN impersonator_id int8 NULL REFERENCES users (id) ON DELETE CASCADE;
+
+INSERT INTO audit_event (event, enable)
+VALUES
+       ('user_impersonated', true);
+
+-- +goose Down
+DELETE FROM audit_event WHERE event = 'user_impersonated';
+
+ALTER TABLE tokens
+    DROP COLUMN impersonator_id;
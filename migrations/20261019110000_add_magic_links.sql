@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE magic_links
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INT8      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash TEXT      NOT NULL UNIQUE,
+    expired_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
+
+CREATE INDEX ix_magic_links__user_id ON magic_links (user_id);
+
+INSERT INTO audit_event (event, enable)
+VALUES
+       ('magic_link_requested', true);
+
+-- +goose Down
+DELETE FROM audit_event WHERE event = 'magic_link_requested';
+
+DROP TABLE magic_links;
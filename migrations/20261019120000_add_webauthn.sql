@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE webauthn_credentials
+(
+    id            SERIAL PRIMARY KEY,
+    user_id       INT8      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    credential_id BYTEA     NOT NULL UNIQUE,
+    name          TEXT      NOT NULL,
+    credential    JSONB     NOT NULL,
+    last_used_at  TIMESTAMP NULL,
+    created_at    TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
+
+CREATE INDEX ix_webauthn_credentials__user_id ON webauthn_credentials (user_id);
+
+CREATE TABLE webauthn_sessions
+(
+    id         TEXT PRIMARY KEY,
+    user_id    INT8      NULL REFERENCES users (id) ON DELETE CASCADE,
+    ceremony   TEXT      NOT NULL,
+    data       JSONB     NOT NULL,
+    expired_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
+
+INSERT INTO audit_event (event, enable)
+VALUES
+       ('webauthn_credential_changed', true);
+
+-- +goose Down
+DELETE FROM audit_event WHERE event = 'webauthn_credential_changed';
+
+DROP TABLE webauthn_sessions;
+DROP TABLE webauthn_credentials;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE roles
+    ADD COLUMN allowed_cidrs JSONB NOT NULL DEFAULT '[]'::jsonb,
+    ADD COLUMN time_windows JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+INSERT INTO audit_event (event, enable)
+VALUES
+       ('access_restricted', true);
+
+-- +goose Down
+DELETE FROM audit_event WHERE event = 'access_restricted';
+
+ALTER TABLE roles
+    DROP COLUMN allowed_cidrs,
+    DROP COLUMN time_windows;
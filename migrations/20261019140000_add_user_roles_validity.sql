@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE user_roles
+    ADD COLUMN valid_from TIMESTAMP NULL,
+    ADD COLUMN valid_until TIMESTAMP NULL;
+
+CREATE INDEX IX_user_roles__valid_until ON user_roles (valid_until) WHERE valid_until IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IX_user_roles__valid_until;
+
+ALTER TABLE user_roles
+    DROP COLUMN valid_from,
+    DROP COLUMN valid_until;
@@ -0,0 +1,33 @@
+-- +goose Up
+ALTER TABLE roles
+    ADD COLUMN approver_ids JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+CREATE TABLE access_requests
+(
+    id             SERIAL PRIMARY KEY,
+    user_id        INT8      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_id        INTEGER   NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    justification  TEXT      NOT NULL,
+    status         TEXT      NOT NULL,
+    valid_until    TIMESTAMP NULL,
+    reviewer_id    INT8      NULL REFERENCES users (id) ON DELETE SET NULL,
+    review_comment TEXT      NOT NULL DEFAULT '',
+    created_at     TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc'),
+    updated_at     TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
+
+CREATE UNIQUE INDEX ux_access_requests__user_id_role_id_pending ON access_requests (user_id, role_id)
+    WHERE status = 'PENDING';
+CREATE INDEX ix_access_requests__role_id ON access_requests (role_id);
+
+INSERT INTO audit_event (event, enable)
+VALUES
+       ('access_request_changed', true);
+
+-- +goose Down
+DELETE FROM audit_event WHERE event = 'access_request_changed';
+
+DROP TABLE access_requests;
+
+ALTER TABLE roles
+    DROP COLUMN approver_ids;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE pending_changes
+(
+    id          SERIAL PRIMARY KEY,
+    operation   TEXT      NOT NULL,
+    payload     JSONB     NOT NULL,
+    diff        TEXT      NOT NULL DEFAULT '',
+    status      TEXT      NOT NULL,
+    created_by  INT8      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reviewer_id INT8      NULL REFERENCES users (id) ON DELETE SET NULL,
+    expired_at  TIMESTAMP NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc'),
+    updated_at  TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
+
+CREATE INDEX ix_pending_changes__status ON pending_changes (status);
+
+INSERT INTO audit_event (event, enable)
+VALUES
+       ('pending_change', true);
+
+-- +goose Down
+DELETE FROM audit_event WHERE event = 'pending_change';
+
+DROP TABLE pending_changes;
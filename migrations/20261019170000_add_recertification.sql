@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE recertification_campaigns
+(
+    id           SERIAL PRIMARY KEY,
+    name         TEXT      NOT NULL,
+    user_ids     JSONB     NOT NULL DEFAULT '[]'::jsonb,
+    role_ids     JSONB     NOT NULL DEFAULT '[]'::jsonb,
+    reviewer_ids JSONB     NOT NULL DEFAULT '[]'::jsonb,
+    status       TEXT      NOT NULL,
+    deadline     TIMESTAMP NOT NULL,
+    created_by   INT8      NULL REFERENCES users (id) ON DELETE SET NULL,
+    closed_at    TIMESTAMP NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc'),
+    updated_at   TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
+
+CREATE INDEX ix_recertification_campaigns__status_deadline ON recertification_campaigns (status, deadline);
+
+CREATE TABLE recertification_items
+(
+    id             SERIAL PRIMARY KEY,
+    campaign_id    INTEGER   NOT NULL REFERENCES recertification_campaigns (id) ON DELETE CASCADE,
+    user_id        INT8      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_id        INTEGER   NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    last_active_at TIMESTAMP NULL,
+    decision       TEXT      NULL,
+    comment        TEXT      NOT NULL DEFAULT '',
+    reviewer_id    INT8      NULL REFERENCES users (id) ON DELETE SET NULL,
+    decided_at     TIMESTAMP NULL
+);
+
+CREATE UNIQUE INDEX ux_recertification_items__campaign_id_user_id_role_id
+    ON recertification_items (campaign_id, user_id, role_id);
+
+INSERT INTO audit_event (event, enable)
+VALUES
+       ('recertification', true);
+
+-- +goose Down
+DELETE FROM audit_event WHERE event = 'recertification';
+
+DROP TABLE recertification_items;
+DROP TABLE recertification_campaigns;
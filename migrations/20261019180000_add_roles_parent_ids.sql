@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE roles
+    ADD COLUMN parent_ids JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- +goose Down
+ALTER TABLE roles
+    DROP COLUMN parent_ids;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE roles
+    ADD COLUMN resource_scopes JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- +goose Down
+ALTER TABLE roles
+    DROP COLUMN resource_scopes;
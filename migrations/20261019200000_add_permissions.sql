@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE permissions
+(
+    key         TEXT PRIMARY KEY,
+    name        TEXT      NOT NULL,
+    description TEXT      NOT NULL DEFAULT '',
+    category    TEXT      NOT NULL DEFAULT '',
+    module      TEXT      NOT NULL,
+    deprecated  BOOLEAN   NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc'),
+    updated_at  TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
+
+-- +goose Down
+DROP TABLE permissions;
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE role_versions
+(
+    id             SERIAL PRIMARY KEY,
+    role_id        INTEGER   NOT NULL,
+    version        INTEGER   NOT NULL,
+    operation      TEXT      NOT NULL,
+    snapshot       JSONB     NOT NULL,
+    changed_by     INT8      NULL REFERENCES users (id) ON DELETE SET NULL,
+    change_message TEXT      NOT NULL DEFAULT '',
+    created_at     TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc'),
+    UNIQUE (role_id, version)
+);
+
+INSERT INTO role_versions (role_id, version, operation, snapshot, change_message)
+SELECT id,
+       1,
+       'CREATE',
+       jsonb_build_object(
+               'name', name,
+               'externalGroup', external_group,
+               'permissions', permissions,
+               'allowedCidrs', allowed_cidrs,
+               'timeWindows', time_windows,
+               'approverIds', approver_ids,
+               'parentIds', parent_ids,
+               'resourceScopes', resource_scopes
+       ),
+       'Исходное состояние'
+FROM roles;
+
+-- +goose Down
+DROP TABLE role_versions;
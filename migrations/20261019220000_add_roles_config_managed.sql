@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE roles
+    ADD COLUMN config_managed BOOL NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE roles
+    DROP COLUMN config_managed;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE roles
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE users
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE roles
+    DROP COLUMN version;
+ALTER TABLE users
+    DROP COLUMN version;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE magic_link_requests
+(
+    id         SERIAL PRIMARY KEY,
+    email      TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
+
+CREATE INDEX ix_magic_link_requests__email_created_at ON magic_link_requests (email, created_at);
+
+-- +goose Down
+DROP TABLE magic_link_requests;
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+const (
+	idAccessRequestsColumn            = "id"
+	userIdAccessRequestsColumn        = "user_id"
+	roleIdAccessRequestsColumn        = "role_id"
+	justificationAccessRequestsColumn = "justification"
+	statusAccessRequestsColumn        = "status"
+	validUntilAccessRequestsColumn    = "valid_until"
+	reviewerIdAccessRequestsColumn    = "reviewer_id"
+	reviewCommentAccessRequestsColumn = "review_comment"
+	createdAtAccessRequestsColumn     = "created_at"
+	updatedAtAccessRequestsColumn     = "updated_at"
+)
+
+type AccessRequest struct {
+	db db.DB
+}
+
+func NewAccessRequest(db db.DB) AccessRequest {
+	return AccessRequest{db: db}
+}
+
+func (r AccessRequest) GetAccessRequestById(ctx context.Context, id int) (*entity.AccessRequest, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessRequest.GetAccessRequestById")
+
+	q, args, err := query.New().
+		Select(accessRequestColumns()...).
+		From("access_requests").
+		Where(squirrel.Eq{idAccessRequestsColumn: id}).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.AccessRequest{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+func (r AccessRequest) AllByRequest(
+	ctx context.Context,
+	req domain.AccessRequestPageRequest,
+	scope *entity.AccessRequestScope,
+) ([]entity.AccessRequest, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessRequest.AllByRequest")
+
+	q := query.New().
+		Select(accessRequestColumns()...).
+		From("access_requests").
+		OrderBy("created_at DESC", "id DESC").
+		Offset(req.Offset).
+		Limit(req.Limit)
+
+	query, args, err := reqAccessRequestQuery(q, req.Query, scope).ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.AccessRequest, 0)
+	err = r.db.Select(ctx, &result, query, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", query)
+	}
+
+	return result, nil
+}
+
+func (r AccessRequest) Count(
+	ctx context.Context,
+	reqQuery *domain.AccessRequestQuery,
+	scope *entity.AccessRequestScope,
+) (int64, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessRequest.Count")
+
+	q := query.New().
+		Select("count(*)").
+		From("access_requests")
+
+	query, args, err := reqAccessRequestQuery(q, reqQuery, scope).ToSql()
+	if err != nil {
+		return 0, errors.WithMessage(err, "build query")
+	}
+
+	count := int64(0)
+	err = r.db.SelectRow(ctx, &count, query, args...)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "select: %s", query)
+	}
+
+	return count, nil
+}
+
+// InsertAccessRequest saves pending request, domain.ErrAlreadyExists is returned if pending request of the user for the role exists
+func (r AccessRequest) InsertAccessRequest(ctx context.Context, req entity.AccessRequest) (*entity.AccessRequest, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessRequest.InsertAccessRequest")
+
+	q, args, err := query.New().
+		Insert("access_requests").
+		Columns(
+			userIdAccessRequestsColumn,
+			roleIdAccessRequestsColumn,
+			justificationAccessRequestsColumn,
+			statusAccessRequestsColumn,
+			validUntilAccessRequestsColumn,
+		).
+		Values(req.UserId, req.RoleId, req.Justification, entity.AccessRequestPending, req.ValidUntil).
+		Suffix("ON CONFLICT (user_id, role_id) WHERE status = 'PENDING' DO NOTHING RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.AccessRequest{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrAlreadyExists
+	case err != nil:
+		return nil, errors.WithMessagef(err, "insert: %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+// ResolveAccessRequest moves pending request to the final status,
+// domain.ErrAccessRequestNotPending is returned if request was resolved earlier
+func (r AccessRequest) ResolveAccessRequest(ctx context.Context, req entity.AccessRequest) (*entity.AccessRequest, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessRequest.ResolveAccessRequest")
+
+	q, args, err := query.New().
+		Update("access_requests").
+		SetMap(map[string]any{
+			statusAccessRequestsColumn:        req.Status,
+			validUntilAccessRequestsColumn:    req.ValidUntil,
+			reviewerIdAccessRequestsColumn:    req.ReviewerId,
+			reviewCommentAccessRequestsColumn: req.ReviewComment,
+			updatedAtAccessRequestsColumn:     time.Now().UTC(),
+		}).
+		Where(squirrel.Eq{
+			idAccessRequestsColumn:     req.Id,
+			statusAccessRequestsColumn: entity.AccessRequestPending,
+		}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.AccessRequest{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrAccessRequestNotPending
+	case err != nil:
+		return nil, errors.WithMessagef(err, "update: %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+func reqAccessRequestQuery(
+	q squirrel.SelectBuilder,
+	reqQuery *domain.AccessRequestQuery,
+	scope *entity.AccessRequestScope,
+) squirrel.SelectBuilder {
+	if scope != nil {
+		q = q.Where(squirrel.Or{
+			squirrel.Eq{userIdAccessRequestsColumn: scope.UserId},
+			squirrel.Eq{roleIdAccessRequestsColumn: scope.RoleIds},
+		})
+	}
+
+	if reqQuery == nil {
+		return q
+	}
+
+	if reqQuery.UserId != nil {
+		q = q.Where(squirrel.Eq{userIdAccessRequestsColumn: reqQuery.UserId})
+	}
+
+	if reqQuery.RoleId != nil {
+		q = q.Where(squirrel.Eq{roleIdAccessRequestsColumn: reqQuery.RoleId})
+	}
+
+	if reqQuery.Status != nil {
+		q = q.Where(squirrel.Eq{statusAccessRequestsColumn: reqQuery.Status})
+	}
+
+	if reqQuery.CreatedAt != nil {
+		q = q.Where(squirrel.GtOrEq{createdAtAccessRequestsColumn: reqQuery.CreatedAt.From}).
+			Where(squirrel.Lt{createdAtAccessRequestsColumn: reqQuery.CreatedAt.To})
+	}
+
+	return q
+}
+
+func accessRequestColumns() []string {
+	return []string{
+		idAccessRequestsColumn,
+		userIdAccessRequestsColumn,
+		roleIdAccessRequestsColumn,
+		justificationAccessRequestsColumn,
+		statusAccessRequestsColumn,
+		validUntilAccessRequestsColumn,
+		reviewerIdAccessRequestsColumn,
+		reviewCommentAccessRequestsColumn,
+		createdAtAccessRequestsColumn,
+		updatedAtAccessRequestsColumn,
+	}
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+const (
+	idExternalGroupRulesColumn        = "id"
+	patternExternalGroupRulesColumn   = "pattern"
+	matchTypeExternalGroupRulesColumn = "match_type"
+	roleIdExternalGroupRulesColumn    = "role_id"
+	priorityExternalGroupRulesColumn  = "priority"
+	terminalExternalGroupRulesColumn  = "terminal"
+	createdAtExternalGroupRulesColumn = "created_at"
+	updatedAtExternalGroupRulesColumn = "updated_at"
+)
+
+type ExternalGroupRule struct {
+	db db.DB
+}
+
+func NewExternalGroupRule(db db.DB) ExternalGroupRule {
+	return ExternalGroupRule{db: db}
+}
+
+func (r ExternalGroupRule) GetExternalGroupRules(ctx context.Context) ([]entity.ExternalGroupRule, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "ExternalGroupRule.GetExternalGroupRules")
+
+	q, args, err := query.New().
+		Select(
+			idExternalGroupRulesColumn,
+			patternExternalGroupRulesColumn,
+			matchTypeExternalGroupRulesColumn,
+			roleIdExternalGroupRulesColumn,
+			priorityExternalGroupRulesColumn,
+			terminalExternalGroupRulesColumn,
+			createdAtExternalGroupRulesColumn,
+			updatedAtExternalGroupRulesColumn,
+		).
+		From("external_group_rules").
+		OrderBy("priority DESC", "id ASC").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	rules := make([]entity.ExternalGroupRule, 0)
+	err = r.db.Select(ctx, &rules, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+
+	return rules, nil
+}
+
+func (r ExternalGroupRule) InsertExternalGroupRule(ctx context.Context, rule entity.ExternalGroupRule) (*entity.ExternalGroupRule, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "ExternalGroupRule.InsertExternalGroupRule")
+
+	q, args, err := query.New().
+		Insert("external_group_rules").
+		Columns(
+			patternExternalGroupRulesColumn,
+			matchTypeExternalGroupRulesColumn,
+			roleIdExternalGroupRulesColumn,
+			priorityExternalGroupRulesColumn,
+			terminalExternalGroupRulesColumn,
+		).
+		Values(rule.Pattern, rule.MatchType, rule.RoleId, rule.Priority, rule.Terminal).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	var result entity.ExternalGroupRule
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "insert: %s", q)
+	}
+
+	return &result, nil
+}
+
+func (r ExternalGroupRule) UpdateExternalGroupRule(ctx context.Context, rule entity.ExternalGroupRule) (*entity.ExternalGroupRule, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "ExternalGroupRule.UpdateExternalGroupRule")
+
+	q, args, err := query.New().
+		Update("external_group_rules").
+		SetMap(map[string]any{
+			patternExternalGroupRulesColumn:   rule.Pattern,
+			matchTypeExternalGroupRulesColumn: rule.MatchType,
+			roleIdExternalGroupRulesColumn:    rule.RoleId,
+			priorityExternalGroupRulesColumn:  rule.Priority,
+			terminalExternalGroupRulesColumn:  rule.Terminal,
+			updatedAtExternalGroupRulesColumn: time.Now().UTC(),
+		}).
+		Where(squirrel.Eq{"id": rule.Id}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	var result entity.ExternalGroupRule
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "update: %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+func (r ExternalGroupRule) DeleteExternalGroupRule(ctx context.Context, id int) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "ExternalGroupRule.DeleteExternalGroupRule")
+
+	q, args, err := query.New().
+		Delete("external_group_rules").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	return nil
+}
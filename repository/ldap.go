@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+const (
+	defaultLdapTimeout        = 10 * time.Second
+	defaultEmailAttribute     = "mail"
+	defaultFirstNameAttribute = "givenName"
+	defaultLastNameAttribute  = "sn"
+	defaultFullNameAttribute  = "displayName"
+	memberOfAttribute         = "memberOf"
+)
+
+type Ldap struct {
+	cfg *conf.Ldap
+}
+
+func NewLdap(cfg *conf.Ldap) Ldap {
+	return Ldap{
+		cfg: cfg,
+	}
+}
+
+// Authenticate binds to the directory as the user and reads the user entry with the user's own credentials
+func (r Ldap) Authenticate(ctx context.Context, login string, password string) (*entity.LdapUser, error) {
+	conn, err := r.dial(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "dial")
+	}
+	defer conn.Close()
+
+	err = conn.Bind(r.bindName(login), password)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return nil, errors.WithMessage(domain.ErrUnauthenticated, "invalid ldap credentials")
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "bind")
+	}
+
+	emailAttr := valueOrDefault(r.cfg.EmailAttribute, defaultEmailAttribute)
+	firstNameAttr := valueOrDefault(r.cfg.FirstNameAttribute, defaultFirstNameAttribute)
+	lastNameAttr := valueOrDefault(r.cfg.LastNameAttribute, defaultLastNameAttribute)
+	fullNameAttr := valueOrDefault(r.cfg.FullNameAttribute, defaultFullNameAttribute)
+	req := ldap.NewSearchRequest(
+		r.cfg.BaseDn,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2, //nolint:mnd
+		int(r.timeout().Seconds()),
+		false,
+		fmt.Sprintf(r.cfg.UserFilter, ldap.EscapeFilter(login)),
+		[]string{emailAttr, firstNameAttr, lastNameAttr, fullNameAttr, memberOfAttribute},
+		nil,
+	)
+	result, err := conn.Search(req)
+	switch {
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject):
+		return nil, errors.WithMessage(domain.ErrUnauthenticated, "ldap user not found")
+	case err != nil:
+		return nil, errors.WithMessage(err, "search user")
+	case len(result.Entries) != 1:
+		return nil, errors.WithMessagef(domain.ErrUnauthenticated, "expected exactly one ldap entry, got %d", len(result.Entries))
+	}
+
+	entry := result.Entries[0]
+	return &entity.LdapUser{
+		Email:     entry.GetAttributeValue(emailAttr),
+		FirstName: entry.GetAttributeValue(firstNameAttr),
+		LastName:  entry.GetAttributeValue(lastNameAttr),
+		FullName:  entry.GetAttributeValue(fullNameAttr),
+		Groups:    entry.GetAttributeValues(memberOfAttribute),
+	}, nil
+}
+
+// bindName returns the name, the directory is bound with, the login is escaped only for dn templates,
+// upn templates like %s@corp.local get the login as is
+func (r Ldap) bindName(login string) string {
+	if strings.Contains(r.cfg.BindDnTemplate, "=") {
+		login = ldap.EscapeDN(login)
+	}
+	return fmt.Sprintf(r.cfg.BindDnTemplate, login)
+}
+
+func (r Ldap) dial(ctx context.Context) (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: r.cfg.InsecureSkipVerify, //nolint:gosec
+		MinVersion:         tls.VersionTLS12,
+	}
+	if r.cfg.RootCa != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(r.cfg.RootCa)) {
+			return nil, errors.New("invalid root ca")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	timeout := r.timeout()
+	dialer := &net.Dialer{Timeout: timeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	conn, err := ldap.DialURL(r.cfg.Url, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "dial url %s", r.cfg.Url)
+	}
+	conn.SetTimeout(timeout)
+
+	if r.cfg.StartTls {
+		serverUrl, err := url.Parse(r.cfg.Url)
+		if err != nil {
+			_ = conn.Close()
+			return nil, errors.WithMessage(err, "parse url")
+		}
+		tlsConfig.ServerName = serverUrl.Hostname()
+		err = conn.StartTLS(tlsConfig)
+		if err != nil {
+			_ = conn.Close()
+			return nil, errors.WithMessage(err, "start tls")
+		}
+	}
+
+	return conn, nil
+}
+
+func (r Ldap) timeout() time.Duration {
+	if r.cfg.TimeoutInSec <= 0 {
+		return defaultLdapTimeout
+	}
+	return time.Duration(r.cfg.TimeoutInSec) * time.Second
+}
+
+func valueOrDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+const (
+	userIdMagicLinksColumn    = "user_id"
+	tokenHashMagicLinksColumn = "token_hash"
+	expiredAtMagicLinksColumn = "expired_at"
+	usedAtMagicLinksColumn    = "used_at"
+	createdAtMagicLinksColumn = "created_at"
+
+	emailMagicLinkRequestsColumn = "email"
+)
+
+type MagicLink struct {
+	db db.DB
+}
+
+func NewMagicLink(db db.DB) MagicLink {
+	return MagicLink{db: db}
+}
+
+func (r MagicLink) InsertMagicLink(ctx context.Context, link entity.MagicLink) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "MagicLink.InsertMagicLink")
+
+	q, args, err := query.New().
+		Insert("magic_links").
+		Columns(userIdMagicLinksColumn, tokenHashMagicLinksColumn, expiredAtMagicLinksColumn, createdAtMagicLinksColumn).
+		Values(link.UserId, link.TokenHash, link.ExpiredAt, link.CreatedAt).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	return nil
+}
+
+// UseMagicLink marks unused and not expired link as used, so link can be exchanged only once
+func (r MagicLink) UseMagicLink(ctx context.Context, tokenHash string, usedAt time.Time) (*entity.MagicLink, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "MagicLink.UseMagicLink")
+
+	q, args, err := query.New().
+		Update("magic_links").
+		Set(usedAtMagicLinksColumn, usedAt).
+		Where(squirrel.Eq{tokenHashMagicLinksColumn: tokenHash, usedAtMagicLinksColumn: nil}).
+		Where(squirrel.Gt{expiredAtMagicLinksColumn: usedAt}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	link := entity.MagicLink{}
+	err = r.db.SelectRow(ctx, &link, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "select row: %s", q)
+	}
+
+	return &link, nil
+}
+
+func (r MagicLink) InsertMagicLinkRequest(ctx context.Context, email string, createdAt time.Time) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "MagicLink.InsertMagicLinkRequest")
+
+	q, args, err := query.New().
+		Insert("magic_link_requests").
+		Columns(emailMagicLinkRequestsColumn, createdAtMagicLinksColumn).
+		Values(email, createdAt).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	return nil
+}
+
+func (r MagicLink) CountMagicLinkRequestsSince(ctx context.Context, email string, since time.Time) (int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "MagicLink.CountMagicLinkRequestsSince")
+
+	q, args, err := query.New().
+		Select("count(*)").
+		From("magic_link_requests").
+		Where(squirrel.Eq{emailMagicLinkRequestsColumn: email}).
+		Where(squirrel.GtOrEq{createdAtMagicLinksColumn: since}).
+		ToSql()
+	if err != nil {
+		return 0, errors.WithMessage(err, "build query")
+	}
+
+	count := 0
+	err = r.db.SelectRow(ctx, &count, q, args...)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "select row: %s", q)
+	}
+
+	return count, nil
+}
+
+func (r MagicLink) DeleteMagicLinkRequestsBefore(ctx context.Context, before time.Time) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "MagicLink.DeleteMagicLinkRequestsBefore")
+
+	q, args, err := query.New().
+		Delete("magic_link_requests").
+		Where(squirrel.Lt{createdAtMagicLinksColumn: before}).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+const (
+	idPendingChangesColumn         = "id"
+	operationPendingChangesColumn  = "operation"
+	payloadPendingChangesColumn    = "payload"
+	diffPendingChangesColumn       = "diff"
+	statusPendingChangesColumn     = "status"
+	createdByPendingChangesColumn  = "created_by"
+	reviewerIdPendingChangesColumn = "reviewer_id"
+	expiredAtPendingChangesColumn  = "expired_at"
+	createdAtPendingChangesColumn  = "created_at"
+	updatedAtPendingChangesColumn  = "updated_at"
+)
+
+type PendingChange struct {
+	db db.DB
+}
+
+func NewPendingChange(db db.DB) PendingChange {
+	return PendingChange{db: db}
+}
+
+// GetPendingChangeByIdForUpdate locks the change until the end of the transaction
+func (r PendingChange) GetPendingChangeByIdForUpdate(ctx context.Context, id int) (*entity.PendingChange, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "PendingChange.GetPendingChangeByIdForUpdate")
+
+	q, args, err := query.New().
+		Select(pendingChangeColumns()...).
+		From("pending_changes").
+		Where(squirrel.Eq{idPendingChangesColumn: id}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.PendingChange{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+func (r PendingChange) AllByRequest(ctx context.Context, req domain.PendingChangePageRequest) ([]entity.PendingChange, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "PendingChange.AllByRequest")
+
+	q := query.New().
+		Select(pendingChangeColumns()...).
+		From("pending_changes").
+		OrderBy("created_at DESC", "id DESC").
+		Offset(req.Offset).
+		Limit(req.Limit)
+
+	query, args, err := reqPendingChangeQuery(q, req.Query).ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.PendingChange, 0)
+	err = r.db.Select(ctx, &result, query, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", query)
+	}
+
+	return result, nil
+}
+
+func (r PendingChange) Count(ctx context.Context, reqQuery *domain.PendingChangeQuery) (int64, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "PendingChange.Count")
+
+	q := query.New().
+		Select("count(*)").
+		From("pending_changes")
+
+	query, args, err := reqPendingChangeQuery(q, reqQuery).ToSql()
+	if err != nil {
+		return 0, errors.WithMessage(err, "build query")
+	}
+
+	count := int64(0)
+	err = r.db.SelectRow(ctx, &count, query, args...)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "select: %s", query)
+	}
+
+	return count, nil
+}
+
+func (r PendingChange) InsertPendingChange(ctx context.Context, change entity.PendingChange) (*entity.PendingChange, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "PendingChange.InsertPendingChange")
+
+	q, args, err := query.New().
+		Insert("pending_changes").
+		Columns(
+			operationPendingChangesColumn,
+			payloadPendingChangesColumn,
+			diffPendingChangesColumn,
+			statusPendingChangesColumn,
+			createdByPendingChangesColumn,
+			expiredAtPendingChangesColumn,
+		).
+		Values(
+			change.Operation,
+			[]byte(change.Payload),
+			change.Diff,
+			entity.PendingChangePending,
+			change.CreatedBy,
+			change.ExpiredAt,
+		).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.PendingChange{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "insert: %s", q)
+	}
+
+	return &result, nil
+}
+
+// ResolvePendingChange moves not expired pending change to status,
+// domain.ErrPendingChangeNotPending is returned if the change was resolved earlier or expired
+func (r PendingChange) ResolvePendingChange(
+	ctx context.Context,
+	id int,
+	status string,
+	reviewerId int64,
+	now time.Time,
+) (*entity.PendingChange, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "PendingChange.ResolvePendingChange")
+
+	q, args, err := query.New().
+		Update("pending_changes").
+		SetMap(map[string]any{
+			statusPendingChangesColumn:     status,
+			reviewerIdPendingChangesColumn: reviewerId,
+			updatedAtPendingChangesColumn:  now,
+		}).
+		Where(squirrel.Eq{
+			idPendingChangesColumn:     id,
+			statusPendingChangesColumn: entity.PendingChangePending,
+		}).
+		Where(squirrel.Gt{expiredAtPendingChangesColumn: now}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.PendingChange{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrPendingChangeNotPending
+	case err != nil:
+		return nil, errors.WithMessagef(err, "update: %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+func (r PendingChange) ExpirePendingChanges(ctx context.Context, now time.Time) ([]entity.PendingChange, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "PendingChange.ExpirePendingChanges")
+
+	q, args, err := query.New().
+		Update("pending_changes").
+		SetMap(map[string]any{
+			statusPendingChangesColumn:    entity.PendingChangeExpired,
+			updatedAtPendingChangesColumn: now,
+		}).
+		Where(squirrel.Eq{statusPendingChangesColumn: entity.PendingChangePending}).
+		Where(squirrel.LtOrEq{expiredAtPendingChangesColumn: now}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.PendingChange, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "update: %s", q)
+	}
+
+	return result, nil
+}
+
+func reqPendingChangeQuery(q squirrel.SelectBuilder, reqQuery *domain.PendingChangeQuery) squirrel.SelectBuilder {
+	if reqQuery == nil {
+		return q
+	}
+
+	if reqQuery.Operation != nil {
+		q = q.Where(squirrel.Eq{operationPendingChangesColumn: reqQuery.Operation})
+	}
+
+	if reqQuery.Status != nil {
+		q = q.Where(squirrel.Eq{statusPendingChangesColumn: reqQuery.Status})
+	}
+
+	if reqQuery.CreatedBy != nil {
+		q = q.Where(squirrel.Eq{createdByPendingChangesColumn: reqQuery.CreatedBy})
+	}
+
+	return q
+}
+
+func pendingChangeColumns() []string {
+	return []string{
+		idPendingChangesColumn,
+		operationPendingChangesColumn,
+		payloadPendingChangesColumn,
+		diffPendingChangesColumn,
+		statusPendingChangesColumn,
+		createdByPendingChangesColumn,
+		reviewerIdPendingChangesColumn,
+		expiredAtPendingChangesColumn,
+		createdAtPendingChangesColumn,
+		updatedAtPendingChangesColumn,
+	}
+}
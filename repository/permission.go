@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+
+	"msp-admin-service/entity"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+)
+
+type Permission struct {
+	db db.DB
+}
+
+func NewPermission(db db.DB) Permission {
+	return Permission{
+		db: db,
+	}
+}
+
+func (r Permission) All(ctx context.Context) ([]entity.Permission, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Permission.All")
+
+	q, args, err := query.New().
+		Select("*").
+		From("permissions").
+		OrderBy("category", "key").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.Permission, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+	return result, nil
+}
+
+// Upsert inserts permissions or updates permissions of the same module, permissions of other modules are kept
+func (r Permission) Upsert(ctx context.Context, permissions []entity.Permission) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Permission.Upsert")
+
+	qBuilder := query.New().
+		Insert("permissions").
+		Columns("key", "name", "description", "category", "module")
+	for _, permission := range permissions {
+		qBuilder = qBuilder.Values(permission.Key, permission.Name, permission.Description, permission.Category, permission.Module)
+	}
+	q, args, err := qBuilder.
+		Suffix("ON CONFLICT (key) DO UPDATE SET " +
+			"name = EXCLUDED.name, description = EXCLUDED.description, category = EXCLUDED.category, " +
+			"deprecated = false, updated_at = (now() at time zone 'utc') " +
+			"WHERE permissions.module = EXCLUDED.module").
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+	return nil
+}
+
+func (r Permission) Deprecate(ctx context.Context, module string, keys []string) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Permission.Deprecate")
+
+	q, args, err := query.New().
+		Update("permissions").
+		Set("deprecated", true).
+		Set("updated_at", squirrel.Expr("(now() at time zone 'utc')")).
+		Where(squirrel.Eq{"module": module, "key": keys}).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+	return nil
+}
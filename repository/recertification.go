@@ -0,0 +1,565 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+const (
+	idRecertificationCampaignsColumn          = "id"
+	nameRecertificationCampaignsColumn        = "name"
+	userIdsRecertificationCampaignsColumn     = "user_ids"
+	roleIdsRecertificationCampaignsColumn     = "role_ids"
+	reviewerIdsRecertificationCampaignsColumn = "reviewer_ids"
+	statusRecertificationCampaignsColumn      = "status"
+	deadlineRecertificationCampaignsColumn    = "deadline"
+	createdByRecertificationCampaignsColumn   = "created_by"
+	closedAtRecertificationCampaignsColumn    = "closed_at"
+	updatedAtRecertificationCampaignsColumn   = "updated_at"
+
+	idRecertificationItemsColumn           = "id"
+	campaignIdRecertificationItemsColumn   = "campaign_id"
+	userIdRecertificationItemsColumn       = "user_id"
+	roleIdRecertificationItemsColumn       = "role_id"
+	lastActiveAtRecertificationItemsColumn = "last_active_at"
+	decisionRecertificationItemsColumn     = "decision"
+	commentRecertificationItemsColumn      = "comment"
+	reviewerIdRecertificationItemsColumn   = "reviewer_id"
+	decidedAtRecertificationItemsColumn    = "decided_at"
+)
+
+type Recertification struct {
+	db db.DB
+}
+
+func NewRecertification(db db.DB) Recertification {
+	return Recertification{db: db}
+}
+
+func (r Recertification) GetCampaignById(ctx context.Context, id int) (*entity.RecertificationCampaign, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.GetCampaignById")
+
+	q, args, err := query.New().
+		Select("*").
+		From("recertification_campaigns").
+		Where(squirrel.Eq{idRecertificationCampaignsColumn: id}).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.RecertificationCampaign{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+func (r Recertification) GetCampaignsByIds(ctx context.Context, ids []int) ([]entity.RecertificationCampaign, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.GetCampaignsByIds")
+
+	q, args, err := query.New().
+		Select("*").
+		From("recertification_campaigns").
+		Where(squirrel.Eq{idRecertificationCampaignsColumn: ids}).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.RecertificationCampaign, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+
+	return result, nil
+}
+
+func (r Recertification) AllCampaigns(
+	ctx context.Context,
+	req domain.RecertificationCampaignPageRequest,
+) ([]entity.RecertificationCampaign, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.AllCampaigns")
+
+	q := query.New().
+		Select("*").
+		From("recertification_campaigns").
+		OrderBy("created_at DESC", "id DESC").
+		Offset(req.Offset).
+		Limit(req.Limit)
+
+	query, args, err := reqRecertificationCampaignQuery(q, req.Query).ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.RecertificationCampaign, 0)
+	err = r.db.Select(ctx, &result, query, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", query)
+	}
+
+	return result, nil
+}
+
+func (r Recertification) CountCampaigns(ctx context.Context, reqQuery *domain.RecertificationCampaignQuery) (int64, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.CountCampaigns")
+
+	q := query.New().
+		Select("count(*)").
+		From("recertification_campaigns")
+
+	query, args, err := reqRecertificationCampaignQuery(q, reqQuery).ToSql()
+	if err != nil {
+		return 0, errors.WithMessage(err, "build query")
+	}
+
+	count := int64(0)
+	err = r.db.SelectRow(ctx, &count, query, args...)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "select: %s", query)
+	}
+
+	return count, nil
+}
+
+func (r Recertification) InsertCampaign(
+	ctx context.Context,
+	campaign entity.RecertificationCampaign,
+) (*entity.RecertificationCampaign, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.InsertCampaign")
+
+	q, args, err := query.New().
+		Insert("recertification_campaigns").
+		Columns(
+			nameRecertificationCampaignsColumn,
+			userIdsRecertificationCampaignsColumn,
+			roleIdsRecertificationCampaignsColumn,
+			reviewerIdsRecertificationCampaignsColumn,
+			statusRecertificationCampaignsColumn,
+			deadlineRecertificationCampaignsColumn,
+			createdByRecertificationCampaignsColumn,
+		).
+		Values(
+			campaign.Name,
+			campaign.UserIds,
+			campaign.RoleIds,
+			campaign.ReviewerIds,
+			entity.RecertificationActive,
+			campaign.Deadline,
+			campaign.CreatedBy,
+		).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.RecertificationCampaign{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "insert: %s", q)
+	}
+
+	return &result, nil
+}
+
+// CloseCampaign moves active campaign to closed status, domain.ErrRecertificationClosed is returned if it was closed earlier
+func (r Recertification) CloseCampaign(ctx context.Context, id int, now time.Time) (*entity.RecertificationCampaign, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.CloseCampaign")
+
+	q, args, err := query.New().
+		Update("recertification_campaigns").
+		SetMap(map[string]any{
+			statusRecertificationCampaignsColumn:    entity.RecertificationClosed,
+			closedAtRecertificationCampaignsColumn:  now,
+			updatedAtRecertificationCampaignsColumn: now,
+		}).
+		Where(squirrel.Eq{
+			idRecertificationCampaignsColumn:     id,
+			statusRecertificationCampaignsColumn: entity.RecertificationActive,
+		}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.RecertificationCampaign{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrRecertificationClosed
+	case err != nil:
+		return nil, errors.WithMessagef(err, "update: %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+func (r Recertification) GetOverdueCampaigns(ctx context.Context, now time.Time) ([]entity.RecertificationCampaign, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.GetOverdueCampaigns")
+
+	q, args, err := query.New().
+		Select("*").
+		From("recertification_campaigns").
+		Where(squirrel.Eq{statusRecertificationCampaignsColumn: entity.RecertificationActive}).
+		Where(squirrel.LtOrEq{deadlineRecertificationCampaignsColumn: now}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.RecertificationCampaign, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+
+	return result, nil
+}
+
+func (r Recertification) Progress(ctx context.Context, campaignIds []int) (map[int]entity.RecertificationProgress, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.Progress")
+
+	q, args, err := query.New().
+		Select(
+			"campaign_id",
+			"count(*) total",
+			"count(*) FILTER (WHERE decision = 'KEEP') kept",
+			"count(*) FILTER (WHERE decision IN ('REVOKE', 'AUTO_REVOKE')) revoked",
+		).
+		From("recertification_items").
+		Where(squirrel.Eq{campaignIdRecertificationItemsColumn: campaignIds}).
+		GroupBy(campaignIdRecertificationItemsColumn).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	progress := make([]entity.RecertificationProgress, 0)
+	err = r.db.Select(ctx, &progress, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+
+	result := make(map[int]entity.RecertificationProgress, len(progress))
+	for _, p := range progress {
+		result[p.CampaignId] = p
+	}
+	return result, nil
+}
+
+// ScopeItems returns current role links of userIds and roleIds with the last user activity,
+// links matching both lists are returned if both are set
+func (r Recertification) ScopeItems(ctx context.Context, userIds []int64, roleIds []int) ([]entity.RecertificationItem, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.ScopeItems")
+
+	q := query.New().
+		Select("ur.user_id", "ur.role_id", "u.last_active_at").
+		From("user_roles ur").
+		Join("users u on u.id = ur.user_id").
+		OrderBy("ur.user_id", "ur.role_id")
+	if len(userIds) > 0 {
+		q = q.Where(squirrel.Eq{"ur.user_id": userIds})
+	}
+	if len(roleIds) > 0 {
+		q = q.Where(squirrel.Eq{"ur.role_id": roleIds})
+	}
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.RecertificationItem, 0)
+	err = r.db.Select(ctx, &result, query, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", query)
+	}
+
+	return result, nil
+}
+
+func (r Recertification) InsertItems(ctx context.Context, items []entity.RecertificationItem) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.InsertItems")
+
+	q := query.New().
+		Insert("recertification_items").
+		Columns(
+			campaignIdRecertificationItemsColumn,
+			userIdRecertificationItemsColumn,
+			roleIdRecertificationItemsColumn,
+			lastActiveAtRecertificationItemsColumn,
+		)
+	for _, item := range items {
+		q = q.Values(item.CampaignId, item.UserId, item.RoleId, item.LastActiveAt)
+	}
+	query, args, err := q.ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", query)
+	}
+
+	return nil
+}
+
+func (r Recertification) GetItemsByIds(ctx context.Context, ids []int) ([]entity.RecertificationItem, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.GetItemsByIds")
+
+	q, args, err := query.New().
+		Select("*").
+		From("recertification_items").
+		Where(squirrel.Eq{idRecertificationItemsColumn: ids}).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.RecertificationItem, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+
+	return result, nil
+}
+
+// AllItems returns items of campaigns, where reviewerId is a reviewer
+func (r Recertification) AllItems(
+	ctx context.Context,
+	req domain.RecertificationItemPageRequest,
+	reviewerId int64,
+) ([]entity.RecertificationItemView, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.AllItems")
+
+	q := recertificationItemViewQuery().
+		OrderBy("i.campaign_id DESC", "i.id").
+		Offset(req.Offset).
+		Limit(req.Limit)
+
+	q, err := reqRecertificationItemQuery(q, req.Query, reviewerId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.RecertificationItemView, 0)
+	err = r.db.Select(ctx, &result, query, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", query)
+	}
+
+	return result, nil
+}
+
+func (r Recertification) CountItems(ctx context.Context, reqQuery *domain.RecertificationItemQuery, reviewerId int64) (int64, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.CountItems")
+
+	q := query.New().
+		Select("count(*)").
+		From("recertification_items i")
+
+	q, err := reqRecertificationItemQuery(q, reqQuery, reviewerId)
+	if err != nil {
+		return 0, errors.WithMessage(err, "build query")
+	}
+	query, args, err := q.ToSql()
+	if err != nil {
+		return 0, errors.WithMessage(err, "build query")
+	}
+
+	count := int64(0)
+	err = r.db.SelectRow(ctx, &count, query, args...)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "select: %s", query)
+	}
+
+	return count, nil
+}
+
+func (r Recertification) GetItemsByCampaignId(ctx context.Context, campaignId int) ([]entity.RecertificationItemView, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.GetItemsByCampaignId")
+
+	q, args, err := recertificationItemViewQuery().
+		Where(squirrel.Eq{"i.campaign_id": campaignId}).
+		OrderBy("i.user_id", "i.role_id").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.RecertificationItemView, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+
+	return result, nil
+}
+
+func (r Recertification) DecideItems(
+	ctx context.Context,
+	ids []int,
+	decision string,
+	comment string,
+	reviewerId int64,
+	now time.Time,
+) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.DecideItems")
+
+	q, args, err := query.New().
+		Update("recertification_items").
+		SetMap(map[string]any{
+			decisionRecertificationItemsColumn:   decision,
+			commentRecertificationItemsColumn:    comment,
+			reviewerIdRecertificationItemsColumn: reviewerId,
+			decidedAtRecertificationItemsColumn:  now,
+		}).
+		Where(squirrel.Eq{idRecertificationItemsColumn: ids}).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	return nil
+}
+
+// AutoRevokeItems marks not reviewed items of the campaign as revoked automatically
+func (r Recertification) AutoRevokeItems(ctx context.Context, campaignId int, now time.Time) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.AutoRevokeItems")
+
+	q, args, err := query.New().
+		Update("recertification_items").
+		SetMap(map[string]any{
+			decisionRecertificationItemsColumn:  entity.RecertificationAutoRevoke,
+			decidedAtRecertificationItemsColumn: now,
+		}).
+		Where(squirrel.Eq{
+			campaignIdRecertificationItemsColumn: campaignId,
+			decisionRecertificationItemsColumn:   nil,
+		}).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	return nil
+}
+
+func (r Recertification) GetRevokedItems(ctx context.Context, campaignId int) ([]entity.RecertificationItem, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Recertification.GetRevokedItems")
+
+	q, args, err := query.New().
+		Select("*").
+		From("recertification_items").
+		Where(squirrel.Eq{
+			campaignIdRecertificationItemsColumn: campaignId,
+			decisionRecertificationItemsColumn:   []string{entity.RecertificationRevoke, entity.RecertificationAutoRevoke},
+		}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.RecertificationItem, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+
+	return result, nil
+}
+
+func recertificationItemViewQuery() squirrel.SelectBuilder {
+	return query.New().
+		Select("i.*", "u.email user_email", "r.name role_name").
+		From("recertification_items i").
+		Join("users u on u.id = i.user_id").
+		Join("roles r on r.id = i.role_id")
+}
+
+func reqRecertificationCampaignQuery(
+	q squirrel.SelectBuilder,
+	reqQuery *domain.RecertificationCampaignQuery,
+) squirrel.SelectBuilder {
+	if reqQuery == nil {
+		return q
+	}
+
+	if reqQuery.Status != nil {
+		q = q.Where(squirrel.Eq{statusRecertificationCampaignsColumn: reqQuery.Status})
+	}
+
+	return q
+}
+
+func reqRecertificationItemQuery(
+	q squirrel.SelectBuilder,
+	reqQuery *domain.RecertificationItemQuery,
+	reviewerId int64,
+) (squirrel.SelectBuilder, error) {
+	reviewerIds, err := entity.IdList{reviewerId}.Value()
+	if err != nil {
+		return q, errors.WithMessage(err, "marshal reviewer ids")
+	}
+	q = q.Where(squirrel.Expr(
+		"i.campaign_id IN (SELECT id FROM recertification_campaigns WHERE reviewer_ids @> ?::jsonb)",
+		reviewerIds,
+	))
+
+	if reqQuery == nil {
+		return q, nil
+	}
+
+	if reqQuery.CampaignId != nil {
+		q = q.Where(squirrel.Eq{"i.campaign_id": reqQuery.CampaignId})
+	}
+
+	if reqQuery.UserId != nil {
+		q = q.Where(squirrel.Eq{"i.user_id": reqQuery.UserId})
+	}
+
+	if reqQuery.RoleId != nil {
+		q = q.Where(squirrel.Eq{"i.role_id": reqQuery.RoleId})
+	}
+
+	if reqQuery.Undecided {
+		q = q.Where(squirrel.Eq{"i.decision": nil})
+	}
+
+	return q, nil
+}
@@ -18,14 +18,21 @@ type Role struct {
 }
 
 const (
-	idRolesColumn            = "id"
-	nameRolesColumn          = "name"
-	createdAtRolesColumn     = "created_at"
-	updatedAtRolesColumn     = "updated_at"
-	permissionsRolesColumn   = "permissions"
-	externalGroupRolesColumn = "external_group"
-	immutableRolesColumn     = "immutable"
-	exclusiveRolesColumn     = "exclusive"
+	idRolesColumn             = "id"
+	nameRolesColumn           = "name"
+	createdAtRolesColumn      = "created_at"
+	updatedAtRolesColumn      = "updated_at"
+	permissionsRolesColumn    = "permissions"
+	externalGroupRolesColumn  = "external_group"
+	immutableRolesColumn      = "immutable"
+	exclusiveRolesColumn      = "exclusive"
+	allowedCidrsRolesColumn   = "allowed_cidrs"
+	timeWindowsRolesColumn    = "time_windows"
+	approverIdsRolesColumn    = "approver_ids"
+	parentIdsRolesColumn      = "parent_ids"
+	resourceScopesRolesColumn = "resource_scopes"
+	configManagedRolesColumn  = "config_managed"
+	versionRolesColumn        = "version"
 )
 
 func NewRole(db db.DB) Role {
@@ -36,7 +43,10 @@ func (r Role) GetRoleByIds(ctx context.Context, id []int) ([]entity.Role, error)
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.GetRoleByIds")
 
 	q, args, err := query.New().
-		Select("id, name, external_group, permissions, created_at, updated_at").
+		Select(
+			"id, name, external_group, permissions, allowed_cidrs, time_windows, approver_ids, parent_ids, resource_scopes, config_managed, " +
+				"version, created_at, updated_at",
+		).
 		From("roles").
 		Where(squirrel.Eq{"id": id}).
 		ToSql()
@@ -68,6 +78,13 @@ func (r Role) GetRoleByName(ctx context.Context, name string) (*entity.Role, err
 			externalGroupRolesColumn,
 			immutableRolesColumn,
 			exclusiveRolesColumn,
+			allowedCidrsRolesColumn,
+			timeWindowsRolesColumn,
+			approverIdsRolesColumn,
+			parentIdsRolesColumn,
+			resourceScopesRolesColumn,
+			configManagedRolesColumn,
+			versionRolesColumn,
 		).
 		From("roles").
 		Where(squirrel.Eq{"name": name}).
@@ -102,6 +119,13 @@ func (r Role) GetRolesByExternalGroup(ctx context.Context, groups []string) ([]e
 			externalGroupRolesColumn,
 			immutableRolesColumn,
 			exclusiveRolesColumn,
+			allowedCidrsRolesColumn,
+			timeWindowsRolesColumn,
+			approverIdsRolesColumn,
+			parentIdsRolesColumn,
+			resourceScopesRolesColumn,
+			configManagedRolesColumn,
+			versionRolesColumn,
 		).
 		From("roles").
 		Where(squirrel.Eq{"external_group": groups}).
@@ -119,9 +143,41 @@ func (r Role) GetRolesByExternalGroup(ctx context.Context, groups []string) ([]e
 	return roles, nil
 }
 
+// GetRolesByApproverId returns roles, requests for which the user can approve
+func (r Role) GetRolesByApproverId(ctx context.Context, userId int64) ([]entity.Role, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.GetRolesByApproverId")
+
+	approverIds, err := entity.IdList{userId}.Value()
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal approver ids")
+	}
+	q, args, err := query.New().
+		Select(
+			idRolesColumn,
+			nameRolesColumn,
+			approverIdsRolesColumn,
+		).
+		From("roles").
+		Where(squirrel.Expr("approver_ids @> ?::jsonb", approverIds)).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	roles := make([]entity.Role, 0)
+	err = r.db.Select(ctx, &roles, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+
+	return roles, nil
+}
+
 func (r Role) All(ctx context.Context) ([]entity.Role, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.All")
-	q := "select id, name, external_group, permissions, immutable, exclusive, created_at, updated_at from roles order by created_at"
+	q := "select id, name, external_group, permissions, immutable, exclusive, allowed_cidrs, time_windows, approver_ids, parent_ids, " +
+		"resource_scopes, config_managed, version, created_at, updated_at " +
+		"from roles order by created_at"
 	roles := make([]entity.Role, 0)
 	err := r.db.Select(ctx, &roles, q)
 	if err != nil {
@@ -134,8 +190,11 @@ func (r Role) InsertRole(ctx context.Context, role entity.Role) (*entity.Role, e
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.InsertRole")
 
 	q, args, err := query.New().Insert("roles").
-		Columns("name", "permissions", "external_group").
-		Values(role.Name, role.Permissions, role.ExternalGroup).
+		Columns("name", "permissions", "external_group", "allowed_cidrs", "time_windows", "approver_ids", "parent_ids", "resource_scopes").
+		Values(
+			role.Name, role.Permissions, role.ExternalGroup, role.AllowedCidrs, role.TimeWindows, role.ApproverIds, role.ParentIds,
+			role.ResourceScopes,
+		).
 		Suffix("RETURNING *").ToSql()
 	if err != nil {
 		return nil, errors.WithMessage(err, "build query")
@@ -150,14 +209,26 @@ func (r Role) InsertRole(ctx context.Context, role entity.Role) (*entity.Role, e
 	return &result, nil
 }
 
+// Update increments the version of the role, if role.Version is not zero, the role is updated only with the same version,
+// domain.ErrVersionConflict is returned otherwise
 func (r Role) Update(ctx context.Context, role entity.Role) (*entity.Role, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.Update")
 
+	where := squirrel.Eq{idRolesColumn: role.Id}
+	if role.Version != 0 {
+		where[versionRolesColumn] = role.Version
+	}
 	q, args, err := query.New().Update("roles").
 		Set("name", role.Name).
 		Set("permissions", role.Permissions).
 		Set("external_group", role.ExternalGroup).
-		Where(squirrel.Eq{"id": role.Id}).
+		Set("allowed_cidrs", role.AllowedCidrs).
+		Set("time_windows", role.TimeWindows).
+		Set("approver_ids", role.ApproverIds).
+		Set("parent_ids", role.ParentIds).
+		Set("resource_scopes", role.ResourceScopes).
+		Set(versionRolesColumn, squirrel.Expr("version + 1")).
+		Where(where).
 		Suffix("RETURNING *").ToSql()
 	if err != nil {
 		return nil, errors.WithMessage(err, "build query")
@@ -165,16 +236,80 @@ func (r Role) Update(ctx context.Context, role entity.Role) (*entity.Role, error
 
 	var result entity.Role
 	err = r.db.SelectRow(ctx, &result, q, args...)
-	if err != nil {
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && role.Version != 0:
+		return nil, errors.WithMessagef(domain.ErrVersionConflict, "role %d version %d", role.Id, role.Version)
+	case err != nil:
 		return nil, errors.WithMessagef(err, "update: %s", q)
 	}
 
 	return &result, nil
 }
 
+func (r Role) UpdateFlags(ctx context.Context, id int, immutable bool, exclusive bool) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.UpdateFlags")
+
+	q, args, err := query.New().Update("roles").
+		Set(immutableRolesColumn, immutable).
+		Set(exclusiveRolesColumn, exclusive).
+		Where(squirrel.Eq{idRolesColumn: id}).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	return nil
+}
+
+func (r Role) SetConfigManaged(ctx context.Context, id int, configManaged bool) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.SetConfigManaged")
+
+	q, args, err := query.New().Update("roles").
+		Set(configManagedRolesColumn, configManaged).
+		Where(squirrel.Eq{idRolesColumn: id}).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	return nil
+}
+
+// Lock locks roles for changes until the end of the transaction, reading roles is not blocked
+func (r Role) Lock(ctx context.Context) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.Lock")
+
+	q := "lock table roles in share row exclusive mode"
+	_, err := r.db.Exec(ctx, q)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	return nil
+}
+
+// Delete deletes the role and removes it from parents of other roles
 func (r Role) Delete(ctx context.Context, id int) error {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.Delete")
 
+	parentQ := "update roles set parent_ids = coalesce(" +
+		"(select jsonb_agg(e) from jsonb_array_elements(parent_ids) e where e <> to_jsonb($1::int)), '[]'::jsonb) " +
+		"where parent_ids @> to_jsonb($1::int)"
+	_, err := r.db.Exec(ctx, parentQ, id)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", parentQ)
+	}
+
 	q, args, err := query.New().Delete("roles").Where(squirrel.Eq{"id": id}).ToSql()
 	if err != nil {
 		return errors.WithMessage(err, "build query")
@@ -187,3 +322,27 @@ func (r Role) Delete(ctx context.Context, id int) error {
 
 	return nil
 }
+
+// GetRolesWithParents returns roles with ids and all their ancestors
+func (r Role) GetRolesWithParents(ctx context.Context, ids []int) ([]entity.Role, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.GetRolesWithParents")
+
+	baseQ, args, err := query.New().
+		Select("*").
+		From("roles").
+		Where(squirrel.Eq{idRolesColumn: ids}).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+	q := "with recursive tree as (" + baseQ +
+		" union select r.* from roles r join tree t on t.parent_ids @> to_jsonb(r.id)) select * from tree"
+
+	roles := make([]entity.Role, 0)
+	err = r.db.Select(ctx, &roles, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+
+	return roles, nil
+}
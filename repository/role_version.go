@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+)
+
+type RoleVersion struct {
+	db db.DB
+}
+
+func NewRoleVersion(db db.DB) RoleVersion {
+	return RoleVersion{
+		db: db,
+	}
+}
+
+// InsertVersion saves the version with the next number for the role
+func (r RoleVersion) InsertVersion(ctx context.Context, version entity.RoleVersion) (*entity.RoleVersion, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "RoleVersion.InsertVersion")
+
+	q, args, err := query.New().
+		Insert("role_versions").
+		Columns("role_id", "version", "operation", "snapshot", "changed_by", "change_message").
+		Values(
+			version.RoleId,
+			squirrel.Expr("(select coalesce(max(version), 0) + 1 from role_versions where role_id = ?)", version.RoleId),
+			version.Operation,
+			version.Snapshot,
+			version.ChangedBy,
+			version.ChangeMessage,
+		).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.RoleVersion{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select row: %s", q)
+	}
+	return &result, nil
+}
+
+func (r RoleVersion) GetVersions(ctx context.Context, roleId int) ([]entity.RoleVersion, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "RoleVersion.GetVersions")
+
+	q, args, err := query.New().
+		Select("*").
+		From("role_versions").
+		Where(squirrel.Eq{"role_id": roleId}).
+		OrderBy("version DESC").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.RoleVersion, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+	return result, nil
+}
+
+func (r RoleVersion) GetVersion(ctx context.Context, roleId int, version int) (*entity.RoleVersion, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "RoleVersion.GetVersion")
+
+	q, args, err := query.New().
+		Select("*").
+		From("role_versions").
+		Where(squirrel.Eq{"role_id": roleId, "version": version}).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.RoleVersion{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "select row: %s", q)
+	default:
+		return &result, nil
+	}
+}
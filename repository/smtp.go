@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"msp-admin-service/conf"
+)
+
+type Smtp struct {
+	cfg *conf.Smtp
+}
+
+func NewSmtp(cfg *conf.Smtp) Smtp {
+	return Smtp{
+		cfg: cfg,
+	}
+}
+
+// Send sends plain text email, STARTTLS is used if server supports it
+func (r Smtp) Send(_ context.Context, to string, subject string, body string) error {
+	if r.cfg == nil {
+		return errors.New("smtp is not configured")
+	}
+
+	var auth smtp.Auth
+	if r.cfg.Username != "" {
+		auth = smtp.PlainAuth("", r.cfg.Username, r.cfg.Password, r.cfg.Host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + r.cfg.From,
+		"To: " + to,
+		"Subject: " + mime.BEncoding.Encode("UTF-8", subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+	address := net.JoinHostPort(r.cfg.Host, strconv.Itoa(r.cfg.Port))
+	err := smtp.SendMail(address, auth, r.cfg.From, []string{to}, []byte(msg))
+	if err != nil {
+		return errors.WithMessagef(err, "send mail via %s", address)
+	}
+
+	return nil
+}
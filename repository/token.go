@@ -43,8 +43,8 @@ func (r Token) Save(ctx context.Context, token entity.Token) error {
 
 	q := `
 	INSERT INTO tokens
-		(token, user_id, status, expired_at, created_at, updated_at)
-		VALUES (:token, :user_id, :status, :expired_at, :created_at, :updated_at)
+		(token, user_id, impersonator_id, status, expired_at, created_at, updated_at)
+		VALUES (:token, :user_id, :impersonator_id, :status, :expired_at, :created_at, :updated_at)
 	`
 	_, err := r.db.ExecNamed(ctx, q, token)
 	if err != nil {
@@ -59,7 +59,7 @@ func (r Token) Get(ctx context.Context, token string) (*entity.Token, error) {
 
 	result := entity.Token{}
 	q := `
-	SELECT token, user_id, status, expired_at, created_at, updated_at 
+	SELECT id, token, user_id, impersonator_id, status, expired_at, created_at, updated_at
 		FROM tokens
 		WHERE token = $1;
 	`
@@ -90,6 +90,22 @@ func (r Token) RevokeByUserId(ctx context.Context, userId int64, updatedAt time.
 	return nil
 }
 
+func (r Token) RevokeByImpersonatorId(ctx context.Context, userId int64, impersonatorId int64, updatedAt time.Time) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.RevokeByImpersonatorId")
+
+	q := `
+	UPDATE tokens
+		SET status = $1, updated_at = $2
+		WHERE user_id = $3 AND impersonator_id = $4 AND status = $5;
+	`
+	_, err := r.db.Exec(ctx, q, entity.TokenStatusRevoked, updatedAt, userId, impersonatorId, entity.TokenStatusAllowed)
+	if err != nil {
+		return errors.WithMessage(err, "update token status")
+	}
+
+	return nil
+}
+
 func (r Token) All(ctx context.Context) ([]entity.Token, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.All")
 
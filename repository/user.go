@@ -30,6 +30,7 @@ const (
 	descriptionUsersColumn  = "description"
 	lastActiveAtUsersColumn = "last_active_at"
 	fullNameUsersColumn     = "full_name"
+	versionUsersColumn      = "version"
 )
 
 type User struct {
@@ -73,7 +74,7 @@ func (u User) GetUserById(ctx context.Context, identity int64) (*entity.User, er
 	q, args, err := query.New().
 		Select(idUsersColumn, firstNameUsersColumn, lastNameUsersColumn, emailUsersColumn, passwordUsersColumn, createdAtUsersColumn,
 			updatedAtUsersColumn, sudirUserIdUsersColumn, blockedUsersColumn, descriptionUsersColumn, lastActiveAtUsersColumn,
-			fullNameUsersColumn).
+			fullNameUsersColumn, versionUsersColumn).
 		From("users").
 		Where(squirrel.Eq{"id": identity}).
 		ToSql()
@@ -94,6 +95,33 @@ func (u User) GetUserById(ctx context.Context, identity int64) (*entity.User, er
 	}
 }
 
+func (u User) GetUserBySudirUserId(ctx context.Context, sudirUserId string) (*entity.User, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "User.GetUserBySudirUserId")
+
+	q, args, err := query.New().
+		Select(idUsersColumn, firstNameUsersColumn, lastNameUsersColumn, emailUsersColumn, passwordUsersColumn, createdAtUsersColumn,
+			updatedAtUsersColumn, sudirUserIdUsersColumn, blockedUsersColumn, descriptionUsersColumn, lastActiveAtUsersColumn,
+			fullNameUsersColumn, versionUsersColumn).
+		From("users").
+		Where(squirrel.Eq{"sudir_user_id": sudirUserId}).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	user := entity.User{}
+	err = u.db.SelectRow(ctx, &user, q, args...)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrNotFound
+	case err != nil:
+		return nil, errors.WithMessage(err, "db select")
+	default:
+		return &user, nil
+	}
+}
+
 func (u User) GetUserByEmailAndSudirId(ctx context.Context, email string, sudirUserId string) (*entity.User, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "User.GetUserByEmail")
 
@@ -163,7 +191,8 @@ func (u User) GetUsers(ctx context.Context, req domain.UsersPageRequest) ([]enti
 	q := query.New().
 		Select(idUsersColumn, firstNameUsersColumn, lastNameUsersColumn, emailUsersColumn, passwordUsersColumn, createdAtUsersColumn,
 			updatedAtUsersColumn, sudirUserIdUsersColumn, blockedUsersColumn, descriptionUsersColumn, lastActiveAtUsersColumn,
-			fullNameUsersColumn, "(SELECT max(created_at) FROM tokens WHERE tokens.user_id = users.id) as last_session_created_at").
+			fullNameUsersColumn, versionUsersColumn,
+			"(SELECT max(created_at) FROM tokens WHERE tokens.user_id = users.id) as last_session_created_at").
 		From("users").
 		Offset(req.Offset).
 		Limit(req.Limit)
@@ -194,7 +223,8 @@ func (u User) GetAllUsers(ctx context.Context) ([]entity.User, error) {
 	query, args, err := query.New().
 		Select(idUsersColumn, firstNameUsersColumn, lastNameUsersColumn, emailUsersColumn, passwordUsersColumn, createdAtUsersColumn,
 			updatedAtUsersColumn, sudirUserIdUsersColumn, blockedUsersColumn, descriptionUsersColumn, lastActiveAtUsersColumn,
-			fullNameUsersColumn, "(SELECT max(created_at) FROM tokens WHERE tokens.user_id = users.id) as last_session_created_at").
+			fullNameUsersColumn, versionUsersColumn,
+			"(SELECT max(created_at) FROM tokens WHERE tokens.user_id = users.id) as last_session_created_at").
 		From("users").ToSql()
 	if err != nil {
 		return nil, errors.WithMessage(err, "build query")
@@ -209,6 +239,30 @@ func (u User) GetAllUsers(ctx context.Context) ([]entity.User, error) {
 	return users, nil
 }
 
+// GetUsersByRoleId returns users, who hold the role, temporary links are included
+func (u User) GetUsersByRoleId(ctx context.Context, roleId int) ([]entity.User, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "User.GetUsersByRoleId")
+
+	q, args, err := query.New().
+		Select("u.id", "u.first_name", "u.last_name", "u.email", "u.full_name", "u.sudir_user_id", "u.blocked").
+		From("users u").
+		Join("user_roles ur on ur.user_id = u.id").
+		Where(squirrel.Eq{"ur.role_id": roleId}).
+		OrderBy("u.id").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	users := make([]entity.User, 0)
+	err = u.db.Select(ctx, &users, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "db select: %s", q)
+	}
+
+	return users, nil
+}
+
 func (u User) GetUsersByEmail(ctx context.Context, email string) ([]entity.User, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "User.GetUserByEmail")
 
@@ -260,9 +314,15 @@ func (u User) Insert(ctx context.Context, user entity.User) (int, error) {
 	return id, nil
 }
 
+// UpdateUser increments the version of the user, if user.Version is not zero, the user is updated only with the same version,
+// domain.ErrVersionConflict is returned otherwise
 func (u User) UpdateUser(ctx context.Context, id int64, user entity.UpdateUser) (*entity.User, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "User.UpdateUser")
 
+	where := squirrel.Eq{idUsersColumn: id}
+	if user.Version != 0 {
+		where[versionUsersColumn] = user.Version
+	}
 	// return every except password
 	q, args, err := query.New().
 		Update("users").
@@ -272,9 +332,10 @@ func (u User) UpdateUser(ctx context.Context, id int64, user entity.UpdateUser)
 			fullNameUsersColumn:    user.FullName,
 			emailUsersColumn:       user.Email,
 			descriptionUsersColumn: user.Description,
+			versionUsersColumn:     squirrel.Expr("version + 1"),
 		}).
-		Where(squirrel.Eq{"id": id}).
-		Suffix("RETURNING id, first_name, last_name, full_name, email, sudir_user_id, description, created_at, updated_at").
+		Where(where).
+		Suffix("RETURNING id, first_name, last_name, full_name, email, sudir_user_id, description, version, created_at, updated_at").
 		ToSql()
 	if err != nil {
 		return nil, errors.WithMessage(err, "build query")
@@ -282,7 +343,10 @@ func (u User) UpdateUser(ctx context.Context, id int64, user entity.UpdateUser)
 
 	returning := entity.User{}
 	err = u.db.SelectRow(ctx, &returning, q, args...)
-	if err != nil {
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && user.Version != 0:
+		return nil, errors.WithMessagef(domain.ErrVersionConflict, "user %d version %d", id, user.Version)
+	case err != nil:
 		return nil, errors.WithMessage(err, "db select")
 	}
 
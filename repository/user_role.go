@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/pkg/errors"
@@ -11,6 +12,8 @@ import (
 	"msp-admin-service/entity"
 )
 
+const userRoleReturning = "RETURNING user_id, role_id, valid_from, valid_until"
+
 type UserRole struct {
 	db db.DB
 }
@@ -22,7 +25,7 @@ func NewUserRole(db db.DB) UserRole {
 func (u UserRole) GetRolesByUserIds(ctx context.Context, identity []int) ([]entity.UserRole, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "UserRole.GetRolesByUserIds")
 
-	rolesQ, args, err := query.New().Select("role_id", "user_id").
+	rolesQ, args, err := query.New().Select("role_id", "user_id", "valid_from", "valid_until").
 		From("user_roles").Where(squirrel.Eq{"user_id": identity}).ToSql()
 	if err != nil {
 		return nil, errors.WithMessage(err, "build query")
@@ -47,7 +50,9 @@ func (u UserRole) GetRoleEntitiesByUserId(ctx context.Context, userId int) ([]en
 		Join("roles r on ur.role_id = r.id").
 		Where(squirrel.Eq{
 			"ur.user_id": userId,
-		}).OrderBy("r.id").
+		}).
+		Where(activeUserRoleCondition("ur", time.Now().UTC())).
+		OrderBy("r.id").
 		ToSql()
 	if err != nil {
 		return nil, errors.WithMessage(err, "build query")
@@ -63,38 +68,195 @@ func (u UserRole) GetRoleEntitiesByUserId(ctx context.Context, userId int) ([]en
 }
 
 func (u UserRole) UpsertUserRoleLinks(ctx context.Context, id int, roleIds []int) error {
-	ctx = sql_metrics.OperationLabelToContext(ctx, "UserRole.UpsertUserRoleLinks")
+	links := make([]entity.UserRole, 0, len(roleIds))
+	for _, roleId := range roleIds {
+		links = append(links, entity.UserRole{
+			UserId: id,
+			RoleId: roleId,
+		})
+	}
+
+	err := u.ReplaceUserRoleLinks(ctx, id, links)
+	if err != nil {
+		return errors.WithMessage(err, "replace user role links")
+	}
+
+	return nil
+}
+
+// ReplaceUserRoleLinks replaces all role links of the user with links
+func (u UserRole) ReplaceUserRoleLinks(ctx context.Context, userId int, links []entity.UserRole) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "UserRole.ReplaceUserRoleLinks")
 
 	deleteQ, args, err := query.New().
-		Delete("user_roles").Where(squirrel.Eq{"user_id": id}).ToSql()
+		Delete("user_roles").Where(squirrel.Eq{"user_id": userId}).
+		Suffix(userRoleReturning).
+		ToSql()
 	if err != nil {
 		return errors.WithMessage(err, "build query")
 	}
 
-	_, err = u.db.Exec(ctx, deleteQ, args...)
+	_, err = u.selectChangedLinks(ctx, deleteQ, args)
 	if err != nil {
-		return errors.WithMessagef(err, "exec: %s", deleteQ)
+		return err
 	}
 
-	if len(roleIds) == 0 {
+	if len(links) == 0 {
 		return nil
 	}
 
 	rolesQ := query.New().
 		Insert("user_roles").
-		Columns("user_id", "role_id")
-	for _, roleId := range roleIds {
-		rolesQ = rolesQ.Values(id, roleId)
+		Columns("user_id", "role_id", "valid_from", "valid_until").
+		Suffix(userRoleReturning)
+	for _, link := range links {
+		rolesQ = rolesQ.Values(userId, link.RoleId, link.ValidFrom, link.ValidUntil)
 	}
 	rolesQResult, args, err := rolesQ.ToSql()
 	if err != nil {
 		return errors.WithMessage(err, "build query")
 	}
 
-	_, err = u.db.Exec(ctx, rolesQResult, args...)
+	_, err = u.selectChangedLinks(ctx, rolesQResult, args)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpsertUserRoleLink adds role link or replaces validity period of existing one
+func (u UserRole) UpsertUserRoleLink(ctx context.Context, link entity.UserRole) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "UserRole.UpsertUserRoleLink")
+
+	q, args, err := query.New().
+		Insert("user_roles").
+		Columns("user_id", "role_id", "valid_from", "valid_until").
+		Values(link.UserId, link.RoleId, link.ValidFrom, link.ValidUntil).
+		Suffix("ON CONFLICT (user_id, role_id) DO UPDATE SET valid_from = EXCLUDED.valid_from, valid_until = EXCLUDED.valid_until " +
+			userRoleReturning).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = u.selectChangedLinks(ctx, q, args)
 	if err != nil {
-		return errors.WithMessagef(err, "exec: %s", rolesQResult)
+		return err
 	}
 
 	return nil
 }
+
+func (u UserRole) DeleteExpiredUserRoleLinks(ctx context.Context, now time.Time) ([]entity.UserRole, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "UserRole.DeleteExpiredUserRoleLinks")
+
+	q, args, err := query.New().
+		Delete("user_roles").
+		Where(squirrel.LtOrEq{"valid_until": now}).
+		Suffix(userRoleReturning).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	return u.selectChangedLinks(ctx, q, args)
+}
+
+// DeleteUserRoleLinks deletes links by user and role, returns deleted links
+func (u UserRole) DeleteUserRoleLinks(ctx context.Context, links []entity.UserRole) ([]entity.UserRole, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "UserRole.DeleteUserRoleLinks")
+
+	if len(links) == 0 {
+		return []entity.UserRole{}, nil
+	}
+
+	condition := squirrel.Or{}
+	for _, link := range links {
+		condition = append(condition, squirrel.Eq{"user_id": link.UserId, "role_id": link.RoleId})
+	}
+	q, args, err := query.New().
+		Delete("user_roles").
+		Where(condition).
+		Suffix(userRoleReturning).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	return u.selectChangedLinks(ctx, q, args)
+}
+
+// InsertUserRoleLinks adds links, existing links of users with the same role are kept unchanged
+func (u UserRole) InsertUserRoleLinks(ctx context.Context, links []entity.UserRole) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "UserRole.InsertUserRoleLinks")
+
+	if len(links) == 0 {
+		return nil
+	}
+
+	rolesQ := query.New().
+		Insert("user_roles").
+		Columns("user_id", "role_id", "valid_from", "valid_until").
+		Suffix("ON CONFLICT (user_id, role_id) DO NOTHING " + userRoleReturning)
+	for _, link := range links {
+		rolesQ = rolesQ.Values(link.UserId, link.RoleId, link.ValidFrom, link.ValidUntil)
+	}
+	q, args, err := rolesQ.ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = u.selectChangedLinks(ctx, q, args)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteRoleLinks deletes links of all users with the role, returns deleted links
+func (u UserRole) DeleteRoleLinks(ctx context.Context, roleId int) ([]entity.UserRole, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "UserRole.DeleteRoleLinks")
+
+	q, args, err := query.New().
+		Delete("user_roles").
+		Where(squirrel.Eq{"role_id": roleId}).
+		Suffix(userRoleReturning).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	return u.selectChangedLinks(ctx, q, args)
+}
+
+// selectChangedLinks executes the statement q, which changes role links and returns them,
+// versions of the users are incremented in the same statement, so update_user with the version read before the change fails
+func (u UserRole) selectChangedLinks(ctx context.Context, q string, args []any) ([]entity.UserRole, error) {
+	q = `WITH changed AS (` + q + `),
+	bumped AS (UPDATE users SET version = version + 1 WHERE id IN (SELECT user_id FROM changed))
+	SELECT user_id, role_id, valid_from, valid_until FROM changed`
+
+	result := make([]entity.UserRole, 0)
+	err := u.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "db select: %s", q)
+	}
+
+	return result, nil
+}
+
+// activeUserRoleCondition filters links, which validity period contains now
+func activeUserRoleCondition(alias string, now time.Time) squirrel.Sqlizer {
+	return squirrel.And{
+		squirrel.Or{
+			squirrel.Eq{alias + ".valid_from": nil},
+			squirrel.LtOrEq{alias + ".valid_from": now},
+		},
+		squirrel.Or{
+			squirrel.Eq{alias + ".valid_until": nil},
+			squirrel.Gt{alias + ".valid_until": now},
+		},
+	}
+}
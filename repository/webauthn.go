@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+const (
+	idWebauthnColumn           = "id"
+	userIdWebauthnColumn       = "user_id"
+	credentialIdWebauthnColumn = "credential_id"
+	nameWebauthnColumn         = "name"
+	credentialWebauthnColumn   = "credential"
+	lastUsedAtWebauthnColumn   = "last_used_at"
+	createdAtWebauthnColumn    = "created_at"
+	ceremonyWebauthnColumn     = "ceremony"
+	dataWebauthnColumn         = "data"
+	expiredAtWebauthnColumn    = "expired_at"
+)
+
+type Webauthn struct {
+	db db.DB
+}
+
+func NewWebauthn(db db.DB) Webauthn {
+	return Webauthn{db: db}
+}
+
+func (r Webauthn) GetWebauthnCredentials(ctx context.Context, userId int64) ([]entity.WebauthnCredential, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Webauthn.GetWebauthnCredentials")
+
+	q, args, err := query.New().
+		Select("*").
+		From("webauthn_credentials").
+		Where(squirrel.Eq{userIdWebauthnColumn: userId}).
+		OrderBy(idWebauthnColumn).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.WebauthnCredential, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select: %s", q)
+	}
+
+	return result, nil
+}
+
+func (r Webauthn) InsertWebauthnCredential(
+	ctx context.Context,
+	credential entity.WebauthnCredential,
+) (*entity.WebauthnCredential, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Webauthn.InsertWebauthnCredential")
+
+	q, args, err := query.New().
+		Insert("webauthn_credentials").
+		Columns(userIdWebauthnColumn, credentialIdWebauthnColumn, nameWebauthnColumn,
+			credentialWebauthnColumn, createdAtWebauthnColumn).
+		Values(credential.UserId, credential.CredentialId, credential.Name,
+			credential.Credential, credential.CreatedAt).
+		Suffix("ON CONFLICT (credential_id) DO NOTHING RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.WebauthnCredential{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrAlreadyExists
+	case err != nil:
+		return nil, errors.WithMessagef(err, "select row: %s", q)
+	}
+
+	return &result, nil
+}
+
+// UpdateWebauthnCredentialUsage saves credential state (e.g. signature counter) after successful assertion
+func (r Webauthn) UpdateWebauthnCredentialUsage(
+	ctx context.Context,
+	id int,
+	credential json.RawMessage,
+	lastUsedAt time.Time,
+) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Webauthn.UpdateWebauthnCredentialUsage")
+
+	q, args, err := query.New().
+		Update("webauthn_credentials").
+		SetMap(map[string]any{
+			credentialWebauthnColumn: credential,
+			lastUsedAtWebauthnColumn: lastUsedAt,
+		}).
+		Where(squirrel.Eq{idWebauthnColumn: id}).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	return nil
+}
+
+func (r Webauthn) RenameWebauthnCredential(
+	ctx context.Context,
+	userId int64,
+	id int,
+	name string,
+) (*entity.WebauthnCredential, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Webauthn.RenameWebauthnCredential")
+
+	q, args, err := query.New().
+		Update("webauthn_credentials").
+		Set(nameWebauthnColumn, name).
+		Where(squirrel.Eq{idWebauthnColumn: id, userIdWebauthnColumn: userId}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.WebauthnCredential{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "select row: %s", q)
+	}
+
+	return &result, nil
+}
+
+func (r Webauthn) DeleteWebauthnCredential(ctx context.Context, userId int64, id int) (*entity.WebauthnCredential, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Webauthn.DeleteWebauthnCredential")
+
+	q, args, err := query.New().
+		Delete("webauthn_credentials").
+		Where(squirrel.Eq{idWebauthnColumn: id, userIdWebauthnColumn: userId}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.WebauthnCredential{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "select row: %s", q)
+	}
+
+	return &result, nil
+}
+
+// InsertWebauthnSession saves ceremony state and removes expired ceremonies
+func (r Webauthn) InsertWebauthnSession(ctx context.Context, session entity.WebauthnSession) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Webauthn.InsertWebauthnSession")
+
+	q, args, err := query.New().
+		Delete("webauthn_sessions").
+		Where(squirrel.LtOrEq{expiredAtWebauthnColumn: session.CreatedAt}).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build delete query")
+	}
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	q, args, err = query.New().
+		Insert("webauthn_sessions").
+		Columns(idWebauthnColumn, userIdWebauthnColumn, ceremonyWebauthnColumn, dataWebauthnColumn,
+			expiredAtWebauthnColumn, createdAtWebauthnColumn).
+		Values(session.Id, session.UserId, session.Ceremony, session.Data, session.ExpiredAt, session.CreatedAt).
+		ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build insert query")
+	}
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec: %s", q)
+	}
+
+	return nil
+}
+
+// ConsumeWebauthnSession deletes not expired ceremony, so each challenge can be answered only once
+func (r Webauthn) ConsumeWebauthnSession(ctx context.Context, id string, now time.Time) (*entity.WebauthnSession, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Webauthn.ConsumeWebauthnSession")
+
+	q, args, err := query.New().
+		Delete("webauthn_sessions").
+		Where(squirrel.Eq{idWebauthnColumn: id}).
+		Where(squirrel.Gt{expiredAtWebauthnColumn: now}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := entity.WebauthnSession{}
+	err = r.db.SelectRow(ctx, &result, q, args...)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "select row: %s", q)
+	}
+
+	return &result, nil
+}
@@ -10,14 +10,22 @@ import (
 )
 
 type Controllers struct {
-	Auth          controller.Auth
-	User          controller.User
-	Customization controller.Customization
-	Secure        controller.Secure
-	Session       controller.Session
-	Audit         controller.Audit
-	Role          controller.Role
-	Permissions   controller.Permissions
+	Auth               controller.Auth
+	User               controller.User
+	Customization      controller.Customization
+	Secure             controller.Secure
+	Session            controller.Session
+	Audit              controller.Audit
+	Role               controller.Role
+	Permissions        controller.Permissions
+	ExternalGroupRule  controller.ExternalGroupRule
+	AccessRequest      controller.AccessRequest
+	PendingChange      controller.PendingChange
+	Recertification    controller.Recertification
+	Impersonation      controller.Impersonation
+	Webauthn           controller.Webauthn
+	SeparationOfDuties controller.SeparationOfDuties
+	AccessReport       controller.AccessReport
 }
 
 func EndpointDescriptors() []cluster.EndpointDescriptor {
@@ -45,6 +53,31 @@ func endpointDescriptors(c Controllers) []cluster.EndpointDescriptor {
 			Inner:   false,
 			Handler: c.Auth.LoginWithSudir,
 		},
+		{
+			Path:    "admin/auth/login_with_ldap",
+			Inner:   false,
+			Handler: c.Auth.LoginWithLdap,
+		},
+		{
+			Path:    "admin/auth/request_magic_link",
+			Inner:   false,
+			Handler: c.Auth.RequestMagicLink,
+		},
+		{
+			Path:    "admin/auth/login_with_magic_link",
+			Inner:   false,
+			Handler: c.Auth.LoginWithMagicLink,
+		},
+		{
+			Path:    "admin/auth/webauthn/begin",
+			Inner:   false,
+			Handler: c.Auth.BeginWebauthnLogin,
+		},
+		{
+			Path:    "admin/auth/webauthn/finish",
+			Inner:   false,
+			Handler: c.Auth.FinishWebauthnLogin,
+		},
 		{
 			Path:    "admin/auth/logout",
 			Inner:   true,
@@ -55,6 +88,17 @@ func endpointDescriptors(c Controllers) []cluster.EndpointDescriptor {
 			Inner:   true,
 			Handler: c.Auth.LogoutWithReason,
 		},
+		{
+			Path:    "admin/auth/impersonate",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("user_impersonate"),
+			Handler: c.Impersonation.Impersonate,
+		},
+		{
+			Path:    "admin/auth/end_impersonation",
+			Inner:   true,
+			Handler: c.Impersonation.EndImpersonation,
+		},
 		{
 			Path:    "admin/user/get_profile",
 			Inner:   true,
@@ -87,6 +131,12 @@ func endpointDescriptors(c Controllers) []cluster.EndpointDescriptor {
 			Extra:   cluster.RequireAdminPermission("user_update"),
 			Handler: c.User.UpdateUser,
 		},
+		{
+			Path:    "admin/user/grant_role",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("user_update"),
+			Handler: c.User.GrantRole,
+		},
 		{
 			Path:    "admin/user/delete_user",
 			Inner:   true,
@@ -109,6 +159,31 @@ func endpointDescriptors(c Controllers) []cluster.EndpointDescriptor {
 			Inner:   true,
 			Handler: c.User.ChangePassword,
 		},
+		{
+			Path:    "admin/user/webauthn/begin_registration",
+			Inner:   true,
+			Handler: c.Webauthn.BeginRegistration,
+		},
+		{
+			Path:    "admin/user/webauthn/finish_registration",
+			Inner:   true,
+			Handler: c.Webauthn.FinishRegistration,
+		},
+		{
+			Path:    "admin/user/webauthn/credentials",
+			Inner:   true,
+			Handler: c.Webauthn.Credentials,
+		},
+		{
+			Path:    "admin/user/webauthn/rename_credential",
+			Inner:   true,
+			Handler: c.Webauthn.RenameCredential,
+		},
+		{
+			Path:    "admin/user/webauthn/delete_credential",
+			Inner:   true,
+			Handler: c.Webauthn.DeleteCredential,
+		},
 		{
 			Path:    "admin/role/all",
 			Inner:   true,
@@ -127,6 +202,18 @@ func endpointDescriptors(c Controllers) []cluster.EndpointDescriptor {
 			Extra:   cluster.RequireAdminPermission("role_add"),
 			Handler: c.Role.CreateRole,
 		},
+		{
+			Path:    "admin/role/clone",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_add"),
+			Handler: c.Role.Clone,
+		},
+		{
+			Path:    "admin/role/templates",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_view"),
+			Handler: c.Role.Templates,
+		},
 		{
 			Path:    "admin/role/update",
 			Inner:   true,
@@ -139,6 +226,209 @@ func endpointDescriptors(c Controllers) []cluster.EndpointDescriptor {
 			Extra:   cluster.RequireAdminPermission("role_delete"),
 			Handler: c.Role.DeleteRole,
 		},
+		{
+			Path:    "admin/role/delete_preview",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_view"),
+			Handler: c.Role.DeletePreview,
+		},
+		{
+			Path:    "admin/role/unknown_permissions",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_view"),
+			Handler: c.Role.UnknownPermissions,
+		},
+		{
+			Path:    "admin/role/remove_unknown_permissions",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_update"),
+			Handler: c.Role.RemoveUnknownPermissions,
+		},
+		{
+			Path:    "admin/role/versions",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_view"),
+			Handler: c.Role.Versions,
+		},
+		{
+			Path:    "admin/role/version_diff",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_view"),
+			Handler: c.Role.VersionDiff,
+		},
+		{
+			Path:    "admin/role/rollback",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_update"),
+			Handler: c.Role.Rollback,
+		},
+		{
+			Path:    "admin/role/export",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_view"),
+			Handler: c.Role.Export,
+		},
+		{
+			Path:    "admin/role/import",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_import"),
+			Handler: c.Role.Import,
+		},
+		{
+			Path:    "admin/external_group_rule/all",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_view"),
+			Handler: c.ExternalGroupRule.All,
+		},
+		{
+			Path:    "admin/external_group_rule/create",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_update"),
+			Handler: c.ExternalGroupRule.Create,
+		},
+		{
+			Path:    "admin/external_group_rule/update",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_update"),
+			Handler: c.ExternalGroupRule.Update,
+		},
+		{
+			Path:    "admin/external_group_rule/delete",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_update"),
+			Handler: c.ExternalGroupRule.Delete,
+		},
+		{
+			Path:    "admin/external_group_rule/dry_run",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("role_view"),
+			Handler: c.ExternalGroupRule.DryRun,
+		},
+		{
+			Path:    "admin/access_request/all",
+			Inner:   true,
+			Handler: c.AccessRequest.All,
+		},
+		{
+			Path:    "admin/access_request/create",
+			Inner:   true,
+			Handler: c.AccessRequest.Create,
+		},
+		{
+			Path:    "admin/access_request/cancel",
+			Inner:   true,
+			Handler: c.AccessRequest.Cancel,
+		},
+		{
+			Path:    "admin/access_request/approve",
+			Inner:   true,
+			Handler: c.AccessRequest.Approve,
+		},
+		{
+			Path:    "admin/access_request/reject",
+			Inner:   true,
+			Handler: c.AccessRequest.Reject,
+		},
+		{
+			Path:    "admin/pending_change/all",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("change_approve"),
+			Handler: c.PendingChange.All,
+		},
+		{
+			Path:    "admin/pending_change/approve",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("change_approve"),
+			Handler: c.PendingChange.Approve,
+		},
+		{
+			Path:    "admin/pending_change/reject",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("change_approve"),
+			Handler: c.PendingChange.Reject,
+		},
+		{
+			Path:    "admin/recertification/start",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("recertification_manage"),
+			Handler: c.Recertification.Start,
+		},
+		{
+			Path:    "admin/recertification/all",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("recertification_manage"),
+			Handler: c.Recertification.All,
+		},
+		{
+			Path:    "admin/recertification/get",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("recertification_manage"),
+			Handler: c.Recertification.Get,
+		},
+		{
+			Path:    "admin/recertification/close",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("recertification_manage"),
+			Handler: c.Recertification.Close,
+		},
+		{
+			Path:    "admin/recertification/report",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("recertification_manage"),
+			Handler: c.Recertification.Report,
+		},
+		{
+			Path:    "admin/recertification/items",
+			Inner:   true,
+			Handler: c.Recertification.Items,
+		},
+		{
+			Path:    "admin/recertification/decide",
+			Inner:   true,
+			Handler: c.Recertification.Decide,
+		},
+		{
+			Path:    "admin/separation_of_duties/violations",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("user_view"),
+			Handler: c.SeparationOfDuties.Violations,
+		},
+		{
+			Path:    "admin/report/users_by_permission",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("access_report_view"),
+			Handler: c.AccessReport.UsersByPermission,
+		},
+		{
+			Path:    "admin/report/user_permissions",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("access_report_view"),
+			Handler: c.AccessReport.UserPermissions,
+		},
+		{
+			Path:    "admin/report/roles_by_permission",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("access_report_view"),
+			Handler: c.AccessReport.RolesByPermission,
+		},
+		{
+			Path:    "admin/report/unused_roles",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("access_report_view"),
+			Handler: c.AccessReport.UnusedRoles,
+		},
+		{
+			Path:    "admin/report/inactive_users",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("access_report_view"),
+			Handler: c.AccessReport.InactiveUsers,
+		},
+		{
+			Path:    "admin/report/csv",
+			Inner:   true,
+			Extra:   cluster.RequireAdminPermission("access_report_view"),
+			Handler: c.AccessReport.Csv,
+		},
 		{
 			Path:    "admin/session/all",
 			Inner:   true,
@@ -179,5 +469,10 @@ func endpointDescriptors(c Controllers) []cluster.EndpointDescriptor {
 			Inner:   true,
 			Handler: c.Secure.Authorize,
 		},
+		{
+			Path:    "admin/permission/register",
+			Inner:   true,
+			Handler: c.Permissions.Register,
+		},
 	}
 }
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"msp-admin-service/conf"
+	"msp-admin-service/controller"
+	"msp-admin-service/domain"
+)
+
+// ScimHandler returns SCIM 2.0 REST handler, when SCIM is not configured every request is rejected
+func ScimHandler(c controller.Scim, cfg *conf.Scim) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /scim/v2/ServiceProviderConfig", c.ServiceProviderConfig)
+
+	mux.HandleFunc("GET /scim/v2/Users", c.ListUsers)
+	mux.HandleFunc("POST /scim/v2/Users", c.CreateUser)
+	mux.HandleFunc("GET /scim/v2/Users/{id}", c.GetUser)
+	mux.HandleFunc("PUT /scim/v2/Users/{id}", c.ReplaceUser)
+	mux.HandleFunc("PATCH /scim/v2/Users/{id}", c.PatchUser)
+	mux.HandleFunc("DELETE /scim/v2/Users/{id}", c.DeleteUser)
+
+	mux.HandleFunc("GET /scim/v2/Groups", c.ListGroups)
+	mux.HandleFunc("POST /scim/v2/Groups", c.CreateGroup)
+	mux.HandleFunc("GET /scim/v2/Groups/{id}", c.GetGroup)
+	mux.HandleFunc("PUT /scim/v2/Groups/{id}", c.ReplaceGroup)
+	mux.HandleFunc("PATCH /scim/v2/Groups/{id}", c.PatchGroup)
+	mux.HandleFunc("DELETE /scim/v2/Groups/{id}", c.DeleteGroup)
+
+	return scimBearerAuth(cfg, mux)
+}
+
+func scimBearerAuth(cfg *conf.Scim, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if cfg == nil || !ok ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(cfg.BearerToken)) != 1 {
+			w.Header().Set("Content-Type", "application/scim+json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"schemas":["` + domain.ScimSchemaError + `"],"status":"401","detail":"unauthorized"}`))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/validator"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+	"msp-admin-service/service/permission_match"
+	"msp-admin-service/service/role_hierarchy"
+)
+
+type accessReportRoleRepo interface {
+	All(ctx context.Context) ([]entity.Role, error)
+}
+
+type accessReportUserRepo interface {
+	GetUserById(ctx context.Context, identity int64) (*entity.User, error)
+	GetAllUsers(ctx context.Context) ([]entity.User, error)
+}
+
+type accessReportUserRoleRepo interface {
+	GetRolesByUserIds(ctx context.Context, identity []int) ([]entity.UserRole, error)
+}
+
+// accessSnapshot is roles with inherited permissions and role links of users
+type accessSnapshot struct {
+	roles          []entity.Role
+	ownPermissions map[int][]string
+	userRoles      map[int][]entity.Role
+	holders        map[int]int
+}
+
+// AccessReport answers, who has access to what, by effective permissions of users and roles
+type AccessReport struct {
+	roleRepo     accessReportRoleRepo
+	userRepo     accessReportUserRepo
+	userRoleRepo accessReportUserRoleRepo
+}
+
+func NewAccessReport(
+	roleRepo accessReportRoleRepo,
+	userRepo accessReportUserRepo,
+	userRoleRepo accessReportUserRoleRepo,
+) AccessReport {
+	return AccessReport{
+		roleRepo:     roleRepo,
+		userRepo:     userRepo,
+		userRoleRepo: userRoleRepo,
+	}
+}
+
+// UsersByPermission returns users, whose active roles grant the permission and do not deny it
+func (s AccessReport) UsersByPermission(ctx context.Context, req domain.PermissionReportRequest) ([]domain.UserAccess, error) {
+	users, err := s.allUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := s.snapshot(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.UserAccess, 0)
+	for _, user := range users {
+		roles := snapshot.userRoles[int(user.Id)]
+		if !permission_match.Allowed(mergePermissions(roles), req.Permission) {
+			continue
+		}
+		grantingRoles := slices.DeleteFunc(slices.Clone(roles), func(role entity.Role) bool {
+			return !permission_match.Allowed(role.Permissions, req.Permission)
+		})
+		result = append(result, toUserAccess(user, grantingRoles))
+	}
+	return result, nil
+}
+
+// UserPermissions returns effective permissions of active roles of the user
+func (s AccessReport) UserPermissions(ctx context.Context, req domain.UserPermissionsReportRequest) (*domain.UserPermissionsReport, error) {
+	user, err := s.userRepo.GetUserById(ctx, req.UserId)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get user by id %d", req.UserId)
+	}
+	snapshot, err := s.snapshot(ctx, []entity.User{*user})
+	if err != nil {
+		return nil, err
+	}
+
+	roles := snapshot.userRoles[int(user.Id)]
+	permissions := mergePermissions(roles)
+	slices.Sort(permissions)
+	return &domain.UserPermissionsReport{
+		UserId:      user.Id,
+		Email:       user.Email,
+		FullName:    user.FullName,
+		Roles:       roleNames(roles),
+		Permissions: permissions,
+	}, nil
+}
+
+// RolesByPermission returns roles, which grant the permission themselves or by parent roles
+func (s AccessReport) RolesByPermission(ctx context.Context, req domain.PermissionReportRequest) ([]domain.RoleAccess, error) {
+	users, err := s.allUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := s.snapshot(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.RoleAccess, 0)
+	for _, role := range snapshot.roles {
+		if !permission_match.Allowed(mergePermissions([]entity.Role{role}), req.Permission) {
+			continue
+		}
+		own := snapshot.ownPermissions[role.Id]
+		result = append(result, domain.RoleAccess{
+			RoleId:     role.Id,
+			Name:       role.Name,
+			Inherited:  !permission_match.Allowed(own, req.Permission),
+			UsersCount: snapshot.holders[role.Id],
+		})
+	}
+	return result, nil
+}
+
+// UnusedRoles returns roles, which are not linked to any user
+func (s AccessReport) UnusedRoles(ctx context.Context) ([]domain.RoleAccess, error) {
+	users, err := s.allUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := s.snapshot(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.RoleAccess, 0)
+	for _, role := range snapshot.roles {
+		if snapshot.holders[role.Id] > 0 {
+			continue
+		}
+		result = append(result, domain.RoleAccess{
+			RoleId: role.Id,
+			Name:   role.Name,
+		})
+	}
+	return result, nil
+}
+
+// InactiveUsers returns not blocked users with active roles, who were not active for req.Days days
+func (s AccessReport) InactiveUsers(ctx context.Context, req domain.InactiveUsersReportRequest) ([]domain.UserAccess, error) {
+	users, err := s.allUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := s.snapshot(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+
+	activeAfter := time.Now().UTC().AddDate(0, 0, -req.Days)
+	result := make([]domain.UserAccess, 0)
+	for _, user := range users {
+		roles := snapshot.userRoles[int(user.Id)]
+		if user.Blocked || len(roles) == 0 || user.LastActiveAt.After(activeAfter) {
+			continue
+		}
+		result = append(result, toUserAccess(user, roles))
+	}
+	return result, nil
+}
+
+// Csv builds the report req.Report with parameters from the request
+func (s AccessReport) Csv(ctx context.Context, req domain.AccessReportCsvRequest) (*domain.AccessReportFile, error) {
+	rows, err := s.csvRows(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := strings.Builder{}
+	writer := csv.NewWriter(&builder)
+	err = writer.WriteAll(rows)
+	if err != nil {
+		return nil, errors.WithMessage(err, "write csv")
+	}
+
+	return &domain.AccessReportFile{
+		FileName: fmt.Sprintf("%s.csv", req.Report),
+		Content:  builder.String(),
+	}, nil
+}
+
+func (s AccessReport) csvRows(ctx context.Context, req domain.AccessReportCsvRequest) ([][]string, error) {
+	err := checkReportParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Report {
+	case domain.AccessReportUsersByPermission:
+		users, err := s.UsersByPermission(ctx, domain.PermissionReportRequest{Permission: req.Permission})
+		if err != nil {
+			return nil, err
+		}
+		return userAccessRows(users), nil
+	case domain.AccessReportUserPermissions:
+		report, err := s.UserPermissions(ctx, domain.UserPermissionsReportRequest{UserId: req.UserId})
+		if err != nil {
+			return nil, err
+		}
+		return userPermissionsRows(*report), nil
+	case domain.AccessReportRolesByPermission:
+		roles, err := s.RolesByPermission(ctx, domain.PermissionReportRequest{Permission: req.Permission})
+		if err != nil {
+			return nil, err
+		}
+		return roleAccessRows(roles), nil
+	case domain.AccessReportUnusedRoles:
+		roles, err := s.UnusedRoles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return roleAccessRows(roles), nil
+	case domain.AccessReportInactiveUsers:
+		users, err := s.InactiveUsers(ctx, domain.InactiveUsersReportRequest{Days: req.Days})
+		if err != nil {
+			return nil, err
+		}
+		return userAccessRows(users), nil
+	default:
+		return nil, errors.WithMessagef(domain.ErrInvalid, "unknown report %s", req.Report)
+	}
+}
+
+// allUsers returns users ordered by id
+func (s AccessReport) allUsers(ctx context.Context) ([]entity.User, error) {
+	users, err := s.userRepo.GetAllUsers(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all users")
+	}
+	slices.SortFunc(users, func(a entity.User, b entity.User) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+	return users, nil
+}
+
+// snapshot loads all roles with inherited permissions and active roles of the users
+func (s AccessReport) snapshot(ctx context.Context, users []entity.User) (*accessSnapshot, error) {
+	roles, err := s.roleRepo.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all roles")
+	}
+	hierarchy := role_hierarchy.New(roles)
+	rolesById := make(map[int]entity.Role, len(roles))
+	ownPermissions := make(map[int][]string, len(roles))
+	for i, role := range roles {
+		ownPermissions[role.Id] = role.Permissions
+		roles[i] = hierarchy.WithInherited(role)
+		rolesById[role.Id] = roles[i]
+	}
+
+	userIds := make([]int, 0, len(users))
+	for _, user := range users {
+		userIds = append(userIds, int(user.Id))
+	}
+	links, err := s.userRoleRepo.GetRolesByUserIds(ctx, userIds)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get user roles")
+	}
+
+	holders := make(map[int]int)
+	userRoles := make(map[int][]entity.Role)
+	for _, link := range activeUserRoles(links, time.Now().UTC()) {
+		holders[link.RoleId]++
+		role, ok := rolesById[link.RoleId]
+		if ok {
+			userRoles[link.UserId] = append(userRoles[link.UserId], role)
+		}
+	}
+
+	return &accessSnapshot{
+		roles:          roles,
+		ownPermissions: ownPermissions,
+		userRoles:      userRoles,
+		holders:        holders,
+	}, nil
+}
+
+// checkReportParams returns domain.ErrInvalid, if parameters of the report are missing
+func checkReportParams(req domain.AccessReportCsvRequest) error {
+	var params any
+	switch req.Report {
+	case domain.AccessReportUsersByPermission, domain.AccessReportRolesByPermission:
+		params = domain.PermissionReportRequest{Permission: req.Permission}
+	case domain.AccessReportUserPermissions:
+		params = domain.UserPermissionsReportRequest{UserId: req.UserId}
+	case domain.AccessReportInactiveUsers:
+		params = domain.InactiveUsersReportRequest{Days: req.Days}
+	default:
+		return nil
+	}
+	err := validator.Default.ValidateToError(params)
+	if err != nil {
+		return errors.WithMessage(domain.ErrInvalid, err.Error())
+	}
+	return nil
+}
+
+func toUserAccess(user entity.User, roles []entity.Role) domain.UserAccess {
+	return domain.UserAccess{
+		UserId:       user.Id,
+		Email:        user.Email,
+		FullName:     user.FullName,
+		Blocked:      user.Blocked,
+		LastActiveAt: user.LastActiveAt,
+		Roles:        roleNames(roles),
+	}
+}
+
+func roleNames(roles []entity.Role) []string {
+	result := make([]string, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, role.Name)
+	}
+	return result
+}
+
+func userAccessRows(users []domain.UserAccess) [][]string {
+	rows := [][]string{{"ID пользователя", "Email", "ФИО", "Заблокирован", "Последняя активность", "Роли"}}
+	for _, user := range users {
+		rows = append(rows, []string{
+			strconv.FormatInt(user.UserId, 10),
+			user.Email,
+			user.FullName,
+			strconv.FormatBool(user.Blocked),
+			timeToReport(&user.LastActiveAt),
+			strings.Join(user.Roles, ", "),
+		})
+	}
+	return rows
+}
+
+func userPermissionsRows(report domain.UserPermissionsReport) [][]string {
+	rows := [][]string{{"ID пользователя", "Email", "ФИО", "Разрешение"}}
+	for _, permission := range report.Permissions {
+		rows = append(rows, []string{strconv.FormatInt(report.UserId, 10), report.Email, report.FullName, permission})
+	}
+	return rows
+}
+
+func roleAccessRows(roles []domain.RoleAccess) [][]string {
+	rows := [][]string{{"ID роли", "Роль", "Унаследовано", "Количество пользователей"}}
+	for _, role := range roles {
+		rows = append(rows, []string{
+			strconv.Itoa(role.RoleId),
+			role.Name,
+			strconv.FormatBool(role.Inherited),
+			strconv.Itoa(role.UsersCount),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+	"msp-admin-service/service/permission_match"
+)
+
+// AccessRequestApprovePermission allows to review requests for any role
+const AccessRequestApprovePermission = "access_request_approve"
+
+type accessRequestRepo interface {
+	GetAccessRequestById(ctx context.Context, id int) (*entity.AccessRequest, error)
+	AllByRequest(
+		ctx context.Context,
+		req domain.AccessRequestPageRequest,
+		scope *entity.AccessRequestScope,
+	) ([]entity.AccessRequest, error)
+	Count(ctx context.Context, reqQuery *domain.AccessRequestQuery, scope *entity.AccessRequestScope) (int64, error)
+	InsertAccessRequest(ctx context.Context, req entity.AccessRequest) (*entity.AccessRequest, error)
+	ResolveAccessRequest(ctx context.Context, req entity.AccessRequest) (*entity.AccessRequest, error)
+}
+
+type accessRequestRoleRepo interface {
+	GetRoleByIds(ctx context.Context, id []int) ([]entity.Role, error)
+	GetRolesByApproverId(ctx context.Context, userId int64) ([]entity.Role, error)
+}
+
+type accessRequestUserRoleRepo interface {
+	GetRolesByUserIds(ctx context.Context, identity []int) ([]entity.UserRole, error)
+	GetRoleEntitiesByUserId(ctx context.Context, userId int) ([]entity.Role, error)
+}
+
+type AccessRequestTransaction interface {
+	ResolveAccessRequest(ctx context.Context, req entity.AccessRequest) (*entity.AccessRequest, error)
+	GetRolesByUserIds(ctx context.Context, identity []int) ([]entity.UserRole, error)
+	ReplaceUserRoleLinks(ctx context.Context, userId int, links []entity.UserRole) error
+}
+
+type AccessRequestTransactionRunner interface {
+	AccessRequestTransaction(ctx context.Context, tx func(ctx context.Context, tx AccessRequestTransaction) error) error
+}
+
+type AccessRequest struct {
+	requestRepo  accessRequestRepo
+	roleRepo     accessRequestRoleRepo
+	userRoleRepo accessRequestUserRoleRepo
+	roleResolver roleResolver
+	sodChecker   sodChecker
+	txRunner     AccessRequestTransactionRunner
+	auditService auditService
+}
+
+func NewAccessRequest(
+	requestRepo accessRequestRepo,
+	roleRepo accessRequestRoleRepo,
+	userRoleRepo accessRequestUserRoleRepo,
+	roleResolver roleResolver,
+	sodChecker sodChecker,
+	txRunner AccessRequestTransactionRunner,
+	auditService auditService,
+) AccessRequest {
+	return AccessRequest{
+		requestRepo:  requestRepo,
+		roleRepo:     roleRepo,
+		userRoleRepo: userRoleRepo,
+		roleResolver: roleResolver,
+		sodChecker:   sodChecker,
+		txRunner:     txRunner,
+		auditService: auditService,
+	}
+}
+
+// All returns requests visible to the admin: every request with AccessRequestApprovePermission,
+// otherwise own requests and requests for roles, where the admin is an approver
+func (s AccessRequest) All(
+	ctx context.Context,
+	req domain.AccessRequestPageRequest,
+	adminId int64,
+) (*domain.AccessRequestResponse, error) {
+	scope, err := s.scope(ctx, adminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get access request scope")
+	}
+
+	requests, err := s.requestRepo.AllByRequest(ctx, req, scope)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get access requests")
+	}
+
+	count, err := s.requestRepo.Count(ctx, req.Query, scope)
+	if err != nil {
+		return nil, errors.WithMessage(err, "count access requests")
+	}
+
+	items := make([]domain.AccessRequest, 0, len(requests))
+	for _, request := range requests {
+		items = append(items, s.toDomain(request))
+	}
+	return &domain.AccessRequestResponse{
+		TotalCount: int(count),
+		Items:      items,
+	}, nil
+}
+
+func (s AccessRequest) Create(
+	ctx context.Context,
+	req domain.CreateAccessRequestRequest,
+	userId int64,
+) (*domain.AccessRequest, error) {
+	err := validateRolePeriod(nil, req.ValidUntil, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.getRole(ctx, req.RoleId)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := s.userRoleRepo.GetRolesByUserIds(ctx, []int{int(userId)})
+	if err != nil {
+		return nil, errors.WithMessage(err, "get user roles")
+	}
+	hasPermanentRole := slices.ContainsFunc(activeUserRoles(links, time.Now().UTC()), func(link entity.UserRole) bool {
+		return link.RoleId == req.RoleId && link.ValidUntil == nil
+	})
+	if hasPermanentRole {
+		return nil, errors.WithMessagef(domain.ErrAlreadyExists, "user already has role %d", req.RoleId)
+	}
+
+	request, err := s.requestRepo.InsertAccessRequest(ctx, entity.AccessRequest{
+		UserId:        userId,
+		RoleId:        req.RoleId,
+		Justification: req.Justification,
+		ValidUntil:    utcTime(req.ValidUntil),
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "insert access request")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, userId,
+		fmt.Sprintf("Запрос доступа. Создание запроса %d на роль %s (%s). Обоснование: %s",
+			request.Id, role.Name, rolePeriodToString(entity.UserRole{ValidUntil: request.ValidUntil}), request.Justification),
+		entity.EventAccessRequestChanged,
+	)
+
+	return new(s.toDomain(*request)), nil
+}
+
+func (s AccessRequest) Cancel(ctx context.Context, req domain.CancelAccessRequestRequest, userId int64) (*domain.AccessRequest, error) {
+	request, err := s.requestRepo.GetAccessRequestById(ctx, req.Id)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get access request %d", req.Id)
+	}
+	if request.UserId != userId {
+		return nil, errors.WithMessage(domain.ErrAccessRequestDenied, "request of another user")
+	}
+	role, err := s.getRole(ctx, request.RoleId)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Status = entity.AccessRequestCancelled
+	cancelled, err := s.requestRepo.ResolveAccessRequest(ctx, *request)
+	if err != nil {
+		return nil, errors.WithMessage(err, "resolve access request")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, userId,
+		fmt.Sprintf("Запрос доступа. Отмена запроса %d на роль %s", cancelled.Id, role.Name),
+		entity.EventAccessRequestChanged,
+	)
+
+	return new(s.toDomain(*cancelled)), nil
+}
+
+// Approve resolves the request and grants the role to the requester in one transaction
+func (s AccessRequest) Approve(
+	ctx context.Context,
+	req domain.ApproveAccessRequestRequest,
+	adminId int64,
+) (*domain.AccessRequest, error) {
+	request, role, err := s.getForReview(ctx, req.Id, adminId)
+	if err != nil {
+		return nil, err
+	}
+
+	validUntil := request.ValidUntil
+	if req.ValidUntil != nil {
+		validUntil = utcTime(req.ValidUntil)
+	}
+	err = validateRolePeriod(nil, validUntil, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	link := entity.UserRole{
+		UserId:     int(request.UserId),
+		RoleId:     request.RoleId,
+		ValidUntil: validUntil,
+	}
+	var approved *entity.AccessRequest
+	err = s.txRunner.AccessRequestTransaction(ctx, func(ctx context.Context, tx AccessRequestTransaction) error {
+		approved, err = tx.ResolveAccessRequest(ctx, entity.AccessRequest{
+			Id:            request.Id,
+			Status:        entity.AccessRequestApproved,
+			ValidUntil:    validUntil,
+			ReviewerId:    &adminId,
+			ReviewComment: req.Comment,
+		})
+		if err != nil {
+			return errors.WithMessage(err, "resolve access request")
+		}
+
+		links, err := tx.GetRolesByUserIds(ctx, []int{link.UserId})
+		if err != nil {
+			return errors.WithMessage(err, "get user roles")
+		}
+		links = withUserRoleLink(links, link)
+		err = s.sodChecker.Check(ctx, RolesIds(links))
+		if err != nil {
+			return errors.WithMessage(err, "check separation of duties")
+		}
+		err = tx.ReplaceUserRoleLinks(ctx, link.UserId, links)
+		if err != nil {
+			return errors.WithMessage(err, "update user role links")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "approve access request transaction")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Запрос доступа. Одобрение запроса %d пользователя %d на роль %s (%s). Комментарий: %s",
+			approved.Id, approved.UserId, role.Name, rolePeriodToString(link), approved.ReviewComment),
+		entity.EventAccessRequestChanged,
+	)
+
+	return new(s.toDomain(*approved)), nil
+}
+
+func (s AccessRequest) Reject(
+	ctx context.Context,
+	req domain.RejectAccessRequestRequest,
+	adminId int64,
+) (*domain.AccessRequest, error) {
+	request, role, err := s.getForReview(ctx, req.Id, adminId)
+	if err != nil {
+		return nil, err
+	}
+
+	rejected, err := s.requestRepo.ResolveAccessRequest(ctx, entity.AccessRequest{
+		Id:            request.Id,
+		Status:        entity.AccessRequestRejected,
+		ValidUntil:    request.ValidUntil,
+		ReviewerId:    &adminId,
+		ReviewComment: req.Comment,
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "resolve access request")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Запрос доступа. Отклонение запроса %d пользователя %d на роль %s. Комментарий: %s",
+			rejected.Id, rejected.UserId, role.Name, rejected.ReviewComment),
+		entity.EventAccessRequestChanged,
+	)
+
+	return new(s.toDomain(*rejected)), nil
+}
+
+// getForReview returns pending request and its role, if the admin is allowed to review it
+func (s AccessRequest) getForReview(ctx context.Context, id int, adminId int64) (*entity.AccessRequest, *entity.Role, error) {
+	request, err := s.requestRepo.GetAccessRequestById(ctx, id)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "get access request %d", id)
+	}
+	if request.Status != entity.AccessRequestPending {
+		return nil, nil, domain.ErrAccessRequestNotPending
+	}
+	if request.UserId == adminId {
+		return nil, nil, errors.WithMessage(domain.ErrAccessRequestDenied, "self review")
+	}
+
+	role, err := s.getRole(ctx, request.RoleId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if slices.Contains(role.ApproverIds, adminId) {
+		return request, role, nil
+	}
+
+	allowed, err := s.hasApprovePermission(ctx, adminId)
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case !allowed:
+		return nil, nil, errors.WithMessagef(domain.ErrAccessRequestDenied, "user %d is not an approver of role %d", adminId, role.Id)
+	default:
+		return request, role, nil
+	}
+}
+
+func (s AccessRequest) scope(ctx context.Context, adminId int64) (*entity.AccessRequestScope, error) {
+	allowed, err := s.hasApprovePermission(ctx, adminId)
+	if err != nil {
+		return nil, err
+	}
+	if allowed {
+		return nil, nil // nolint:nilnil
+	}
+
+	roles, err := s.roleRepo.GetRolesByApproverId(ctx, adminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get roles by approver id")
+	}
+	roleIds := make([]int, 0, len(roles))
+	for _, role := range roles {
+		roleIds = append(roleIds, role.Id)
+	}
+	return &entity.AccessRequestScope{
+		UserId:  adminId,
+		RoleIds: roleIds,
+	}, nil
+}
+
+func (s AccessRequest) hasApprovePermission(ctx context.Context, adminId int64) (bool, error) {
+	roles, err := s.userRoleRepo.GetRoleEntitiesByUserId(ctx, int(adminId))
+	if err != nil {
+		return false, errors.WithMessage(err, "get user roles")
+	}
+	roles, err = s.roleResolver.Resolve(ctx, roles)
+	if err != nil {
+		return false, errors.WithMessage(err, "resolve inherited permissions")
+	}
+	return permission_match.Allowed(mergePermissions(roles), AccessRequestApprovePermission), nil
+}
+
+func (s AccessRequest) getRole(ctx context.Context, roleId int) (*entity.Role, error) {
+	roles, err := s.roleRepo.GetRoleByIds(ctx, []int{roleId})
+	switch {
+	case err != nil:
+		return nil, errors.WithMessage(err, "get role by id")
+	case len(roles) == 0:
+		return nil, errors.WithMessagef(domain.ErrNotFound, "role %d", roleId)
+	default:
+		return &roles[0], nil
+	}
+}
+
+func (s AccessRequest) toDomain(request entity.AccessRequest) domain.AccessRequest {
+	return domain.AccessRequest{
+		Id:            request.Id,
+		UserId:        request.UserId,
+		RoleId:        request.RoleId,
+		Justification: request.Justification,
+		Status:        request.Status,
+		ValidUntil:    request.ValidUntil,
+		ReviewerId:    request.ReviewerId,
+		ReviewComment: request.ReviewComment,
+		CreatedAt:     request.CreatedAt,
+		UpdatedAt:     request.UpdatedAt,
+	}
+}
+
+// withUserRoleLink adds link to links, period of existing link of the same role is replaced
+func withUserRoleLink(links []entity.UserRole, link entity.UserRole) []entity.UserRole {
+	result := make([]entity.UserRole, 0, len(links)+1)
+	for _, existing := range links {
+		if existing.RoleId != link.RoleId {
+			result = append(result, existing)
+		}
+	}
+	return append(result, link)
+}
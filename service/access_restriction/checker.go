@@ -0,0 +1,159 @@
+package access_restriction
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"time"
+	// embedded timezone database, containers often miss system one
+	_ "time/tzdata"
+
+	"msp-admin-service/conf"
+	"msp-admin-service/entity"
+)
+
+const (
+	timeLayout     = "15:04"
+	minutesPerHour = 60
+	daysPerWeek    = 7
+)
+
+// Violation describes role restriction, that denies access
+type Violation struct {
+	RoleName string
+	Reason   string
+	ClientIp string
+}
+
+func (v Violation) String() string {
+	clientIp := v.ClientIp
+	if clientIp == "" {
+		clientIp = "неизвестен"
+	}
+	return fmt.Sprintf("роль «%s», %s, IP-адрес: %s", v.RoleName, v.Reason, clientIp)
+}
+
+// Checker evaluates network and time restrictions of roles
+type Checker struct {
+	location    *time.Location
+	locationErr error
+}
+
+func NewChecker(cfg conf.AccessRestrictions) Checker {
+	location := time.UTC
+	var locationErr error
+	if cfg.Timezone != "" {
+		location, locationErr = time.LoadLocation(cfg.Timezone)
+	}
+	return Checker{
+		location:    location,
+		locationErr: locationErr,
+	}
+}
+
+// CheckRoles returns the first violated restriction, nil if every role is usable from clientIp at the moment
+func (c Checker) CheckRoles(roles []entity.Role, clientIp string) *Violation {
+	for _, role := range roles {
+		violation := c.CheckRole(role, clientIp)
+		if violation != nil {
+			return violation
+		}
+	}
+	return nil
+}
+
+// CheckRole returns violated restriction, nil if role is usable from clientIp at the moment
+func (c Checker) CheckRole(role entity.Role, clientIp string) *Violation {
+	if len(role.AllowedCidrs) > 0 && !addressAllowed(role.AllowedCidrs, clientIp) {
+		return &Violation{
+			RoleName: role.Name,
+			Reason:   "адрес вне разрешенных сетей",
+			ClientIp: clientIp,
+		}
+	}
+
+	if len(role.TimeWindows) == 0 {
+		return nil
+	}
+	if c.locationErr != nil {
+		// time restrictions can not be evaluated, so access is denied
+		return &Violation{
+			RoleName: role.Name,
+			Reason:   fmt.Sprintf("некорректный часовой пояс в конфигурации: %v", c.locationErr),
+			ClientIp: clientIp,
+		}
+	}
+	now := time.Now().In(c.location)
+	if !slices.ContainsFunc(role.TimeWindows, func(window entity.TimeWindow) bool {
+		return inTimeWindow(window, now)
+	}) {
+		return &Violation{
+			RoleName: role.Name,
+			Reason:   "время вне разрешенных интервалов",
+			ClientIp: clientIp,
+		}
+	}
+
+	return nil
+}
+
+func addressAllowed(cidrs []string, clientIp string) bool {
+	addr, err := netip.ParseAddr(clientIp)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func inTimeWindow(window entity.TimeWindow, now time.Time) bool {
+	from, err := time.Parse(timeLayout, window.From)
+	if err != nil {
+		return false
+	}
+	to, err := time.Parse(timeLayout, window.To)
+	if err != nil {
+		return false
+	}
+
+	fromMinutes := from.Hour()*minutesPerHour + from.Minute()
+	toMinutes := to.Hour()*minutesPerHour + to.Minute()
+	minutes := now.Hour()*minutesPerHour + now.Minute()
+	weekday := isoWeekday(now)
+
+	switch {
+	case fromMinutes == toMinutes:
+		return onWeekday(window.Weekdays, weekday)
+	case fromMinutes < toMinutes:
+		return minutes >= fromMinutes && minutes < toMinutes && onWeekday(window.Weekdays, weekday)
+	case minutes >= fromMinutes:
+		return onWeekday(window.Weekdays, weekday)
+	default:
+		// interval passes midnight and belongs to the weekday it starts
+		previousWeekday := (weekday+daysPerWeek-2)%daysPerWeek + 1
+		return minutes < toMinutes && onWeekday(window.Weekdays, previousWeekday)
+	}
+}
+
+func onWeekday(weekdays []int, weekday int) bool {
+	return len(weekdays) == 0 || slices.Contains(weekdays, weekday)
+}
+
+// isoWeekday returns 1 for monday and 7 for sunday
+func isoWeekday(t time.Time) int {
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		return daysPerWeek
+	}
+	return weekday
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"msp-admin-service/conf"
@@ -41,12 +42,19 @@ func NewAudit(
 	settings []conf.AuditEventSetting,
 ) Audit {
 	expectedEventList := map[string]bool{
-		entity.EventSuccessLogin:  true,
-		entity.EventErrorLogin:    true,
-		entity.EventSuccessLogout: true,
-		entity.EventRoleChanged:   true,
-		entity.EventUserChanged:   true,
-		entity.EventUserBlocked:   true,
+		entity.EventSuccessLogin:         true,
+		entity.EventErrorLogin:           true,
+		entity.EventSuccessLogout:        true,
+		entity.EventRoleChanged:          true,
+		entity.EventUserChanged:          true,
+		entity.EventUserBlocked:          true,
+		entity.EventUserImpersonated:     true,
+		entity.EventMagicLinkRequested:   true,
+		entity.EventWebauthnChanged:      true,
+		entity.EventAccessRestricted:     true,
+		entity.EventAccessRequestChanged: true,
+		entity.EventPendingChange:        true,
+		entity.EventRecertification:      true,
 	}
 
 	eventName := make(map[string]conf.AuditEventSetting)
@@ -81,6 +89,11 @@ func (s Audit) SaveAuditAsync(ctx context.Context, userId int64, message string,
 			return
 		}
 
+		impersonatorId, ok := domain.ImpersonatorFromContext(ctx)
+		if ok && impersonatorId != userId {
+			message = fmt.Sprintf("Администратор %d действует от имени пользователя %d. %s", impersonatorId, userId, message)
+		}
+
 		audit := entity.Audit{
 			UserId:    int(userId),
 			Message:   message,
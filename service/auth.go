@@ -2,15 +2,17 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	"msp-admin-service/domain"
 	"msp-admin-service/entity"
+	"msp-admin-service/service/access_restriction"
 
 	"github.com/pkg/errors"
 	"github.com/txix-open/isp-kit/log"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthTransaction interface {
@@ -18,6 +20,13 @@ type AuthTransaction interface {
 	roleRepo
 	UserRoleRepo
 	TokenSaver
+	magicLinkRepo
+	webauthnRepo
+	userRoleEntityRepo
+}
+
+type userRoleEntityRepo interface {
+	GetRoleEntitiesByUserId(ctx context.Context, userId int) ([]entity.Role, error)
 }
 
 type AuthTransactionRunner interface {
@@ -30,25 +39,62 @@ type auditService interface {
 
 type userRepository interface {
 	GetUserByEmail(ctx context.Context, email string) (*entity.User, error)
+	GetUserById(ctx context.Context, identity int64) (*entity.User, error)
+	GetUserBySudirUserId(ctx context.Context, sudirUserId string) (*entity.User, error)
 	UpsertBySudirUserId(ctx context.Context, user entity.User) (*entity.User, error)
 	UpdateUser(ctx context.Context, id int64, user entity.UpdateUser) (*entity.User, error)
 	UpdateLastActiveAt(ctx context.Context, userId int64, lastActiveAt time.Time) error
+	ChangePassword(ctx context.Context, userId int64, newPassword string) error
 }
 
 type tokenService interface {
 	GenerateToken(ctx context.Context, repo TokenSaver, id int64) (string, string, error)
 	RevokeAllByUserId(ctx context.Context, userId int64) error
+	RevokeImpersonation(ctx context.Context, userId int64, impersonatorId int64) error
 }
 
 type sudirService interface {
 	Authenticate(ctx context.Context, authCode string, repo roleRepo) (*entity.SudirUser, error)
 }
 
+// passwordAuthProvider is an external login backend, that checks user credentials and resolves user roles
+type passwordAuthProvider interface {
+	Authenticate(ctx context.Context, login string, password string, repo roleRepo) (*entity.SudirUser, error)
+	ExternalUserId(login string) string
+}
+
+// magicLinkProvider issues and consumes single-use login links of local users
+type magicLinkProvider interface {
+	Enabled() bool
+	Limit(ctx context.Context, repo magicLinkRepo, email string) error
+	Issue(ctx context.Context, repo magicLinkRepo, user entity.User) error
+	Consume(ctx context.Context, repo magicLinkRepo, token string) (int64, error)
+}
+
+// webauthnProvider runs assertion ceremonies with passkeys of local users
+type webauthnProvider interface {
+	Enabled() bool
+	BeginLogin(ctx context.Context, repo webauthnRepo, user *entity.User) (*domain.WebauthnChallenge, error)
+	BeginSecondFactor(ctx context.Context, repo webauthnRepo, user entity.User) (*domain.WebauthnChallenge, error)
+	FinishLogin(ctx context.Context, repo webauthnLoginRepo, request domain.WebauthnFinishRequest) (int64, string, error)
+}
+
+// accessChecker evaluates network and time restrictions of roles
+type accessChecker interface {
+	CheckRoles(roles []entity.Role, clientIp string) *access_restriction.Violation
+}
+
 type Auth struct {
 	userRepository           userRepository
 	txRunner                 AuthTransactionRunner
 	tokenService             tokenService
+	passwordHasher           passwordHasher
 	sudirService             sudirService
+	ldapProvider             passwordAuthProvider
+	magicLinkProvider        magicLinkProvider
+	webauthnProvider         webauthnProvider
+	accessChecker            accessChecker
+	sodChecker               sodChecker
 	auditService             auditService
 	logger                   log.Logger
 	maxInFlightLoginRequests int64
@@ -60,7 +106,13 @@ func NewAuth(
 	userRepository userRepository,
 	txRunner AuthTransactionRunner,
 	tokenService tokenService,
+	passwordHasher passwordHasher,
 	sudirService sudirService,
+	ldapProvider passwordAuthProvider,
+	magicLinkProvider magicLinkProvider,
+	webauthnProvider webauthnProvider,
+	accessChecker accessChecker,
+	sodChecker sodChecker,
 	auditService auditService,
 	logger log.Logger,
 	delayLoginRequestInSec int,
@@ -70,7 +122,13 @@ func NewAuth(
 		userRepository:           userRepository,
 		txRunner:                 txRunner,
 		tokenService:             tokenService,
+		passwordHasher:           passwordHasher,
 		sudirService:             sudirService,
+		ldapProvider:             ldapProvider,
+		magicLinkProvider:        magicLinkProvider,
+		webauthnProvider:         webauthnProvider,
+		accessChecker:            accessChecker,
+		sodChecker:               sodChecker,
 		auditService:             auditService,
 		logger:                   logger,
 		delayLoginRequest:        time.Duration(delayLoginRequestInSec) * time.Second,
@@ -80,20 +138,19 @@ func NewAuth(
 }
 
 func (a Auth) Login(ctx context.Context, request domain.LoginRequest) (*domain.LoginResponse, error) {
-	value := a.inFlightLoginRequests.Add(1)
-	defer a.inFlightLoginRequests.Add(-1)
-
-	if value > a.maxInFlightLoginRequests {
-		return nil, domain.ErrTooManyLoginRequests
+	release, err := a.throttleLogin()
+	if err != nil {
+		return nil, err
 	}
-	time.Sleep(a.delayLoginRequest)
+	defer release()
 
 	var (
 		tokenString string
 		expired     string
+		challenge   *domain.WebauthnChallenge
 	)
 
-	err := a.txRunner.AuthTransaction(ctx, func(ctx context.Context, tx AuthTransaction) error {
+	err = a.txRunner.AuthTransaction(ctx, func(ctx context.Context, tx AuthTransaction) error {
 		user, err := tx.GetUserByEmail(ctx, request.Email)
 		switch {
 		case errors.Is(err, domain.ErrNotFound):
@@ -109,10 +166,29 @@ func (a Auth) Login(ctx context.Context, request domain.LoginRequest) (*domain.L
 			return errors.WithMessagef(domain.ErrUnauthenticated, "user '%d' is blocked", user.Id)
 		}
 
-		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password))
+		rehash, err := a.passwordHasher.Verify(user.Password, request.Password)
 		if err != nil {
 			a.auditService.SaveAuditAsync(ctx, user.Id, "Неуспешный вход. Неверный пароль", entity.EventErrorLogin)
-			return errors.WithMessage(domain.ErrUnauthenticated, "wrong password")
+			return errors.WithMessagef(domain.ErrUnauthenticated, "wrong password: %v", err)
+		}
+		if rehash {
+			err = a.rehashPassword(ctx, tx, user.Id, request.Password)
+			if err != nil {
+				return errors.WithMessage(err, "rehash password")
+			}
+		}
+
+		err = a.checkAccessRestrictions(ctx, tx, user.Id)
+		if err != nil {
+			return err
+		}
+
+		challenge, err = a.webauthnProvider.BeginSecondFactor(ctx, tx, *user)
+		if err != nil {
+			return errors.WithMessage(err, "begin webauthn second factor")
+		}
+		if challenge != nil {
+			return nil
 		}
 
 		tokenString, expired, err = a.tokenService.GenerateToken(ctx, tx, user.Id)
@@ -138,6 +214,7 @@ func (a Auth) Login(ctx context.Context, request domain.LoginRequest) (*domain.L
 		Token:      tokenString,
 		Expired:    expired,
 		HeaderName: domain.AdminAuthHeaderName,
+		Webauthn:   challenge,
 	}, nil
 }
 
@@ -166,27 +243,199 @@ func (a Auth) LoginWithSudir(ctx context.Context, request domain.LoginSudirReque
 			return domain.ErrUnauthenticated
 		}
 
-		user, err = tx.UpsertBySudirUserId(ctx, entity.User{
-			SudirUserId: &sudirUser.SudirUserId,
-			FirstName:   sudirUser.FirstName,
-			LastName:    sudirUser.LastName,
-			FullName:    sudirUser.FullName,
-			Email:       sudirUser.Email,
-			Password:    "",
-			Blocked:     false,
-			UpdatedAt:   time.Now().UTC(),
-			CreatedAt:   time.Now().UTC(),
-		})
+		user, tokenString, expired, err = a.loginExternalUser(ctx, tx, sudirUser)
 		if errors.Is(err, domain.ErrUserIsBlocked) {
 			return errors.Errorf("user with sudir user id = %s is blocked", sudirUser.SudirUserId)
 		}
 		if err != nil {
-			return errors.WithMessage(err, "upsert by sudir user id")
+			return errors.WithMessage(err, "login external user")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "auth transaction")
+	}
+
+	a.auditService.SaveAuditAsync(ctx, user.Id, "Успешный вход через СУДИР", entity.EventSuccessLogin)
+
+	return &domain.LoginResponse{
+		Token:      tokenString,
+		Expired:    expired,
+		HeaderName: domain.AdminAuthHeaderName,
+	}, nil
+}
+
+func (a Auth) LoginWithLdap(ctx context.Context, request domain.LoginLdapRequest) (*domain.LoginResponse, error) {
+	release, err := a.throttleLogin()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		user *entity.User
+
+		tokenString string
+		expired     string
+	)
+
+	err = a.txRunner.AuthTransaction(ctx, func(ctx context.Context, tx AuthTransaction) error {
+		ldapUser, err := a.ldapProvider.Authenticate(ctx, request.Login, request.Password, tx)
+		switch {
+		case errors.Is(err, domain.ErrUnauthenticated):
+			a.auditLdapLoginError(ctx, tx, request.Login)
+			return errors.WithMessage(err, "ldap authenticate")
+		case err != nil:
+			return errors.WithMessage(err, "ldap authenticate")
+		case ldapUser.Email == "":
+			a.logger.Error(ctx, "ldap authenticate: missing email", log.String("dn", ldapUser.SudirUserId))
+			return domain.ErrUnauthenticated
+		}
+
+		user, tokenString, expired, err = a.loginExternalUser(ctx, tx, ldapUser)
+		if errors.Is(err, domain.ErrUserIsBlocked) {
+			return errors.WithMessagef(domain.ErrUnauthenticated, "user with dn = %s is blocked", ldapUser.SudirUserId)
+		}
+		if err != nil {
+			return errors.WithMessage(err, "login external user")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "auth transaction")
+	}
+
+	a.auditService.SaveAuditAsync(ctx, user.Id, "Успешный вход через LDAP", entity.EventSuccessLogin)
+
+	return &domain.LoginResponse{
+		Token:      tokenString,
+		Expired:    expired,
+		HeaderName: domain.AdminAuthHeaderName,
+	}, nil
+}
+
+// auditLdapLoginError saves failed ldap login to audit of the local user, unknown logins are not audited
+func (a Auth) auditLdapLoginError(ctx context.Context, tx AuthTransaction, login string) {
+	user, err := tx.GetUserBySudirUserId(ctx, a.ldapProvider.ExternalUserId(login))
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return
+	case err != nil:
+		a.logger.Error(ctx, "ldap login audit: get user by sudir user id", log.Any("error", err))
+		return
+	}
+
+	a.auditService.SaveAuditAsync(ctx, user.Id, "Неуспешный вход через LDAP. Неверный логин или пароль", entity.EventErrorLogin)
+}
+
+// RequestMagicLink emails login link, requests are limited by the email and every path returns the same response
+// for unknown emails, users, who can not log in with the link, and errors of sending to not disclose registered users
+//
+//nolint:nilerr
+func (a Auth) RequestMagicLink(ctx context.Context, request domain.MagicLinkRequest) error {
+	if !a.magicLinkProvider.Enabled() {
+		return domain.ErrMagicLinkIsMissed
+	}
+
+	release, err := a.throttleLogin()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var user *entity.User
+	err = a.txRunner.AuthTransaction(ctx, func(ctx context.Context, tx AuthTransaction) error {
+		err := a.magicLinkProvider.Limit(ctx, tx, request.Email)
+		if err != nil {
+			return errors.WithMessage(err, "limit magic link requests")
+		}
+
+		user, err = tx.GetUserByEmail(ctx, request.Email)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			user = nil
+			return nil
+		case err != nil:
+			return errors.WithMessage(err, "get user by email")
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "auth transaction")
+	}
+
+	switch {
+	case user == nil:
+		return nil
+	case user.SudirUserId != nil:
+		a.auditService.SaveAuditAsync(ctx, user.Id, "Неуспешный запрос ссылки для входа. Пользователь входит через СУДИР", entity.EventErrorLogin)
+		return nil
+	case user.Blocked:
+		a.auditService.SaveAuditAsync(ctx, user.Id, "Неуспешный запрос ссылки для входа. Пользователь заблокирован", entity.EventErrorLogin)
+		return nil
+	}
+
+	err = a.txRunner.AuthTransaction(ctx, func(ctx context.Context, tx AuthTransaction) error {
+		return a.magicLinkProvider.Issue(ctx, tx, *user)
+	})
+	if err != nil {
+		a.logger.Error(ctx, "request magic link: issue magic link", log.Any("error", err))
+		a.auditService.SaveAuditAsync(ctx, user.Id, "Неуспешный запрос ссылки для входа. Ошибка отправки ссылки", entity.EventErrorLogin)
+		return nil
+	}
+
+	a.auditService.SaveAuditAsync(ctx, user.Id, "Запрошена ссылка для входа", entity.EventMagicLinkRequested)
+
+	return nil
+}
+
+func (a Auth) LoginWithMagicLink(ctx context.Context, request domain.LoginMagicLinkRequest) (*domain.LoginResponse, error) {
+	release, err := a.throttleLogin()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		tokenString string
+		expired     string
+		challenge   *domain.WebauthnChallenge
+	)
+
+	err = a.txRunner.AuthTransaction(ctx, func(ctx context.Context, tx AuthTransaction) error {
+		userId, err := a.magicLinkProvider.Consume(ctx, tx, request.Token)
+		if err != nil {
+			return errors.WithMessage(err, "consume magic link")
+		}
+
+		user, err := tx.GetUserById(ctx, userId)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			return errors.WithMessage(domain.ErrUnauthenticated, "user not found")
+		case err != nil:
+			return errors.WithMessage(err, "get user by id")
+		case user.SudirUserId != nil:
+			return domain.ErrSudirAuthorization
+		}
+
+		if user.Blocked {
+			a.auditService.SaveAuditAsync(ctx, user.Id, "Неуспешный вход по ссылке. Пользователь заблокирован", entity.EventErrorLogin)
+			return errors.WithMessagef(domain.ErrUnauthenticated, "user '%d' is blocked", user.Id)
+		}
+
+		err = a.checkAccessRestrictions(ctx, tx, user.Id)
+		if err != nil {
+			return err
 		}
 
-		err = tx.UpsertUserRoleLinks(ctx, int(user.Id), sudirUser.RoleIds)
+		challenge, err = a.webauthnProvider.BeginSecondFactor(ctx, tx, *user)
 		if err != nil {
-			return errors.WithMessage(err, "upsert user role links")
+			return errors.WithMessage(err, "begin webauthn second factor")
+		}
+		if challenge != nil {
+			return nil
 		}
 
 		tokenString, expired, err = a.tokenService.GenerateToken(ctx, tx, user.Id)
@@ -200,13 +449,126 @@ func (a Auth) LoginWithSudir(ctx context.Context, request domain.LoginSudirReque
 			return errors.WithMessage(err, "update user last_active_at")
 		}
 
+		a.auditService.SaveAuditAsync(ctx, user.Id, "Успешный вход по ссылке", entity.EventSuccessLogin)
+
 		return nil
 	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "auth transaction")
 	}
 
-	a.auditService.SaveAuditAsync(ctx, user.Id, "Успешный вход через СУДИР", entity.EventSuccessLogin)
+	return &domain.LoginResponse{
+		Token:      tokenString,
+		Expired:    expired,
+		HeaderName: domain.AdminAuthHeaderName,
+		Webauthn:   challenge,
+	}, nil
+}
+
+func (a Auth) BeginWebauthnLogin(ctx context.Context, request domain.WebauthnBeginRequest) (*domain.WebauthnChallenge, error) {
+	if !a.webauthnProvider.Enabled() {
+		return nil, domain.ErrWebauthnIsMissed
+	}
+
+	release, err := a.throttleLogin()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var challenge *domain.WebauthnChallenge
+	err = a.txRunner.AuthTransaction(ctx, func(ctx context.Context, tx AuthTransaction) error {
+		var user *entity.User
+		if request.Email != "" {
+			// unknown email falls back to discoverable login to not disclose registered users
+			user, err = tx.GetUserByEmail(ctx, request.Email)
+			switch {
+			case errors.Is(err, domain.ErrNotFound):
+				user = nil
+			case err != nil:
+				return errors.WithMessage(err, "get user by email")
+			}
+		}
+
+		challenge, err = a.webauthnProvider.BeginLogin(ctx, tx, user)
+		if err != nil {
+			return errors.WithMessage(err, "begin webauthn login")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "auth transaction")
+	}
+
+	return challenge, nil
+}
+
+// FinishWebauthnLogin completes passkey login or second factor of password login
+func (a Auth) FinishWebauthnLogin(ctx context.Context, request domain.WebauthnFinishRequest) (*domain.LoginResponse, error) {
+	if !a.webauthnProvider.Enabled() {
+		return nil, domain.ErrWebauthnIsMissed
+	}
+
+	release, err := a.throttleLogin()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		tokenString string
+		expired     string
+	)
+
+	err = a.txRunner.AuthTransaction(ctx, func(ctx context.Context, tx AuthTransaction) error {
+		userId, ceremony, err := a.webauthnProvider.FinishLogin(ctx, tx, request)
+		if err != nil {
+			return errors.WithMessage(err, "finish webauthn login")
+		}
+
+		user, err := tx.GetUserById(ctx, userId)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			return errors.WithMessage(domain.ErrUnauthenticated, "user not found")
+		case err != nil:
+			return errors.WithMessage(err, "get user by id")
+		case user.SudirUserId != nil:
+			return domain.ErrSudirAuthorization
+		}
+
+		if user.Blocked {
+			a.auditService.SaveAuditAsync(ctx, user.Id, "Неуспешный вход по ключу доступа. Пользователь заблокирован", entity.EventErrorLogin)
+			return errors.WithMessagef(domain.ErrUnauthenticated, "user '%d' is blocked", user.Id)
+		}
+
+		err = a.checkAccessRestrictions(ctx, tx, user.Id)
+		if err != nil {
+			return err
+		}
+
+		tokenString, expired, err = a.tokenService.GenerateToken(ctx, tx, user.Id)
+		if err != nil {
+			return errors.WithMessage(err, "generate token")
+		}
+
+		lastActiveAt := time.Now().UTC()
+		err = tx.UpdateLastActiveAt(ctx, user.Id, lastActiveAt)
+		if err != nil {
+			return errors.WithMessage(err, "update user last_active_at")
+		}
+
+		message := "Успешный вход по ключу доступа"
+		if ceremony == entity.WebauthnCeremonySecondFactor {
+			message = "Успешный вход с подтверждением ключом доступа"
+		}
+		a.auditService.SaveAuditAsync(ctx, user.Id, message, entity.EventSuccessLogin)
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "auth transaction")
+	}
 
 	return &domain.LoginResponse{
 		Token:      tokenString,
@@ -216,6 +578,16 @@ func (a Auth) LoginWithSudir(ctx context.Context, request domain.LoginSudirReque
 }
 
 func (a Auth) Logout(ctx context.Context, adminId int64, request *domain.LogoutRequest) error {
+	// impersonated session must not terminate sessions of the user itself
+	if impersonatorId, ok := domain.ImpersonatorFromContext(ctx); ok {
+		err := a.tokenService.RevokeImpersonation(ctx, adminId, impersonatorId)
+		if err != nil {
+			return errors.WithMessage(err, "revoke impersonation tokens")
+		}
+		a.auditService.SaveAuditAsync(ctx, adminId, "Выход", entity.EventSuccessLogout)
+		return nil
+	}
+
 	err := a.txRunner.AuthTransaction(ctx, func(ctx context.Context, tx AuthTransaction) error {
 		err := a.tokenService.RevokeAllByUserId(ctx, adminId)
 		if err != nil {
@@ -242,3 +614,120 @@ func (a Auth) Logout(ctx context.Context, adminId int64, request *domain.LogoutR
 
 	return nil
 }
+
+// loginExternalUser upserts user from external identity provider, synchronizes roles and issues token
+func (a Auth) loginExternalUser(
+	ctx context.Context,
+	tx AuthTransaction,
+	externalUser *entity.SudirUser,
+) (*entity.User, string, string, error) {
+	user, err := tx.UpsertBySudirUserId(ctx, entity.User{
+		SudirUserId: &externalUser.SudirUserId,
+		FirstName:   externalUser.FirstName,
+		LastName:    externalUser.LastName,
+		FullName:    externalUser.FullName,
+		Email:       externalUser.Email,
+		Password:    "",
+		Blocked:     false,
+		UpdatedAt:   time.Now().UTC(),
+		CreatedAt:   time.Now().UTC(),
+	})
+	if errors.Is(err, domain.ErrUserIsBlocked) {
+		return nil, "", "", err // nolint:wrapcheck
+	}
+	if err != nil {
+		return nil, "", "", errors.WithMessage(err, "upsert by sudir user id")
+	}
+
+	err = a.checkSeparationOfDuties(ctx, user.Id, externalUser.RoleIds)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	err = tx.UpsertUserRoleLinks(ctx, int(user.Id), externalUser.RoleIds)
+	if err != nil {
+		return nil, "", "", errors.WithMessage(err, "upsert user role links")
+	}
+
+	err = a.checkAccessRestrictions(ctx, tx, user.Id)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	tokenString, expired, err := a.tokenService.GenerateToken(ctx, tx, user.Id)
+	if err != nil {
+		return nil, "", "", errors.WithMessage(err, "generate token")
+	}
+
+	lastActiveAt := time.Now().UTC()
+	err = tx.UpdateLastActiveAt(ctx, user.Id, lastActiveAt)
+	if err != nil {
+		return nil, "", "", errors.WithMessage(err, "update user last_active_at")
+	}
+
+	return user, tokenString, expired, nil
+}
+
+// checkSeparationOfDuties rejects login, when roles of the external system violate the separation of duties
+func (a Auth) checkSeparationOfDuties(ctx context.Context, userId int64, roleIds []int) error {
+	err := a.sodChecker.Check(ctx, roleIds)
+	violation := domain.SodViolationError{}
+	switch {
+	case errors.As(err, &violation):
+		a.auditService.SaveAuditAsync(ctx, userId,
+			fmt.Sprintf("Неуспешный вход. Нарушено разделение обязанностей %s, роли: %s", violation.Constraint, strings.Join(violation.Roles, ", ")),
+			entity.EventAccessRestricted,
+		)
+		return errors.WithMessagef(err, "user '%d'", userId)
+	case err != nil:
+		return errors.WithMessage(err, "check separation of duties")
+	default:
+		return nil
+	}
+}
+
+// checkAccessRestrictions rejects login, when any role of the user is restricted for client address or current time
+func (a Auth) checkAccessRestrictions(ctx context.Context, tx AuthTransaction, userId int64) error {
+	roles, err := tx.GetRoleEntitiesByUserId(ctx, int(userId))
+	if err != nil {
+		return errors.WithMessage(err, "get role entities by user id")
+	}
+
+	violation := a.accessChecker.CheckRoles(roles, domain.ClientIpFromContext(ctx))
+	if violation == nil {
+		return nil
+	}
+
+	a.auditService.SaveAuditAsync(ctx, userId, fmt.Sprintf("Неуспешный вход. Доступ ограничен: %s", violation), entity.EventAccessRestricted)
+	return errors.WithMessagef(domain.ErrAccessRestricted, "user '%d', role '%s'", userId, violation.RoleName)
+}
+
+// rehashPassword replaces hash produced by outdated algorithm or parameters
+func (a Auth) rehashPassword(ctx context.Context, tx AuthTransaction, userId int64, password string) error {
+	hash, err := a.passwordHasher.Hash(password)
+	if err != nil {
+		return errors.WithMessage(err, "hash password")
+	}
+
+	err = tx.ChangePassword(ctx, userId, hash)
+	if err != nil {
+		return errors.WithMessage(err, "change password")
+	}
+
+	return nil
+}
+
+func (a Auth) throttleLogin() (func(), error) {
+	value := a.inFlightLoginRequests.Add(1)
+	release := func() {
+		a.inFlightLoginRequests.Add(-1)
+	}
+
+	if value > a.maxInFlightLoginRequests {
+		release()
+		return nil, domain.ErrTooManyLoginRequests
+	}
+	time.Sleep(a.delayLoginRequest)
+
+	return release, nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/json"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+const (
+	privilegedRoleGrantOperation = "privileged_role_grant"
+	defaultPendingChangeLifeTime = 24 * time.Hour
+)
+
+type changeGateRepo interface {
+	InsertPendingChange(ctx context.Context, change entity.PendingChange) (*entity.PendingChange, error)
+}
+
+// ChangeGate decides, which operations require approval of the second admin, and saves them as pending changes
+type ChangeGate struct {
+	repo            changeGateRepo
+	auditService    auditService
+	operations      map[string]bool
+	privilegedRoles []string
+	lifeTime        time.Duration
+}
+
+func NewChangeGate(cfg conf.FourEyes, repo changeGateRepo, auditService auditService) ChangeGate {
+	operations := make(map[string]bool, len(cfg.Operations))
+	for _, operation := range cfg.Operations {
+		if operation == privilegedRoleGrantOperation {
+			operations[entity.ChangeUserGrantRole] = true
+			operations[entity.ChangeUserUpdate] = true
+			operations[entity.ChangeUserCreate] = true
+			continue
+		}
+		if operation == entity.ChangeRoleUpdate {
+			operations[entity.ChangeRoleRollback] = true
+		}
+		operations[operation] = true
+	}
+	lifeTime := time.Duration(cfg.ExpireSec) * time.Second
+	if lifeTime <= 0 {
+		lifeTime = defaultPendingChangeLifeTime
+	}
+	return ChangeGate{
+		repo:            repo,
+		auditService:    auditService,
+		operations:      operations,
+		privilegedRoles: cfg.PrivilegedRoles,
+		lifeTime:        lifeTime,
+	}
+}
+
+func (g ChangeGate) Required(operation string) bool {
+	return g.operations[operation]
+}
+
+// PrivilegedRoleNames returns names of privileged roles among roles
+func (g ChangeGate) PrivilegedRoleNames(roles []entity.Role) []string {
+	result := make([]string, 0)
+	for _, role := range roles {
+		if slices.Contains(g.privilegedRoles, role.Name) {
+			result = append(result, role.Name)
+		}
+	}
+	return result
+}
+
+// Submit saves the change for approval and returns domain.PendingChangeError
+func (g ChangeGate) Submit(ctx context.Context, operation string, payload any, diff string, adminId int64) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.WithMessage(err, "marshal payload")
+	}
+
+	change, err := g.repo.InsertPendingChange(ctx, entity.PendingChange{
+		Operation: operation,
+		Payload:   data,
+		Diff:      diff,
+		CreatedBy: adminId,
+		ExpiredAt: time.Now().UTC().Add(g.lifeTime),
+	})
+	if err != nil {
+		return errors.WithMessage(err, "insert pending change")
+	}
+
+	g.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Изменение %d ожидает подтверждения. Операция: %s, действует до %s.\n %s",
+			change.Id, change.Operation, change.ExpiredAt.Format(time.DateTime), change.Diff),
+		entity.EventPendingChange,
+	)
+
+	return domain.PendingChangeError{Id: change.Id}
+}
@@ -0,0 +1,26 @@
+package expired_role_worker
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/bgjob"
+	"github.com/txix-open/isp-kit/bgjobx"
+)
+
+const (
+	QueueName = "delete_expired_user_roles"
+)
+
+func EnqueueSeedJob(ctx context.Context, client *bgjobx.Client) error {
+	err := client.Enqueue(ctx, bgjob.EnqueueRequest{
+		Id:    "delete_expired_user_roles",
+		Queue: QueueName,
+		Type:  "delete_expired_user_roles",
+	})
+	if err != nil && !errors.Is(err, bgjob.ErrJobAlreadyExist) {
+		return errors.WithMessage(err, "enqueue job")
+	}
+
+	return nil
+}
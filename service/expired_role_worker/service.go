@@ -0,0 +1,75 @@
+package expired_role_worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"msp-admin-service/entity"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/bgjob"
+	"github.com/txix-open/isp-kit/bgjobx/handler"
+	"github.com/txix-open/isp-kit/log"
+)
+
+const (
+	defaultRetryTimeout = 5 * time.Minute
+	rescheduleInterval  = 1 * time.Minute
+)
+
+type AuditService interface {
+	SaveAuditAsync(ctx context.Context, userId int64, message string, event string)
+}
+
+type UserRoleRepo interface {
+	DeleteExpiredUserRoleLinks(ctx context.Context, now time.Time) ([]entity.UserRole, error)
+}
+
+type Service struct {
+	logger       log.Logger
+	userRoleRepo UserRoleRepo
+	auditService AuditService
+}
+
+func NewExpiredRoleWorker(
+	logger log.Logger,
+	userRoleRepo UserRoleRepo,
+	auditService AuditService,
+) Service {
+	return Service{
+		logger:       logger,
+		userRoleRepo: userRoleRepo,
+		auditService: auditService,
+	}
+}
+
+func (w Service) Handle(ctx context.Context, _ bgjob.Job) handler.Result {
+	ctx = log.ToContext(ctx, log.String("worker", "expiredRoleWorker"))
+	w.logger.Debug(ctx, "begin work")
+
+	err := w.do(ctx)
+	if err != nil {
+		return handler.Retry(defaultRetryTimeout, errors.WithMessage(err, "expiredRoleWorker do"))
+	}
+
+	w.logger.Debug(ctx, "end work")
+	return handler.Reschedule(handler.ByAfterTime(rescheduleInterval, time.Now()))
+}
+
+func (w Service) do(ctx context.Context) error {
+	links, err := w.userRoleRepo.DeleteExpiredUserRoleLinks(ctx, time.Now().UTC())
+	if err != nil {
+		return errors.WithMessage(err, "delete expired user role links")
+	}
+
+	for _, link := range links {
+		w.logger.Info(ctx, "expired user role removed", log.Int("userId", link.UserId), log.Int("roleId", link.RoleId))
+		w.auditService.SaveAuditAsync(ctx, int64(link.UserId),
+			fmt.Sprintf("Пользователь. Истек срок действия роли %d пользователя %d, роль удалена", link.RoleId, link.UserId),
+			entity.EventUserChanged,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+type externalGroupRoleRepo interface {
+	GetRolesByExternalGroup(ctx context.Context, groups []string) ([]entity.Role, error)
+	GetExternalGroupRules(ctx context.Context) ([]entity.ExternalGroupRule, error)
+}
+
+type externalGroupRuleRepo interface {
+	GetExternalGroupRules(ctx context.Context) ([]entity.ExternalGroupRule, error)
+	InsertExternalGroupRule(ctx context.Context, rule entity.ExternalGroupRule) (*entity.ExternalGroupRule, error)
+	UpdateExternalGroupRule(ctx context.Context, rule entity.ExternalGroupRule) (*entity.ExternalGroupRule, error)
+	DeleteExternalGroupRule(ctx context.Context, id int) error
+}
+
+type externalGroupRuleRoleRepo interface {
+	GetRoleByIds(ctx context.Context, id []int) ([]entity.Role, error)
+	GetRolesByExternalGroup(ctx context.Context, groups []string) ([]entity.Role, error)
+}
+
+type ExternalGroupRule struct {
+	ruleRepo     externalGroupRuleRepo
+	roleRepo     externalGroupRuleRoleRepo
+	auditService auditService
+}
+
+func NewExternalGroupRule(
+	ruleRepo externalGroupRuleRepo,
+	roleRepo externalGroupRuleRoleRepo,
+	auditService auditService,
+) ExternalGroupRule {
+	return ExternalGroupRule{
+		ruleRepo:     ruleRepo,
+		roleRepo:     roleRepo,
+		auditService: auditService,
+	}
+}
+
+func (s ExternalGroupRule) All(ctx context.Context) ([]domain.ExternalGroupRule, error) {
+	rules, err := s.ruleRepo.GetExternalGroupRules(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get external group rules")
+	}
+
+	result := make([]domain.ExternalGroupRule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, s.toDomain(rule))
+	}
+	return result, nil
+}
+
+func (s ExternalGroupRule) Create(
+	ctx context.Context,
+	req domain.CreateExternalGroupRuleRequest,
+	adminId int64,
+) (*domain.ExternalGroupRule, error) {
+	rule := entity.ExternalGroupRule{
+		Pattern:   req.Pattern,
+		MatchType: req.MatchType,
+		RoleId:    req.RoleId,
+		Priority:  req.Priority,
+		Terminal:  req.Terminal,
+	}
+	err := s.validate(ctx, rule)
+	if err != nil {
+		return nil, errors.WithMessage(err, "validate rule")
+	}
+
+	created, err := s.ruleRepo.InsertExternalGroupRule(ctx, rule)
+	if err != nil {
+		return nil, errors.WithMessage(err, "insert external group rule")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Правило сопоставления групп. Создание правила %d. \n %s", created.Id, ruleDiff(entity.ExternalGroupRule{}, *created)),
+		entity.EventRoleChanged,
+	)
+
+	return new(s.toDomain(*created)), nil
+}
+
+func (s ExternalGroupRule) Update(
+	ctx context.Context,
+	req domain.UpdateExternalGroupRuleRequest,
+	adminId int64,
+) (*domain.ExternalGroupRule, error) {
+	rule := entity.ExternalGroupRule{
+		Id:        req.Id,
+		Pattern:   req.Pattern,
+		MatchType: req.MatchType,
+		RoleId:    req.RoleId,
+		Priority:  req.Priority,
+		Terminal:  req.Terminal,
+	}
+	err := s.validate(ctx, rule)
+	if err != nil {
+		return nil, errors.WithMessage(err, "validate rule")
+	}
+
+	rules, err := s.ruleRepo.GetExternalGroupRules(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get external group rules")
+	}
+	idx := slices.IndexFunc(rules, func(r entity.ExternalGroupRule) bool {
+		return r.Id == req.Id
+	})
+	if idx < 0 {
+		return nil, domain.ErrNotFound
+	}
+	oldRule := rules[idx]
+
+	updated, err := s.ruleRepo.UpdateExternalGroupRule(ctx, rule)
+	if err != nil {
+		return nil, errors.WithMessage(err, "update external group rule")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Правило сопоставления групп. Изменение правила %d. \n %s", updated.Id, ruleDiff(oldRule, *updated)),
+		entity.EventRoleChanged,
+	)
+
+	return new(s.toDomain(*updated)), nil
+}
+
+func (s ExternalGroupRule) Delete(ctx context.Context, req domain.DeleteExternalGroupRuleRequest, adminId int64) error {
+	err := s.ruleRepo.DeleteExternalGroupRule(ctx, req.Id)
+	if err != nil {
+		return errors.WithMessage(err, "delete external group rule")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Правило сопоставления групп. Удаление правила. ID: %d", req.Id),
+		entity.EventRoleChanged,
+	)
+	return nil
+}
+
+func (s ExternalGroupRule) DryRun(ctx context.Context, req domain.ExternalGroupDryRunRequest) (*domain.ExternalGroupDryRunResponse, error) {
+	roleIds, matched, err := resolveExternalGroupRoles(ctx, dryRunRoleRepo{s.roleRepo, s.ruleRepo}, req.Groups)
+	if err != nil {
+		return nil, errors.WithMessage(err, "resolve external group roles")
+	}
+
+	matchedRules := make([]domain.ExternalGroupRule, 0, len(matched))
+	for _, rule := range matched {
+		matchedRules = append(matchedRules, s.toDomain(rule))
+	}
+
+	return &domain.ExternalGroupDryRunResponse{
+		RoleIds:      roleIds,
+		MatchedRules: matchedRules,
+	}, nil
+}
+
+func (s ExternalGroupRule) validate(ctx context.Context, rule entity.ExternalGroupRule) error {
+	switch {
+	case rule.MatchType == entity.GroupMatchDefault:
+		break
+	case rule.Pattern == "":
+		return errors.WithMessage(domain.ErrInvalid, "pattern is required")
+	case rule.MatchType == entity.GroupMatchRegex:
+		_, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return errors.WithMessagef(domain.ErrInvalid, "invalid regex: %v", err)
+		}
+	}
+
+	roles, err := s.roleRepo.GetRoleByIds(ctx, []int{rule.RoleId})
+	switch {
+	case err != nil:
+		return errors.WithMessage(err, "get role by id")
+	case len(roles) == 0:
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (s ExternalGroupRule) toDomain(rule entity.ExternalGroupRule) domain.ExternalGroupRule {
+	return domain.ExternalGroupRule{
+		Id:        rule.Id,
+		Pattern:   rule.Pattern,
+		MatchType: rule.MatchType,
+		RoleId:    rule.RoleId,
+		Priority:  rule.Priority,
+		Terminal:  rule.Terminal,
+		CreatedAt: rule.CreatedAt,
+		UpdatedAt: rule.UpdatedAt,
+	}
+}
+
+type dryRunRoleRepo struct {
+	externalGroupRuleRoleRepo
+	externalGroupRuleRepo
+}
+
+// resolveExternalGroupRoles returns role ids for the external groups.
+// Exact matches with roles.external_group are always applied, rules are evaluated by priority
+// until the first matched terminal rule.
+func resolveExternalGroupRoles(
+	ctx context.Context,
+	repo externalGroupRoleRepo,
+	groups []string,
+) ([]int, []entity.ExternalGroupRule, error) {
+	roleIds := make([]int, 0)
+	if len(groups) > 0 {
+		roles, err := repo.GetRolesByExternalGroup(ctx, groups)
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "get roles by external groups")
+		}
+		for _, role := range roles {
+			roleIds = append(roleIds, role.Id)
+		}
+	}
+
+	rules, err := repo.GetExternalGroupRules(ctx)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "get external group rules")
+	}
+	matched := matchExternalGroupRules(rules, groups)
+	for _, rule := range matched {
+		if !slices.Contains(roleIds, rule.RoleId) {
+			roleIds = append(roleIds, rule.RoleId)
+		}
+	}
+	slices.Sort(roleIds)
+
+	return roleIds, matched, nil
+}
+
+func matchExternalGroupRules(rules []entity.ExternalGroupRule, groups []string) []entity.ExternalGroupRule {
+	matched := make([]entity.ExternalGroupRule, 0)
+	for _, rule := range rules {
+		if !matchExternalGroupRule(rule, groups) {
+			continue
+		}
+		matched = append(matched, rule)
+		if rule.Terminal {
+			break
+		}
+	}
+	return matched
+}
+
+func matchExternalGroupRule(rule entity.ExternalGroupRule, groups []string) bool {
+	if rule.MatchType == entity.GroupMatchDefault {
+		return true
+	}
+
+	var re *regexp.Regexp
+	if rule.MatchType == entity.GroupMatchRegex {
+		var err error
+		re, err = regexp.Compile(rule.Pattern)
+		if err != nil {
+			return false
+		}
+	}
+
+	for _, group := range groups {
+		switch rule.MatchType {
+		case entity.GroupMatchExact:
+			if group == rule.Pattern {
+				return true
+			}
+		case entity.GroupMatchPrefix:
+			if strings.HasPrefix(group, rule.Pattern) {
+				return true
+			}
+		case entity.GroupMatchRegex:
+			if re.MatchString(group) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ruleDiff(oldRule entity.ExternalGroupRule, newRule entity.ExternalGroupRule) string {
+	return diffToString(map[string]any{
+		"Шаблон":         oldRule.Pattern,
+		"Тип совпадения": oldRule.MatchType,
+		"Роль (ID)":      oldRule.RoleId,
+		"Приоритет":      oldRule.Priority,
+		"Завершающее":    oldRule.Terminal,
+	}, map[string]any{
+		"Шаблон":         newRule.Pattern,
+		"Тип совпадения": newRule.MatchType,
+		"Роль (ID)":      newRule.RoleId,
+		"Приоритет":      newRule.Priority,
+		"Завершающее":    newRule.Terminal,
+	})
+}
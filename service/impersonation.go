@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+	"msp-admin-service/service/permission_match"
+)
+
+const defaultImpersonationLifeTime = 15 * time.Minute
+
+type impersonationUserRepo interface {
+	GetUserById(ctx context.Context, identity int64) (*entity.User, error)
+}
+
+type impersonationUserRoleRepo interface {
+	GetRoleEntitiesByUserId(ctx context.Context, userId int) ([]entity.Role, error)
+}
+
+type impersonationTokenRepo interface {
+	TokenSaver
+	Get(ctx context.Context, token string) (*entity.Token, error)
+	UpdateStatus(ctx context.Context, id int, status string) error
+}
+
+type impersonationTokenService interface {
+	GenerateImpersonationToken(
+		ctx context.Context,
+		repo TokenSaver,
+		userId int64,
+		impersonatorId int64,
+		lifeTime time.Duration,
+	) (string, string, error)
+}
+
+type Impersonation struct {
+	userRepo     impersonationUserRepo
+	userRoleRepo impersonationUserRoleRepo
+	roleResolver roleResolver
+	catalog      permissionCatalog
+	tokenRepo    impersonationTokenRepo
+	tokenService impersonationTokenService
+	auditService auditService
+	lifeTime     time.Duration
+}
+
+func NewImpersonation(
+	userRepo impersonationUserRepo,
+	userRoleRepo impersonationUserRoleRepo,
+	roleResolver roleResolver,
+	catalog permissionCatalog,
+	tokenRepo impersonationTokenRepo,
+	tokenService impersonationTokenService,
+	auditService auditService,
+	lifeTimeInSec int,
+) Impersonation {
+	lifeTime := time.Duration(lifeTimeInSec) * time.Second
+	if lifeTime <= 0 {
+		lifeTime = defaultImpersonationLifeTime
+	}
+	return Impersonation{
+		userRepo:     userRepo,
+		userRoleRepo: userRoleRepo,
+		roleResolver: roleResolver,
+		catalog:      catalog,
+		tokenRepo:    tokenRepo,
+		tokenService: tokenService,
+		auditService: auditService,
+		lifeTime:     lifeTime,
+	}
+}
+
+// Impersonate issues time-limited token of target user,
+// target user must not be blocked and must not have immutable roles or permissions the admin does not have
+func (s Impersonation) Impersonate(
+	ctx context.Context,
+	adminId int64,
+	req domain.ImpersonateRequest,
+) (*domain.ImpersonateResponse, error) {
+	if _, ok := domain.ImpersonatorFromContext(ctx); ok {
+		return nil, errors.WithMessage(domain.ErrImpersonationDenied, "nested impersonation")
+	}
+	if req.UserId == adminId {
+		return nil, errors.WithMessage(domain.ErrImpersonationDenied, "self impersonation")
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, req.UserId)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get user by id %d", req.UserId)
+	}
+	if user.Blocked {
+		return nil, errors.WithMessage(domain.ErrImpersonationDenied, "user is blocked")
+	}
+
+	err = s.checkPrivileges(ctx, adminId, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	token, expired, err := s.tokenService.GenerateImpersonationToken(ctx, s.tokenRepo, req.UserId, adminId, s.lifeTime)
+	if err != nil {
+		return nil, errors.WithMessage(err, "generate impersonation token")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Начало входа от имени пользователя ID %d. Сессия действительна до %s", req.UserId, expired),
+		entity.EventUserImpersonated,
+	)
+
+	return &domain.ImpersonateResponse{
+		Token:      token,
+		Expired:    expired,
+		HeaderName: domain.AdminAuthHeaderName,
+		UserId:     req.UserId,
+	}, nil
+}
+
+// End revokes impersonation token, regular tokens are rejected
+func (s Impersonation) End(ctx context.Context, token string) error {
+	tokenInfo, err := s.tokenRepo.Get(ctx, token)
+	if err != nil {
+		return errors.WithMessage(err, "get token")
+	}
+	if tokenInfo.ImpersonatorId == nil {
+		return domain.ErrNotImpersonated
+	}
+
+	err = s.tokenRepo.UpdateStatus(ctx, tokenInfo.Id, entity.TokenStatusRevoked)
+	if err != nil {
+		return errors.WithMessage(err, "revoke token")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, *tokenInfo.ImpersonatorId,
+		fmt.Sprintf("Завершение входа от имени пользователя ID %d", tokenInfo.UserId),
+		entity.EventUserImpersonated,
+	)
+
+	return nil
+}
+
+// ImpersonatorId returns impersonator of the user, if the token of the user is issued by Impersonate
+func (s Impersonation) ImpersonatorId(ctx context.Context, token string, userId int64) (int64, bool, error) {
+	tokenInfo, err := s.tokenRepo.Get(ctx, token)
+	switch {
+	case errors.Is(err, domain.ErrTokenNotFound):
+		return 0, false, nil
+	case err != nil:
+		return 0, false, errors.WithMessage(err, "get token")
+	case tokenInfo.ImpersonatorId == nil || tokenInfo.UserId != userId:
+		return 0, false, nil
+	default:
+		return *tokenInfo.ImpersonatorId, true, nil
+	}
+}
+
+func (s Impersonation) checkPrivileges(ctx context.Context, adminId int64, userId int64) error {
+	userRoles, err := s.userRoleRepo.GetRoleEntitiesByUserId(ctx, int(userId))
+	if err != nil {
+		return errors.WithMessage(err, "get user roles")
+	}
+	for _, role := range userRoles {
+		if role.Immutable {
+			return errors.WithMessagef(domain.ErrImpersonationDenied, "user has immutable role '%s'", role.Name)
+		}
+	}
+
+	adminRoles, err := s.userRoleRepo.GetRoleEntitiesByUserId(ctx, int(adminId))
+	if err != nil {
+		return errors.WithMessage(err, "get admin roles")
+	}
+	adminRoles, err = s.roleResolver.Resolve(ctx, adminRoles)
+	if err != nil {
+		return errors.WithMessage(err, "resolve admin inherited permissions")
+	}
+	userRoles, err = s.roleResolver.Resolve(ctx, userRoles)
+	if err != nil {
+		return errors.WithMessage(err, "resolve user inherited permissions")
+	}
+	userPermissions, err := effectivePermissions(ctx, s.catalog, userRoles)
+	if err != nil {
+		return errors.WithMessage(err, "get user effective permissions")
+	}
+	adminPermissions := mergePermissions(adminRoles)
+	for _, permission := range userPermissions {
+		if permission_match.IsDeny(permission) {
+			continue
+		}
+		if !permission_match.Covers(adminPermissions, permission) {
+			return errors.WithMessagef(domain.ErrImpersonationDenied, "user has permission '%s' the admin does not have", permission)
+		}
+		if !scopesCovered(adminRoles, userRoles, permission) {
+			return errors.WithMessagef(domain.ErrImpersonationDenied, "user has permission '%s' for resources the admin does not have", permission)
+		}
+	}
+
+	return nil
+}
+
+// scopesCovered reports whether resources, for which roles of the admin grant the permission,
+// include all resources, for which roles of the user grant it
+func scopesCovered(adminRoles []entity.Role, userRoles []entity.Role, permission string) bool {
+	adminScopes, adminUnlimited := permissionScopes(adminRoles, permission)
+	if adminUnlimited {
+		return true
+	}
+	userScopes, userUnlimited := permissionScopes(userRoles, permission)
+	if userUnlimited {
+		return false
+	}
+	for _, scope := range userScopes {
+		for _, resourceId := range scope.ResourceIds {
+			if !permission_match.InScopes(adminScopes, scope.ResourceType, resourceId) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// permissionScopes returns scopes of roles, which grant the permission,
+// unlimited is true, if any of them grants the permission for all resources
+func permissionScopes(roles []entity.Role, permission string) ([]entity.ResourceScope, bool) {
+	scopes := make([]entity.ResourceScope, 0)
+	for _, role := range roles {
+		if !permission_match.Allowed(role.Permissions, permission) {
+			continue
+		}
+		roleScopes := permission_match.Scopes(role, permission)
+		if len(roleScopes) == 0 {
+			return nil, true
+		}
+		scopes = append(scopes, roleScopes...)
+	}
+	return scopes, false
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/pkg/errors"
+)
+
+// ldapUserIdPrefix separates ldap accounts from sudir accounts, both are stored in users.sudir_user_id
+const ldapUserIdPrefix = "ldap:"
+
+type ldapRepo interface {
+	Authenticate(ctx context.Context, login string, password string) (*entity.LdapUser, error)
+}
+
+type Ldap struct {
+	cfg      *conf.Ldap
+	ldapRepo ldapRepo
+}
+
+func NewLdap(cfg *conf.Ldap, ldapRepo ldapRepo) Ldap {
+	return Ldap{
+		cfg:      cfg,
+		ldapRepo: ldapRepo,
+	}
+}
+
+// ExternalUserId returns the id, the user with login is stored with,
+// the login is used instead of the entry dn, so the id is known before the bind with any bind template
+func (s Ldap) ExternalUserId(login string) string {
+	return ldapUserIdPrefix + strings.ToLower(login)
+}
+
+func (s Ldap) Authenticate(ctx context.Context, login string, password string, roleRepo roleRepo) (*entity.SudirUser, error) {
+	if s.cfg == nil {
+		return nil, domain.ErrLdapAuthIsMissed
+	}
+	if password == "" {
+		return nil, errors.WithMessage(domain.ErrUnauthenticated, "empty password")
+	}
+
+	user, err := s.ldapRepo.Authenticate(ctx, login, password)
+	if err != nil {
+		return nil, errors.WithMessage(err, "ldap authenticate")
+	}
+
+	rolesIds, _, err := resolveExternalGroupRoles(ctx, roleRepo, user.Groups)
+	if err != nil {
+		return nil, errors.WithMessage(err, "resolve external groups roles")
+	}
+
+	fullName := user.FullName
+	if fullName == "" {
+		fullName = createFullName(user.FirstName, user.LastName)
+	}
+
+	return &entity.SudirUser{
+		RoleIds:     rolesIds,
+		SudirUserId: s.ExternalUserId(login),
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		FullName:    fullName,
+		Email:       user.Email,
+	}, nil
+}
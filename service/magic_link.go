@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+const (
+	defaultMagicLinkLifeTime           = 10 * time.Minute
+	defaultMagicLinkMaxRequestsPerHour = 5
+	magicLinkSubject                   = "Вход в панель администрирования"
+)
+
+type magicLinkRepo interface {
+	InsertMagicLink(ctx context.Context, link entity.MagicLink) error
+	UseMagicLink(ctx context.Context, tokenHash string, usedAt time.Time) (*entity.MagicLink, error)
+	InsertMagicLinkRequest(ctx context.Context, email string, createdAt time.Time) error
+	CountMagicLinkRequestsSince(ctx context.Context, email string, since time.Time) (int, error)
+	DeleteMagicLinkRequestsBefore(ctx context.Context, before time.Time) error
+}
+
+type mailSender interface {
+	Send(ctx context.Context, to string, subject string, body string) error
+}
+
+type MagicLink struct {
+	cfg        *conf.MagicLink
+	mailSender mailSender
+}
+
+func NewMagicLink(cfg *conf.MagicLink, mailSender mailSender) MagicLink {
+	return MagicLink{
+		cfg:        cfg,
+		mailSender: mailSender,
+	}
+}
+
+func (s MagicLink) Enabled() bool {
+	return s.cfg != nil
+}
+
+// Limit registers the request of link for the email and returns domain.ErrTooManyLoginRequests, if the limit is exceeded,
+// requests are counted by the email regardless of whether the user exists
+func (s MagicLink) Limit(ctx context.Context, repo magicLinkRepo, email string) error {
+	if s.cfg == nil {
+		return domain.ErrMagicLinkIsMissed
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	now := time.Now().UTC()
+	since := now.Add(-time.Hour)
+	err := repo.DeleteMagicLinkRequestsBefore(ctx, since)
+	if err != nil {
+		return errors.WithMessage(err, "delete outdated magic link requests")
+	}
+	count, err := repo.CountMagicLinkRequestsSince(ctx, email, since)
+	if err != nil {
+		return errors.WithMessage(err, "count magic link requests")
+	}
+	maxRequests := s.cfg.MaxRequestsPerHour
+	if maxRequests <= 0 {
+		maxRequests = defaultMagicLinkMaxRequestsPerHour
+	}
+	if count >= maxRequests {
+		return errors.WithMessagef(domain.ErrTooManyLoginRequests, "%d magic links requested for the email", count)
+	}
+
+	err = repo.InsertMagicLinkRequest(ctx, email, now)
+	if err != nil {
+		return errors.WithMessage(err, "insert magic link request")
+	}
+
+	return nil
+}
+
+// Issue saves hash of single-use token and emails link with the token to the user
+func (s MagicLink) Issue(ctx context.Context, repo magicLinkRepo, user entity.User) error {
+	if s.cfg == nil {
+		return domain.ErrMagicLinkIsMissed
+	}
+
+	random := make([]byte, 32) //nolint:mnd
+	_, err := rand.Read(random)
+	if err != nil {
+		return errors.WithMessage(err, "crypto/rand read")
+	}
+	token := hex.EncodeToString(random)
+
+	now := time.Now().UTC()
+	expiredAt := now.Add(s.lifeTime())
+	err = repo.InsertMagicLink(ctx, entity.MagicLink{
+		UserId:    user.Id,
+		TokenHash: magicLinkHash(token),
+		ExpiredAt: expiredAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "insert magic link")
+	}
+
+	body := fmt.Sprintf("Ссылка для входа в панель администрирования:\n%s\n\n"+
+		"Ссылка действительна до %s (UTC) и может быть использована один раз.\n"+
+		"Если вы не запрашивали вход, проигнорируйте это письмо.",
+		fmt.Sprintf(s.cfg.UrlTemplate, token), expiredAt.Format(time.DateTime),
+	)
+	err = s.mailSender.Send(ctx, user.Email, magicLinkSubject, body)
+	if err != nil {
+		return errors.WithMessage(err, "send magic link")
+	}
+
+	return nil
+}
+
+// Consume marks link as used and returns id of link owner
+func (s MagicLink) Consume(ctx context.Context, repo magicLinkRepo, token string) (int64, error) {
+	if s.cfg == nil {
+		return 0, domain.ErrMagicLinkIsMissed
+	}
+
+	link, err := repo.UseMagicLink(ctx, magicLinkHash(token), time.Now().UTC())
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return 0, errors.WithMessage(domain.ErrUnauthenticated, "magic link is invalid, expired or already used")
+	case err != nil:
+		return 0, errors.WithMessage(err, "use magic link")
+	}
+
+	return link.UserId, nil
+}
+
+func (s MagicLink) lifeTime() time.Duration {
+	if s.cfg.ExpireSec <= 0 {
+		return defaultMagicLinkLifeTime
+	}
+	return time.Duration(s.cfg.ExpireSec) * time.Second
+}
+
+func magicLinkHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,129 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"msp-admin-service/conf"
+)
+
+const (
+	defaultArgon2idIterations  = 2
+	defaultArgon2idMemoryKib   = 19 * 1024
+	defaultArgon2idParallelism = 1
+	argon2idSaltLen            = 16
+	argon2idKeyLen             = 32
+	argon2idPrefix             = "$argon2id$"
+)
+
+type argon2idParams struct {
+	iterations  uint32
+	memoryKib   uint32
+	parallelism uint8
+}
+
+// argon2idAlgorithm produces hashes in PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type argon2idAlgorithm struct {
+	params argon2idParams
+}
+
+func newArgon2id(cfg conf.Argon2id) argon2idAlgorithm {
+	params := argon2idParams{
+		iterations:  defaultArgon2idIterations,
+		memoryKib:   defaultArgon2idMemoryKib,
+		parallelism: defaultArgon2idParallelism,
+	}
+	if cfg.Iterations > 0 {
+		params.iterations = uint32(cfg.Iterations) // nolint:gosec
+	}
+	if cfg.MemoryKib > 0 {
+		params.memoryKib = uint32(cfg.MemoryKib) // nolint:gosec
+	}
+	if cfg.Parallelism > 0 {
+		params.parallelism = uint8(min(cfg.Parallelism, 255)) // nolint:gosec,mnd
+	}
+	return argon2idAlgorithm{params: params}
+}
+
+func (a argon2idAlgorithm) Hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLen)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", errors.WithMessage(err, "crypto/rand read")
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.params.iterations, a.params.memoryKib, a.params.parallelism, argon2idKeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version,
+		a.params.memoryKib, a.params.iterations, a.params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a argon2idAlgorithm) Verify(hash string, password string) (bool, error) {
+	params, salt, key, err := a.parse(hash)
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, params.iterations, params.memoryKib, params.parallelism, uint32(len(key))) // nolint:gosec
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (a argon2idAlgorithm) Supports(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+func (a argon2idAlgorithm) Outdated(hash string) bool {
+	params, salt, key, err := a.parse(hash)
+	if err != nil {
+		return true
+	}
+	return params != a.params || len(salt) != argon2idSaltLen || len(key) != argon2idKeyLen
+}
+
+func (a argon2idAlgorithm) parse(hash string) (argon2idParams, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(hash, argon2idPrefix), "$")
+	if len(parts) != 4 { // nolint:mnd
+		return argon2idParams{}, nil, nil, errors.New("invalid argon2id hash format")
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[0], "v=%d", &version)
+	if err != nil {
+		return argon2idParams{}, nil, nil, errors.WithMessage(err, "parse argon2id version")
+	}
+	if version != argon2.Version {
+		return argon2idParams{}, nil, nil, errors.Errorf("unsupported argon2id version %d", version)
+	}
+
+	params := argon2idParams{}
+	_, err = fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &params.memoryKib, &params.iterations, &params.parallelism)
+	if err != nil {
+		return argon2idParams{}, nil, nil, errors.WithMessage(err, "parse argon2id params")
+	}
+	if params.iterations == 0 || params.parallelism == 0 {
+		return argon2idParams{}, nil, nil, errors.New("invalid argon2id params")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return argon2idParams{}, nil, nil, errors.WithMessage(err, "decode argon2id salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return argon2idParams{}, nil, nil, errors.WithMessage(err, "decode argon2id key")
+	}
+	if len(key) == 0 {
+		return argon2idParams{}, nil, nil, errors.New("empty argon2id key")
+	}
+
+	return params, salt, key, nil
+}
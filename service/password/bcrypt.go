@@ -0,0 +1,52 @@
+package password
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultBcryptCost = 12
+)
+
+type bcryptAlgorithm struct {
+	cost int
+}
+
+func newBcrypt(cost int) bcryptAlgorithm {
+	if cost <= 0 {
+		cost = defaultBcryptCost
+	}
+	return bcryptAlgorithm{cost: cost}
+}
+
+func (a bcryptAlgorithm) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), a.cost)
+	if err != nil {
+		return "", errors.WithMessage(err, "gen bcrypt from password")
+	}
+	return string(hash), nil
+}
+
+func (a bcryptAlgorithm) Verify(hash string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	switch {
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	case err != nil:
+		return false, errors.WithMessage(err, "compare bcrypt hash")
+	default:
+		return true, nil
+	}
+}
+
+func (a bcryptAlgorithm) Supports(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (a bcryptAlgorithm) Outdated(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != a.cost
+}
@@ -0,0 +1,76 @@
+package password
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// algorithm is a hash function with self-describing output format
+type algorithm interface {
+	Hash(password string) (string, error)
+	Verify(hash string, password string) (bool, error)
+	// Supports reports whether hash was produced by the algorithm
+	Supports(hash string) bool
+	// Outdated reports whether hash was produced with other parameters
+	Outdated(hash string) bool
+}
+
+// Hasher hashes new passwords with configured algorithm and verifies hashes of any supported algorithm
+type Hasher struct {
+	current    algorithm
+	algorithms []algorithm
+}
+
+func NewHasher(cfg conf.PasswordHashing) Hasher {
+	argon := newArgon2id(cfg.Argon2id)
+	bcrypt := newBcrypt(cfg.BcryptCost)
+
+	var current algorithm = argon
+	if strings.EqualFold(cfg.Algorithm, AlgorithmBcrypt) {
+		current = bcrypt
+	}
+
+	return Hasher{
+		current:    current,
+		algorithms: []algorithm{argon, bcrypt},
+	}
+}
+
+func (h Hasher) Hash(password string) (string, error) {
+	hash, err := h.current.Hash(password)
+	if err != nil {
+		return "", errors.WithMessage(err, "hash password")
+	}
+	return hash, nil
+}
+
+// Verify returns domain.ErrInvalidPassword if password does not match hash,
+// rehash is true when hash should be replaced with Hash(password)
+func (h Hasher) Verify(hash string, password string) (bool, error) {
+	for _, algorithm := range h.algorithms {
+		if !algorithm.Supports(hash) {
+			continue
+		}
+
+		ok, err := algorithm.Verify(hash, password)
+		if err != nil {
+			return false, errors.WithMessagef(domain.ErrInvalidPassword, "malformed hash: %v", err)
+		}
+		if !ok {
+			return false, domain.ErrInvalidPassword
+		}
+
+		rehash := algorithm != h.current || algorithm.Outdated(hash)
+		return rehash, nil
+	}
+
+	return false, errors.WithMessage(domain.ErrInvalidPassword, "unsupported hash format")
+}
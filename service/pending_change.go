@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/json"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+type pendingChangeRepo interface {
+	AllByRequest(ctx context.Context, req domain.PendingChangePageRequest) ([]entity.PendingChange, error)
+	Count(ctx context.Context, reqQuery *domain.PendingChangeQuery) (int64, error)
+	ExpirePendingChanges(ctx context.Context, now time.Time) ([]entity.PendingChange, error)
+}
+
+type PendingChangeTransaction interface {
+	GetPendingChangeByIdForUpdate(ctx context.Context, id int) (*entity.PendingChange, error)
+	ResolvePendingChange(ctx context.Context, id int, status string, reviewerId int64, now time.Time) (*entity.PendingChange, error)
+}
+
+// PendingChangeTransactionRunner runs the transaction, which transactions of role and user services join
+type PendingChangeTransactionRunner interface {
+	PendingChangeTransaction(ctx context.Context, tx func(ctx context.Context, tx PendingChangeTransaction) error) error
+}
+
+type pendingChangeRoleService interface {
+	update(ctx context.Context, req domain.UpdateRoleRequest, adminId int64, operation string) (*domain.Role, error)
+	delete(ctx context.Context, req domain.DeleteRoleRequest, adminId int64) error
+	importRbac(ctx context.Context, req domain.RbacImportRequest, adminId int64) (*domain.RbacImportResponse, error)
+}
+
+type pendingChangeUserService interface {
+	createUser(ctx context.Context, req domain.CreateUserRequest, passwordHash string, adminId int64) (*domain.User, error)
+	updateUser(ctx context.Context, req domain.UpdateUserRequest, adminId int64) (*domain.User, error)
+	deleteUsers(ctx context.Context, ids []int64, adminId int64) (int, error)
+	grantRole(ctx context.Context, req domain.GrantRoleRequest, adminId int64) (*domain.User, error)
+}
+
+type PendingChange struct {
+	repo         pendingChangeRepo
+	txRunner     PendingChangeTransactionRunner
+	roleService  pendingChangeRoleService
+	userService  pendingChangeUserService
+	auditService auditService
+}
+
+func NewPendingChange(
+	repo pendingChangeRepo,
+	txRunner PendingChangeTransactionRunner,
+	roleService pendingChangeRoleService,
+	userService pendingChangeUserService,
+	auditService auditService,
+) PendingChange {
+	return PendingChange{
+		repo:         repo,
+		txRunner:     txRunner,
+		roleService:  roleService,
+		userService:  userService,
+		auditService: auditService,
+	}
+}
+
+func (s PendingChange) All(ctx context.Context, req domain.PendingChangePageRequest) (*domain.PendingChangeResponse, error) {
+	err := s.expire(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "expire pending changes")
+	}
+
+	changes, err := s.repo.AllByRequest(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get pending changes")
+	}
+
+	count, err := s.repo.Count(ctx, req.Query)
+	if err != nil {
+		return nil, errors.WithMessage(err, "count pending changes")
+	}
+
+	items := make([]domain.PendingChange, 0, len(changes))
+	for _, change := range changes {
+		items = append(items, s.toDomain(change))
+	}
+	return &domain.PendingChangeResponse{
+		TotalCount: int(count),
+		Items:      items,
+	}, nil
+}
+
+// Approve applies the change on behalf of its author in the transaction, which locks the change,
+// the change stays pending if it can not be applied
+func (s PendingChange) Approve(ctx context.Context, req domain.ReviewPendingChangeRequest, adminId int64) (*domain.PendingChange, error) {
+	var change *entity.PendingChange
+	err := s.txRunner.PendingChangeTransaction(ctx, func(ctx context.Context, tx PendingChangeTransaction) error {
+		var err error
+		change, err = s.resolve(ctx, tx, req.Id, entity.PendingChangeApproved, adminId)
+		if err != nil {
+			return err
+		}
+		err = s.apply(ctx, *change)
+		if err != nil {
+			return errors.WithMessagef(err, "apply pending change %d", change.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Изменение %d подтверждено и применено. Операция: %s, автор: %d.\n %s",
+			change.Id, change.Operation, change.CreatedBy, change.Diff),
+		entity.EventPendingChange,
+	)
+
+	return new(s.toDomain(*change)), nil
+}
+
+func (s PendingChange) Reject(ctx context.Context, req domain.ReviewPendingChangeRequest, adminId int64) (*domain.PendingChange, error) {
+	var change *entity.PendingChange
+	err := s.txRunner.PendingChangeTransaction(ctx, func(ctx context.Context, tx PendingChangeTransaction) error {
+		var err error
+		change, err = s.resolve(ctx, tx, req.Id, entity.PendingChangeRejected, adminId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Изменение %d отклонено. Операция: %s, автор: %d", change.Id, change.Operation, change.CreatedBy),
+		entity.EventPendingChange,
+	)
+
+	return new(s.toDomain(*change)), nil
+}
+
+func (s PendingChange) resolve(
+	ctx context.Context,
+	tx PendingChangeTransaction,
+	id int,
+	status string,
+	adminId int64,
+) (*entity.PendingChange, error) {
+	change, err := tx.GetPendingChangeByIdForUpdate(ctx, id)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get pending change %d", id)
+	}
+	if change.CreatedBy == adminId {
+		return nil, errors.WithMessage(domain.ErrPendingChangeDenied, "change must be reviewed by another admin")
+	}
+
+	change, err = tx.ResolvePendingChange(ctx, id, status, adminId, time.Now().UTC())
+	if err != nil {
+		return nil, errors.WithMessage(err, "resolve pending change")
+	}
+	return change, nil
+}
+
+// roleVersionOperations maps pending role changes to operations of saved role versions
+var roleVersionOperations = map[string]string{
+	entity.ChangeRoleUpdate:   entity.RoleVersionUpdate,
+	entity.ChangeRoleRollback: entity.RoleVersionRollback,
+}
+
+func (s PendingChange) apply(ctx context.Context, change entity.PendingChange) error {
+	switch change.Operation {
+	case entity.ChangeRoleUpdate, entity.ChangeRoleRollback:
+		req := domain.UpdateRoleRequest{}
+		err := json.Unmarshal(change.Payload, &req)
+		if err != nil {
+			return errors.WithMessage(err, "unmarshal payload")
+		}
+		_, err = s.roleService.update(ctx, req, change.CreatedBy, roleVersionOperations[change.Operation])
+		return err
+	case entity.ChangeRoleDelete:
+		req := domain.DeleteRoleRequest{}
+		err := json.Unmarshal(change.Payload, &req)
+		if err != nil {
+			return errors.WithMessage(err, "unmarshal payload")
+		}
+		return s.roleService.delete(ctx, req, change.CreatedBy)
+	case entity.ChangeRoleImport:
+		req := domain.RbacImportRequest{}
+		err := json.Unmarshal(change.Payload, &req)
+		if err != nil {
+			return errors.WithMessage(err, "unmarshal payload")
+		}
+		_, err = s.roleService.importRbac(ctx, req, change.CreatedBy)
+		return err
+	case entity.ChangeUserDelete:
+		req := domain.IdentitiesRequest{}
+		err := json.Unmarshal(change.Payload, &req)
+		if err != nil {
+			return errors.WithMessage(err, "unmarshal payload")
+		}
+		_, err = s.userService.deleteUsers(ctx, req.Ids, change.CreatedBy)
+		return err
+	case entity.ChangeUserGrantRole:
+		req := domain.GrantRoleRequest{}
+		err := json.Unmarshal(change.Payload, &req)
+		if err != nil {
+			return errors.WithMessage(err, "unmarshal payload")
+		}
+		_, err = s.userService.grantRole(ctx, req, change.CreatedBy)
+		return err
+	case entity.ChangeUserCreate:
+		req := domain.CreateUserChange{}
+		err := json.Unmarshal(change.Payload, &req)
+		if err != nil {
+			return errors.WithMessage(err, "unmarshal payload")
+		}
+		_, err = s.userService.createUser(ctx, req.CreateUserRequest, req.PasswordHash, change.CreatedBy)
+		return err
+	case entity.ChangeUserUpdate:
+		req := domain.UpdateUserRequest{}
+		err := json.Unmarshal(change.Payload, &req)
+		if err != nil {
+			return errors.WithMessage(err, "unmarshal payload")
+		}
+		_, err = s.userService.updateUser(ctx, req, change.CreatedBy)
+		return err
+	default:
+		return errors.Errorf("unknown operation %s", change.Operation)
+	}
+}
+
+func (s PendingChange) expire(ctx context.Context) error {
+	changes, err := s.repo.ExpirePendingChanges(ctx, time.Now().UTC())
+	if err != nil {
+		return errors.WithMessage(err, "expire pending changes")
+	}
+
+	for _, change := range changes {
+		s.auditService.SaveAuditAsync(ctx, change.CreatedBy,
+			fmt.Sprintf("Изменение %d не подтверждено до %s и отменено. Операция: %s",
+				change.Id, change.ExpiredAt.Format(time.DateTime), change.Operation),
+			entity.EventPendingChange,
+		)
+	}
+	return nil
+}
+
+func (s PendingChange) toDomain(change entity.PendingChange) domain.PendingChange {
+	return domain.PendingChange{
+		Id:         change.Id,
+		Operation:  change.Operation,
+		Payload:    publicPayload(change),
+		Diff:       change.Diff,
+		Status:     change.Status,
+		CreatedBy:  change.CreatedBy,
+		ReviewerId: change.ReviewerId,
+		ExpiredAt:  change.ExpiredAt,
+		CreatedAt:  change.CreatedAt,
+		UpdatedAt:  change.UpdatedAt,
+	}
+}
+
+// publicPayload returns the payload of the change without the password hash of the created user
+func publicPayload(change entity.PendingChange) json.RawMessage {
+	if change.Operation != entity.ChangeUserCreate {
+		return change.Payload
+	}
+	req := domain.CreateUserChange{}
+	err := json.Unmarshal(change.Payload, &req)
+	if err != nil {
+		return nil
+	}
+	payload, err := json.Marshal(req.CreateUserRequest)
+	if err != nil {
+		return nil
+	}
+	return payload
+}
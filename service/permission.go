@@ -2,30 +2,133 @@ package service
 
 import (
 	"context"
+	"slices"
+	"strings"
 
+	"github.com/pkg/errors"
 	"msp-admin-service/conf"
 	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+	"msp-admin-service/service/permission_match"
 )
 
+// configPermissionModule owns permissions seeded from remote config
+const configPermissionModule = "msp-admin-service"
+
+type permissionRepo interface {
+	All(ctx context.Context) ([]entity.Permission, error)
+	Upsert(ctx context.Context, permissions []entity.Permission) error
+	Deprecate(ctx context.Context, module string, keys []string) error
+}
+
+// Permission is the catalog of permissions registered by modules,
+// permissions from remote config are used as a seed unless the key is registered
 type Permission struct {
-	permissions []domain.Permission
+	repo permissionRepo
+	seed []domain.Permission
 }
 
-func NewPermission(permissions []conf.Permission) *Permission {
+func NewPermission(repo permissionRepo, permissions []conf.Permission) *Permission {
 	return &Permission{
-		permissions: toDomain(permissions),
+		repo: repo,
+		seed: toDomain(permissions),
+	}
+}
+
+// All returns permissions of the catalog except deprecated
+func (s *Permission) All(ctx context.Context) ([]domain.Permission, error) {
+	registered, err := s.repo.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get registered permissions")
 	}
+
+	result := make([]domain.Permission, 0, len(registered)+len(s.seed))
+	for _, permission := range registered {
+		if permission.Deprecated {
+			continue
+		}
+		result = append(result, domain.Permission{
+			Key:         permission.Key,
+			Name:        permission.Name,
+			Description: permission.Description,
+			Category:    permission.Category,
+			Module:      permission.Module,
+		})
+	}
+	for _, permission := range s.seed {
+		exists := slices.ContainsFunc(registered, func(registered entity.Permission) bool {
+			return registered.Key == permission.Key
+		})
+		if !exists {
+			result = append(result, permission)
+		}
+	}
+	return result, nil
 }
-func (s *Permission) All(_ context.Context) []domain.Permission {
-	return s.permissions
+
+// Groups returns permissions of the catalog grouped by category
+func (s *Permission) Groups(ctx context.Context) ([]domain.PermissionGroup, error) {
+	permissions, err := s.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]domain.PermissionGroup, 0)
+	for _, permission := range permissions {
+		i := slices.IndexFunc(groups, func(group domain.PermissionGroup) bool {
+			return group.Category == permission.Category
+		})
+		if i < 0 {
+			groups = append(groups, domain.PermissionGroup{Category: permission.Category})
+			i = len(groups) - 1
+		}
+		groups[i].Permissions = append(groups[i].Permissions, permission)
+	}
+	slices.SortFunc(groups, func(a domain.PermissionGroup, b domain.PermissionGroup) int {
+		return strings.Compare(a.Category, b.Category)
+	})
+	return groups, nil
+}
+
+// Register saves permissions of the module and deprecates DeprecatedKeys,
+// keys registered by other modules are not changed
+func (s *Permission) Register(ctx context.Context, req domain.RegisterPermissionsRequest) error {
+	permissions := make([]entity.Permission, 0, len(req.Permissions))
+	for _, permission := range req.Permissions {
+		if strings.ContainsAny(permission.Key, permission_match.Wildcard+permission_match.DenyPrefix) {
+			return errors.WithMessagef(domain.ErrInvalidPermission, "permission key '%s' is a pattern", permission.Key)
+		}
+		permissions = append(permissions, entity.Permission{
+			Key:         permission.Key,
+			Name:        permission.Name,
+			Description: permission.Description,
+			Category:    permission.Category,
+			Module:      req.Module,
+		})
+	}
+
+	if len(permissions) > 0 {
+		err := s.repo.Upsert(ctx, permissions)
+		if err != nil {
+			return errors.WithMessage(err, "upsert permissions")
+		}
+	}
+	if len(req.DeprecatedKeys) > 0 {
+		err := s.repo.Deprecate(ctx, req.Module, req.DeprecatedKeys)
+		if err != nil {
+			return errors.WithMessage(err, "deprecate permissions")
+		}
+	}
+	return nil
 }
 
 func toDomain(permissions []conf.Permission) []domain.Permission {
 	permList := make([]domain.Permission, 0, len(permissions))
 	for _, perm := range permissions {
 		permList = append(permList, domain.Permission{
-			Key:  perm.Key,
-			Name: perm.Name,
+			Key:    perm.Key,
+			Name:   perm.Name,
+			Module: configPermissionModule,
 		})
 	}
 
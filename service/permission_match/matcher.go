@@ -0,0 +1,172 @@
+package permission_match
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+const (
+	// Wildcard at the end of the pattern matches any suffix, e.g. `module_*` or `module:configuration:*`
+	Wildcard = "*"
+	// DenyPrefix marks the entry as explicit deny, which overrides grants of all roles
+	DenyPrefix = "!"
+)
+
+var ErrInvalidPattern = errors.New("invalid permission pattern")
+
+// Match reports whether the grant pattern matches the permission
+func Match(pattern string, permission string) bool {
+	prefix, ok := strings.CutSuffix(pattern, Wildcard)
+	if !ok {
+		return pattern == permission
+	}
+	return strings.HasPrefix(permission, prefix)
+}
+
+// Allowed reports whether the permission is granted by entries and not denied by any of them
+func Allowed(entries []string, permission string) bool {
+	if Denied(entries, permission) {
+		return false
+	}
+	for _, entry := range entries {
+		if !IsDeny(entry) && Match(entry, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers reports whether the grant pattern is granted by entries as a whole,
+// i.e. some grant entry matches all permissions of the pattern and no deny entry matches any of them
+func Covers(entries []string, pattern string) bool {
+	granted := false
+	for _, entry := range entries {
+		denyPattern, ok := strings.CutPrefix(entry, DenyPrefix)
+		switch {
+		case ok && overlaps(denyPattern, pattern):
+			return false
+		case !ok && Match(entry, pattern):
+			granted = true
+		}
+	}
+	return granted
+}
+
+// Expand returns keys granted by entries, wildcard grants are expanded and denied keys are excluded,
+// exact grants missing in keys are kept, entries are returned as is if keys are empty
+func Expand(entries []string, keys []string) []string {
+	if len(keys) == 0 {
+		return entries
+	}
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if Allowed(entries, key) && !slices.Contains(result, key) {
+			result = append(result, key)
+		}
+	}
+	for _, entry := range entries {
+		if IsDeny(entry) || strings.HasSuffix(entry, Wildcard) || slices.Contains(result, entry) {
+			continue
+		}
+		if !Denied(entries, entry) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// overlaps reports whether patterns match at least one common permission
+func overlaps(a string, b string) bool {
+	return Match(a, b) || Match(b, a)
+}
+
+// Denied reports whether the permission is matched by any deny entry
+func Denied(entries []string, permission string) bool {
+	for _, entry := range entries {
+		pattern, ok := strings.CutPrefix(entry, DenyPrefix)
+		if ok && Match(pattern, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scopes returns scopes of the role, which limit the permission, empty means the role grants it for all resources
+func Scopes(role entity.Role, permission string) []entity.ResourceScope {
+	result := make([]entity.ResourceScope, 0)
+	for _, scope := range role.ResourceScopes {
+		if Match(scope.Permission, permission) {
+			result = append(result, scope)
+		}
+	}
+	return result
+}
+
+// InScopes reports whether the resource is listed in any of scopes
+func InScopes(scopes []entity.ResourceScope, resourceType string, resourceId string) bool {
+	return slices.ContainsFunc(scopes, func(scope entity.ResourceScope) bool {
+		return scope.ResourceType == resourceType && slices.Contains(scope.ResourceIds, resourceId)
+	})
+}
+
+// MergeScopes joins resource ids of scopes with the same permission and resource type
+func MergeScopes(scopes []entity.ResourceScope) []domain.ResourceScope {
+	result := make([]domain.ResourceScope, 0, len(scopes))
+	for _, scope := range scopes {
+		i := slices.IndexFunc(result, func(merged domain.ResourceScope) bool {
+			return merged.Permission == scope.Permission && merged.ResourceType == scope.ResourceType
+		})
+		if i < 0 {
+			result = append(result, domain.ResourceScope{
+				Permission:   scope.Permission,
+				ResourceType: scope.ResourceType,
+				ResourceIds:  slices.Clone(scope.ResourceIds),
+			})
+			continue
+		}
+		for _, id := range scope.ResourceIds {
+			if !slices.Contains(result[i].ResourceIds, id) {
+				result[i].ResourceIds = append(result[i].ResourceIds, id)
+			}
+		}
+	}
+	return result
+}
+
+// Unknown returns entries, which do not match any of keys
+func Unknown(entries []string, keys []string) []string {
+	result := make([]string, 0)
+	for _, entry := range entries {
+		pattern := strings.TrimPrefix(entry, DenyPrefix)
+		known := slices.ContainsFunc(keys, func(key string) bool {
+			return Match(pattern, key)
+		})
+		if !known && !slices.Contains(result, entry) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+func IsDeny(entry string) bool {
+	return strings.HasPrefix(entry, DenyPrefix)
+}
+
+// Validate checks, that the entry is not empty and the wildcard is used only at the end
+func Validate(entry string) error {
+	pattern := strings.TrimPrefix(entry, DenyPrefix)
+	switch {
+	case pattern == "":
+		return errors.WithMessagef(ErrInvalidPattern, "empty permission '%s'", entry)
+	case strings.Contains(strings.TrimSuffix(pattern, Wildcard), Wildcard):
+		return errors.WithMessagef(ErrInvalidPattern, "wildcard is allowed only at the end of '%s'", entry)
+	case strings.HasPrefix(pattern, DenyPrefix):
+		return errors.WithMessagef(ErrInvalidPattern, "double deny in '%s'", entry)
+	default:
+		return nil
+	}
+}
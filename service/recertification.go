@@ -0,0 +1,496 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/log"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+type recertificationRepo interface {
+	GetCampaignById(ctx context.Context, id int) (*entity.RecertificationCampaign, error)
+	GetCampaignsByIds(ctx context.Context, ids []int) ([]entity.RecertificationCampaign, error)
+	AllCampaigns(ctx context.Context, req domain.RecertificationCampaignPageRequest) ([]entity.RecertificationCampaign, error)
+	CountCampaigns(ctx context.Context, reqQuery *domain.RecertificationCampaignQuery) (int64, error)
+	GetOverdueCampaigns(ctx context.Context, now time.Time) ([]entity.RecertificationCampaign, error)
+	Progress(ctx context.Context, campaignIds []int) (map[int]entity.RecertificationProgress, error)
+	ScopeItems(ctx context.Context, userIds []int64, roleIds []int) ([]entity.RecertificationItem, error)
+	GetItemsByIds(ctx context.Context, ids []int) ([]entity.RecertificationItem, error)
+	AllItems(ctx context.Context, req domain.RecertificationItemPageRequest, reviewerId int64) ([]entity.RecertificationItemView, error)
+	CountItems(ctx context.Context, reqQuery *domain.RecertificationItemQuery, reviewerId int64) (int64, error)
+	GetItemsByCampaignId(ctx context.Context, campaignId int) ([]entity.RecertificationItemView, error)
+	DecideItems(ctx context.Context, ids []int, decision string, comment string, reviewerId int64, now time.Time) error
+}
+
+type recertificationTokenRepo interface {
+	LastAccessByUserIds(ctx context.Context, userIds []int) (map[int64]*time.Time, error)
+}
+
+type RecertificationTransaction interface {
+	InsertCampaign(ctx context.Context, campaign entity.RecertificationCampaign) (*entity.RecertificationCampaign, error)
+	InsertItems(ctx context.Context, items []entity.RecertificationItem) error
+	CloseCampaign(ctx context.Context, id int, now time.Time) (*entity.RecertificationCampaign, error)
+	Progress(ctx context.Context, campaignIds []int) (map[int]entity.RecertificationProgress, error)
+	AutoRevokeItems(ctx context.Context, campaignId int, now time.Time) error
+	GetRevokedItems(ctx context.Context, campaignId int) ([]entity.RecertificationItem, error)
+	DeleteUserRoleLinks(ctx context.Context, links []entity.UserRole) ([]entity.UserRole, error)
+}
+
+type RecertificationTransactionRunner interface {
+	RecertificationTransaction(ctx context.Context, tx func(ctx context.Context, tx RecertificationTransaction) error) error
+}
+
+type Recertification struct {
+	repo         recertificationRepo
+	tokenRepo    recertificationTokenRepo
+	txRunner     RecertificationTransactionRunner
+	auditService auditService
+	logger       log.Logger
+}
+
+func NewRecertification(
+	repo recertificationRepo,
+	tokenRepo recertificationTokenRepo,
+	txRunner RecertificationTransactionRunner,
+	auditService auditService,
+	logger log.Logger,
+) Recertification {
+	return Recertification{
+		repo:         repo,
+		tokenRepo:    tokenRepo,
+		txRunner:     txRunner,
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+// Start creates the campaign with an item for every current role link in the scope
+func (s Recertification) Start(
+	ctx context.Context,
+	req domain.StartRecertificationRequest,
+	adminId int64,
+) (*domain.RecertificationCampaign, error) {
+	if !req.Deadline.After(time.Now()) {
+		return nil, domain.ErrRecertificationDeadline
+	}
+	if len(req.UserIds) == 0 && len(req.RoleIds) == 0 {
+		return nil, errors.WithMessage(domain.ErrRecertificationScope, "users or roles are required")
+	}
+
+	items, err := s.repo.ScopeItems(ctx, req.UserIds, req.RoleIds)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get role links")
+	}
+	if len(items) == 0 {
+		return nil, domain.ErrRecertificationScope
+	}
+	items, err = s.withLastAccess(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+
+	var campaign *entity.RecertificationCampaign
+	err = s.txRunner.RecertificationTransaction(ctx, func(ctx context.Context, tx RecertificationTransaction) error {
+		campaign, err = tx.InsertCampaign(ctx, entity.RecertificationCampaign{
+			Name:        req.Name,
+			UserIds:     req.UserIds,
+			RoleIds:     req.RoleIds,
+			ReviewerIds: req.ReviewerIds,
+			Deadline:    req.Deadline.UTC(),
+			CreatedBy:   &adminId,
+		})
+		if err != nil {
+			return errors.WithMessage(err, "insert campaign")
+		}
+
+		for i := range items {
+			items[i].CampaignId = campaign.Id
+		}
+		err = tx.InsertItems(ctx, items)
+		if err != nil {
+			return errors.WithMessage(err, "insert items")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "recertification transaction")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Пересмотр доступа. Начат пересмотр %d (%s), назначений ролей: %d, срок до %s, проверяющие: %v",
+			campaign.Id, campaign.Name, len(items), campaign.Deadline.Format(time.DateTime), req.ReviewerIds),
+		entity.EventRecertification,
+	)
+
+	return new(s.toDomain(*campaign, entity.RecertificationProgress{Total: len(items)})), nil
+}
+
+func (s Recertification) All(
+	ctx context.Context,
+	req domain.RecertificationCampaignPageRequest,
+) (*domain.RecertificationCampaignResponse, error) {
+	campaigns, err := s.repo.AllCampaigns(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get campaigns")
+	}
+
+	count, err := s.repo.CountCampaigns(ctx, req.Query)
+	if err != nil {
+		return nil, errors.WithMessage(err, "count campaigns")
+	}
+
+	ids := make([]int, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		ids = append(ids, campaign.Id)
+	}
+	progress, err := s.repo.Progress(ctx, ids)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get progress")
+	}
+
+	items := make([]domain.RecertificationCampaign, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		items = append(items, s.toDomain(campaign, progress[campaign.Id]))
+	}
+	return &domain.RecertificationCampaignResponse{
+		TotalCount: int(count),
+		Items:      items,
+	}, nil
+}
+
+func (s Recertification) Get(ctx context.Context, req domain.RecertificationIdRequest) (*domain.RecertificationCampaign, error) {
+	campaign, err := s.repo.GetCampaignById(ctx, req.Id)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get campaign %d", req.Id)
+	}
+
+	progress, err := s.repo.Progress(ctx, []int{campaign.Id})
+	if err != nil {
+		return nil, errors.WithMessage(err, "get progress")
+	}
+
+	return new(s.toDomain(*campaign, progress[campaign.Id])), nil
+}
+
+// Close closes the campaign before the deadline and revokes roles, all items must be reviewed
+func (s Recertification) Close(
+	ctx context.Context,
+	req domain.RecertificationIdRequest,
+	adminId int64,
+) (*domain.RecertificationCampaign, error) {
+	_, err := s.repo.GetCampaignById(ctx, req.Id)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get campaign %d", req.Id)
+	}
+
+	campaign, progress, err := s.close(ctx, req.Id, false, adminId)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(s.toDomain(*campaign, progress)), nil
+}
+
+// CloseOverdue closes campaigns after the deadline, not reviewed items are revoked automatically
+func (s Recertification) CloseOverdue(ctx context.Context) error {
+	campaigns, err := s.repo.GetOverdueCampaigns(ctx, time.Now().UTC())
+	if err != nil {
+		return errors.WithMessage(err, "get overdue campaigns")
+	}
+
+	for _, campaign := range campaigns {
+		authorId := int64(0)
+		if campaign.CreatedBy != nil {
+			authorId = *campaign.CreatedBy
+		}
+		_, _, err := s.close(ctx, campaign.Id, true, authorId)
+		if errors.Is(err, domain.ErrRecertificationClosed) {
+			continue
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "close campaign %d", campaign.Id)
+		}
+		s.logger.Info(ctx, "overdue recertification campaign closed", log.Int("campaignId", campaign.Id))
+	}
+
+	return nil
+}
+
+func (s Recertification) Items(
+	ctx context.Context,
+	req domain.RecertificationItemPageRequest,
+	adminId int64,
+) (*domain.RecertificationItemResponse, error) {
+	views, err := s.repo.AllItems(ctx, req, adminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get items")
+	}
+
+	count, err := s.repo.CountItems(ctx, req.Query, adminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "count items")
+	}
+
+	items := make([]domain.RecertificationItem, 0, len(views))
+	for _, view := range views {
+		items = append(items, s.itemToDomain(view))
+	}
+	return &domain.RecertificationItemResponse{
+		TotalCount: int(count),
+		Items:      items,
+	}, nil
+}
+
+// Decide saves the decision of the reviewer, roles are revoked when the campaign is closed
+func (s Recertification) Decide(ctx context.Context, req domain.DecideRecertificationRequest, adminId int64) error {
+	items, err := s.repo.GetItemsByIds(ctx, req.Ids)
+	if err != nil {
+		return errors.WithMessage(err, "get items")
+	}
+	if len(items) != len(slices.Compact(slices.Sorted(slices.Values(req.Ids)))) {
+		return errors.WithMessage(domain.ErrNotFound, "items not found")
+	}
+
+	campaignIds := make([]int, 0, len(items))
+	for _, item := range items {
+		if item.UserId == adminId {
+			return errors.WithMessage(domain.ErrRecertificationDenied, "review of own role")
+		}
+		campaignIds = append(campaignIds, item.CampaignId)
+	}
+	campaigns, err := s.repo.GetCampaignsByIds(ctx, slices.Compact(slices.Sorted(slices.Values(campaignIds))))
+	if err != nil {
+		return errors.WithMessage(err, "get campaigns")
+	}
+	for _, campaign := range campaigns {
+		if !slices.Contains(campaign.ReviewerIds, adminId) {
+			return errors.WithMessagef(domain.ErrRecertificationDenied, "not a reviewer of campaign %d", campaign.Id)
+		}
+		if campaign.Status != entity.RecertificationActive {
+			return errors.WithMessagef(domain.ErrRecertificationClosed, "campaign %d", campaign.Id)
+		}
+	}
+
+	err = s.repo.DecideItems(ctx, req.Ids, req.Decision, req.Comment, adminId, time.Now().UTC())
+	if err != nil {
+		return errors.WithMessage(err, "decide items")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Пересмотр доступа. Решение %s по назначениям %v пересмотров %v. Комментарий: %s",
+			req.Decision, req.Ids, campaignIds, req.Comment),
+		entity.EventRecertification,
+	)
+
+	return nil
+}
+
+// Report returns decisions of the campaign in CSV
+func (s Recertification) Report(ctx context.Context, req domain.RecertificationIdRequest) (*domain.RecertificationReport, error) {
+	campaign, err := s.repo.GetCampaignById(ctx, req.Id)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get campaign %d", req.Id)
+	}
+
+	items, err := s.repo.GetItemsByCampaignId(ctx, campaign.Id)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get items")
+	}
+
+	builder := strings.Builder{}
+	writer := csv.NewWriter(&builder)
+	rows := [][]string{{
+		"ID пользователя", "Email", "ID роли", "Роль", "Последняя активность",
+		"Решение", "Комментарий", "ID проверяющего", "Дата решения",
+	}}
+	for _, item := range items {
+		rows = append(rows, []string{
+			strconv.FormatInt(item.UserId, 10),
+			item.UserEmail,
+			strconv.Itoa(item.RoleId),
+			item.RoleName,
+			timeToReport(item.LastActiveAt),
+			stringToReport(item.Decision),
+			item.Comment,
+			idToReport(item.ReviewerId),
+			timeToReport(item.DecidedAt),
+		})
+	}
+	err = writer.WriteAll(rows)
+	if err != nil {
+		return nil, errors.WithMessage(err, "write csv")
+	}
+
+	return &domain.RecertificationReport{
+		FileName: fmt.Sprintf("recertification_%d.csv", campaign.Id),
+		Content:  builder.String(),
+	}, nil
+}
+
+// close closes the campaign and revokes roles in one transaction,
+// not reviewed items are revoked with autoRevoke, otherwise all items must be reviewed
+func (s Recertification) close(
+	ctx context.Context,
+	campaignId int,
+	autoRevoke bool,
+	adminId int64,
+) (*entity.RecertificationCampaign, entity.RecertificationProgress, error) {
+	var (
+		campaign *entity.RecertificationCampaign
+		progress entity.RecertificationProgress
+		revoked  []entity.UserRole
+	)
+	err := s.txRunner.RecertificationTransaction(ctx, func(ctx context.Context, tx RecertificationTransaction) error {
+		now := time.Now().UTC()
+		var err error
+		campaign, err = tx.CloseCampaign(ctx, campaignId, now)
+		if err != nil {
+			return errors.WithMessage(err, "close campaign")
+		}
+
+		if autoRevoke {
+			err = tx.AutoRevokeItems(ctx, campaignId, now)
+			if err != nil {
+				return errors.WithMessage(err, "auto revoke items")
+			}
+		}
+
+		progressByCampaign, err := tx.Progress(ctx, []int{campaignId})
+		if err != nil {
+			return errors.WithMessage(err, "get progress")
+		}
+		progress = progressByCampaign[campaignId]
+		if progress.Kept+progress.Revoked < progress.Total {
+			return domain.ErrRecertificationUndone
+		}
+
+		items, err := tx.GetRevokedItems(ctx, campaignId)
+		if err != nil {
+			return errors.WithMessage(err, "get revoked items")
+		}
+		links := make([]entity.UserRole, 0, len(items))
+		for _, item := range items {
+			links = append(links, entity.UserRole{UserId: int(item.UserId), RoleId: item.RoleId})
+		}
+		revoked, err = tx.DeleteUserRoleLinks(ctx, links)
+		if err != nil {
+			return errors.WithMessage(err, "delete user role links")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, progress, errors.WithMessage(err, "recertification transaction")
+	}
+
+	for _, link := range revoked {
+		s.auditService.SaveAuditAsync(ctx, adminId,
+			fmt.Sprintf("Пересмотр доступа. По итогам пересмотра %d роль %d отозвана у пользователя %d",
+				campaign.Id, link.RoleId, link.UserId),
+			entity.EventRecertification,
+		)
+	}
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Пересмотр доступа. Пересмотр %d (%s) завершен%s, сохранено назначений: %d, отозвано: %d",
+			campaign.Id, campaign.Name, closeReason(autoRevoke), progress.Kept, progress.Revoked),
+		entity.EventRecertification,
+	)
+
+	return campaign, progress, nil
+}
+
+// withLastAccess sets the last activity of the user to the latest of last_active_at and session start
+func (s Recertification) withLastAccess(ctx context.Context, items []entity.RecertificationItem) ([]entity.RecertificationItem, error) {
+	userIds := make([]int, 0, len(items))
+	for _, item := range items {
+		userIds = append(userIds, int(item.UserId))
+	}
+	lastAccess, err := s.tokenRepo.LastAccessByUserIds(ctx, slices.Compact(userIds))
+	if err != nil {
+		return nil, errors.WithMessage(err, "get last access")
+	}
+
+	for i, item := range items {
+		sessionAccess := lastAccess[item.UserId]
+		if sessionAccess != nil && (item.LastActiveAt == nil || sessionAccess.After(*item.LastActiveAt)) {
+			items[i].LastActiveAt = sessionAccess
+		}
+	}
+	return items, nil
+}
+
+func (s Recertification) toDomain(
+	campaign entity.RecertificationCampaign,
+	progress entity.RecertificationProgress,
+) domain.RecertificationCampaign {
+	return domain.RecertificationCampaign{
+		Id:          campaign.Id,
+		Name:        campaign.Name,
+		UserIds:     campaign.UserIds,
+		RoleIds:     campaign.RoleIds,
+		ReviewerIds: campaign.ReviewerIds,
+		Status:      campaign.Status,
+		Deadline:    campaign.Deadline,
+		CreatedBy:   campaign.CreatedBy,
+		ClosedAt:    campaign.ClosedAt,
+		CreatedAt:   campaign.CreatedAt,
+		UpdatedAt:   campaign.UpdatedAt,
+		Progress: domain.RecertificationProgress{
+			Total:    progress.Total,
+			Reviewed: progress.Kept + progress.Revoked,
+			Kept:     progress.Kept,
+			Revoked:  progress.Revoked,
+		},
+	}
+}
+
+func (s Recertification) itemToDomain(item entity.RecertificationItemView) domain.RecertificationItem {
+	return domain.RecertificationItem{
+		Id:           item.Id,
+		CampaignId:   item.CampaignId,
+		UserId:       item.UserId,
+		UserEmail:    item.UserEmail,
+		RoleId:       item.RoleId,
+		RoleName:     item.RoleName,
+		LastActiveAt: item.LastActiveAt,
+		Decision:     item.Decision,
+		Comment:      item.Comment,
+		ReviewerId:   item.ReviewerId,
+		DecidedAt:    item.DecidedAt,
+	}
+}
+
+func closeReason(autoRevoke bool) string {
+	if autoRevoke {
+		return " по истечении срока"
+	}
+	return ""
+}
+
+func timeToReport(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.Format(time.DateTime)
+}
+
+func stringToReport(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func idToReport(value *int64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatInt(*value, 10)
+}
@@ -0,0 +1,26 @@
+package recertification_worker
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/bgjob"
+	"github.com/txix-open/isp-kit/bgjobx"
+)
+
+const (
+	QueueName = "close_overdue_recertifications"
+)
+
+func EnqueueSeedJob(ctx context.Context, client *bgjobx.Client) error {
+	err := client.Enqueue(ctx, bgjob.EnqueueRequest{
+		Id:    "close_overdue_recertifications",
+		Queue: QueueName,
+		Type:  "close_overdue_recertifications",
+	})
+	if err != nil && !errors.Is(err, bgjob.ErrJobAlreadyExist) {
+		return errors.WithMessage(err, "enqueue job")
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package recertification_worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/bgjob"
+	"github.com/txix-open/isp-kit/bgjobx/handler"
+	"github.com/txix-open/isp-kit/log"
+)
+
+const (
+	defaultRetryTimeout = 5 * time.Minute
+	rescheduleInterval  = 1 * time.Minute
+)
+
+type RecertificationService interface {
+	CloseOverdue(ctx context.Context) error
+}
+
+type Service struct {
+	logger                 log.Logger
+	recertificationService RecertificationService
+}
+
+func NewRecertificationWorker(
+	logger log.Logger,
+	recertificationService RecertificationService,
+) Service {
+	return Service{
+		logger:                 logger,
+		recertificationService: recertificationService,
+	}
+}
+
+func (w Service) Handle(ctx context.Context, _ bgjob.Job) handler.Result {
+	ctx = log.ToContext(ctx, log.String("worker", "recertificationWorker"))
+	w.logger.Debug(ctx, "begin work")
+
+	err := w.recertificationService.CloseOverdue(ctx)
+	if err != nil {
+		return handler.Retry(defaultRetryTimeout, errors.WithMessage(err, "recertificationWorker close overdue"))
+	}
+
+	w.logger.Debug(ctx, "end work")
+	return handler.Reschedule(handler.ByAfterTime(rescheduleInterval, time.Now()))
+}
@@ -3,11 +3,15 @@ package service
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"slices"
 
 	"github.com/pkg/errors"
+	"msp-admin-service/conf"
 	"msp-admin-service/domain"
 	"msp-admin-service/entity"
+	"msp-admin-service/service/permission_match"
+	"msp-admin-service/service/role_hierarchy"
 )
 
 type roleRoleRepo interface {
@@ -19,15 +23,79 @@ type roleRoleRepo interface {
 	GetRoleByIds(ctx context.Context, id []int) ([]entity.Role, error)
 }
 
+type roleVersionRepo interface {
+	GetVersions(ctx context.Context, roleId int) ([]entity.RoleVersion, error)
+	GetVersion(ctx context.Context, roleId int, version int) (*entity.RoleVersion, error)
+}
+
+type roleUserRepo interface {
+	GetUsersByRoleId(ctx context.Context, roleId int) ([]entity.User, error)
+}
+
+type roleUserRoleRepo interface {
+	GetRolesByUserIds(ctx context.Context, identity []int) ([]entity.UserRole, error)
+}
+
+type RoleTransaction interface {
+	InsertRole(ctx context.Context, role entity.Role) (*entity.Role, error)
+	Update(ctx context.Context, role entity.Role) (*entity.Role, error)
+	Delete(ctx context.Context, id int) error
+	UpdateFlags(ctx context.Context, id int, immutable bool, exclusive bool) error
+	InsertVersion(ctx context.Context, version entity.RoleVersion) (*entity.RoleVersion, error)
+	InsertUserRoleLinks(ctx context.Context, links []entity.UserRole) error
+	DeleteRoleLinks(ctx context.Context, roleId int) ([]entity.UserRole, error)
+}
+
+type RoleTransactionRunner interface {
+	RoleTransaction(ctx context.Context, tx func(ctx context.Context, tx RoleTransaction) error) error
+}
+
+type permissionCatalog interface {
+	All(ctx context.Context) ([]domain.Permission, error)
+}
+
+type changeGate interface {
+	Required(operation string) bool
+	PrivilegedRoleNames(roles []entity.Role) []string
+	Submit(ctx context.Context, operation string, payload any, diff string, adminId int64) error
+}
+
 type Role struct {
-	roleRepo     roleRoleRepo
-	auditService auditService
+	roleRepo         roleRoleRepo
+	versionRepo      roleVersionRepo
+	userRepo         roleUserRepo
+	userRoleRepo     roleUserRoleRepo
+	txRunner         RoleTransactionRunner
+	catalog          permissionCatalog
+	changeGate       changeGate
+	sodChecker       sodChecker
+	auditService     auditService
+	readOnlySuffixes []string
 }
 
-func NewRole(roleRepo roleRoleRepo, audit auditService) Role {
+func NewRole(
+	roleRepo roleRoleRepo,
+	versionRepo roleVersionRepo,
+	userRepo roleUserRepo,
+	userRoleRepo roleUserRoleRepo,
+	txRunner RoleTransactionRunner,
+	catalog permissionCatalog,
+	changeGate changeGate,
+	sodChecker sodChecker,
+	audit auditService,
+	templates conf.RoleTemplates,
+) Role {
 	return Role{
-		roleRepo:     roleRepo,
-		auditService: audit,
+		roleRepo:         roleRepo,
+		versionRepo:      versionRepo,
+		userRepo:         userRepo,
+		userRoleRepo:     userRoleRepo,
+		txRunner:         txRunner,
+		catalog:          catalog,
+		changeGate:       changeGate,
+		sodChecker:       sodChecker,
+		auditService:     audit,
+		readOnlySuffixes: templates.ReadOnlySuffixes,
 	}
 }
 
@@ -37,15 +105,21 @@ func (u Role) All(ctx context.Context) ([]domain.Role, error) {
 		return nil, errors.WithMessage(err, "get all roles")
 	}
 
+	hierarchy := role_hierarchy.New(roles)
 	resRoles := make([]domain.Role, 0)
 	for _, role := range roles {
-		resRoles = append(resRoles, u.toDomain(role))
+		resRoles = append(resRoles, u.toDomain(role, hierarchy))
 	}
 
 	return resRoles, nil
 }
 
 func (u Role) Create(ctx context.Context, req domain.CreateRoleRequest, adminId int64) (*domain.Role, error) {
+	err := u.checkPermissions(ctx, req.Permissions, req.ResourceScopes)
+	if err != nil {
+		return nil, err
+	}
+
 	role, err := u.roleRepo.GetRoleByName(ctx, req.Name)
 	switch {
 	case errors.Is(err, domain.ErrNotFound):
@@ -56,35 +130,81 @@ func (u Role) Create(ctx context.Context, req domain.CreateRoleRequest, adminId
 		return nil, domain.ErrAlreadyExists
 	}
 
-	role, err = u.roleRepo.InsertRole(ctx, entity.Role{
-		Name:          req.Name,
-		ExternalGroup: req.ExternalGroup,
-		Permissions:   req.Permissions,
-	})
+	hierarchy, err := u.checkParents(ctx, 0, req.ParentIds)
+	if err != nil {
+		return nil, err
+	}
 
+	err = u.txRunner.RoleTransaction(ctx, func(ctx context.Context, tx RoleTransaction) error {
+		role, err = tx.InsertRole(ctx, entity.Role{
+			Name:           req.Name,
+			ExternalGroup:  req.ExternalGroup,
+			Permissions:    req.Permissions,
+			AllowedCidrs:   req.AllowedCidrs,
+			TimeWindows:    toEntityTimeWindows(req.TimeWindows),
+			ApproverIds:    req.ApproverIds,
+			ParentIds:      req.ParentIds,
+			ResourceScopes: toEntityResourceScopes(req.ResourceScopes),
+		})
+		if err != nil {
+			return errors.WithMessage(err, "insert role")
+		}
+		return saveVersion(ctx, tx, entity.RoleVersionCreate, *role, req.ChangeMessage, adminId)
+	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "create role")
 	}
 
 	slices.Sort(role.Permissions)
-	diff := diffToString(map[string]any{
-		"Название":   "",
-		"Группа ЕСК": "",
-		"Разрешения": []int{},
-	}, map[string]any{
-		"Название":   role.Name,
-		"Группа ЕСК": role.ExternalGroup,
-		"Разрешения": role.Permissions,
-	})
+	diff := roleDiff(entity.Role{}, *role)
 	u.auditService.SaveAuditAsync(ctx, adminId,
 		fmt.Sprintf("Роль. Создание новой роли %s. Причина: %s. \n %s", req.Name, req.ChangeMessage, diff),
 		entity.EventRoleChanged,
 	)
 
-	return new(u.toDomain(*role)), nil
+	return new(u.toDomain(*role, hierarchy)), nil
 }
 
+// Update applies the change immediately or saves it for approval, if role_update requires it
 func (u Role) Update(ctx context.Context, req domain.UpdateRoleRequest, adminId int64) (*domain.Role, error) {
+	if !u.changeGate.Required(entity.ChangeRoleUpdate) {
+		return u.update(ctx, req, adminId, entity.RoleVersionUpdate)
+	}
+	return nil, u.submitUpdate(ctx, entity.ChangeRoleUpdate, req, adminId)
+}
+
+// submitUpdate validates the change and saves it for approval as operation
+func (u Role) submitUpdate(ctx context.Context, operation string, req domain.UpdateRoleRequest, adminId int64) error {
+	err := u.checkPermissions(ctx, req.Permissions, req.ResourceScopes)
+	if err != nil {
+		return err
+	}
+	roles, err := u.roleRepo.GetRoleByIds(ctx, []int{req.Id})
+	switch {
+	case err != nil:
+		return errors.WithMessagef(err, "get role by id")
+	case len(roles) == 0:
+		return domain.ErrNotFound
+	case roles[0].ConfigManaged:
+		return domain.ErrConfigManagedRole
+	case req.Version != 0 && roles[0].Version != req.Version:
+		return u.versionConflict(ctx, req.Id)
+	}
+	_, err = u.checkParents(ctx, req.Id, req.ParentIds)
+	if err != nil {
+		return err
+	}
+	diff := fmt.Sprintf("Роль %s. Причина: %s.\n %s", roles[0].Name, req.ChangeMessage, roleDiff(roles[0], u.toEntity(req)))
+	return u.changeGate.Submit(ctx, operation, req, diff, adminId)
+}
+
+// update applies the change and saves the version with operation
+func (u Role) update(ctx context.Context, req domain.UpdateRoleRequest, adminId int64, operation string) (*domain.Role, error) {
+	err := u.checkPermissions(ctx, req.Permissions, req.ResourceScopes)
+	if err != nil {
+		return nil, err
+	}
+
 	roleByName, err := u.roleRepo.GetRoleByName(ctx, req.Name)
 	switch {
 	case errors.Is(err, domain.ErrNotFound):
@@ -99,61 +219,682 @@ func (u Role) Update(ctx context.Context, req domain.UpdateRoleRequest, adminId
 		return nil, errors.WithMessagef(err, "get role by id")
 	case len(roles) == 0:
 		return nil, domain.ErrNotFound
+	case roles[0].ConfigManaged:
+		return nil, domain.ErrConfigManagedRole
 	case roleByName != nil && roleByName.Id != roles[0].Id:
 		return nil, domain.ErrAlreadyExists
 	}
 	oldRole := roles[0]
 
-	role, err := u.roleRepo.Update(ctx, entity.Role{
-		Id:            req.Id,
-		Name:          req.Name,
-		ExternalGroup: req.ExternalGroup,
-		Permissions:   req.Permissions,
-	})
-
+	hierarchy, err := u.checkParents(ctx, req.Id, req.ParentIds)
 	if err != nil {
+		return nil, err
+	}
+
+	var role *entity.Role
+	err = u.txRunner.RoleTransaction(ctx, func(ctx context.Context, tx RoleTransaction) error {
+		role, err = tx.Update(ctx, u.toEntity(req))
+		if err != nil {
+			return errors.WithMessage(err, "update role")
+		}
+		return saveVersion(ctx, tx, operation, *role, req.ChangeMessage, adminId)
+	})
+	switch {
+	case errors.Is(err, domain.ErrVersionConflict):
+		return nil, u.versionConflict(ctx, req.Id)
+	case err != nil:
 		return nil, errors.WithMessage(err, "update role")
 	}
 
-	slices.Sort(oldRole.Permissions)
 	slices.Sort(role.Permissions)
-	diff := diffToString(map[string]any{
-		"Название":   oldRole.Name,
-		"Группа ЕСК": oldRole.ExternalGroup,
-		"Разрешения": oldRole.Permissions,
-	}, map[string]any{
-		"Название":   role.Name,
-		"Группа ЕСК": role.ExternalGroup,
-		"Разрешения": role.Permissions,
-	})
+	diff := roleDiff(oldRole, *role)
 	u.auditService.SaveAuditAsync(ctx, adminId,
 		fmt.Sprintf("Роль. Изменение роли %s. Причина: %s. \n %s", req.Name, req.ChangeMessage, diff),
 		entity.EventRoleChanged,
 	)
-	return new(u.toDomain(*role)), nil
+	return new(u.toDomain(*role, hierarchy)), nil
 }
 
+// Delete deletes the role immediately or saves the change for approval, if role_delete requires it
+// or users are reassigned to the privileged role
 func (u Role) Delete(ctx context.Context, req domain.DeleteRoleRequest, adminId int64) error {
-	err := u.roleRepo.Delete(ctx, req.Id)
+	role, target, err := u.checkDelete(ctx, req)
+	if err != nil {
+		return err
+	}
+	privilegedTarget := target != nil && u.changeGate.Required(entity.ChangeUserGrantRole) &&
+		len(u.changeGate.PrivilegedRoleNames([]entity.Role{*target})) > 0
+	if !u.changeGate.Required(entity.ChangeRoleDelete) && !privilegedTarget {
+		return u.delete(ctx, req, adminId)
+	}
+
+	diff := roleDiff(*role, entity.Role{})
+	if target != nil {
+		diff = fmt.Sprintf("%s\n Пользователям назначается роль %s", diff, target.Name)
+	}
+	return u.changeGate.Submit(ctx, entity.ChangeRoleDelete, req, fmt.Sprintf("Удаление роли %s.\n %s", role.Name, diff), adminId)
+}
+
+// DeletePreview returns users, who lose the role on deletion
+func (u Role) DeletePreview(ctx context.Context, req domain.RoleDeletePreviewRequest) (*domain.RoleDeletePreview, error) {
+	roles, err := u.roleRepo.GetRoleByIds(ctx, []int{req.Id})
+	switch {
+	case err != nil:
+		return nil, errors.WithMessagef(err, "get role by id")
+	case len(roles) == 0:
+		return nil, domain.ErrNotFound
+	}
+
+	users, err := u.userRepo.GetUsersByRoleId(ctx, req.Id)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get users by role id")
+	}
+	holders := toRoleHolders(users)
+	return &domain.RoleDeletePreview{
+		RoleId:     roles[0].Id,
+		RoleName:   roles[0].Name,
+		UsersCount: len(holders),
+		Users:      holders,
+	}, nil
+}
+
+// delete revokes the role from users or reassigns it and deletes the role in one transaction
+func (u Role) delete(ctx context.Context, req domain.DeleteRoleRequest, adminId int64) error {
+	role, _, err := u.checkDelete(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	var links []entity.UserRole
+	err = u.txRunner.RoleTransaction(ctx, func(ctx context.Context, tx RoleTransaction) error {
+		links, err = tx.DeleteRoleLinks(ctx, req.Id)
+		if err != nil {
+			return errors.WithMessage(err, "delete role links")
+		}
+		if len(links) > 0 && req.ReassignToRoleId == 0 && !req.Force {
+			return errors.WithMessagef(domain.ErrRoleInUse, "%d users", len(links))
+		}
+		if req.ReassignToRoleId != 0 {
+			err = tx.InsertUserRoleLinks(ctx, reassignedLinks(links, req.ReassignToRoleId))
+			if err != nil {
+				return errors.WithMessage(err, "insert reassigned role links")
+			}
+		}
+
+		err = tx.Delete(ctx, req.Id)
+		if err != nil {
+			return errors.WithMessage(err, "delete role")
+		}
+		return saveVersion(ctx, tx, entity.RoleVersionDelete, *role, "", adminId)
+	})
 	if err != nil {
 		return errors.WithMessage(err, "delete role")
 	}
 
-	u.auditService.SaveAuditAsync(ctx, adminId, fmt.Sprintf("Роль. Удаление роли. ID: %d", req.Id),
-		entity.EventRoleChanged,
-	)
+	userIds := linkUserIds(links)
+	message := fmt.Sprintf("Роль. Удаление роли %s. ID: %d", role.Name, req.Id)
+	switch {
+	case len(userIds) > 0 && req.ReassignToRoleId != 0:
+		message = fmt.Sprintf("%s. Пользователям %v назначена роль с ID %d", message, userIds, req.ReassignToRoleId)
+	case len(userIds) > 0:
+		message = fmt.Sprintf("%s. Роль отозвана у пользователей %v", message, userIds)
+	}
+	u.auditService.SaveAuditAsync(ctx, adminId, message, entity.EventRoleChanged)
+	return nil
+}
+
+// linkUserIds returns sorted ids of users of links
+func linkUserIds(links []entity.UserRole) []int {
+	userIds := make([]int, 0, len(links))
+	for _, link := range links {
+		userIds = append(userIds, link.UserId)
+	}
+	slices.Sort(userIds)
+	return userIds
+}
+
+func toRoleHolders(users []entity.User) []domain.RoleHolder {
+	holders := make([]domain.RoleHolder, 0, len(users))
+	for _, user := range users {
+		holders = append(holders, domain.RoleHolder{
+			Id:       user.Id,
+			Email:    user.Email,
+			FullName: user.FullName,
+		})
+	}
+	return holders
+}
+
+// checkDelete returns the role and the role to reassign users to, domain.ErrRoleInUse is returned, if users hold the role
+// and neither reassignment nor force is requested, domain.SodViolationError is returned, if reassigned users violate
+// the separation of duties
+func (u Role) checkDelete(ctx context.Context, req domain.DeleteRoleRequest) (*entity.Role, *entity.Role, error) {
+	roleIds := []int{req.Id}
+	if req.ReassignToRoleId != 0 {
+		roleIds = append(roleIds, req.ReassignToRoleId)
+	}
+	roles, err := u.roleRepo.GetRoleByIds(ctx, roleIds)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "get role by id")
+	}
+	i := slices.IndexFunc(roles, func(role entity.Role) bool {
+		return role.Id == req.Id
+	})
+	switch {
+	case i == -1:
+		return nil, nil, domain.ErrNotFound
+	case roles[i].ConfigManaged:
+		return nil, nil, domain.ErrConfigManagedRole
+	case req.ReassignToRoleId == req.Id || (req.ReassignToRoleId != 0 && len(roles) != len(roleIds)):
+		return nil, nil, errors.WithMessagef(domain.ErrInvalidReassignRole, "role %d", req.ReassignToRoleId)
+	case req.ReassignToRoleId != 0:
+		target := roles[1-i]
+		err = u.checkReassign(ctx, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &roles[i], &target, nil
+	case req.Force:
+		return &roles[i], nil, nil
+	}
+
+	users, err := u.userRepo.GetUsersByRoleId(ctx, req.Id)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "get users by role id")
+	}
+	if len(users) > 0 {
+		return nil, nil, errors.WithMessagef(domain.ErrRoleInUse, "%d users", len(users))
+	}
+	return &roles[i], nil, nil
+}
+
+// checkReassign checks the separation of duties for roles of users, who hold the deleted role, after reassignment
+func (u Role) checkReassign(ctx context.Context, req domain.DeleteRoleRequest) error {
+	users, err := u.userRepo.GetUsersByRoleId(ctx, req.Id)
+	if err != nil {
+		return errors.WithMessage(err, "get users by role id")
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	userIds := make([]int, 0, len(users))
+	for _, user := range users {
+		userIds = append(userIds, int(user.Id))
+	}
+	links, err := u.userRoleRepo.GetRolesByUserIds(ctx, userIds)
+	if err != nil {
+		return errors.WithMessage(err, "get user roles")
+	}
+
+	userRoleIds := make(map[int][]int, len(userIds))
+	for _, link := range links {
+		if link.RoleId != req.Id && link.RoleId != req.ReassignToRoleId {
+			userRoleIds[link.UserId] = append(userRoleIds[link.UserId], link.RoleId)
+		}
+	}
+	for _, userId := range userIds {
+		err = u.sodChecker.Check(ctx, append(userRoleIds[userId], req.ReassignToRoleId))
+		if err != nil {
+			return errors.WithMessagef(err, "check separation of duties for user %d", userId)
+		}
+	}
+	return nil
+}
+
+func reassignedLinks(links []entity.UserRole, roleId int) []entity.UserRole {
+	result := make([]entity.UserRole, 0, len(links))
+	for _, link := range links {
+		link.RoleId = roleId
+		result = append(result, link)
+	}
+	return result
+}
+
+// UnknownPermissions returns roles with permissions or resource scopes, which do not match the catalog
+func (u Role) UnknownPermissions(ctx context.Context) ([]domain.RoleUnknownPermissions, error) {
+	roles, err := u.roleRepo.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all roles")
+	}
+	keys, err := catalogKeys(ctx, u.catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.RoleUnknownPermissions, 0)
+	if len(keys) == 0 {
+		return result, nil
+	}
+	for _, role := range roles {
+		unknown := permission_match.Unknown(roleEntries(role), keys)
+		if len(unknown) == 0 {
+			continue
+		}
+		result = append(result, domain.RoleUnknownPermissions{
+			RoleId:        role.Id,
+			RoleName:      role.Name,
+			Immutable:     role.Immutable,
+			ConfigManaged: role.ConfigManaged,
+			Permissions:   unknown,
+		})
+	}
+	return result, nil
+}
+
+// RemoveUnknownPermissions removes permissions and resource scopes, which do not match the catalog, from roles.
+// Immutable and config managed roles are skipped, the cleanup only reduces grants and does not require approval
+func (u Role) RemoveUnknownPermissions(
+	ctx context.Context,
+	req domain.RemoveUnknownPermissionsRequest,
+	adminId int64,
+) ([]domain.RoleUnknownPermissions, error) {
+	reports, err := u.UnknownPermissions(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get roles with unknown permissions")
+	}
+	reports = slices.DeleteFunc(reports, func(report domain.RoleUnknownPermissions) bool {
+		return report.Immutable || report.ConfigManaged || len(req.RoleIds) > 0 && !slices.Contains(req.RoleIds, report.RoleId)
+	})
+	if len(reports) == 0 {
+		return reports, nil
+	}
+
+	roleIds := make([]int, 0, len(reports))
+	for _, report := range reports {
+		roleIds = append(roleIds, report.RoleId)
+	}
+	roles, err := u.roleRepo.GetRoleByIds(ctx, roleIds)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get roles by ids")
+	}
+
+	for _, oldRole := range roles {
+		i := slices.IndexFunc(reports, func(report domain.RoleUnknownPermissions) bool {
+			return report.RoleId == oldRole.Id
+		})
+		unknown := reports[i].Permissions
+
+		role := oldRole
+		role.Permissions = slices.DeleteFunc(slices.Clone(oldRole.Permissions), func(permission string) bool {
+			return slices.Contains(unknown, permission)
+		})
+		role.ResourceScopes = slices.DeleteFunc(slices.Clone(oldRole.ResourceScopes), func(scope entity.ResourceScope) bool {
+			return slices.Contains(unknown, scope.Permission)
+		})
+		var updated *entity.Role
+		err = u.txRunner.RoleTransaction(ctx, func(ctx context.Context, tx RoleTransaction) error {
+			updated, err = tx.Update(ctx, role)
+			if err != nil {
+				return errors.WithMessage(err, "update role")
+			}
+			return saveVersion(ctx, tx, entity.RoleVersionUpdate, *updated, "Удаление разрешений вне каталога", adminId)
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "update role %d", role.Id)
+		}
+
+		slices.Sort(updated.Permissions)
+		u.auditService.SaveAuditAsync(ctx, adminId,
+			fmt.Sprintf("Роль. Удаление неизвестных разрешений роли %s. \n %s", updated.Name, roleDiff(oldRole, *updated)),
+			entity.EventRoleChanged,
+		)
+	}
+
+	return reports, nil
+}
+
+// Versions returns versions of the role from the latest, versions of deleted roles are kept
+func (u Role) Versions(ctx context.Context, req domain.RoleVersionsRequest) ([]domain.RoleVersion, error) {
+	versions, err := u.versionRepo.GetVersions(ctx, req.RoleId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get role versions")
+	}
+
+	result := make([]domain.RoleVersion, 0, len(versions))
+	for _, version := range versions {
+		result = append(result, toDomainRoleVersion(version))
+	}
+	return result, nil
+}
+
+// VersionDiff returns changes of the role from FromVersion to ToVersion
+func (u Role) VersionDiff(ctx context.Context, req domain.RoleVersionDiffRequest) (*domain.RoleVersionDiff, error) {
+	from, err := u.versionRepo.GetVersion(ctx, req.RoleId, req.FromVersion)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get role version %d", req.FromVersion)
+	}
+	to, err := u.versionRepo.GetVersion(ctx, req.RoleId, req.ToVersion)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get role version %d", req.ToVersion)
+	}
+
+	fromSnapshot := toDomainRoleSnapshot(from.Snapshot)
+	toSnapshot := toDomainRoleSnapshot(to.Snapshot)
+	return &domain.RoleVersionDiff{
+		RoleId:      req.RoleId,
+		FromVersion: req.FromVersion,
+		ToVersion:   req.ToVersion,
+		AddedPermissions: slices.DeleteFunc(slices.Clone(toSnapshot.Permissions), func(permission string) bool {
+			return slices.Contains(fromSnapshot.Permissions, permission)
+		}),
+		RemovedPermissions: slices.DeleteFunc(slices.Clone(fromSnapshot.Permissions), func(permission string) bool {
+			return slices.Contains(toSnapshot.Permissions, permission)
+		}),
+		Changes: snapshotChanges(fromSnapshot, toSnapshot),
+	}, nil
+}
+
+// Rollback restores the state of the role from the version, the role must exist.
+// Rollback is saved for approval as role_rollback, if role_update requires it
+func (u Role) Rollback(ctx context.Context, req domain.RollbackRoleRequest, adminId int64) (*domain.Role, error) {
+	version, err := u.versionRepo.GetVersion(ctx, req.RoleId, req.Version)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get role version %d", req.Version)
+	}
+
+	snapshot := toDomainRoleSnapshot(version.Snapshot)
+	updateReq := domain.UpdateRoleRequest{
+		Id:             req.RoleId,
+		Name:           snapshot.Name,
+		ExternalGroup:  snapshot.ExternalGroup,
+		ChangeMessage:  fmt.Sprintf("Откат к версии %d. %s", req.Version, req.ChangeMessage),
+		Permissions:    snapshot.Permissions,
+		AllowedCidrs:   snapshot.AllowedCidrs,
+		TimeWindows:    snapshot.TimeWindows,
+		ApproverIds:    snapshot.ApproverIds,
+		ParentIds:      snapshot.ParentIds,
+		ResourceScopes: snapshot.ResourceScopes,
+	}
+	if u.changeGate.Required(entity.ChangeRoleRollback) {
+		return nil, u.submitUpdate(ctx, entity.ChangeRoleRollback, updateReq, adminId)
+	}
+	return u.update(ctx, updateReq, adminId, entity.RoleVersionRollback)
+}
+
+// checkPermissions returns domain.ErrInvalidPermission, if any permission is not a valid grant or deny pattern
+// or resource scope is set for deny entry, and domain.UnknownPermissionsError, if permissions do not match the catalog
+func (u Role) checkPermissions(ctx context.Context, permissions []string, scopes []domain.ResourceScope) error {
+	for _, permission := range permissions {
+		err := permission_match.Validate(permission)
+		if err != nil {
+			return errors.WithMessage(domain.ErrInvalidPermission, err.Error())
+		}
+	}
+	for _, scope := range scopes {
+		err := permission_match.Validate(scope.Permission)
+		if err != nil {
+			return errors.WithMessage(domain.ErrInvalidPermission, err.Error())
+		}
+		if permission_match.IsDeny(scope.Permission) {
+			return errors.WithMessagef(domain.ErrInvalidPermission, "resource scope of deny entry '%s'", scope.Permission)
+		}
+	}
+
+	entries := slices.Clone(permissions)
+	for _, scope := range scopes {
+		entries = append(entries, scope.Permission)
+	}
+	keys, err := catalogKeys(ctx, u.catalog)
+	if err != nil {
+		return err
+	}
+	unknown := permission_match.Unknown(entries, keys)
+	if len(keys) > 0 && len(unknown) > 0 {
+		return domain.UnknownPermissionsError{Keys: unknown}
+	}
 	return nil
 }
 
-func (u Role) toDomain(role entity.Role) domain.Role {
+// catalogKeys returns keys of the permission catalog, permissions are not checked if the catalog is empty
+func catalogKeys(ctx context.Context, catalog permissionCatalog) ([]string, error) {
+	permissions, err := catalog.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get permission catalog")
+	}
+	keys := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		keys = append(keys, permission.Key)
+	}
+	return keys, nil
+}
+
+// checkParents returns domain.ErrInvalidRoleParent, if parents do not exist or the role becomes its own ancestor
+func (u Role) checkParents(ctx context.Context, roleId int, parentIds []int) (role_hierarchy.Hierarchy, error) {
+	roles, err := u.roleRepo.All(ctx)
+	if err != nil {
+		return role_hierarchy.Hierarchy{}, errors.WithMessage(err, "get all roles")
+	}
+
+	for _, parentId := range parentIds {
+		exists := slices.ContainsFunc(roles, func(role entity.Role) bool {
+			return role.Id == parentId
+		})
+		if !exists {
+			return role_hierarchy.Hierarchy{}, errors.WithMessagef(domain.ErrInvalidRoleParent, "parent role %d not found", parentId)
+		}
+	}
+
+	hierarchy := role_hierarchy.New(roles)
+	if roleId != 0 && hierarchy.HasCycle(roleId, parentIds) {
+		return role_hierarchy.Hierarchy{}, errors.WithMessage(domain.ErrInvalidRoleParent, "role hierarchy cycle")
+	}
+	return hierarchy, nil
+}
+
+func (u Role) toDomain(role entity.Role, hierarchy role_hierarchy.Hierarchy) domain.Role {
 	return domain.Role{
-		Id:            role.Id,
-		Name:          role.Name,
-		ExternalGroup: role.ExternalGroup,
-		Permissions:   role.Permissions,
-		Immutable:     role.Immutable,
-		Exclusive:     role.Exclusive,
-		CreatedAt:     role.CreatedAt,
-		UpdatedAt:     role.UpdatedAt,
+		Id:                   role.Id,
+		Name:                 role.Name,
+		ExternalGroup:        role.ExternalGroup,
+		Permissions:          role.Permissions,
+		InheritedPermissions: hierarchy.InheritedPermissions(role),
+		AllowedCidrs:         role.AllowedCidrs,
+		TimeWindows:          toDomainTimeWindows(role.TimeWindows),
+		ApproverIds:          role.ApproverIds,
+		ParentIds:            idsOrEmpty(role.ParentIds),
+		ResourceScopes:       toDomainResourceScopes(role.ResourceScopes),
+		Immutable:            role.Immutable,
+		Exclusive:            role.Exclusive,
+		ConfigManaged:        role.ConfigManaged,
+		Version:              role.Version,
+		CreatedAt:            role.CreatedAt,
+		UpdatedAt:            role.UpdatedAt,
+	}
+}
+
+func (u Role) toEntity(req domain.UpdateRoleRequest) entity.Role {
+	return entity.Role{
+		Id:             req.Id,
+		Name:           req.Name,
+		ExternalGroup:  req.ExternalGroup,
+		Permissions:    req.Permissions,
+		AllowedCidrs:   req.AllowedCidrs,
+		TimeWindows:    toEntityTimeWindows(req.TimeWindows),
+		ApproverIds:    req.ApproverIds,
+		ParentIds:      req.ParentIds,
+		ResourceScopes: toEntityResourceScopes(req.ResourceScopes),
+		Version:        req.Version,
+	}
+}
+
+// versionConflict returns domain.VersionConflictError with the current state of the role
+func (u Role) versionConflict(ctx context.Context, roleId int) error {
+	roles, err := u.roleRepo.All(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "get all roles")
+	}
+	i := slices.IndexFunc(roles, func(role entity.Role) bool {
+		return role.Id == roleId
+	})
+	if i < 0 {
+		return errors.WithMessagef(domain.ErrNotFound, "role %d", roleId)
+	}
+	return domain.VersionConflictError{Current: u.toDomain(roles[i], role_hierarchy.New(roles))}
+}
+
+func roleDiff(oldRole entity.Role, role entity.Role) string {
+	oldPermissions := stringsOrEmpty(slices.Sorted(slices.Values(oldRole.Permissions)))
+	permissions := stringsOrEmpty(slices.Sorted(slices.Values(role.Permissions)))
+	return diffToString(map[string]any{
+		"Название":          oldRole.Name,
+		"Группа ЕСК":        oldRole.ExternalGroup,
+		"Разрешения":        oldPermissions,
+		"Разрешенные сети":  stringsOrEmpty(oldRole.AllowedCidrs),
+		"Интервалы времени": timeWindowsToStrings(oldRole.TimeWindows),
+		"Согласующие (ID)":  idsOrEmpty(oldRole.ApproverIds),
+		"Родительские роли": idsOrEmpty(oldRole.ParentIds),
+		"Ресурсы":           resourceScopesToStrings(oldRole.ResourceScopes),
+	}, map[string]any{
+		"Название":          role.Name,
+		"Группа ЕСК":        role.ExternalGroup,
+		"Разрешения":        permissions,
+		"Разрешенные сети":  stringsOrEmpty(role.AllowedCidrs),
+		"Интервалы времени": timeWindowsToStrings(role.TimeWindows),
+		"Согласующие (ID)":  idsOrEmpty(role.ApproverIds),
+		"Родительские роли": idsOrEmpty(role.ParentIds),
+		"Ресурсы":           resourceScopesToStrings(role.ResourceScopes),
+	})
+}
+
+func stringsOrEmpty(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func idsOrEmpty[T int | int64](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
+
+func toEntityTimeWindows(windows []domain.TimeWindow) entity.TimeWindowList {
+	result := make(entity.TimeWindowList, 0, len(windows))
+	for _, window := range windows {
+		result = append(result, entity.TimeWindow(window))
+	}
+	return result
+}
+
+func toDomainTimeWindows(windows entity.TimeWindowList) []domain.TimeWindow {
+	result := make([]domain.TimeWindow, 0, len(windows))
+	for _, window := range windows {
+		result = append(result, domain.TimeWindow(window))
+	}
+	return result
+}
+
+func timeWindowsToStrings(windows entity.TimeWindowList) []string {
+	result := make([]string, 0, len(windows))
+	for _, window := range windows {
+		weekdays := "ежедневно"
+		if len(window.Weekdays) > 0 {
+			weekdays = fmt.Sprintf("дни %v", window.Weekdays)
+		}
+		result = append(result, fmt.Sprintf("%s %s-%s", weekdays, window.From, window.To))
+	}
+	return result
+}
+
+func toEntityResourceScopes(scopes []domain.ResourceScope) entity.ResourceScopeList {
+	result := make(entity.ResourceScopeList, 0, len(scopes))
+	for _, scope := range scopes {
+		result = append(result, entity.ResourceScope(scope))
+	}
+	return result
+}
+
+func toDomainResourceScopes(scopes entity.ResourceScopeList) []domain.ResourceScope {
+	result := make([]domain.ResourceScope, 0, len(scopes))
+	for _, scope := range scopes {
+		result = append(result, domain.ResourceScope(scope))
+	}
+	return result
+}
+
+func resourceScopesToStrings(scopes entity.ResourceScopeList) []string {
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		result = append(result, fmt.Sprintf("%s: %s %v", scope.Permission, scope.ResourceType, scope.ResourceIds))
+	}
+	return result
+}
+
+func roleEntries(role entity.Role) []string {
+	entries := slices.Clone(role.Permissions)
+	for _, scope := range role.ResourceScopes {
+		entries = append(entries, scope.Permission)
+	}
+	return entries
+}
+
+func saveVersion(ctx context.Context, tx RoleTransaction, operation string, role entity.Role, changeMessage string, adminId int64) error {
+	var changedBy *int64
+	if adminId != 0 {
+		changedBy = &adminId
+	}
+	_, err := tx.InsertVersion(ctx, entity.RoleVersion{
+		RoleId:    role.Id,
+		Operation: operation,
+		Snapshot: entity.RoleSnapshot{
+			Name:           role.Name,
+			ExternalGroup:  role.ExternalGroup,
+			Permissions:    role.Permissions,
+			AllowedCidrs:   role.AllowedCidrs,
+			TimeWindows:    role.TimeWindows,
+			ApproverIds:    role.ApproverIds,
+			ParentIds:      role.ParentIds,
+			ResourceScopes: role.ResourceScopes,
+		},
+		ChangedBy:     changedBy,
+		ChangeMessage: changeMessage,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "insert role version")
+	}
+	return nil
+}
+
+func toDomainRoleVersion(version entity.RoleVersion) domain.RoleVersion {
+	return domain.RoleVersion{
+		Id:            version.Id,
+		RoleId:        version.RoleId,
+		Version:       version.Version,
+		Operation:     version.Operation,
+		Snapshot:      toDomainRoleSnapshot(version.Snapshot),
+		ChangedBy:     version.ChangedBy,
+		ChangeMessage: version.ChangeMessage,
+		CreatedAt:     version.CreatedAt,
 	}
 }
+
+func toDomainRoleSnapshot(snapshot entity.RoleSnapshot) domain.RoleSnapshot {
+	return domain.RoleSnapshot{
+		Name:           snapshot.Name,
+		ExternalGroup:  snapshot.ExternalGroup,
+		Permissions:    stringsOrEmpty(snapshot.Permissions),
+		AllowedCidrs:   stringsOrEmpty(snapshot.AllowedCidrs),
+		TimeWindows:    toDomainTimeWindows(snapshot.TimeWindows),
+		ApproverIds:    idsOrEmpty(snapshot.ApproverIds),
+		ParentIds:      idsOrEmpty(snapshot.ParentIds),
+		ResourceScopes: toDomainResourceScopes(snapshot.ResourceScopes),
+	}
+}
+
+func snapshotChanges(from domain.RoleSnapshot, to domain.RoleSnapshot) []domain.RoleFieldChange {
+	fields := []domain.RoleFieldChange{
+		{Field: "name", From: from.Name, To: to.Name},
+		{Field: "externalGroup", From: from.ExternalGroup, To: to.ExternalGroup},
+		{Field: "permissions", From: from.Permissions, To: to.Permissions},
+		{Field: "allowedCidrs", From: from.AllowedCidrs, To: to.AllowedCidrs},
+		{Field: "timeWindows", From: from.TimeWindows, To: to.TimeWindows},
+		{Field: "approverIds", From: from.ApproverIds, To: to.ApproverIds},
+		{Field: "parentIds", From: from.ParentIds, To: to.ParentIds},
+		{Field: "resourceScopes", From: from.ResourceScopes, To: to.ResourceScopes},
+	}
+	return slices.DeleteFunc(fields, func(field domain.RoleFieldChange) bool {
+		return reflect.DeepEqual(field.From, field.To)
+	})
+}
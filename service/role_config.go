@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/log"
+	"msp-admin-service/conf"
+	"msp-admin-service/entity"
+	"msp-admin-service/service/permission_match"
+)
+
+const (
+	roleConfigAdminId       = 0
+	roleConfigChangeMessage = "Синхронизация с конфигурацией"
+)
+
+type RoleConfigTransaction interface {
+	RoleTransaction
+	Lock(ctx context.Context) error
+	All(ctx context.Context) ([]entity.Role, error)
+	SetConfigManaged(ctx context.Context, id int, configManaged bool) error
+}
+
+type RoleConfigTransactionRunner interface {
+	RoleConfigTransaction(ctx context.Context, tx func(ctx context.Context, tx RoleConfigTransaction) error) error
+}
+
+// roleConfigChange is the change of the role by reconciliation for logging and audit
+type roleConfigChange struct {
+	roleName  string
+	operation string
+	message   string
+}
+
+// RoleConfig reconciles roles of the remote config into the roles table
+type RoleConfig struct {
+	roles        []conf.Role
+	txRunner     RoleConfigTransactionRunner
+	auditService auditService
+	logger       log.Logger
+}
+
+func NewRoleConfig(
+	roles []conf.Role,
+	txRunner RoleConfigTransactionRunner,
+	auditService auditService,
+	logger log.Logger,
+) RoleConfig {
+	return RoleConfig{
+		roles:        roles,
+		txRunner:     txRunner,
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+// Reconcile creates missing roles of the config, updates permissions and external groups of existing ones
+// and marks them as config managed. Roles removed from the config are unmarked and stay unchanged,
+// roles with invalid permissions or duplicate names are skipped
+func (s RoleConfig) Reconcile(ctx context.Context) error {
+	roles := s.validRoles(ctx)
+	changes := make([]roleConfigChange, 0)
+	err := s.txRunner.RoleConfigTransaction(ctx, func(ctx context.Context, tx RoleConfigTransaction) error {
+		err := tx.Lock(ctx)
+		if err != nil {
+			return errors.WithMessage(err, "lock roles")
+		}
+		existing, err := tx.All(ctx)
+		if err != nil {
+			return errors.WithMessage(err, "get all roles")
+		}
+
+		for _, role := range roles {
+			i := slices.IndexFunc(existing, func(oldRole entity.Role) bool {
+				return oldRole.Name == role.Name
+			})
+			var change *roleConfigChange
+			if i == -1 {
+				change, err = s.createRole(ctx, tx, role)
+			} else {
+				change, err = s.updateRole(ctx, tx, existing[i], role)
+			}
+			if err != nil {
+				return errors.WithMessagef(err, "reconcile role '%s'", role.Name)
+			}
+			if change != nil {
+				changes = append(changes, *change)
+			}
+		}
+
+		for _, oldRole := range existing {
+			released := oldRole.ConfigManaged && !slices.ContainsFunc(roles, func(role conf.Role) bool {
+				return role.Name == oldRole.Name
+			})
+			if !released {
+				continue
+			}
+			err = tx.SetConfigManaged(ctx, oldRole.Id, false)
+			if err != nil {
+				return errors.WithMessagef(err, "release role '%s'", oldRole.Name)
+			}
+			changes = append(changes, roleConfigChange{
+				roleName:  oldRole.Name,
+				operation: "release",
+				message:   fmt.Sprintf("Роль. Роль %s удалена из конфигурации и больше не управляется ею", oldRole.Name),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "reconcile config roles")
+	}
+
+	for _, change := range changes {
+		s.logger.Info(ctx, "config roles: role is reconciled",
+			log.String("role", change.roleName), log.String("operation", change.operation),
+		)
+		s.auditService.SaveAuditAsync(ctx, roleConfigAdminId, change.message, entity.EventRoleChanged)
+	}
+	s.logger.Info(ctx, "config roles: reconciliation is completed", log.Int("roles", len(roles)), log.Int("changes", len(changes)))
+	return nil
+}
+
+func (s RoleConfig) createRole(ctx context.Context, tx RoleConfigTransaction, role conf.Role) (*roleConfigChange, error) {
+	created, err := tx.InsertRole(ctx, entity.Role{
+		Name:          role.Name,
+		ExternalGroup: role.ExternalGroup,
+		Permissions:   stringsOrEmpty(role.Permissions),
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "insert role")
+	}
+	err = tx.SetConfigManaged(ctx, created.Id, true)
+	if err != nil {
+		return nil, errors.WithMessage(err, "mark role")
+	}
+	err = saveVersion(ctx, tx, entity.RoleVersionCreate, *created, roleConfigChangeMessage, roleConfigAdminId)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(created.Permissions)
+	return &roleConfigChange{
+		roleName:  role.Name,
+		operation: "create",
+		message:   fmt.Sprintf("Роль. Создание роли %s из конфигурации. \n %s", role.Name, roleDiff(entity.Role{}, *created)),
+	}, nil
+}
+
+// updateRole returns nil, if the role is already marked and has permissions and external group of the config
+func (s RoleConfig) updateRole(
+	ctx context.Context,
+	tx RoleConfigTransaction,
+	oldRole entity.Role,
+	role conf.Role,
+) (*roleConfigChange, error) {
+	changed := oldRole.ExternalGroup != role.ExternalGroup ||
+		!slices.Equal(slices.Sorted(slices.Values(oldRole.Permissions)), slices.Sorted(slices.Values(role.Permissions)))
+	if !oldRole.ConfigManaged {
+		err := tx.SetConfigManaged(ctx, oldRole.Id, true)
+		if err != nil {
+			return nil, errors.WithMessage(err, "mark role")
+		}
+	}
+	if !changed {
+		if oldRole.ConfigManaged {
+			return nil, nil //nolint:nilnil
+		}
+		return &roleConfigChange{
+			roleName:  role.Name,
+			operation: "mark",
+			message:   fmt.Sprintf("Роль. Роль %s управляется конфигурацией", role.Name),
+		}, nil
+	}
+
+	newRole := oldRole
+	newRole.ExternalGroup = role.ExternalGroup
+	newRole.Permissions = stringsOrEmpty(role.Permissions)
+	updated, err := tx.Update(ctx, newRole)
+	if err != nil {
+		return nil, errors.WithMessage(err, "update role")
+	}
+	err = saveVersion(ctx, tx, entity.RoleVersionUpdate, *updated, roleConfigChangeMessage, roleConfigAdminId)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(updated.Permissions)
+	return &roleConfigChange{
+		roleName:  role.Name,
+		operation: "update",
+		message:   fmt.Sprintf("Роль. Изменение роли %s из конфигурации. \n %s", role.Name, roleDiff(oldRole, *updated)),
+	}, nil
+}
+
+// validRoles returns roles of the config without duplicates and roles with invalid permissions
+func (s RoleConfig) validRoles(ctx context.Context) []conf.Role {
+	result := make([]conf.Role, 0, len(s.roles))
+	for _, role := range s.roles {
+		duplicate := slices.ContainsFunc(result, func(validRole conf.Role) bool {
+			return validRole.Name == role.Name
+		})
+		if duplicate {
+			s.logger.Error(ctx, "config roles: duplicate role is skipped", log.String("role", role.Name))
+			continue
+		}
+		err := validatePermissions(role.Permissions)
+		if err != nil {
+			s.logger.Error(ctx, "config roles: role with invalid permissions is skipped",
+				log.String("role", role.Name), log.String("error", err.Error()),
+			)
+			continue
+		}
+		result = append(result, role)
+	}
+	return result
+}
+
+func validatePermissions(permissions []string) error {
+	for _, permission := range permissions {
+		err := permission_match.Validate(permission)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package role_hierarchy
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	"msp-admin-service/entity"
+)
+
+type RoleRepo interface {
+	GetRolesWithParents(ctx context.Context, ids []int) ([]entity.Role, error)
+}
+
+// Hierarchy resolves permissions inherited from parent roles, unknown parents are ignored
+type Hierarchy struct {
+	rolesById map[int]entity.Role
+}
+
+func New(roles []entity.Role) Hierarchy {
+	rolesById := make(map[int]entity.Role, len(roles))
+	for _, role := range roles {
+		rolesById[role.Id] = role
+	}
+	return Hierarchy{
+		rolesById: rolesById,
+	}
+}
+
+// InheritedPermissions returns sorted permissions of all ancestors, which the role does not have itself
+func (h Hierarchy) InheritedPermissions(role entity.Role) []string {
+	result := make([]string, 0)
+	for _, parent := range h.ancestors(role.ParentIds) {
+		for _, permission := range parent.Permissions {
+			if !slices.Contains(role.Permissions, permission) && !slices.Contains(result, permission) {
+				result = append(result, permission)
+			}
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+// WithInherited returns the role with own and inherited permissions,
+// resource scopes of ancestors are inherited for permissions, which the role does not have itself
+func (h Hierarchy) WithInherited(role entity.Role) entity.Role {
+	inherited := h.InheritedPermissions(role)
+	if len(inherited) == 0 {
+		return role
+	}
+	scopes := slices.Clone(role.ResourceScopes)
+	for _, parent := range h.ancestors(role.ParentIds) {
+		for _, scope := range parent.ResourceScopes {
+			if !slices.Contains(role.Permissions, scope.Permission) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	role.Permissions = append(slices.Clone(role.Permissions), inherited...)
+	role.ResourceScopes = scopes
+	return role
+}
+
+// HasCycle reports whether the role becomes its own ancestor with parentIds
+func (h Hierarchy) HasCycle(roleId int, parentIds []int) bool {
+	if slices.Contains(parentIds, roleId) {
+		return true
+	}
+	return slices.ContainsFunc(h.ancestors(parentIds), func(role entity.Role) bool {
+		return role.Id == roleId
+	})
+}
+
+func (h Hierarchy) ancestors(parentIds []int) []entity.Role {
+	result := make([]entity.Role, 0)
+	visited := make(map[int]bool)
+	queue := slices.Clone(parentIds)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		role, ok := h.rolesById[id]
+		if !ok {
+			continue
+		}
+		result = append(result, role)
+		queue = append(queue, role.ParentIds...)
+	}
+	return result
+}
+
+// Resolver loads ancestors of roles to resolve effective permissions
+type Resolver struct {
+	roleRepo RoleRepo
+}
+
+func NewResolver(roleRepo RoleRepo) Resolver {
+	return Resolver{
+		roleRepo: roleRepo,
+	}
+}
+
+// Resolve returns roles with own and inherited permissions
+func (r Resolver) Resolve(ctx context.Context, roles []entity.Role) ([]entity.Role, error) {
+	ids := make([]int, 0, len(roles))
+	for _, role := range roles {
+		if len(role.ParentIds) > 0 {
+			ids = append(ids, role.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return roles, nil
+	}
+
+	withParents, err := r.roleRepo.GetRolesWithParents(ctx, ids)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get roles with parents")
+	}
+
+	hierarchy := New(withParents)
+	result := make([]entity.Role, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, hierarchy.WithInherited(role))
+	}
+	return result, nil
+}
@@ -0,0 +1,502 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/json"
+	"github.com/txix-open/isp-kit/validator"
+	"gopkg.in/yaml.v3"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+	"msp-admin-service/service/role_hierarchy"
+)
+
+// rbacPlan is the list of changes to apply the document, roles are the document and existing roles by name
+type rbacPlan struct {
+	changes  []domain.RbacRoleChange
+	roles    map[string]domain.RbacRole
+	existing map[string]entity.Role
+}
+
+// rbacAppliedChange is the applied change of the role for audit, links are revoked from users by deletion
+type rbacAppliedChange struct {
+	operation string
+	oldRole   entity.Role
+	role      entity.Role
+	links     []entity.UserRole
+}
+
+// Export returns all roles as the document, parents are referenced by names
+func (u Role) Export(ctx context.Context, req domain.RbacExportRequest) (*domain.RbacExportResponse, error) {
+	roles, err := u.roleRepo.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all roles")
+	}
+
+	namesById := make(map[int]string, len(roles))
+	for _, role := range roles {
+		namesById[role.Id] = role.Name
+	}
+	doc := domain.RbacDocument{
+		Version:    domain.RbacDocumentVersion,
+		ExportedAt: time.Now().UTC(),
+		Roles:      make([]domain.RbacRole, 0, len(roles)),
+	}
+	for _, role := range roles {
+		doc.Roles = append(doc.Roles, toRbacRole(role, namesById))
+	}
+
+	format := rbacFormat(req.Format)
+	content, err := marshalRbacDocument(doc, format)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal rbac document")
+	}
+	return &domain.RbacExportResponse{
+		Format:  format,
+		Content: string(content),
+	}, nil
+}
+
+// Import applies the document immediately or saves it for approval, if role_import requires it.
+// Roles are matched by name, roles absent in the document are deleted, changes of immutable roles
+// and deletion of roles held by users require Force, config managed roles can not be changed
+func (u Role) Import(ctx context.Context, req domain.RbacImportRequest, adminId int64) (*domain.RbacImportResponse, error) {
+	if req.DryRun || !u.changeGate.Required(entity.ChangeRoleImport) {
+		return u.importRbac(ctx, req, adminId)
+	}
+
+	plan, err := u.planImport(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.changes) == 0 {
+		return &domain.RbacImportResponse{Changes: plan.changes}, nil
+	}
+	diff := fmt.Sprintf("Импорт конфигурации ролей. Причина: %s.\n %s", req.ChangeMessage, rbacChangesToString(plan.changes))
+	return nil, u.changeGate.Submit(ctx, entity.ChangeRoleImport, req, diff, adminId)
+}
+
+// importRbac applies the document in one transaction, versions of changed roles are saved
+func (u Role) importRbac(ctx context.Context, req domain.RbacImportRequest, adminId int64) (*domain.RbacImportResponse, error) {
+	plan, err := u.planImport(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	result := &domain.RbacImportResponse{
+		DryRun:  req.DryRun,
+		Changes: plan.changes,
+	}
+	if req.DryRun || len(plan.changes) == 0 {
+		return result, nil
+	}
+
+	changeMessage := fmt.Sprintf("Импорт конфигурации ролей. %s", req.ChangeMessage)
+	applied := make([]rbacAppliedChange, 0, len(plan.changes))
+	err = u.txRunner.RoleTransaction(ctx, func(ctx context.Context, tx RoleTransaction) error {
+		applied, err = applyRbacPlan(ctx, tx, *plan, changeMessage, adminId)
+		return err
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "import roles")
+	}
+
+	for _, change := range applied {
+		var message string
+		switch change.operation {
+		case entity.RoleVersionCreate:
+			message = fmt.Sprintf("Роль. Импорт конфигурации ролей, создание роли %s. Причина: %s. \n %s",
+				change.role.Name, req.ChangeMessage, roleDiff(entity.Role{}, change.role))
+		case entity.RoleVersionUpdate:
+			message = fmt.Sprintf("Роль. Импорт конфигурации ролей, изменение роли %s. Причина: %s. \n %s",
+				change.role.Name, req.ChangeMessage, roleDiff(change.oldRole, change.role))
+		default:
+			message = fmt.Sprintf("Роль. Импорт конфигурации ролей, удаление роли %s. ID: %d. Причина: %s",
+				change.oldRole.Name, change.oldRole.Id, req.ChangeMessage)
+			if len(change.links) > 0 {
+				message = fmt.Sprintf("%s. Роль отозвана у пользователей %v", message, linkUserIds(change.links))
+			}
+		}
+		u.auditService.SaveAuditAsync(ctx, adminId, message, entity.EventRoleChanged)
+	}
+
+	return result, nil
+}
+
+// planImport returns changes to apply the document, changed roles are checked unless DryRun is set
+func (u Role) planImport(ctx context.Context, req domain.RbacImportRequest) (*rbacPlan, error) {
+	doc, err := unmarshalRbacDocument(req.Content, rbacFormat(req.Format))
+	if err != nil {
+		return nil, err
+	}
+	err = checkRbacDocument(*doc)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range doc.Roles {
+		err = u.checkPermissions(ctx, role.Permissions, role.ResourceScopes)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "role '%s'", role.Name)
+		}
+	}
+
+	roles, err := u.roleRepo.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all roles")
+	}
+	plan := rbacPlan{
+		changes:  make([]domain.RbacRoleChange, 0),
+		roles:    make(map[string]domain.RbacRole, len(doc.Roles)),
+		existing: make(map[string]entity.Role, len(roles)),
+	}
+	namesById := make(map[int]string, len(roles))
+	for _, role := range roles {
+		plan.existing[role.Name] = role
+		namesById[role.Id] = role.Name
+	}
+
+	for _, role := range doc.Roles {
+		role = normalizeRbacRole(role)
+		plan.roles[role.Name] = role
+		oldRole, exists := plan.existing[role.Name]
+		if !exists {
+			plan.changes = append(plan.changes, domain.RbacRoleChange{
+				RoleName:  role.Name,
+				Operation: entity.RoleVersionCreate,
+				Fields:    rbacRoleChanges(normalizeRbacRole(domain.RbacRole{}), role),
+			})
+			continue
+		}
+		fields := rbacRoleChanges(toRbacRole(oldRole, namesById), role)
+		if len(fields) > 0 {
+			plan.changes = append(plan.changes, domain.RbacRoleChange{
+				RoleName:  role.Name,
+				Operation: entity.RoleVersionUpdate,
+				Immutable: oldRole.Immutable,
+				Fields:    fields,
+			})
+		}
+	}
+	for _, oldRole := range roles {
+		if _, exists := plan.roles[oldRole.Name]; exists {
+			continue
+		}
+		change, err := u.deleteRbacChange(ctx, oldRole, namesById)
+		if err != nil {
+			return nil, err
+		}
+		plan.changes = append(plan.changes, *change)
+	}
+
+	if !req.DryRun {
+		err = plan.checkRoles(req.Force)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &plan, nil
+}
+
+// deleteRbacChange returns deletion of the role with users, who lose it
+func (u Role) deleteRbacChange(ctx context.Context, oldRole entity.Role, namesById map[int]string) (*domain.RbacRoleChange, error) {
+	users, err := u.userRepo.GetUsersByRoleId(ctx, oldRole.Id)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get users of role '%s'", oldRole.Name)
+	}
+	change := domain.RbacRoleChange{
+		RoleName:  oldRole.Name,
+		Operation: entity.RoleVersionDelete,
+		Immutable: oldRole.Immutable,
+		Fields:    rbacRoleChanges(toRbacRole(oldRole, namesById), normalizeRbacRole(domain.RbacRole{})),
+	}
+	if len(users) > 0 {
+		change.Users = toRoleHolders(users)
+	}
+	return &change, nil
+}
+
+// checkRoles returns domain.ErrConfigManagedRole, if config managed roles are changed,
+// domain.ErrImmutableRole, if immutable roles are changed without force,
+// and domain.ErrRoleInUse, if roles held by users are deleted without force
+func (p rbacPlan) checkRoles(force bool) error {
+	configManagedNames := make([]string, 0)
+	immutableNames := make([]string, 0)
+	inUseNames := make([]string, 0)
+	for _, change := range p.changes {
+		if p.existing[change.RoleName].ConfigManaged {
+			configManagedNames = append(configManagedNames, change.RoleName)
+		}
+		if change.Immutable && !force {
+			immutableNames = append(immutableNames, change.RoleName)
+		}
+		if len(change.Users) > 0 && !force {
+			inUseNames = append(inUseNames, change.RoleName)
+		}
+	}
+	if len(configManagedNames) > 0 {
+		return errors.WithMessagef(domain.ErrConfigManagedRole, "roles %s", strings.Join(configManagedNames, ", "))
+	}
+	if len(immutableNames) > 0 {
+		return errors.WithMessagef(domain.ErrImmutableRole, "roles %s", strings.Join(immutableNames, ", "))
+	}
+	if len(inUseNames) > 0 {
+		return errors.WithMessagef(domain.ErrRoleInUse, "roles %s", strings.Join(inUseNames, ", "))
+	}
+	return nil
+}
+
+// applyRbacPlan revokes and deletes roles, then creates roles and then sets fields and parents of created and updated roles
+func applyRbacPlan(
+	ctx context.Context,
+	tx RoleTransaction,
+	plan rbacPlan,
+	changeMessage string,
+	adminId int64,
+) ([]rbacAppliedChange, error) {
+	result := make([]rbacAppliedChange, 0, len(plan.changes))
+	idsByName := make(map[string]int, len(plan.existing))
+	for name, role := range plan.existing {
+		idsByName[name] = role.Id
+	}
+
+	for _, change := range plan.changes {
+		oldRole := plan.existing[change.RoleName]
+		switch change.Operation {
+		case entity.RoleVersionDelete:
+			links, err := tx.DeleteRoleLinks(ctx, oldRole.Id)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "delete links of role '%s'", change.RoleName)
+			}
+			err = tx.Delete(ctx, oldRole.Id)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "delete role '%s'", change.RoleName)
+			}
+			err = saveVersion(ctx, tx, entity.RoleVersionDelete, oldRole, changeMessage, adminId)
+			if err != nil {
+				return nil, err
+			}
+			delete(idsByName, change.RoleName)
+			result = append(result, rbacAppliedChange{operation: change.Operation, oldRole: oldRole, links: links})
+		case entity.RoleVersionCreate:
+			role, err := tx.InsertRole(ctx, entity.Role{Name: change.RoleName, Permissions: entity.PermList{}})
+			if err != nil {
+				return nil, errors.WithMessagef(err, "insert role '%s'", change.RoleName)
+			}
+			idsByName[change.RoleName] = role.Id
+		}
+	}
+
+	for _, change := range plan.changes {
+		if change.Operation == entity.RoleVersionDelete {
+			continue
+		}
+		oldRole := plan.existing[change.RoleName]
+		role := fromRbacRole(oldRole, plan.roles[change.RoleName], idsByName)
+		updated, err := tx.Update(ctx, role)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "update role '%s'", change.RoleName)
+		}
+		err = tx.UpdateFlags(ctx, updated.Id, role.Immutable, role.Exclusive)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "update flags of role '%s'", change.RoleName)
+		}
+		err = saveVersion(ctx, tx, change.Operation, *updated, changeMessage, adminId)
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(updated.Permissions)
+		result = append(result, rbacAppliedChange{operation: change.Operation, oldRole: oldRole, role: *updated})
+	}
+
+	return result, nil
+}
+
+// checkRbacDocument returns domain.ErrInvalidRbacDocument, if the document is invalid or has duplicate roles,
+// and domain.ErrInvalidRoleParent, if parents are not in the document or make a cycle
+func checkRbacDocument(doc domain.RbacDocument) error {
+	if doc.Version != domain.RbacDocumentVersion {
+		return errors.WithMessagef(domain.ErrInvalidRbacDocument, "unsupported version %d", doc.Version)
+	}
+	err := validator.Default.ValidateToError(doc)
+	if err != nil {
+		return errors.WithMessage(domain.ErrInvalidRbacDocument, err.Error())
+	}
+
+	idsByName := make(map[string]int, len(doc.Roles))
+	for i, role := range doc.Roles {
+		if _, exists := idsByName[role.Name]; exists {
+			return errors.WithMessagef(domain.ErrInvalidRbacDocument, "duplicate role '%s'", role.Name)
+		}
+		idsByName[role.Name] = i + 1
+	}
+
+	roles := make([]entity.Role, 0, len(doc.Roles))
+	for _, role := range doc.Roles {
+		parentIds := make(entity.RoleIdList, 0, len(role.ParentNames))
+		for _, parentName := range role.ParentNames {
+			parentId, exists := idsByName[parentName]
+			if !exists {
+				return errors.WithMessagef(domain.ErrInvalidRoleParent, "parent role '%s' of role '%s' not found", parentName, role.Name)
+			}
+			parentIds = append(parentIds, parentId)
+		}
+		roles = append(roles, entity.Role{Id: idsByName[role.Name], ParentIds: parentIds})
+	}
+	hierarchy := role_hierarchy.New(roles)
+	for _, role := range roles {
+		if hierarchy.HasCycle(role.Id, role.ParentIds) {
+			return errors.WithMessagef(domain.ErrInvalidRoleParent, "role hierarchy cycle at role '%s'", doc.Roles[role.Id-1].Name)
+		}
+	}
+	return nil
+}
+
+func toRbacRole(role entity.Role, namesById map[int]string) domain.RbacRole {
+	parentNames := make([]string, 0, len(role.ParentIds))
+	for _, parentId := range role.ParentIds {
+		if name, exists := namesById[parentId]; exists {
+			parentNames = append(parentNames, name)
+		}
+	}
+	return normalizeRbacRole(domain.RbacRole{
+		Name:           role.Name,
+		ExternalGroup:  role.ExternalGroup,
+		Permissions:    role.Permissions,
+		AllowedCidrs:   role.AllowedCidrs,
+		TimeWindows:    toDomainTimeWindows(role.TimeWindows),
+		ParentNames:    parentNames,
+		ResourceScopes: toDomainResourceScopes(role.ResourceScopes),
+		Immutable:      role.Immutable,
+		Exclusive:      role.Exclusive,
+	})
+}
+
+// fromRbacRole returns oldRole with fields of the document role, approvers of oldRole are kept
+func fromRbacRole(oldRole entity.Role, role domain.RbacRole, idsByName map[string]int) entity.Role {
+	parentIds := make(entity.RoleIdList, 0, len(role.ParentNames))
+	for _, parentName := range role.ParentNames {
+		parentIds = append(parentIds, idsByName[parentName])
+	}
+	oldRole.Id = idsByName[role.Name]
+	oldRole.Name = role.Name
+	oldRole.ExternalGroup = role.ExternalGroup
+	oldRole.Permissions = role.Permissions
+	oldRole.AllowedCidrs = role.AllowedCidrs
+	oldRole.TimeWindows = toEntityTimeWindows(role.TimeWindows)
+	oldRole.ParentIds = parentIds
+	oldRole.ResourceScopes = toEntityResourceScopes(role.ResourceScopes)
+	oldRole.Immutable = role.Immutable
+	oldRole.Exclusive = role.Exclusive
+	return oldRole
+}
+
+// normalizeRbacRole sorts permissions and parents and replaces nil lists with empty ones to compare roles
+func normalizeRbacRole(role domain.RbacRole) domain.RbacRole {
+	role.Permissions = slices.Sorted(slices.Values(stringsOrEmpty(role.Permissions)))
+	role.AllowedCidrs = stringsOrEmpty(role.AllowedCidrs)
+	role.ParentNames = slices.Sorted(slices.Values(stringsOrEmpty(role.ParentNames)))
+	timeWindows := make([]domain.TimeWindow, 0, len(role.TimeWindows))
+	for _, window := range role.TimeWindows {
+		window.Weekdays = idsOrEmpty(window.Weekdays)
+		timeWindows = append(timeWindows, window)
+	}
+	role.TimeWindows = timeWindows
+	if role.ResourceScopes == nil {
+		role.ResourceScopes = []domain.ResourceScope{}
+	}
+	return role
+}
+
+func rbacRoleChanges(from domain.RbacRole, to domain.RbacRole) []domain.RoleFieldChange {
+	fields := []domain.RoleFieldChange{
+		{Field: "externalGroup", From: from.ExternalGroup, To: to.ExternalGroup},
+		{Field: "permissions", From: from.Permissions, To: to.Permissions},
+		{Field: "allowedCidrs", From: from.AllowedCidrs, To: to.AllowedCidrs},
+		{Field: "timeWindows", From: from.TimeWindows, To: to.TimeWindows},
+		{Field: "parentNames", From: from.ParentNames, To: to.ParentNames},
+		{Field: "resourceScopes", From: from.ResourceScopes, To: to.ResourceScopes},
+		{Field: "immutable", From: from.Immutable, To: to.Immutable},
+		{Field: "exclusive", From: from.Exclusive, To: to.Exclusive},
+	}
+	return slices.DeleteFunc(fields, func(field domain.RoleFieldChange) bool {
+		return reflect.DeepEqual(field.From, field.To)
+	})
+}
+
+func rbacChangesToString(changes []domain.RbacRoleChange) string {
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		fields := make([]string, 0, len(change.Fields))
+		for _, field := range change.Fields {
+			fields = append(fields, field.Field)
+		}
+		switch {
+		case change.Operation == entity.RoleVersionCreate:
+			lines = append(lines, fmt.Sprintf("Создание роли %s", change.RoleName))
+		case change.Operation == entity.RoleVersionUpdate:
+			lines = append(lines, fmt.Sprintf("Изменение роли %s: %s", change.RoleName, strings.Join(fields, ", ")))
+		case len(change.Users) > 0:
+			lines = append(lines, fmt.Sprintf("Удаление роли %s, роль будет отозвана у %d пользователей", change.RoleName, len(change.Users)))
+		default:
+			lines = append(lines, fmt.Sprintf("Удаление роли %s", change.RoleName))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func rbacFormat(format string) string {
+	if format == "" {
+		return domain.RbacFormatJson
+	}
+	return format
+}
+
+// marshalRbacDocument converts the document to yaml through json to keep the same field names
+func marshalRbacDocument(doc domain.RbacDocument, format string) ([]byte, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal json")
+	}
+	if format != domain.RbacFormatYaml {
+		return data, nil
+	}
+
+	var value any
+	err = yaml.Unmarshal(data, &value)
+	if err != nil {
+		return nil, errors.WithMessage(err, "unmarshal json as yaml")
+	}
+	data, err = yaml.Marshal(value)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal yaml")
+	}
+	return data, nil
+}
+
+// unmarshalRbacDocument returns domain.ErrInvalidRbacDocument, if content is not the document in format
+func unmarshalRbacDocument(content string, format string) (*domain.RbacDocument, error) {
+	data := []byte(content)
+	if format == domain.RbacFormatYaml {
+		var value any
+		err := yaml.Unmarshal(data, &value)
+		if err != nil {
+			return nil, errors.WithMessage(domain.ErrInvalidRbacDocument, err.Error())
+		}
+		data, err = json.Marshal(value)
+		if err != nil {
+			return nil, errors.WithMessage(domain.ErrInvalidRbacDocument, err.Error())
+		}
+	}
+
+	doc := domain.RbacDocument{}
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, errors.WithMessage(domain.ErrInvalidRbacDocument, err.Error())
+	}
+	return &doc, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+	"msp-admin-service/service/permission_match"
+	"msp-admin-service/service/role_hierarchy"
+)
+
+const (
+	defaultReadOnlySuffix = "_view"
+)
+
+// Clone creates the role with permissions, restrictions and parents of the source role under the new name,
+// the read_only template flattens inherited permissions and keeps read ones without parents,
+// approvers and flags are not copied, external group is copied only with CopyExternalGroup,
+// otherwise all members of the group would silently get the clone with added permissions on next login
+func (u Role) Clone(ctx context.Context, req domain.CloneRoleRequest, adminId int64) (*domain.Role, error) {
+	roles, err := u.roleRepo.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all roles")
+	}
+	i := slices.IndexFunc(roles, func(role entity.Role) bool {
+		return role.Id == req.Id
+	})
+	if i == -1 {
+		return nil, errors.WithMessagef(domain.ErrNotFound, "role %d", req.Id)
+	}
+	source := roles[i]
+	if req.Template == domain.RoleTemplateReadOnly {
+		source = u.readOnlyRole(role_hierarchy.New(roles).WithInherited(source))
+	}
+
+	permissions := slices.Clone([]string(source.Permissions))
+	for _, permission := range req.Permissions {
+		if !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	changeMessage := req.ChangeMessage
+	if changeMessage == "" {
+		changeMessage = fmt.Sprintf("Копия роли %s", roles[i].Name)
+	}
+	externalGroup := ""
+	if req.CopyExternalGroup {
+		externalGroup = source.ExternalGroup
+	}
+
+	return u.Create(ctx, domain.CreateRoleRequest{
+		Name:           req.Name,
+		ExternalGroup:  externalGroup,
+		ChangeMessage:  changeMessage,
+		Permissions:    stringsOrEmpty(permissions),
+		AllowedCidrs:   source.AllowedCidrs,
+		TimeWindows:    toDomainTimeWindows(source.TimeWindows),
+		ParentIds:      source.ParentIds,
+		ResourceScopes: toDomainResourceScopes(source.ResourceScopes),
+	}, adminId)
+}
+
+// Templates returns read-only templates of existing roles with inherited permissions,
+// roles without read permissions or without other permissions are skipped
+func (u Role) Templates(ctx context.Context) ([]domain.RoleTemplate, error) {
+	roles, err := u.roleRepo.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all roles")
+	}
+
+	hierarchy := role_hierarchy.New(roles)
+	result := make([]domain.RoleTemplate, 0)
+	for _, role := range roles {
+		role = hierarchy.WithInherited(role)
+		permissions := u.readOnlyRole(role).Permissions
+		hasGrants := slices.ContainsFunc(permissions, func(permission string) bool {
+			return !permission_match.IsDeny(permission)
+		})
+		if !hasGrants || len(permissions) == len(role.Permissions) {
+			continue
+		}
+		result = append(result, domain.RoleTemplate{
+			Template:       domain.RoleTemplateReadOnly,
+			SourceRoleId:   role.Id,
+			SourceRoleName: role.Name,
+			Name:           fmt.Sprintf("%s (только чтение)", role.Name),
+			Permissions:    permissions,
+		})
+	}
+	return result, nil
+}
+
+// readOnlyRole returns the role with read-only permissions, resource scopes, which limit these permissions,
+// and without parents, the role is expected to contain inherited permissions
+func (u Role) readOnlyRole(role entity.Role) entity.Role {
+	role.Permissions = u.readOnlyPermissions(role.Permissions)
+	scopes := make(entity.ResourceScopeList, 0)
+	for _, scope := range role.ResourceScopes {
+		limitsReadOnly := slices.ContainsFunc(role.Permissions, func(permission string) bool {
+			return !permission_match.IsDeny(permission) && permission_match.Match(scope.Permission, permission)
+		})
+		if limitsReadOnly {
+			scopes = append(scopes, scope)
+		}
+	}
+	role.ResourceScopes = scopes
+	role.ParentIds = nil
+	return role
+}
+
+// readOnlyPermissions returns deny entries and grants with read-only suffixes
+func (u Role) readOnlyPermissions(permissions entity.PermList) []string {
+	suffixes := u.readOnlySuffixes
+	if len(suffixes) == 0 {
+		suffixes = []string{defaultReadOnlySuffix}
+	}
+
+	result := make([]string, 0)
+	for _, permission := range permissions {
+		readOnly := slices.ContainsFunc(suffixes, func(suffix string) bool {
+			return strings.HasSuffix(permission, suffix)
+		})
+		if readOnly || permission_match.IsDeny(permission) {
+			result = append(result, permission)
+		}
+	}
+	return result
+}
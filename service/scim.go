@@ -0,0 +1,774 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/json"
+	"msp-admin-service/domain"
+)
+
+const (
+	scimAdminId       = 0
+	scimChangeMessage = "SCIM"
+	scimResourceUser  = "User"
+	scimResourceGroup = "Group"
+	scimUsersPath     = "/scim/v2/Users/"
+	scimGroupsPath    = "/scim/v2/Groups/"
+)
+
+type scimUserService interface {
+	GetAllUsers(ctx context.Context) (*domain.UsersResponse, error)
+	GetById(ctx context.Context, userId int) (*domain.User, error)
+	CreateUser(ctx context.Context, req domain.CreateUserRequest, adminId int64) (*domain.User, error)
+	UpdateUser(ctx context.Context, req domain.UpdateUserRequest, adminId int64) (*domain.User, error)
+	DeleteUsers(ctx context.Context, ids []int64, adminId int64) (int, error)
+	Block(ctx context.Context, adminId int64, userId int) error
+}
+
+type scimRoleService interface {
+	All(ctx context.Context) ([]domain.Role, error)
+	Create(ctx context.Context, req domain.CreateRoleRequest, adminId int64) (*domain.Role, error)
+	Update(ctx context.Context, req domain.UpdateRoleRequest, adminId int64) (*domain.Role, error)
+	Delete(ctx context.Context, req domain.DeleteRoleRequest, adminId int64) error
+}
+
+// Scim maps SCIM 2.0 users to admin users and SCIM groups to roles,
+// all changes are applied through user and role services to keep audit, token revocation and approval
+// of the second admin, changes waiting for approval are returned as domain.PendingChangeError
+type Scim struct {
+	userService scimUserService
+	roleService scimRoleService
+}
+
+func NewScim(userService scimUserService, roleService scimRoleService) Scim {
+	return Scim{
+		userService: userService,
+		roleService: roleService,
+	}
+}
+
+func (s Scim) ListUsers(ctx context.Context, req domain.ScimListRequest) (*domain.ScimListResponse, error) {
+	filter, err := parseScimFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.userService.GetAllUsers(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all users")
+	}
+	roleNames, err := s.roleNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.ScimUser, 0, len(users.Items))
+	for _, user := range users.Items {
+		scimUser := s.toScimUser(user, roleNames)
+		if filter.matches(scimUserAttributes(scimUser)) {
+			result = append(result, scimUser)
+		}
+	}
+
+	return new(scimPage(result, req)), nil
+}
+
+func (s Scim) GetUser(ctx context.Context, id string) (*domain.ScimUser, error) {
+	user, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	roleNames, err := s.roleNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(s.toScimUser(*user, roleNames)), nil
+}
+
+func (s Scim) CreateUser(ctx context.Context, req domain.ScimUser) (*domain.ScimUser, error) {
+	email := scimUserEmail(req)
+	if email == "" {
+		return nil, scimInvalidValue("userName is required")
+	}
+
+	password := req.Password
+	if password == "" {
+		var err error
+		password, err = randomPassword()
+		if err != nil {
+			return nil, errors.WithMessage(err, "generate password")
+		}
+	}
+
+	firstName, lastName := scimUserNames(req)
+	user, err := s.userService.CreateUser(ctx, domain.CreateUserRequest{
+		Roles:     []int{},
+		FirstName: firstName,
+		LastName:  lastName,
+		Email:     email,
+		Password:  password,
+	}, scimAdminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "create user")
+	}
+
+	if req.Active != nil && !*req.Active {
+		err = s.userService.Block(ctx, scimAdminId, int(user.Id))
+		if err != nil {
+			return nil, errors.WithMessage(err, "block user")
+		}
+	}
+
+	return s.GetUser(ctx, strconv.FormatInt(user.Id, 10))
+}
+
+func (s Scim) ReplaceUser(ctx context.Context, id string, req domain.ScimUser) (*domain.ScimUser, error) {
+	user, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	email := scimUserEmail(req)
+	if email == "" {
+		return nil, scimInvalidValue("userName is required")
+	}
+	user.FirstName, user.LastName = scimUserNames(req)
+	user.Email = email
+
+	return s.saveUser(ctx, *user, req.Active)
+}
+
+func (s Scim) PatchUser(ctx context.Context, id string, req domain.ScimPatchRequest) (*domain.ScimUser, error) {
+	user, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var active *bool
+	for _, operation := range req.Operations {
+		if !strings.EqualFold(operation.Op, "replace") && !strings.EqualFold(operation.Op, "add") {
+			return nil, scimInvalidValue("unsupported user patch operation: " + operation.Op)
+		}
+
+		path, err := parseScimPath(operation.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		values := map[string]json.RawMessage{}
+		if path.attribute == "" {
+			err = json.Unmarshal(operation.Value, &values)
+			if err != nil {
+				return nil, scimInvalidValue("object value expected")
+			}
+		} else {
+			values[path.attribute] = operation.Value
+		}
+
+		for attribute, value := range values {
+			err = applyScimUserAttribute(user, &active, strings.ToLower(attribute), value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s.saveUser(ctx, *user, active)
+}
+
+func (s Scim) DeleteUser(ctx context.Context, id string) error {
+	userId, err := parseScimId(id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.userService.DeleteUsers(ctx, []int64{int64(userId)}, scimAdminId)
+	if err != nil {
+		return errors.WithMessage(err, "delete user")
+	}
+	if deleted == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s Scim) ListGroups(ctx context.Context, req domain.ScimListRequest) (*domain.ScimListResponse, error) {
+	filter, err := parseScimFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.roleService.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all roles")
+	}
+	users, err := s.userService.GetAllUsers(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all users")
+	}
+
+	result := make([]domain.ScimGroup, 0, len(roles))
+	for _, role := range roles {
+		group := s.toScimGroup(role, users.Items)
+		if filter.matches(scimGroupAttributes(group)) {
+			result = append(result, group)
+		}
+	}
+
+	return new(scimPage(result, req)), nil
+}
+
+func (s Scim) GetGroup(ctx context.Context, id string) (*domain.ScimGroup, error) {
+	role, err := s.getRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.userService.GetAllUsers(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all users")
+	}
+
+	return new(s.toScimGroup(*role, users.Items)), nil
+}
+
+func (s Scim) CreateGroup(ctx context.Context, req domain.ScimGroup) (*domain.ScimGroup, error) {
+	if req.DisplayName == "" {
+		return nil, scimInvalidValue("displayName is required")
+	}
+
+	role, err := s.roleService.Create(ctx, domain.CreateRoleRequest{
+		Name:          req.DisplayName,
+		ChangeMessage: scimChangeMessage,
+		Permissions:   []string{},
+	}, scimAdminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "create role")
+	}
+
+	err = s.setMembers(ctx, role.Id, scimMemberIds(req.Members))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(ctx, strconv.Itoa(role.Id))
+}
+
+func (s Scim) ReplaceGroup(ctx context.Context, id string, req domain.ScimGroup) (*domain.ScimGroup, error) {
+	role, err := s.getRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.DisplayName == "" {
+		return nil, scimInvalidValue("displayName is required")
+	}
+
+	err = s.renameRole(ctx, *role, req.DisplayName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.setMembers(ctx, role.Id, scimMemberIds(req.Members))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(ctx, id)
+}
+
+//nolint:cyclop
+func (s Scim) PatchGroup(ctx context.Context, id string, req domain.ScimPatchRequest) (*domain.ScimGroup, error) {
+	role, err := s.getRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.memberIds(ctx, role.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, operation := range req.Operations {
+		path, err := parseScimPath(operation.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case path.attribute == "displayname":
+			name, err := scimString(operation.Value)
+			if err != nil {
+				return nil, err
+			}
+			err = s.renameRole(ctx, *role, name)
+			if err != nil {
+				return nil, err
+			}
+			role.Name = name
+		case path.attribute == "" && !strings.EqualFold(operation.Op, "remove"):
+			group := domain.ScimGroup{}
+			err = json.Unmarshal(operation.Value, &group)
+			if err != nil {
+				return nil, scimInvalidValue("object value expected")
+			}
+			if group.DisplayName != "" {
+				err = s.renameRole(ctx, *role, group.DisplayName)
+				if err != nil {
+					return nil, err
+				}
+				role.Name = group.DisplayName
+			}
+			if group.Members != nil {
+				members, err = patchScimMembers(members, operation.Op, nil, group.Members)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case path.attribute == "members":
+			var values []domain.ScimMultiValue
+			if len(operation.Value) > 0 {
+				err = json.Unmarshal(operation.Value, &values)
+				if err != nil {
+					return nil, scimInvalidValue("members array expected")
+				}
+			}
+			members, err = patchScimMembers(members, operation.Op, path.filter, values)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, domain.ScimError{
+				Status:   http.StatusBadRequest,
+				ScimType: domain.ScimErrorTypeInvalidPath,
+				Detail:   "unsupported group patch path: " + operation.Path,
+			}
+		}
+	}
+
+	err = s.setMembers(ctx, role.Id, members)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(ctx, id)
+}
+
+func (s Scim) DeleteGroup(ctx context.Context, id string) error {
+	role, err := s.getRole(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.roleService.Delete(ctx, domain.DeleteRoleRequest{Id: role.Id, Force: true}, scimAdminId)
+	if err != nil {
+		return errors.WithMessage(err, "delete role")
+	}
+
+	return nil
+}
+
+func (s Scim) saveUser(ctx context.Context, user domain.User, active *bool) (*domain.ScimUser, error) {
+	_, err := s.userService.UpdateUser(ctx, domain.UpdateUserRequest{
+		Id:          user.Id,
+		Roles:       user.Roles,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Email:       user.Email,
+		Description: user.Description,
+	}, scimAdminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "update user")
+	}
+
+	if active != nil && *active == user.Blocked {
+		err = s.userService.Block(ctx, scimAdminId, int(user.Id))
+		if err != nil {
+			return nil, errors.WithMessage(err, "change user block status")
+		}
+	}
+
+	return s.GetUser(ctx, strconv.FormatInt(user.Id, 10))
+}
+
+func (s Scim) renameRole(ctx context.Context, role domain.Role, name string) error {
+	if role.Name == name {
+		return nil
+	}
+	if role.Immutable {
+		return domain.ScimError{
+			Status:   http.StatusBadRequest,
+			ScimType: domain.ScimErrorTypeMutability,
+			Detail:   "role is immutable",
+		}
+	}
+	if role.ConfigManaged {
+		return domain.ScimError{
+			Status:   http.StatusBadRequest,
+			ScimType: domain.ScimErrorTypeMutability,
+			Detail:   "role is managed by config",
+		}
+	}
+
+	_, err := s.roleService.Update(ctx, domain.UpdateRoleRequest{
+		Id:             role.Id,
+		Name:           name,
+		ExternalGroup:  role.ExternalGroup,
+		ChangeMessage:  scimChangeMessage,
+		Permissions:    role.Permissions,
+		AllowedCidrs:   role.AllowedCidrs,
+		TimeWindows:    role.TimeWindows,
+		ApproverIds:    role.ApproverIds,
+		ParentIds:      role.ParentIds,
+		ResourceScopes: role.ResourceScopes,
+	}, scimAdminId)
+	if err != nil {
+		return errors.WithMessage(err, "update role")
+	}
+
+	return nil
+}
+
+// setMembers adds and removes the role from users so that exactly userIds are members,
+// changes of other users are applied, if the change of any user waits for approval
+func (s Scim) setMembers(ctx context.Context, roleId int, userIds []int64) error {
+	users, err := s.userService.GetAllUsers(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "get all users")
+	}
+
+	usersById := make(map[int64]domain.User, len(users.Items))
+	for _, user := range users.Items {
+		usersById[user.Id] = user
+	}
+	for _, id := range userIds {
+		if _, ok := usersById[id]; !ok {
+			return scimInvalidValue("unknown member: " + strconv.FormatInt(id, 10))
+		}
+	}
+
+	var pendingErr error
+	for _, user := range users.Items {
+		isMember := slices.Contains(user.Roles, roleId)
+		shouldBeMember := slices.Contains(userIds, user.Id)
+		if isMember == shouldBeMember {
+			continue
+		}
+
+		roles := slices.DeleteFunc(slices.Clone(user.Roles), func(id int) bool { return id == roleId })
+		if shouldBeMember {
+			roles = append(roles, roleId)
+		}
+		_, err = s.userService.UpdateUser(ctx, domain.UpdateUserRequest{
+			Id:          user.Id,
+			Roles:       roles,
+			FirstName:   user.FirstName,
+			LastName:    user.LastName,
+			Email:       user.Email,
+			Description: user.Description,
+		}, scimAdminId)
+		switch {
+		case errors.As(err, &domain.PendingChangeError{}):
+			if pendingErr == nil {
+				pendingErr = err
+			}
+		case err != nil:
+			return errors.WithMessagef(err, "update user %d roles", user.Id)
+		}
+	}
+
+	return pendingErr
+}
+
+func (s Scim) memberIds(ctx context.Context, roleId int) ([]int64, error) {
+	users, err := s.userService.GetAllUsers(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all users")
+	}
+
+	result := make([]int64, 0)
+	for _, user := range users.Items {
+		if slices.Contains(user.Roles, roleId) {
+			result = append(result, user.Id)
+		}
+	}
+	return result, nil
+}
+
+func (s Scim) getUser(ctx context.Context, id string) (*domain.User, error) {
+	userId, err := parseScimId(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.GetById(ctx, userId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get user by id")
+	}
+	return user, nil
+}
+
+func (s Scim) getRole(ctx context.Context, id string) (*domain.Role, error) {
+	roleId, err := parseScimId(id)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.roleService.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all roles")
+	}
+	for _, role := range roles {
+		if role.Id == roleId {
+			return &role, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s Scim) roleNames(ctx context.Context) (map[int]string, error) {
+	roles, err := s.roleService.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all roles")
+	}
+
+	result := make(map[int]string, len(roles))
+	for _, role := range roles {
+		result[role.Id] = role.Name
+	}
+	return result, nil
+}
+
+func (s Scim) toScimUser(user domain.User, roleNames map[int]string) domain.ScimUser {
+	id := strconv.FormatInt(user.Id, 10)
+	groups := make([]domain.ScimMultiValue, 0, len(user.Roles))
+	for _, roleId := range user.Roles {
+		groupId := strconv.Itoa(roleId)
+		groups = append(groups, domain.ScimMultiValue{
+			Value:   groupId,
+			Display: roleNames[roleId],
+			Ref:     scimGroupsPath + groupId,
+		})
+	}
+
+	return domain.ScimUser{
+		Schemas:  []string{domain.ScimSchemaUser},
+		Id:       id,
+		UserName: user.Email,
+		Name: &domain.ScimName{
+			Formatted:  user.FullName,
+			GivenName:  user.FirstName,
+			FamilyName: user.LastName,
+		},
+		DisplayName: user.FullName,
+		Active:      new(!user.Blocked),
+		Emails:      []domain.ScimMultiValue{{Value: user.Email, Type: "work", Primary: true}},
+		Groups:      groups,
+		Meta:        scimMeta(scimResourceUser, scimUsersPath+id, user.CreatedAt, user.UpdatedAt),
+	}
+}
+
+func (s Scim) toScimGroup(role domain.Role, users []domain.User) domain.ScimGroup {
+	id := strconv.Itoa(role.Id)
+	members := make([]domain.ScimMultiValue, 0)
+	for _, user := range users {
+		if !slices.Contains(user.Roles, role.Id) {
+			continue
+		}
+		userId := strconv.FormatInt(user.Id, 10)
+		members = append(members, domain.ScimMultiValue{
+			Value:   userId,
+			Display: user.Email,
+			Ref:     scimUsersPath + userId,
+		})
+	}
+
+	return domain.ScimGroup{
+		Schemas:     []string{domain.ScimSchemaGroup},
+		Id:          id,
+		DisplayName: role.Name,
+		Members:     members,
+		Meta:        scimMeta(scimResourceGroup, scimGroupsPath+id, role.CreatedAt, role.UpdatedAt),
+	}
+}
+
+func scimMeta(resourceType string, location string, createdAt time.Time, updatedAt time.Time) *domain.ScimMeta {
+	return &domain.ScimMeta{
+		ResourceType: resourceType,
+		Created:      createdAt,
+		LastModified: updatedAt,
+		Location:     location,
+	}
+}
+
+func scimUserAttributes(user domain.ScimUser) map[string][]string {
+	emails := make([]string, 0, len(user.Emails))
+	for _, email := range user.Emails {
+		emails = append(emails, email.Value)
+	}
+	return map[string][]string{
+		"id":              {user.Id},
+		"username":        {user.UserName},
+		"displayname":     nonEmpty(user.DisplayName),
+		"name.givenname":  nonEmpty(user.Name.GivenName),
+		"name.familyname": nonEmpty(user.Name.FamilyName),
+		"emails":          emails,
+		"emails.value":    emails,
+	}
+}
+
+func scimGroupAttributes(group domain.ScimGroup) map[string][]string {
+	members := make([]string, 0, len(group.Members))
+	for _, member := range group.Members {
+		members = append(members, member.Value)
+	}
+	return map[string][]string{
+		"id":            {group.Id},
+		"displayname":   {group.DisplayName},
+		"members":       members,
+		"members.value": members,
+	}
+}
+
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+//nolint:cyclop
+func applyScimUserAttribute(user *domain.User, active **bool, attribute string, value json.RawMessage) error {
+	var err error
+	switch attribute {
+	case "active":
+		var isActive bool
+		isActive, err = scimBool(value)
+		*active = &isActive
+	case "username":
+		user.Email, err = scimString(value)
+	case "name.givenname":
+		user.FirstName, err = scimString(value)
+	case "name.familyname":
+		user.LastName, err = scimString(value)
+	case "name":
+		name := domain.ScimName{}
+		err = json.Unmarshal(value, &name)
+		if err != nil {
+			return scimInvalidValue("name object expected")
+		}
+		user.FirstName, user.LastName = name.GivenName, name.FamilyName
+	case "emails":
+		var emails []domain.ScimMultiValue
+		err = json.Unmarshal(value, &emails)
+		if err != nil {
+			return scimInvalidValue("emails array expected")
+		}
+		email := primaryScimValue(emails)
+		if email != "" {
+			user.Email = email
+		}
+	case "displayname", "externalid":
+		return nil
+	default:
+		return domain.ScimError{
+			Status:   http.StatusBadRequest,
+			ScimType: domain.ScimErrorTypeInvalidPath,
+			Detail:   "unsupported user attribute: " + attribute,
+		}
+	}
+	return err
+}
+
+func patchScimMembers(members []int64, op string, filter *scimFilter, values []domain.ScimMultiValue) ([]int64, error) {
+	switch strings.ToLower(op) {
+	case "add":
+		for _, id := range scimMemberIds(values) {
+			if !slices.Contains(members, id) {
+				members = append(members, id)
+			}
+		}
+		return members, nil
+	case "replace":
+		return scimMemberIds(values), nil
+	case "remove":
+		if filter == nil && len(values) == 0 {
+			return []int64{}, nil
+		}
+		removed := scimMemberIds(values)
+		return slices.DeleteFunc(members, func(id int64) bool {
+			if slices.Contains(removed, id) {
+				return true
+			}
+			return filter != nil && filter.matches(map[string][]string{"value": {strconv.FormatInt(id, 10)}})
+		}), nil
+	default:
+		return nil, scimInvalidValue("unsupported patch operation: " + op)
+	}
+}
+
+func scimMemberIds(values []domain.ScimMultiValue) []int64 {
+	result := make([]int64, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.ParseInt(value.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+		result = append(result, id)
+	}
+	return result
+}
+
+func scimUserEmail(user domain.ScimUser) string {
+	email := primaryScimValue(user.Emails)
+	if email != "" {
+		return email
+	}
+	return user.UserName
+}
+
+func scimUserNames(user domain.ScimUser) (string, string) {
+	if user.Name == nil {
+		return "", ""
+	}
+	return user.Name.GivenName, user.Name.FamilyName
+}
+
+func primaryScimValue(values []domain.ScimMultiValue) string {
+	for _, value := range values {
+		if value.Primary {
+			return value.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+func parseScimId(id string) (int, error) {
+	result, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, domain.ErrNotFound
+	}
+	return result, nil
+}
+
+func randomPassword() (string, error) {
+	buff := make([]byte, 32) //nolint:mnd
+	_, err := rand.Read(buff)
+	if err != nil {
+		return "", errors.WithMessage(err, "crypto/rand read")
+	}
+	return hex.EncodeToString(buff), nil
+}
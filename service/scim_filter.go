@@ -0,0 +1,200 @@
+package service
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/txix-open/isp-kit/json"
+	"msp-admin-service/domain"
+)
+
+const (
+	scimDefaultCount = 100
+	scimMaxCount     = 1000
+)
+
+var (
+	scimFilterRegexp     = regexp.MustCompile(`^\s*([A-Za-z][\w.:]*)\s+(eq|ne|co|sw|ew|pr)(?:\s+(.+?))?\s*$`)
+	scimValuePathRegexp  = regexp.MustCompile(`^(\w+)\[(.+)]$`)
+	scimAttributePrefix  = regexp.MustCompile(`^urn:ietf:params:scim:schemas:core:2\.0:(User|Group):`)
+	errScimInvalidFilter = domain.ScimError{
+		Status:   http.StatusBadRequest,
+		ScimType: domain.ScimErrorTypeInvalidFilter,
+		Detail:   "only simple filters 'attribute op \"value\"' are supported",
+	}
+)
+
+// scimFilter is a single SCIM attribute expression, logical operators are not supported
+type scimFilter struct {
+	attribute string
+	operator  string
+	value     string
+}
+
+func parseScimFilter(filter string) (*scimFilter, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	match := scimFilterRegexp.FindStringSubmatch(filter)
+	if match == nil {
+		return nil, errScimInvalidFilter
+	}
+	result := &scimFilter{
+		attribute: strings.ToLower(scimAttributePrefix.ReplaceAllString(match[1], "")),
+		operator:  match[2],
+	}
+	if result.operator == "pr" {
+		return result, nil
+	}
+
+	rawValue := strings.TrimSpace(match[3])
+	if rawValue == "" {
+		return nil, errScimInvalidFilter
+	}
+	if strings.HasPrefix(rawValue, `"`) {
+		err := json.Unmarshal([]byte(rawValue), &result.value)
+		if err != nil {
+			return nil, errScimInvalidFilter
+		}
+	} else {
+		result.value = rawValue
+	}
+
+	return result, nil
+}
+
+// matches checks attribute values of resource, attribute names are case-insensitive
+func (f *scimFilter) matches(attributes map[string][]string) bool {
+	if f == nil {
+		return true
+	}
+
+	values := attributes[f.attribute]
+	if f.operator == "pr" {
+		return len(values) > 0
+	}
+	if f.operator == "ne" {
+		for _, value := range values {
+			if strings.EqualFold(value, f.value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	expected := strings.ToLower(f.value)
+	for _, value := range values {
+		value = strings.ToLower(value)
+		switch f.operator {
+		case "eq":
+			if value == expected {
+				return true
+			}
+		case "co":
+			if strings.Contains(value, expected) {
+				return true
+			}
+		case "sw":
+			if strings.HasPrefix(value, expected) {
+				return true
+			}
+		case "ew":
+			if strings.HasSuffix(value, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func scimPage[T any](items []T, req domain.ScimListRequest) domain.ScimListResponse {
+	startIndex := max(req.StartIndex, 1)
+	count := req.Count
+	if count <= 0 {
+		count = scimDefaultCount
+	}
+	count = min(count, scimMaxCount)
+
+	resources := make([]any, 0)
+	for i := startIndex - 1; i < len(items) && len(resources) < count; i++ {
+		resources = append(resources, items[i])
+	}
+
+	return domain.ScimListResponse{
+		Schemas:      []string{domain.ScimSchemaListResponse},
+		TotalResults: len(items),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// scimPath is a parsed PATCH path like "members[value eq \"2\"]" or "name.givenName"
+type scimPath struct {
+	attribute string
+	filter    *scimFilter
+}
+
+func parseScimPath(path string) (*scimPath, error) {
+	path = strings.TrimSpace(scimAttributePrefix.ReplaceAllString(path, ""))
+	if path == "" {
+		return &scimPath{}, nil
+	}
+
+	match := scimValuePathRegexp.FindStringSubmatch(path)
+	if match == nil {
+		return &scimPath{attribute: strings.ToLower(path)}, nil
+	}
+
+	filter, err := parseScimFilter(match[2])
+	if err != nil || filter == nil {
+		return nil, domain.ScimError{
+			Status:   http.StatusBadRequest,
+			ScimType: domain.ScimErrorTypeInvalidPath,
+			Detail:   "invalid path filter: " + path,
+		}
+	}
+	return &scimPath{attribute: strings.ToLower(match[1]), filter: filter}, nil
+}
+
+func scimInvalidValue(detail string) error {
+	return domain.ScimError{
+		Status:   http.StatusBadRequest,
+		ScimType: domain.ScimErrorTypeInvalidValue,
+		Detail:   detail,
+	}
+}
+
+// scimBool decodes boolean value, some providers send booleans as strings
+func scimBool(raw json.RawMessage) (bool, error) {
+	var value bool
+	err := json.Unmarshal(raw, &value)
+	if err == nil {
+		return value, nil
+	}
+
+	var str string
+	err = json.Unmarshal(raw, &str)
+	if err != nil {
+		return false, scimInvalidValue("boolean value expected")
+	}
+	switch strings.ToLower(str) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, scimInvalidValue("boolean value expected")
+	}
+}
+
+func scimString(raw json.RawMessage) (string, error) {
+	var value string
+	err := json.Unmarshal(raw, &value)
+	if err != nil {
+		return "", scimInvalidValue("string value expected")
+	}
+	return value, nil
+}
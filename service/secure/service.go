@@ -2,12 +2,15 @@ package secure
 
 import (
 	"context"
+	"fmt"
 	"slices"
 	"time"
 
 	"github.com/pkg/errors"
 	"msp-admin-service/domain"
 	"msp-admin-service/entity"
+	"msp-admin-service/service/access_restriction"
+	"msp-admin-service/service/permission_match"
 )
 
 type TokenRep interface {
@@ -18,15 +21,42 @@ type UserRoleRepo interface {
 	GetRoleEntitiesByUserId(ctx context.Context, userId int) ([]entity.Role, error)
 }
 
+type RoleResolver interface {
+	Resolve(ctx context.Context, roles []entity.Role) ([]entity.Role, error)
+}
+
+type AccessChecker interface {
+	CheckRole(role entity.Role, clientIp string) *access_restriction.Violation
+}
+
+type AuditService interface {
+	SaveAuditAsync(ctx context.Context, userId int64, message string, event string)
+}
+
 type Service struct {
-	tokenRep     TokenRep
-	userRoleRepo UserRoleRepo
+	tokenRep         TokenRep
+	userRoleRepo     UserRoleRepo
+	roleResolver     RoleResolver
+	accessChecker    AccessChecker
+	auditService     AuditService
+	checkOnAuthorize bool
 }
 
-func NewService(tokenRep TokenRep, userRoleRepo UserRoleRepo) Service {
+func NewService(
+	tokenRep TokenRep,
+	userRoleRepo UserRoleRepo,
+	roleResolver RoleResolver,
+	accessChecker AccessChecker,
+	auditService AuditService,
+	checkOnAuthorize bool,
+) Service {
 	return Service{
-		tokenRep:     tokenRep,
-		userRoleRepo: userRoleRepo,
+		tokenRep:         tokenRep,
+		userRoleRepo:     userRoleRepo,
+		roleResolver:     roleResolver,
+		accessChecker:    accessChecker,
+		auditService:     auditService,
+		checkOnAuthorize: checkOnAuthorize,
 	}
 }
 
@@ -44,18 +74,72 @@ func (s Service) Authenticate(ctx context.Context, token string) (int64, error)
 	return tokenInfo.UserId, nil
 }
 
-func (s Service) Authorize(ctx context.Context, adminId int, permission string) (bool, error) {
+// Authorize checks the permission of the admin, deny entries of any role override grants.
+// Permission limited by resource scopes is authorized for listed resources or for any resource if resource is nil,
+// the scopes are returned to filter resources
+func (s Service) Authorize(
+	ctx context.Context,
+	adminId int,
+	permission string,
+	resource *domain.ResourceRef,
+) (*domain.SecureAuthzResponse, error) {
 	roles, err := s.userRoleRepo.GetRoleEntitiesByUserId(ctx, adminId)
 	if err != nil {
-		return false, errors.WithMessage(err, "get role entities by user id")
+		return nil, errors.WithMessage(err, "get role entities by user id")
+	}
+	roles, err = s.roleResolver.Resolve(ctx, roles)
+	if err != nil {
+		return nil, errors.WithMessage(err, "resolve inherited permissions")
+	}
+
+	denied := slices.ContainsFunc(roles, func(role entity.Role) bool {
+		return permission_match.Denied(role.Permissions, permission)
+	})
+	if denied {
+		return &domain.SecureAuthzResponse{Authorized: false}, nil
 	}
 
+	var (
+		violation *access_restriction.Violation
+		scopes    []entity.ResourceScope
+	)
 	for _, role := range roles {
-		exist := slices.Contains(role.Permissions, permission)
-		if exist {
-			return true, nil
+		if !permission_match.Allowed(role.Permissions, permission) {
+			continue
+		}
+		if s.checkOnAuthorize {
+			roleViolation := s.accessChecker.CheckRole(role, domain.ClientIpFromContext(ctx))
+			if roleViolation != nil {
+				if violation == nil {
+					violation = roleViolation
+				}
+				continue
+			}
+		}
+
+		roleScopes := permission_match.Scopes(role, permission)
+		if len(roleScopes) == 0 {
+			return &domain.SecureAuthzResponse{Authorized: true}, nil
+		}
+		if resource != nil && !permission_match.InScopes(roleScopes, resource.Type, resource.Id) {
+			continue
 		}
+		scopes = append(scopes, roleScopes...)
+	}
+
+	if len(scopes) > 0 {
+		return &domain.SecureAuthzResponse{
+			Authorized: true,
+			Scopes:     permission_match.MergeScopes(scopes),
+		}, nil
+	}
+
+	if violation != nil {
+		s.auditService.SaveAuditAsync(ctx, int64(adminId),
+			fmt.Sprintf("Отказ в разрешении %s. Доступ ограничен: %s", permission, violation),
+			entity.EventAccessRestricted,
+		)
 	}
 
-	return false, nil
+	return &domain.SecureAuthzResponse{Authorized: false}, nil
 }
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+	"msp-admin-service/service/permission_match"
+)
+
+type sodRoleRepo interface {
+	GetRoleByIds(ctx context.Context, id []int) ([]entity.Role, error)
+	All(ctx context.Context) ([]entity.Role, error)
+}
+
+type sodUserRepo interface {
+	GetAllUsers(ctx context.Context) ([]entity.User, error)
+}
+
+type sodUserRoleRepo interface {
+	GetRolesByUserIds(ctx context.Context, identity []int) ([]entity.UserRole, error)
+}
+
+// SeparationOfDuties checks, that the user is not assigned more than one role
+// and is not granted more than one permission of each constraint of the config
+type SeparationOfDuties struct {
+	constraints  []conf.SodConstraint
+	roleRepo     sodRoleRepo
+	userRepo     sodUserRepo
+	userRoleRepo sodUserRoleRepo
+	roleResolver roleResolver
+}
+
+func NewSeparationOfDuties(
+	constraints []conf.SodConstraint,
+	roleRepo sodRoleRepo,
+	userRepo sodUserRepo,
+	userRoleRepo sodUserRoleRepo,
+	roleResolver roleResolver,
+) SeparationOfDuties {
+	return SeparationOfDuties{
+		constraints:  constraints,
+		roleRepo:     roleRepo,
+		userRepo:     userRepo,
+		userRoleRepo: userRoleRepo,
+		roleResolver: roleResolver,
+	}
+}
+
+// Check returns domain.SodViolationError, if roles violate any constraint,
+// permissions are checked with permissions inherited from parent roles
+func (s SeparationOfDuties) Check(ctx context.Context, roleIds []int) error {
+	if len(s.constraints) == 0 || len(roleIds) == 0 {
+		return nil
+	}
+
+	roles, err := s.roleRepo.GetRoleByIds(ctx, roleIds)
+	if err != nil {
+		return errors.WithMessage(err, "get roles by ids")
+	}
+	roles, err = s.roleResolver.Resolve(ctx, roles)
+	if err != nil {
+		return errors.WithMessage(err, "resolve inherited permissions")
+	}
+
+	violations := s.violations(roles, "")
+	if len(violations) > 0 {
+		return violations[0]
+	}
+	return nil
+}
+
+// Violations returns users, who currently violate constraints, e.g. after the constraint is added to the config
+func (s SeparationOfDuties) Violations(ctx context.Context, req domain.SodViolationsRequest) (*domain.SodViolationsResponse, error) {
+	items := make([]domain.SodViolation, 0)
+	if len(s.constraints) == 0 {
+		return &domain.SodViolationsResponse{Items: items}, nil
+	}
+
+	users, err := s.userRepo.GetAllUsers(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all users")
+	}
+	roles, err := s.roleRepo.All(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all roles")
+	}
+	roles, err = s.roleResolver.Resolve(ctx, roles)
+	if err != nil {
+		return nil, errors.WithMessage(err, "resolve inherited permissions")
+	}
+	userIds := make([]int, 0, len(users))
+	for _, user := range users {
+		userIds = append(userIds, int(user.Id))
+	}
+	links, err := s.userRoleRepo.GetRolesByUserIds(ctx, userIds)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get user roles")
+	}
+
+	rolesById := make(map[int]entity.Role, len(roles))
+	for _, role := range roles {
+		rolesById[role.Id] = role
+	}
+	userRoles := make(map[int][]entity.Role)
+	for _, link := range links {
+		role, ok := rolesById[link.RoleId]
+		if ok {
+			userRoles[link.UserId] = append(userRoles[link.UserId], role)
+		}
+	}
+
+	for _, user := range users {
+		for _, violation := range s.violations(userRoles[int(user.Id)], req.Constraint) {
+			items = append(items, domain.SodViolation{
+				UserId:      user.Id,
+				Email:       user.Email,
+				FullName:    user.FullName,
+				Constraint:  violation.Constraint,
+				Roles:       violation.Roles,
+				Permissions: violation.Permissions,
+			})
+		}
+	}
+	return &domain.SodViolationsResponse{Items: items}, nil
+}
+
+// violations returns violated constraints of roles with inherited permissions,
+// only the constraint with name constraintName is checked if it is not empty
+func (s SeparationOfDuties) violations(roles []entity.Role, constraintName string) []domain.SodViolationError {
+	roleNames := make([]string, 0, len(roles))
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Name)
+	}
+	permissions := mergePermissions(roles)
+
+	result := make([]domain.SodViolationError, 0)
+	for _, constraint := range s.constraints {
+		if constraintName != "" && constraint.Name != constraintName {
+			continue
+		}
+		conflictingRoles := make([]string, 0)
+		for _, roleName := range constraint.Roles {
+			if slices.Contains(roleNames, roleName) {
+				conflictingRoles = append(conflictingRoles, roleName)
+			}
+		}
+		if len(conflictingRoles) > 1 {
+			result = append(result, domain.SodViolationError{
+				Constraint: constraint.Name,
+				Roles:      conflictingRoles,
+			})
+		}
+
+		conflictingPermissions := make([]string, 0)
+		for _, permission := range constraint.Permissions {
+			if permission_match.Allowed(permissions, permission) {
+				conflictingPermissions = append(conflictingPermissions, permission)
+			}
+		}
+		if len(conflictingPermissions) > 1 {
+			result = append(result, domain.SodViolationError{
+				Constraint:  constraint.Name,
+				Roles:       grantingRoleNames(roles, conflictingPermissions),
+				Permissions: conflictingPermissions,
+			})
+		}
+	}
+	return result
+}
+
+// grantingRoleNames returns sorted names of roles, which grant any of permissions
+func grantingRoleNames(roles []entity.Role, permissions []string) []string {
+	result := make([]string, 0)
+	for _, role := range roles {
+		grants := slices.ContainsFunc(permissions, func(permission string) bool {
+			return permission_match.Allowed(role.Permissions, permission)
+		})
+		if grants {
+			result = append(result, role.Name)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
@@ -16,7 +16,7 @@ type sudirRepo interface {
 }
 
 type roleRepo interface {
-	GetRolesByExternalGroup(ctx context.Context, groups []string) ([]entity.Role, error)
+	externalGroupRoleRepo
 	InsertRole(ctx context.Context, role entity.Role) (*entity.Role, error)
 }
 
@@ -57,15 +57,9 @@ func (s Sudir) Authenticate(ctx context.Context, authCode string, roleRepo roleR
 		email = user.Sub
 	}
 
-	rolesIds := make([]int, 0)
-	if len(user.Groups) > 0 {
-		roles, err := roleRepo.GetRolesByExternalGroup(ctx, user.Groups)
-		if err != nil {
-			return nil, errors.WithMessage(err, "get roles by external groups")
-		}
-		for _, role := range roles {
-			rolesIds = append(rolesIds, role.Id)
-		}
+	rolesIds, _, err := resolveExternalGroupRoles(ctx, roleRepo, user.Groups)
+	if err != nil {
+		return nil, errors.WithMessage(err, "resolve external groups roles")
 	}
 
 	return &entity.SudirUser{
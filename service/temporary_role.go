@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/pkg/errors"
+)
+
+// GrantRole grants the role immediately or saves the change for approval, if the role is privileged
+func (u User) GrantRole(ctx context.Context, req domain.GrantRoleRequest, adminId int64) (*domain.User, error) {
+	role, err := u.checkGrantRole(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.changeGate.Required(entity.ChangeUserGrantRole) && len(u.changeGate.PrivilegedRoleNames([]entity.Role{*role})) > 0 {
+		err = u.changeGate.Submit(ctx, entity.ChangeUserGrantRole, req,
+			fmt.Sprintf("Назначение привилегированной роли %s пользователю %d: %s", role.Name, req.UserId, rolePeriodToString(grantedLink(req))),
+			adminId,
+		)
+		return nil, err
+	}
+
+	return u.applyGrantRole(ctx, req, *role, adminId)
+}
+
+func (u User) grantRole(ctx context.Context, req domain.GrantRoleRequest, adminId int64) (*domain.User, error) {
+	role, err := u.checkGrantRole(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return u.applyGrantRole(ctx, req, *role, adminId)
+}
+
+func (u User) checkGrantRole(ctx context.Context, req domain.GrantRoleRequest) (*entity.Role, error) {
+	err := validateRolePeriod(req.ValidFrom, req.ValidUntil, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = u.userRepo.GetUserById(ctx, req.UserId)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get user by id %d", req.UserId)
+	}
+
+	roles, err := u.roleRepoUser.GetRoleByIds(ctx, []int{req.RoleId})
+	switch {
+	case err != nil:
+		return nil, errors.WithMessage(err, "get role by id")
+	case len(roles) == 0:
+		return nil, errors.WithMessagef(domain.ErrNotFound, "role %d", req.RoleId)
+	}
+
+	links, err := u.userRoleRepo.GetRolesByUserIds(ctx, []int{int(req.UserId)})
+	if err != nil {
+		return nil, errors.WithMessage(err, "get user roles")
+	}
+	err = u.sodChecker.Check(ctx, append(RolesIds(links), req.RoleId))
+	if err != nil {
+		return nil, errors.WithMessage(err, "check separation of duties")
+	}
+
+	return &roles[0], nil
+}
+
+func (u User) applyGrantRole(ctx context.Context, req domain.GrantRoleRequest, role entity.Role, adminId int64) (*domain.User, error) {
+	link := grantedLink(req)
+	err := u.txRunner.UserTransaction(ctx, func(ctx context.Context, tx UserTransaction) error {
+		return tx.UpsertUserRoleLink(ctx, link)
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "upsert user role link")
+	}
+
+	u.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Пользователь. Назначение роли %s пользователю %d: %s", role.Name, req.UserId, rolePeriodToString(link)),
+		entity.EventUserChanged,
+	)
+
+	user, err := u.GetById(ctx, int(req.UserId))
+	if err != nil {
+		return nil, errors.WithMessage(err, "get user by id")
+	}
+	return user, nil
+}
+
+func grantedLink(req domain.GrantRoleRequest) entity.UserRole {
+	return entity.UserRole{
+		UserId:     int(req.UserId),
+		RoleId:     req.RoleId,
+		ValidFrom:  utcTime(req.ValidFrom),
+		ValidUntil: utcTime(req.ValidUntil),
+	}
+}
+
+// userRoleLinks builds role links of the user,
+// validity periods of kept roles are taken from oldLinks when temporaryRoles is omitted
+func userRoleLinks(
+	userId int,
+	roleIds []int,
+	temporaryRoles []domain.TemporaryRole,
+	oldLinks []entity.UserRole,
+) ([]entity.UserRole, error) {
+	periods := make(map[int]entity.UserRole)
+	if temporaryRoles == nil {
+		for _, link := range oldLinks {
+			periods[link.RoleId] = link
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, role := range temporaryRoles {
+		err := validateRolePeriod(role.ValidFrom, role.ValidUntil, now)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "role %d", role.RoleId)
+		}
+		periods[role.RoleId] = entity.UserRole{
+			ValidFrom:  utcTime(role.ValidFrom),
+			ValidUntil: utcTime(role.ValidUntil),
+		}
+	}
+
+	links := make([]entity.UserRole, 0, len(roleIds)+len(temporaryRoles))
+	addLink := func(roleId int) {
+		if slices.ContainsFunc(links, func(link entity.UserRole) bool { return link.RoleId == roleId }) {
+			return
+		}
+		period := periods[roleId]
+		links = append(links, entity.UserRole{
+			UserId:     userId,
+			RoleId:     roleId,
+			ValidFrom:  period.ValidFrom,
+			ValidUntil: period.ValidUntil,
+		})
+	}
+	for _, roleId := range roleIds {
+		addLink(roleId)
+	}
+	for _, role := range temporaryRoles {
+		addLink(role.RoleId)
+	}
+
+	return links, nil
+}
+
+func validateRolePeriod(validFrom *time.Time, validUntil *time.Time, now time.Time) error {
+	switch {
+	case validUntil == nil:
+		return nil
+	case !validUntil.After(now):
+		return errors.WithMessage(domain.ErrInvalidRolePeriod, "valid until is in the past")
+	case validFrom != nil && !validUntil.After(*validFrom):
+		return errors.WithMessage(domain.ErrInvalidRolePeriod, "valid until must be after valid from")
+	default:
+		return nil
+	}
+}
+
+// activeUserRoles returns links, which validity period contains now
+func activeUserRoles(links []entity.UserRole, now time.Time) []entity.UserRole {
+	result := make([]entity.UserRole, 0, len(links))
+	for _, link := range links {
+		if link.ValidFrom != nil && link.ValidFrom.After(now) {
+			continue
+		}
+		if link.ValidUntil != nil && !link.ValidUntil.After(now) {
+			continue
+		}
+		result = append(result, link)
+	}
+	return result
+}
+
+func toTemporaryRoles(links []entity.UserRole) []domain.TemporaryRole {
+	result := make([]domain.TemporaryRole, 0)
+	for _, link := range links {
+		if link.ValidFrom == nil && link.ValidUntil == nil {
+			continue
+		}
+		result = append(result, domain.TemporaryRole{
+			RoleId:     link.RoleId,
+			ValidFrom:  link.ValidFrom,
+			ValidUntil: link.ValidUntil,
+		})
+	}
+	return result
+}
+
+func temporaryRolesToStrings(links []entity.UserRole) []string {
+	result := make([]string, 0)
+	for _, link := range links {
+		if link.ValidFrom == nil && link.ValidUntil == nil {
+			continue
+		}
+		result = append(result, fmt.Sprintf("%d %s", link.RoleId, rolePeriodToString(link)))
+	}
+	slices.Sort(result)
+	return result
+}
+
+func rolePeriodToString(link entity.UserRole) string {
+	switch {
+	case link.ValidFrom != nil && link.ValidUntil != nil:
+		return fmt.Sprintf("с %s по %s", link.ValidFrom.Format(time.DateTime), link.ValidUntil.Format(time.DateTime))
+	case link.ValidFrom != nil:
+		return fmt.Sprintf("с %s", link.ValidFrom.Format(time.DateTime))
+	case link.ValidUntil != nil:
+		return fmt.Sprintf("по %s", link.ValidUntil.Format(time.DateTime))
+	default:
+		return "бессрочно"
+	}
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	return new(t.UTC())
+}
@@ -17,6 +17,7 @@ type TokenRep interface {
 	TokenSaver
 	Get(ctx context.Context, token string) (*entity.Token, error)
 	RevokeByUserId(ctx context.Context, userId int64, updatedAt time.Time) error
+	RevokeByImpersonatorId(ctx context.Context, userId int64, impersonatorId int64, updatedAt time.Time) error
 	AllByRequest(ctx context.Context, req domain.SessionPageRequest) ([]entity.Token, error)
 	Count(ctx context.Context, reqQuery *domain.SessionQuery) (int64, error)
 	UpdateStatus(ctx context.Context, id int, status string) error
@@ -39,6 +40,27 @@ func NewToken(tokenRep TokenRep, lifeTimeInSec int) Token {
 }
 
 func (s Token) GenerateToken(ctx context.Context, repo TokenSaver, id int64) (string, string, error) {
+	return s.generate(ctx, repo, id, nil, s.lifeTime)
+}
+
+// GenerateImpersonationToken issues token of userId, that is used by impersonatorId
+func (s Token) GenerateImpersonationToken(
+	ctx context.Context,
+	repo TokenSaver,
+	userId int64,
+	impersonatorId int64,
+	lifeTime time.Duration,
+) (string, string, error) {
+	return s.generate(ctx, repo, userId, &impersonatorId, lifeTime)
+}
+
+func (s Token) generate(
+	ctx context.Context,
+	repo TokenSaver,
+	id int64,
+	impersonatorId *int64,
+	lifeTime time.Duration,
+) (string, string, error) {
 	cryptoRand := make([]byte, 128) //nolint:mnd
 	_, err := rand.Read(cryptoRand)
 	if err != nil {
@@ -47,15 +69,16 @@ func (s Token) GenerateToken(ctx context.Context, repo TokenSaver, id int64) (st
 	random := hex.EncodeToString(cryptoRand)
 
 	createdAt := time.Now().UTC()
-	expiredAt := createdAt.Add(s.lifeTime)
+	expiredAt := createdAt.Add(lifeTime)
 
 	err = repo.Save(ctx, entity.Token{
-		Token:     random,
-		UserId:    id,
-		Status:    entity.TokenStatusAllowed,
-		ExpiredAt: expiredAt,
-		CreatedAt: createdAt,
-		UpdatedAt: createdAt,
+		Token:          random,
+		UserId:         id,
+		ImpersonatorId: impersonatorId,
+		Status:         entity.TokenStatusAllowed,
+		ExpiredAt:      expiredAt,
+		CreatedAt:      createdAt,
+		UpdatedAt:      createdAt,
 	})
 	if err != nil {
 		return "", "", errors.WithMessage(err, "save token")
@@ -74,6 +97,16 @@ func (s Token) RevokeAllByUserId(ctx context.Context, userId int64) error {
 	return nil
 }
 
+func (s Token) RevokeImpersonation(ctx context.Context, userId int64, impersonatorId int64) error {
+	updatedAt := time.Now().UTC()
+	err := s.tokenRep.RevokeByImpersonatorId(ctx, userId, impersonatorId, updatedAt)
+	if err != nil {
+		return errors.WithMessage(err, "set revoked status")
+	}
+
+	return nil
+}
+
 func (s Token) All(ctx context.Context, req domain.SessionPageRequest) (*domain.SessionResponse, error) {
 	var tokens []entity.Token
 	var total int64
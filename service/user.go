@@ -10,10 +10,10 @@ import (
 
 	"msp-admin-service/domain"
 	"msp-admin-service/entity"
+	"msp-admin-service/service/permission_match"
 
 	"github.com/pkg/errors"
 	"github.com/txix-open/isp-kit/log"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserTransaction interface {
@@ -49,45 +49,78 @@ type TokenRepo interface {
 type UserRoleRepo interface {
 	GetRolesByUserIds(ctx context.Context, identity []int) ([]entity.UserRole, error)
 	UpsertUserRoleLinks(ctx context.Context, id int, roleIds []int) error
+	ReplaceUserRoleLinks(ctx context.Context, userId int, links []entity.UserRole) error
+	UpsertUserRoleLink(ctx context.Context, link entity.UserRole) error
+}
+
+// passwordHasher hashes passwords and verifies hashes produced by any supported algorithm
+type passwordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hash string, password string) (bool, error)
 }
 
 type roleRepoUser interface {
 	GetRoleByIds(ctx context.Context, id []int) ([]entity.Role, error)
 }
 
+// roleResolver adds permissions inherited from parent roles
+type roleResolver interface {
+	Resolve(ctx context.Context, roles []entity.Role) ([]entity.Role, error)
+}
+
+// sodChecker returns domain.SodViolationError, if roles violate the separation of duties
+type sodChecker interface {
+	Check(ctx context.Context, roleIds []int) error
+}
+
 type User struct {
-	userRepo      UserRepo
-	userRoleRepo  UserRoleRepo
-	roleRepoUser  roleRepoUser
-	tokenRepo     TokenRepo
-	auditService  auditService
-	txRunner      UserTransactionRunner
-	tokenService  tokenService
-	idleTimeoutMs int
-	logger        log.Logger
+	userRepo       UserRepo
+	userRoleRepo   UserRoleRepo
+	roleRepoUser   roleRepoUser
+	roleResolver   roleResolver
+	catalog        permissionCatalog
+	tokenRepo      TokenRepo
+	auditService   auditService
+	txRunner       UserTransactionRunner
+	tokenService   tokenService
+	passwordHasher passwordHasher
+	changeGate     changeGate
+	sodChecker     sodChecker
+	idleTimeoutMs  int
+	logger         log.Logger
 }
 
 func NewUser(
 	userRepo UserRepo,
 	userRoleRepo UserRoleRepo,
 	roleRepoUser roleRepoUser,
+	roleResolver roleResolver,
+	catalog permissionCatalog,
 	tokenRepo TokenRepo,
 	service auditService,
 	txRunner UserTransactionRunner,
 	tokenService tokenService,
+	passwordHasher passwordHasher,
+	changeGate changeGate,
+	sodChecker sodChecker,
 	idleTimeoutMs int,
 	logger log.Logger,
 ) User {
 	return User{
-		userRepo:      userRepo,
-		userRoleRepo:  userRoleRepo,
-		roleRepoUser:  roleRepoUser,
-		tokenRepo:     tokenRepo,
-		auditService:  service,
-		txRunner:      txRunner,
-		tokenService:  tokenService,
-		idleTimeoutMs: idleTimeoutMs,
-		logger:        logger,
+		userRepo:       userRepo,
+		userRoleRepo:   userRoleRepo,
+		roleRepoUser:   roleRepoUser,
+		roleResolver:   roleResolver,
+		catalog:        catalog,
+		tokenRepo:      tokenRepo,
+		auditService:   service,
+		txRunner:       txRunner,
+		tokenService:   tokenService,
+		passwordHasher: passwordHasher,
+		changeGate:     changeGate,
+		sodChecker:     sodChecker,
+		idleTimeoutMs:  idleTimeoutMs,
+		logger:         logger,
 	}
 }
 
@@ -104,7 +137,7 @@ func (u User) GetProfileById(ctx context.Context, userId int64) (*domain.AdminUs
 	if err != nil {
 		return nil, errors.WithMessagef(err, "get role by user id %d", userId)
 	}
-	roleIds := RolesIds(roles)
+	roleIds := RolesIds(activeUserRoles(roles, time.Now().UTC()))
 
 	var (
 		roleList  []entity.Role
@@ -122,18 +155,27 @@ func (u User) GetProfileById(ctx context.Context, userId int64) (*domain.AdminUs
 				roleNames = append(roleNames, r.Name)
 			}
 		}
+		roleList, err = u.roleResolver.Resolve(ctx, roleList)
+		if err != nil {
+			return nil, errors.WithMessage(err, "resolve inherited permissions")
+		}
 	}
 
+	permissions, err := effectivePermissions(ctx, u.catalog, roleList)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get effective permissions")
+	}
 	return &domain.AdminUserShort{
-		FirstName:     user.FirstName,
-		LastName:      user.LastName,
-		FullName:      user.FullName,
-		Email:         user.Email,
-		Role:          roleName,
-		Roles:         roleIds,
-		RoleNames:     roleNames,
-		IdleTimeoutMs: u.idleTimeoutMs,
-		Permissions:   mergePermissions(roleList),
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		FullName:       user.FullName,
+		Email:          user.Email,
+		Role:           roleName,
+		Roles:          roleIds,
+		RoleNames:      roleNames,
+		IdleTimeoutMs:  u.idleTimeoutMs,
+		Permissions:    permissions,
+		ResourceScopes: mergeResourceScopes(roleList, permissions),
 	}, nil
 }
 
@@ -156,15 +198,17 @@ func (u User) GetUsers(ctx context.Context, req domain.UsersPageRequest) (*domai
 	items := make([]domain.User, 0, len(users))
 	for _, user := range users {
 		roles := make([]int, 0)
+		links := make([]entity.UserRole, 0)
 
 		for _, role := range userRoles {
 			if role.UserId == int(user.Id) {
 				roles = append(roles, role.RoleId)
+				links = append(links, role)
 			}
 		}
 
 		if filteredRoles(req.Query, roles) && filteredLastSession(req.Query, user.LastSessionCreatedAt) {
-			items = append(items, u.toDomain(user, roles, user.LastSessionCreatedAt))
+			items = append(items, u.toDomain(user, roles, links, user.LastSessionCreatedAt))
 		}
 	}
 
@@ -190,22 +234,70 @@ func (u User) GetAllUsers(ctx context.Context) (*domain.UsersResponse, error) {
 	items := make([]domain.User, 0, len(users))
 	for _, user := range users {
 		roles := make([]int, 0)
+		links := make([]entity.UserRole, 0)
 
 		for _, role := range userRoles {
 			if role.UserId == int(user.Id) {
 				roles = append(roles, role.RoleId)
+				links = append(links, role)
 			}
 		}
 
-		items = append(items, u.toDomain(user, roles, user.LastSessionCreatedAt))
+		items = append(items, u.toDomain(user, roles, links, user.LastSessionCreatedAt))
 	}
 
 	return &domain.UsersResponse{Items: items}, nil
 }
 
+// CreateUser creates the user immediately or saves the change for approval, if privileged roles are granted
 func (u User) CreateUser(ctx context.Context, req domain.CreateUserRequest, adminId int64) (*domain.User, error) {
-	var usr entity.User
+	err := u.sodChecker.Check(ctx, req.Roles)
+	if err != nil {
+		return nil, errors.WithMessage(err, "check separation of duties")
+	}
+
+	passwordHash, err := u.cryptPassword(req.Password)
+	if err != nil {
+		return nil, errors.WithMessage(err, "crypt password")
+	}
+	if u.changeGate.Required(entity.ChangeUserCreate) {
+		err = u.submitCreateUser(ctx, req, passwordHash, adminId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return u.createUser(ctx, req, passwordHash, adminId)
+}
+
+// submitCreateUser saves the change for approval, if privileged roles are granted to the new user
+func (u User) submitCreateUser(ctx context.Context, req domain.CreateUserRequest, passwordHash string, adminId int64) error {
+	roles, err := u.roleRepoUser.GetRoleByIds(ctx, req.Roles)
+	if err != nil {
+		return errors.WithMessage(err, "get roles by ids")
+	}
+	privilegedRoles := u.changeGate.PrivilegedRoleNames(roles)
+	if len(privilegedRoles) == 0 {
+		return nil
+	}
+
+	users, err := u.userRepo.GetUsersByEmail(ctx, req.Email)
+	switch {
+	case err != nil:
+		return errors.WithMessage(err, "get users by email")
+	case len(users) > 0:
+		return domain.ErrAlreadyExists
+	}
+
+	req.Password = ""
+	diff := createUserDiff(req)
+	return u.changeGate.Submit(ctx, entity.ChangeUserCreate, domain.CreateUserChange{CreateUserRequest: req, PasswordHash: passwordHash},
+		fmt.Sprintf("Создание пользователя %s, назначение привилегированных ролей %v.\n %s", req.Email, privilegedRoles, diff),
+		adminId,
+	)
+}
 
+func (u User) createUser(ctx context.Context, req domain.CreateUserRequest, passwordHash string, adminId int64) (*domain.User, error) {
+	var usr entity.User
 	err := u.txRunner.UserTransaction(ctx, func(ctx context.Context, tx UserTransaction) error {
 		user, err := tx.GetUserByEmailAndSudirId(ctx, req.Email, "")
 		switch {
@@ -217,11 +309,6 @@ func (u User) CreateUser(ctx context.Context, req domain.CreateUserRequest, admi
 			return domain.ErrAlreadyExists
 		}
 
-		encryptedPassword, err := u.cryptPassword(req.Password)
-		if err != nil {
-			return errors.WithMessage(err, "crypt password")
-		}
-
 		usr = entity.User{
 			SudirUserId: nil,
 			Id:          0,
@@ -229,7 +316,7 @@ func (u User) CreateUser(ctx context.Context, req domain.CreateUserRequest, admi
 			LastName:    req.LastName,
 			FullName:    createFullName(req.FirstName, req.LastName),
 			Email:       req.Email,
-			Password:    encryptedPassword,
+			Password:    passwordHash,
 			Description: req.Description,
 			Blocked:     false,
 			UpdatedAt:   time.Now().UTC(),
@@ -239,6 +326,7 @@ func (u User) CreateUser(ctx context.Context, req domain.CreateUserRequest, admi
 		if err != nil {
 			return errors.WithMessage(err, "create user")
 		}
+		usr.Id = int64(id)
 
 		err = tx.UpsertUserRoleLinks(ctx, id, req.Roles)
 		if err != nil {
@@ -252,27 +340,42 @@ func (u User) CreateUser(ctx context.Context, req domain.CreateUserRequest, admi
 	}
 
 	slices.Sort(req.Roles)
-	diff := diffToString(map[string]any{
+	u.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Пользователь. Создание пользователя %d. \n %s", usr.Id, createUserDiff(req)),
+		entity.EventUserChanged,
+	)
+
+	return new(u.toDomain(usr, req.Roles, nil, nil)), nil
+}
+
+func createUserDiff(req domain.CreateUserRequest) string {
+	roles := slices.Sorted(slices.Values(req.Roles))
+	return diffToString(map[string]any{
 		"ФИО":       "",
 		"Описание":  "",
 		"Email":     "",
 		"Роли (ID)": []int{},
 	}, map[string]any{
-		"ФИО":       usr.FullName,
+		"ФИО":       createFullName(req.FirstName, req.LastName),
 		"Описание":  req.Description,
 		"Email":     req.Email,
-		"Роли (ID)": req.Roles,
+		"Роли (ID)": roles,
 	})
-	u.auditService.SaveAuditAsync(ctx, adminId,
-		fmt.Sprintf("Пользователь. Создание пользователя %d. \n %s", usr.Id, diff),
-		entity.EventUserChanged,
-	)
+}
 
-	return new(u.toDomain(usr, req.Roles, nil)), nil
+// UpdateUser applies the change immediately or saves it for approval, if privileged roles are granted
+func (u User) UpdateUser(ctx context.Context, req domain.UpdateUserRequest, adminId int64) (*domain.User, error) {
+	if u.changeGate.Required(entity.ChangeUserUpdate) {
+		err := u.submitUpdateUser(ctx, req, adminId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return u.updateUser(ctx, req, adminId)
 }
 
 //nolint:cyclop,funlen
-func (u User) UpdateUser(ctx context.Context, req domain.UpdateUserRequest, adminId int64) (*domain.User, error) {
+func (u User) updateUser(ctx context.Context, req domain.UpdateUserRequest, adminId int64) (*domain.User, error) {
 	var (
 		user                 *entity.User
 		updatedUser          *entity.User
@@ -282,6 +385,14 @@ func (u User) UpdateUser(ctx context.Context, req domain.UpdateUserRequest, admi
 	if err != nil {
 		return nil, errors.WithMessage(err, "get user roles")
 	}
+	links, err := userRoleLinks(int(req.Id), req.Roles, req.TemporaryRoles, oldRoles)
+	if err != nil {
+		return nil, err
+	}
+	err = u.sodChecker.Check(ctx, RolesIds(links))
+	if err != nil {
+		return nil, errors.WithMessage(err, "check separation of duties")
+	}
 	err = u.txRunner.UserTransaction(ctx, func(ctx context.Context, tx UserTransaction) error {
 		user, err = tx.GetUserById(ctx, req.Id)
 		switch {
@@ -317,16 +428,23 @@ func (u User) UpdateUser(ctx context.Context, req domain.UpdateUserRequest, admi
 			FullName:    createFullName(req.FirstName, req.LastName),
 			Email:       req.Email,
 			Description: req.Description,
+			Version:     req.Version,
 		}
 		updatedUser, err = tx.UpdateUser(ctx, req.Id, updateEntity)
 		if err != nil {
 			return errors.WithMessage(err, "update user")
 		}
 
-		err = tx.UpsertUserRoleLinks(ctx, int(updatedUser.Id), req.Roles)
+		err = tx.ReplaceUserRoleLinks(ctx, int(updatedUser.Id), links)
 		if err != nil {
 			return errors.WithMessage(err, "update user role links")
 		}
+		// changes of role links increment the version too
+		current, err := tx.GetUserById(ctx, updatedUser.Id)
+		if err != nil {
+			return errors.WithMessage(err, "get updated user")
+		}
+		updatedUser.Version = current.Version
 
 		userLastSession, err := tx.LastAccessByUserIds(ctx, []int{int(updatedUser.Id)})
 		if err != nil {
@@ -336,36 +454,47 @@ func (u User) UpdateUser(ctx context.Context, req domain.UpdateUserRequest, admi
 
 		return nil
 	})
-	if err != nil {
+	switch {
+	case errors.Is(err, domain.ErrVersionConflict):
+		return nil, u.versionConflict(ctx, req.Id)
+	case err != nil:
 		return nil, errors.WithMessage(err, "update user transaction")
 	}
 
-	slices.Sort(req.Roles)
-	diff := diffToString(map[string]any{
-		"ФИО":       user.FullName,
-		"Описание":  user.Description,
-		"Email":     user.Email,
-		"Роли (ID)": RolesIds(oldRoles),
-	}, map[string]any{
-		"ФИО":       updatedUser.FullName,
-		"Описание":  req.Description,
-		"Email":     req.Email,
-		"Роли (ID)": req.Roles,
-	})
+	roleIds := RolesIds(links)
+	diff := updateUserDiff(*user, oldRoles, req, links)
 	u.auditService.SaveAuditAsync(ctx, adminId,
 		fmt.Sprintf("Пользователь. Изменение пользователя %d.\n %s", updatedUser.Id, diff),
 		entity.EventUserChanged,
 	)
 
-	return new(u.toDomain(*updatedUser, req.Roles, lastSessionCreatedAt)), nil
+	return new(u.toDomain(*updatedUser, roleIds, links, lastSessionCreatedAt)), nil
 }
 
+// DeleteUsers deletes users immediately or saves the change for approval, if user_delete requires it
 func (u User) DeleteUsers(ctx context.Context, ids []int64, adminId int64) (int, error) {
 	if len(ids) == 0 {
 		return 0, nil
 	}
+	if u.changeGate.Required(entity.ChangeUserDelete) {
+		err := u.changeGate.Submit(ctx, entity.ChangeUserDelete, domain.IdentitiesRequest{Ids: ids},
+			fmt.Sprintf("Удаление пользователей %v.", ids), adminId)
+		return 0, err
+	}
+	return u.deleteUsers(ctx, ids, adminId)
+}
+
+func (u User) deleteUsers(ctx context.Context, ids []int64, adminId int64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
 
-	count, err := u.userRepo.DeleteUser(ctx, ids)
+	var count int
+	err := u.txRunner.UserTransaction(ctx, func(ctx context.Context, tx UserTransaction) error {
+		var err error
+		count, err = tx.DeleteUser(ctx, ids)
+		return err
+	})
 	if err != nil {
 		return 0, errors.WithMessage(err, "delete users")
 	}
@@ -394,7 +523,7 @@ func (u User) GetById(ctx context.Context, userId int) (*domain.User, error) {
 		return nil, errors.WithMessage(err, "get last sessions by user id")
 	}
 
-	return new(u.toDomain(*user, RolesIds(roles), lastSessions[int64(userId)])), nil
+	return new(u.toDomain(*user, RolesIds(roles), roles, lastSessions[int64(userId)])), nil
 }
 
 func (u User) Block(ctx context.Context, adminId int64, userId int) error {
@@ -446,6 +575,8 @@ func RolesIds(roles []entity.UserRole) []int {
 	return roleList
 }
 
+// mergePermissions returns unique permissions of roles, grants overridden by deny entries are excluded,
+// wildcard patterns and deny entries are kept as is
 func mergePermissions(roles []entity.Role) []string {
 	permissionsMap := make(map[string]bool)
 	permList := make([]string, 0)
@@ -459,7 +590,109 @@ func mergePermissions(roles []entity.Role) []string {
 		}
 	}
 
-	return permList
+	entries := slices.Clone(permList)
+	return slices.DeleteFunc(permList, func(perm string) bool {
+		return !permission_match.IsDeny(perm) && permission_match.Denied(entries, perm)
+	})
+}
+
+// effectivePermissions returns permissions granted by roles, wildcard grants are expanded against the catalog
+// and denied keys are excluded, merged entries are returned as is if the catalog is empty
+func effectivePermissions(ctx context.Context, catalog permissionCatalog, roles []entity.Role) ([]string, error) {
+	keys, err := catalogKeys(ctx, catalog)
+	if err != nil {
+		return nil, err
+	}
+	return permission_match.Expand(mergePermissions(roles), keys), nil
+}
+
+// mergeResourceScopes returns scopes of permissions, which are not granted for all resources by any role
+func mergeResourceScopes(roles []entity.Role, permissions []string) []domain.ResourceScope {
+	scopes := make([]entity.ResourceScope, 0)
+	for _, permission := range permissions {
+		if permission_match.IsDeny(permission) {
+			continue
+		}
+
+		permissionScopes := make([]entity.ResourceScope, 0)
+		unscoped := false
+		for _, role := range roles {
+			if !permission_match.Allowed(role.Permissions, permission) {
+				continue
+			}
+			roleScopes := permission_match.Scopes(role, permission)
+			if len(roleScopes) == 0 {
+				unscoped = true
+				break
+			}
+			permissionScopes = append(permissionScopes, roleScopes...)
+		}
+		if !unscoped {
+			scopes = append(scopes, permissionScopes...)
+		}
+	}
+	return permission_match.MergeScopes(scopes)
+}
+
+// submitUpdateUser saves the change for approval if it grants privileged roles, nil is returned otherwise
+func (u User) submitUpdateUser(ctx context.Context, req domain.UpdateUserRequest, adminId int64) error {
+	oldRoles, err := u.userRoleRepo.GetRolesByUserIds(ctx, []int{int(req.Id)})
+	if err != nil {
+		return errors.WithMessage(err, "get user roles")
+	}
+	links, err := userRoleLinks(int(req.Id), req.Roles, req.TemporaryRoles, oldRoles)
+	if err != nil {
+		return err
+	}
+	err = u.sodChecker.Check(ctx, RolesIds(links))
+	if err != nil {
+		return errors.WithMessage(err, "check separation of duties")
+	}
+
+	oldRoleIds := RolesIds(oldRoles)
+	addedRoleIds := slices.DeleteFunc(RolesIds(links), func(roleId int) bool {
+		return slices.Contains(oldRoleIds, roleId)
+	})
+	if len(addedRoleIds) == 0 {
+		return nil
+	}
+	roles, err := u.roleRepoUser.GetRoleByIds(ctx, addedRoleIds)
+	if err != nil {
+		return errors.WithMessage(err, "get roles by ids")
+	}
+	privilegedRoles := u.changeGate.PrivilegedRoleNames(roles)
+	if len(privilegedRoles) == 0 {
+		return nil
+	}
+
+	user, err := u.userRepo.GetUserById(ctx, req.Id)
+	switch {
+	case err != nil:
+		return errors.WithMessagef(err, "get user by id %d", req.Id)
+	case req.Version != 0 && user.Version != req.Version:
+		return u.versionConflict(ctx, req.Id)
+	}
+	diff := updateUserDiff(*user, oldRoles, req, links)
+	return u.changeGate.Submit(ctx, entity.ChangeUserUpdate, req,
+		fmt.Sprintf("Изменение пользователя %d, назначение привилегированных ролей %v.\n %s", req.Id, privilegedRoles, diff),
+		adminId,
+	)
+}
+
+func updateUserDiff(user entity.User, oldLinks []entity.UserRole, req domain.UpdateUserRequest, links []entity.UserRole) string {
+	return diffToString(map[string]any{
+		"ФИО":            user.FullName,
+		"Описание":       user.Description,
+		"Email":          user.Email,
+		"Роли (ID)":      RolesIds(oldLinks),
+		"Временные роли": temporaryRolesToStrings(oldLinks),
+	}, map[string]any{
+		"ФИО":            createFullName(req.FirstName, req.LastName),
+		"Описание":       req.Description,
+		"Email":          req.Email,
+		"Роли (ID)":      RolesIds(links),
+		"Временные роли": temporaryRolesToStrings(links),
+	})
 }
 
 func diffToString(a map[string]any, b map[string]any) string {
@@ -491,7 +724,7 @@ func (u User) ChangePassword(ctx context.Context, adminId int64, oldPassword str
 			return errors.WithMessage(err, "user.service.ChangePassword: get user by id")
 		}
 
-		err = bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(oldPassword))
+		_, err = u.passwordHasher.Verify(admin.Password, oldPassword)
 		if err != nil {
 			u.auditService.SaveAuditAsync(ctx, adminId, "Указан неверный старый пароль", entity.EventErrorPasswordChange)
 			return domain.ErrInvalidPassword
@@ -530,30 +763,41 @@ func (u User) ChangePassword(ctx context.Context, adminId int64, oldPassword str
 }
 
 func (u User) cryptPassword(password string) (string, error) {
-	passwordBytes, err := bcrypt.GenerateFromPassword([]byte(password), 12) //nolint:mnd
+	hash, err := u.passwordHasher.Hash(password)
 	if err != nil {
-		return "", errors.WithMessage(err, "gen bcrypt from password")
+		return "", errors.WithMessage(err, "hash password")
 	}
 
-	return string(passwordBytes), nil
+	return hash, nil
 }
 
-func (u User) toDomain(user entity.User, roleIds []int, lastSessionCreatedAt *time.Time) domain.User {
+func (u User) toDomain(user entity.User, roleIds []int, links []entity.UserRole, lastSessionCreatedAt *time.Time) domain.User {
 	return domain.User{
 		Id:                   user.Id,
 		Roles:                roleIds,
+		TemporaryRoles:       toTemporaryRoles(links),
 		FirstName:            user.FirstName,
 		LastName:             user.LastName,
 		FullName:             user.FullName,
 		Description:          user.Description,
 		Email:                user.Email,
 		Blocked:              user.Blocked,
+		Version:              user.Version,
 		UpdatedAt:            user.UpdatedAt,
 		CreatedAt:            user.CreatedAt,
 		LastSessionCreatedAt: lastSessionCreatedAt,
 	}
 }
 
+// versionConflict returns domain.VersionConflictError with the current state of the user
+func (u User) versionConflict(ctx context.Context, userId int64) error {
+	current, err := u.GetById(ctx, int(userId))
+	if err != nil {
+		return errors.WithMessage(err, "get current user")
+	}
+	return domain.VersionConflictError{Current: *current}
+}
+
 func filteredRoles(reqQuery *domain.UserQuery, roleIds []int) bool {
 	if reqQuery == nil {
 		return true
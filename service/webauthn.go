@@ -0,0 +1,513 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+)
+
+const (
+	defaultWebauthnCeremonyLifeTime = 5 * time.Minute
+)
+
+type webauthnRepo interface {
+	GetWebauthnCredentials(ctx context.Context, userId int64) ([]entity.WebauthnCredential, error)
+	UpdateWebauthnCredentialUsage(ctx context.Context, id int, credential json.RawMessage, lastUsedAt time.Time) error
+	InsertWebauthnSession(ctx context.Context, session entity.WebauthnSession) error
+	ConsumeWebauthnSession(ctx context.Context, id string, now time.Time) (*entity.WebauthnSession, error)
+}
+
+type webauthnLoginRepo interface {
+	webauthnRepo
+	GetUserById(ctx context.Context, identity int64) (*entity.User, error)
+}
+
+type webauthnCredentialRepo interface {
+	webauthnRepo
+	InsertWebauthnCredential(ctx context.Context, credential entity.WebauthnCredential) (*entity.WebauthnCredential, error)
+	RenameWebauthnCredential(ctx context.Context, userId int64, id int, name string) (*entity.WebauthnCredential, error)
+	DeleteWebauthnCredential(ctx context.Context, userId int64, id int) (*entity.WebauthnCredential, error)
+}
+
+type webauthnUserRepo interface {
+	GetUserById(ctx context.Context, identity int64) (*entity.User, error)
+}
+
+type Webauthn struct {
+	cfg            *conf.Webauthn
+	webauthn       *webauthn.WebAuthn
+	initErr        error
+	userRepo       webauthnUserRepo
+	credentialRepo webauthnCredentialRepo
+	auditService   auditService
+}
+
+func NewWebauthn(
+	cfg *conf.Webauthn,
+	userRepo webauthnUserRepo,
+	credentialRepo webauthnCredentialRepo,
+	auditService auditService,
+) Webauthn {
+	s := Webauthn{
+		cfg:            cfg,
+		userRepo:       userRepo,
+		credentialRepo: credentialRepo,
+		auditService:   auditService,
+	}
+	if cfg == nil {
+		return s
+	}
+
+	displayName := cfg.RpDisplayName
+	if displayName == "" {
+		displayName = cfg.RpId
+	}
+	s.webauthn, s.initErr = webauthn.New(&webauthn.Config{
+		RPID:          cfg.RpId,
+		RPDisplayName: displayName,
+		RPOrigins:     cfg.RpOrigins,
+	})
+	return s
+}
+
+func (s Webauthn) Enabled() bool {
+	return s.cfg != nil
+}
+
+// BeginLogin starts assertion ceremony, if user is nil any discoverable credential (passkey) is accepted
+func (s Webauthn) BeginLogin(ctx context.Context, repo webauthnRepo, user *entity.User) (*domain.WebauthnChallenge, error) {
+	err := s.checkEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	if user != nil {
+		credentials, err := repo.GetWebauthnCredentials(ctx, user.Id)
+		if err != nil {
+			return nil, errors.WithMessage(err, "get webauthn credentials")
+		}
+		if len(credentials) > 0 {
+			return s.beginAssertion(ctx, repo, user, credentials, entity.WebauthnCeremonyLogin)
+		}
+	}
+
+	return s.beginAssertion(ctx, repo, nil, nil, entity.WebauthnCeremonyLogin)
+}
+
+// BeginSecondFactor starts assertion ceremony for user authenticated with password,
+// returns nil if user has no registered credentials
+func (s Webauthn) BeginSecondFactor(ctx context.Context, repo webauthnRepo, user entity.User) (*domain.WebauthnChallenge, error) {
+	if !s.Enabled() {
+		return nil, nil // nolint:nilnil
+	}
+
+	credentials, err := repo.GetWebauthnCredentials(ctx, user.Id)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get webauthn credentials")
+	}
+	if len(credentials) == 0 {
+		return nil, nil // nolint:nilnil
+	}
+	err = s.checkEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.beginAssertion(ctx, repo, &user, credentials, entity.WebauthnCeremonySecondFactor)
+}
+
+// FinishLogin verifies assertion and returns id of credential owner and ceremony type
+func (s Webauthn) FinishLogin(
+	ctx context.Context,
+	repo webauthnLoginRepo,
+	request domain.WebauthnFinishRequest,
+) (int64, string, error) {
+	err := s.checkEnabled()
+	if err != nil {
+		return 0, "", err
+	}
+
+	session, sessionData, err := s.consumeSession(ctx, repo, request.SessionId)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return 0, "", errors.WithMessage(domain.ErrUnauthenticated, "webauthn session is invalid or expired")
+	case err != nil:
+		return 0, "", errors.WithMessage(err, "consume webauthn session")
+	case session.Ceremony == entity.WebauthnCeremonyRegistration:
+		return 0, "", errors.WithMessage(domain.ErrUnauthenticated, "webauthn session is not a login session")
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(request.Credential)
+	if err != nil {
+		return 0, "", errors.WithMessagef(domain.ErrUnauthenticated, "parse assertion: %v", err)
+	}
+
+	var (
+		user       *webauthnUser
+		loadErr    error
+		credential *webauthn.Credential
+	)
+	if session.UserId != nil {
+		user, loadErr = s.loadUser(ctx, repo, repo, *session.UserId)
+		if loadErr != nil {
+			return 0, "", errors.WithMessage(loadErr, "load webauthn user")
+		}
+		credential, err = s.webauthn.ValidateLogin(user, *sessionData, parsed)
+	} else {
+		handler := func(_, userHandle []byte) (webauthn.User, error) {
+			userId, err := strconv.ParseInt(string(userHandle), 10, 64)
+			if err != nil {
+				return nil, errors.WithMessage(domain.ErrNotFound, "invalid user handle")
+			}
+			user, loadErr = s.loadUser(ctx, repo, repo, userId)
+			if loadErr != nil {
+				return nil, loadErr
+			}
+			return user, nil
+		}
+		credential, err = s.webauthn.ValidateDiscoverableLogin(handler, *sessionData, parsed)
+	}
+	switch {
+	case loadErr != nil && !errors.Is(loadErr, domain.ErrNotFound):
+		return 0, "", errors.WithMessage(loadErr, "load webauthn user")
+	case err != nil:
+		return 0, "", errors.WithMessagef(domain.ErrUnauthenticated, "validate assertion: %v", err)
+	case credential.Authenticator.CloneWarning:
+		return 0, "", errors.WithMessagef(domain.ErrUnauthenticated, "signature counter of user '%d' credential is not increased", user.user.Id)
+	}
+
+	err = s.saveUsage(ctx, repo, *user, *credential)
+	if err != nil {
+		return 0, "", errors.WithMessage(err, "save webauthn credential usage")
+	}
+
+	return user.user.Id, session.Ceremony, nil
+}
+
+func (s Webauthn) BeginRegistration(ctx context.Context, adminId int64) (*domain.WebauthnChallenge, error) {
+	err := s.checkEnabled()
+	if err != nil {
+		return nil, err
+	}
+	// passkey of impersonated user would outlive impersonation session
+	if _, ok := domain.ImpersonatorFromContext(ctx); ok {
+		return nil, errors.WithMessage(domain.ErrImpersonationDenied, "register webauthn credential")
+	}
+
+	user, err := s.loadUser(ctx, s.userRepo, s.credentialRepo, adminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "load webauthn user")
+	}
+	if user.user.SudirUserId != nil {
+		return nil, domain.ErrSudirAuthorization
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.credentials))
+	for _, credential := range user.credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+	creation, sessionData, err := s.webauthn.BeginRegistration(user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, errors.WithMessage(err, "begin webauthn registration")
+	}
+
+	return s.saveSession(ctx, s.credentialRepo, &adminId, entity.WebauthnCeremonyRegistration, creation, sessionData)
+}
+
+func (s Webauthn) FinishRegistration(
+	ctx context.Context,
+	adminId int64,
+	request domain.WebauthnFinishRegistrationRequest,
+) (*domain.WebauthnCredential, error) {
+	err := s.checkEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	session, sessionData, err := s.consumeSession(ctx, s.credentialRepo, request.SessionId)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return nil, errors.WithMessage(domain.ErrInvalid, "webauthn session is invalid or expired")
+	case err != nil:
+		return nil, errors.WithMessage(err, "consume webauthn session")
+	case session.Ceremony != entity.WebauthnCeremonyRegistration || session.UserId == nil || *session.UserId != adminId:
+		return nil, errors.WithMessage(domain.ErrInvalid, "webauthn session is not a registration session of user")
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(request.Credential)
+	if err != nil {
+		return nil, errors.WithMessagef(domain.ErrInvalid, "parse attestation: %v", err)
+	}
+
+	user, err := s.loadUser(ctx, s.userRepo, s.credentialRepo, adminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "load webauthn user")
+	}
+	credential, err := s.webauthn.CreateCredential(user, *sessionData, parsed)
+	if err != nil {
+		return nil, errors.WithMessagef(domain.ErrInvalid, "validate attestation: %v", err)
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal webauthn credential")
+	}
+	created, err := s.credentialRepo.InsertWebauthnCredential(ctx, entity.WebauthnCredential{
+		UserId:       adminId,
+		CredentialId: credential.ID,
+		Name:         request.Name,
+		Credential:   data,
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "insert webauthn credential")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Ключи доступа. Добавлен ключ %d «%s»", created.Id, created.Name),
+		entity.EventWebauthnChanged,
+	)
+
+	return new(s.toDomain(*created)), nil
+}
+
+func (s Webauthn) Credentials(ctx context.Context, adminId int64) ([]domain.WebauthnCredential, error) {
+	credentials, err := s.credentialRepo.GetWebauthnCredentials(ctx, adminId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get webauthn credentials")
+	}
+
+	result := make([]domain.WebauthnCredential, 0, len(credentials))
+	for _, credential := range credentials {
+		result = append(result, s.toDomain(credential))
+	}
+	return result, nil
+}
+
+func (s Webauthn) Rename(
+	ctx context.Context,
+	adminId int64,
+	request domain.RenameWebauthnCredentialRequest,
+) (*domain.WebauthnCredential, error) {
+	credential, err := s.credentialRepo.RenameWebauthnCredential(ctx, adminId, request.Id, request.Name)
+	if err != nil {
+		return nil, errors.WithMessage(err, "rename webauthn credential")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Ключи доступа. Ключ %d переименован в «%s»", credential.Id, credential.Name),
+		entity.EventWebauthnChanged,
+	)
+
+	return new(s.toDomain(*credential)), nil
+}
+
+func (s Webauthn) Delete(ctx context.Context, adminId int64, request domain.DeleteWebauthnCredentialRequest) error {
+	credential, err := s.credentialRepo.DeleteWebauthnCredential(ctx, adminId, request.Id)
+	if err != nil {
+		return errors.WithMessage(err, "delete webauthn credential")
+	}
+
+	s.auditService.SaveAuditAsync(ctx, adminId,
+		fmt.Sprintf("Ключи доступа. Удален ключ %d «%s»", credential.Id, credential.Name),
+		entity.EventWebauthnChanged,
+	)
+
+	return nil
+}
+
+func (s Webauthn) beginAssertion(
+	ctx context.Context,
+	repo webauthnRepo,
+	user *entity.User,
+	credentials []entity.WebauthnCredential,
+	ceremony string,
+) (*domain.WebauthnChallenge, error) {
+	if user == nil {
+		assertion, sessionData, err := s.webauthn.BeginDiscoverableLogin()
+		if err != nil {
+			return nil, errors.WithMessage(err, "begin webauthn discoverable login")
+		}
+		return s.saveSession(ctx, repo, nil, ceremony, assertion, sessionData)
+	}
+
+	webauthnUser, err := newWebauthnUser(*user, credentials)
+	if err != nil {
+		return nil, err
+	}
+	assertion, sessionData, err := s.webauthn.BeginLogin(webauthnUser)
+	if err != nil {
+		return nil, errors.WithMessage(err, "begin webauthn login")
+	}
+	return s.saveSession(ctx, repo, &user.Id, ceremony, assertion, sessionData)
+}
+
+func (s Webauthn) saveSession(
+	ctx context.Context,
+	repo webauthnRepo,
+	userId *int64,
+	ceremony string,
+	options any,
+	sessionData *webauthn.SessionData,
+) (*domain.WebauthnChallenge, error) {
+	optionsData, err := json.Marshal(options)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal webauthn options")
+	}
+	data, err := json.Marshal(sessionData)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal webauthn session")
+	}
+
+	now := time.Now().UTC()
+	session := entity.WebauthnSession{
+		Id:        uuid.NewString(),
+		UserId:    userId,
+		Ceremony:  ceremony,
+		Data:      data,
+		ExpiredAt: now.Add(s.ceremonyLifeTime()),
+		CreatedAt: now,
+	}
+	err = repo.InsertWebauthnSession(ctx, session)
+	if err != nil {
+		return nil, errors.WithMessage(err, "insert webauthn session")
+	}
+
+	return &domain.WebauthnChallenge{
+		SessionId: session.Id,
+		Options:   optionsData,
+	}, nil
+}
+
+func (s Webauthn) consumeSession(
+	ctx context.Context,
+	repo webauthnRepo,
+	id string,
+) (*entity.WebauthnSession, *webauthn.SessionData, error) {
+	session, err := repo.ConsumeWebauthnSession(ctx, id, time.Now().UTC())
+	if err != nil {
+		return nil, nil, err // nolint:wrapcheck
+	}
+
+	sessionData := webauthn.SessionData{}
+	err = json.Unmarshal(session.Data, &sessionData)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "unmarshal webauthn session")
+	}
+
+	return session, &sessionData, nil
+}
+
+func (s Webauthn) loadUser(
+	ctx context.Context,
+	userRepo webauthnUserRepo,
+	credentialRepo webauthnRepo,
+	userId int64,
+) (*webauthnUser, error) {
+	user, err := userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get user by id")
+	}
+	credentials, err := credentialRepo.GetWebauthnCredentials(ctx, userId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get webauthn credentials")
+	}
+	return newWebauthnUser(*user, credentials)
+}
+
+func (s Webauthn) saveUsage(ctx context.Context, repo webauthnRepo, user webauthnUser, credential webauthn.Credential) error {
+	for _, stored := range user.stored {
+		if string(stored.CredentialId) != string(credential.ID) {
+			continue
+		}
+
+		data, err := json.Marshal(credential)
+		if err != nil {
+			return errors.WithMessage(err, "marshal webauthn credential")
+		}
+		return repo.UpdateWebauthnCredentialUsage(ctx, stored.Id, data, time.Now().UTC()) // nolint:wrapcheck
+	}
+	return errors.Errorf("webauthn credential of user '%d' is not found", user.user.Id)
+}
+
+func (s Webauthn) checkEnabled() error {
+	if s.cfg == nil {
+		return domain.ErrWebauthnIsMissed
+	}
+	if s.initErr != nil {
+		return errors.WithMessage(s.initErr, "init webauthn")
+	}
+	return nil
+}
+
+func (s Webauthn) ceremonyLifeTime() time.Duration {
+	if s.cfg.CeremonyExpireSec <= 0 {
+		return defaultWebauthnCeremonyLifeTime
+	}
+	return time.Duration(s.cfg.CeremonyExpireSec) * time.Second
+}
+
+func (s Webauthn) toDomain(credential entity.WebauthnCredential) domain.WebauthnCredential {
+	return domain.WebauthnCredential{
+		Id:         credential.Id,
+		Name:       credential.Name,
+		LastUsedAt: credential.LastUsedAt,
+		CreatedAt:  credential.CreatedAt,
+	}
+}
+
+// webauthnUser adapts admin user to webauthn.User, user id is used as user handle
+type webauthnUser struct {
+	user        entity.User
+	stored      []entity.WebauthnCredential
+	credentials []webauthn.Credential
+}
+
+func newWebauthnUser(user entity.User, stored []entity.WebauthnCredential) (*webauthnUser, error) {
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, credential := range stored {
+		value := webauthn.Credential{}
+		err := json.Unmarshal(credential.Credential, &value)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "unmarshal webauthn credential %d", credential.Id)
+		}
+		credentials = append(credentials, value)
+	}
+	return &webauthnUser{
+		user:        user,
+		stored:      stored,
+		credentials: credentials,
+	}, nil
+}
+
+func (u *webauthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatInt(u.user.Id, 10))
+}
+
+func (u *webauthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webauthnUser) WebAuthnDisplayName() string {
+	if u.user.FullName != "" {
+		return u.user.FullName
+	}
+	return u.user.Email
+}
+
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
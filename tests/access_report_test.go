@@ -0,0 +1,145 @@
+package tests_test
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAccessReportTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &AccessReportTestSuite{})
+}
+
+type AccessReportTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *AccessReportTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), conf.Remote{ExpireSec: 3600}, time.Minute)
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+	s.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *AccessReportTestSuite) TestPermissions() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	baseId := InsertRole(s.db, entity.Role{Name: "base", Permissions: []string{"report_view"}})
+	childId := InsertRole(s.db, entity.Role{Name: "child", Permissions: []string{"report_update"}, ParentIds: []int{int(baseId)}})
+	denyId := InsertRole(s.db, entity.Role{Name: "deny", Permissions: []string{"!report_view"}})
+	firstId := InsertUser(s.db, entity.User{Email: "first@a.ru", FullName: "Иванов Иван"})
+	secondId := InsertUser(s.db, entity.User{Email: "second@a.ru"})
+	expiredId := InsertUser(s.db, entity.User{Email: "expired@a.ru"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(firstId), RoleId: int(childId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(secondId), RoleId: int(baseId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(secondId), RoleId: int(denyId)})
+	validUntil := time.Now().UTC().Add(-time.Hour)
+	InsertUserRole(s.db, entity.UserRole{UserId: int(expiredId), RoleId: int(baseId), ValidUntil: &validUntil})
+
+	users := make([]domain.UserAccess, 0)
+	err := s.invoke("admin/report/users_by_permission", adminId, domain.PermissionReportRequest{Permission: "report_view"}, &users)
+	s.Require().NoError(err)
+	s.Require().Len(users, 1)
+	s.Require().Equal(firstId, users[0].UserId)
+	s.Require().Equal("Иванов Иван", users[0].FullName)
+	s.Require().Equal([]string{"child"}, users[0].Roles)
+
+	report := domain.UserPermissionsReport{}
+	err = s.invoke("admin/report/user_permissions", adminId, domain.UserPermissionsReportRequest{UserId: firstId}, &report)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"child"}, report.Roles)
+	s.Require().Equal([]string{"report_update", "report_view"}, report.Permissions)
+	err = s.invoke("admin/report/user_permissions", adminId, domain.UserPermissionsReportRequest{UserId: 100}, nil)
+	s.requireCode(codes.NotFound, err)
+
+	roles := make([]domain.RoleAccess, 0)
+	err = s.invoke("admin/report/roles_by_permission", adminId, domain.PermissionReportRequest{Permission: "report_view"}, &roles)
+	s.Require().NoError(err)
+	s.Require().Equal([]domain.RoleAccess{
+		{RoleId: int(baseId), Name: "base", UsersCount: 2},
+		{RoleId: int(childId), Name: "child", Inherited: true, UsersCount: 1},
+	}, roles)
+}
+
+func (s *AccessReportTestSuite) TestUnusedAndInactive() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru", LastActiveAt: time.Now().UTC()})
+	usedId := InsertRole(s.db, entity.Role{Name: "used"})
+	unusedId := InsertRole(s.db, entity.Role{Name: "unused"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(adminId), RoleId: int(usedId)})
+	inactiveId := InsertUser(s.db, entity.User{Email: "inactive@a.ru", LastActiveAt: time.Now().UTC().AddDate(0, 0, -40)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(inactiveId), RoleId: int(usedId)})
+	blockedId := InsertUser(s.db, entity.User{Email: "blocked@a.ru", Blocked: true, LastActiveAt: time.Now().UTC().AddDate(0, 0, -40)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(blockedId), RoleId: int(usedId)})
+	InsertUser(s.db, entity.User{Email: "no_roles@a.ru", LastActiveAt: time.Now().UTC().AddDate(0, 0, -40)})
+	expiredId := InsertRole(s.db, entity.Role{Name: "expired"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(adminId), RoleId: int(expiredId), ValidUntil: new(time.Now().UTC().Add(-time.Hour))})
+
+	roles := make([]domain.RoleAccess, 0)
+	err := s.invoke("admin/report/unused_roles", adminId, nil, &roles)
+	s.Require().NoError(err)
+	s.Require().True(slices.ContainsFunc(roles, func(role domain.RoleAccess) bool { return role.RoleId == int(unusedId) }))
+	s.Require().True(slices.ContainsFunc(roles, func(role domain.RoleAccess) bool { return role.RoleId == int(expiredId) }))
+	s.Require().False(slices.ContainsFunc(roles, func(role domain.RoleAccess) bool { return role.RoleId == int(usedId) }))
+
+	users := make([]domain.UserAccess, 0)
+	err = s.invoke("admin/report/inactive_users", adminId, domain.InactiveUsersReportRequest{Days: 30}, &users)
+	s.Require().NoError(err)
+	s.Require().Len(users, 1)
+	s.Require().Equal(inactiveId, users[0].UserId)
+	s.Require().Equal([]string{"used"}, users[0].Roles)
+
+	file := domain.AccessReportFile{}
+	err = s.invoke("admin/report/csv", adminId, domain.AccessReportCsvRequest{Report: domain.AccessReportInactiveUsers, Days: 30}, &file)
+	s.Require().NoError(err)
+	s.Require().Equal("inactive_users.csv", file.FileName)
+	s.Require().Contains(file.Content, "ID пользователя,Email,ФИО,Заблокирован,Последняя активность,Роли\n")
+	s.Require().Contains(file.Content, strconv.Itoa(int(inactiveId))+",inactive@a.ru,,false,")
+
+	err = s.invoke("admin/report/csv", adminId, domain.AccessReportCsvRequest{Report: domain.AccessReportInactiveUsers}, nil)
+	s.requireCode(codes.InvalidArgument, err)
+}
+
+func (s *AccessReportTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
+
+func (s *AccessReportTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(req)
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
@@ -0,0 +1,220 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+	"msp-admin-service/service"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAccessRequestTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &AccessRequestTestSuite{})
+}
+
+type AccessRequestTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *AccessRequestTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *AccessRequestTestSuite) TestApproveByRoleApprover() {
+	userId := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+	approverId := InsertUser(s.db, entity.User{Email: "approver@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "editor", ApproverIds: entity.IdList{approverId}})
+	validUntil := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second)
+
+	request := domain.AccessRequest{}
+	err := s.invoke("admin/access_request/create", userId, domain.CreateAccessRequestRequest{
+		RoleId:        int(roleId),
+		Justification: "release duty",
+		ValidUntil:    &validUntil,
+	}, &request)
+	s.Require().NoError(err)
+	s.Require().Equal(entity.AccessRequestPending, request.Status)
+
+	err = s.invoke("admin/access_request/create", userId, domain.CreateAccessRequestRequest{
+		RoleId:        int(roleId),
+		Justification: "release duty",
+	}, nil)
+	s.requireCode(codes.AlreadyExists, err)
+
+	list := domain.AccessRequestResponse{}
+	err = s.invoke("admin/access_request/all", approverId, domain.AccessRequestPageRequest{
+		LimitOffestParams: domain.LimitOffestParams{Limit: 10},
+		Query:             &domain.AccessRequestQuery{Status: []string{entity.AccessRequestPending}},
+	}, &list)
+	s.Require().NoError(err)
+	s.Require().Equal(1, list.TotalCount)
+	s.Require().Equal(request.Id, list.Items[0].Id)
+
+	approved := domain.AccessRequest{}
+	err = s.invoke("admin/access_request/approve", approverId, domain.ApproveAccessRequestRequest{
+		Id:      request.Id,
+		Comment: "ok",
+	}, &approved)
+	s.Require().NoError(err)
+	s.Require().Equal(entity.AccessRequestApproved, approved.Status)
+	s.Require().Equal(approverId, *approved.ReviewerId)
+
+	link := entity.UserRole{}
+	s.db.Must().SelectRow(&link, "select user_id, role_id, valid_from, valid_until from user_roles where user_id = $1", userId)
+	s.Require().Equal(int(roleId), link.RoleId)
+	s.Require().True(validUntil.Equal(*link.ValidUntil))
+
+	err = s.invoke("admin/access_request/reject", approverId, domain.RejectAccessRequestRequest{Id: request.Id}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from audit where event = $1", entity.EventAccessRequestChanged)
+	s.Require().Equal(2, count)
+}
+
+func (s *AccessRequestTestSuite) TestReviewRights() {
+	userId := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+	otherId := InsertUser(s.db, entity.User{Email: "other@a.ru"})
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	approverRoleId := InsertRole(s.db, entity.Role{
+		Name:        "approver",
+		Permissions: []string{service.AccessRequestApprovePermission},
+	})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(adminId), RoleId: int(approverRoleId)})
+	roleId := InsertRole(s.db, entity.Role{Name: "editor"})
+
+	request := domain.AccessRequest{}
+	err := s.invoke("admin/access_request/create", adminId, domain.CreateAccessRequestRequest{
+		RoleId:        int(roleId),
+		Justification: "self",
+	}, &request)
+	s.Require().NoError(err)
+	err = s.invoke("admin/access_request/approve", adminId, domain.ApproveAccessRequestRequest{Id: request.Id}, nil)
+	s.requireCode(codes.PermissionDenied, err)
+
+	err = s.invoke("admin/access_request/create", userId, domain.CreateAccessRequestRequest{
+		RoleId:        int(roleId),
+		Justification: "need",
+	}, &request)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/access_request/reject", otherId, domain.RejectAccessRequestRequest{Id: request.Id}, nil)
+	s.requireCode(codes.PermissionDenied, err)
+
+	list := domain.AccessRequestResponse{}
+	err = s.invoke("admin/access_request/all", otherId, domain.AccessRequestPageRequest{
+		LimitOffestParams: domain.LimitOffestParams{Limit: 10},
+	}, &list)
+	s.Require().NoError(err)
+	s.Require().Equal(0, list.TotalCount)
+
+	err = s.invoke("admin/access_request/all", adminId, domain.AccessRequestPageRequest{
+		LimitOffestParams: domain.LimitOffestParams{Limit: 10},
+	}, &list)
+	s.Require().NoError(err)
+	s.Require().Equal(2, list.TotalCount)
+
+	rejected := domain.AccessRequest{}
+	err = s.invoke("admin/access_request/reject", adminId, domain.RejectAccessRequestRequest{
+		Id:      request.Id,
+		Comment: "not needed",
+	}, &rejected)
+	s.Require().NoError(err)
+	s.Require().Equal(entity.AccessRequestRejected, rejected.Status)
+	s.Require().Equal("not needed", rejected.ReviewComment)
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1", userId)
+	s.Require().Equal(0, count)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *AccessRequestTestSuite) TestCancel() {
+	userId := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+	otherId := InsertUser(s.db, entity.User{Email: "other@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "editor"})
+
+	request := domain.AccessRequest{}
+	err := s.invoke("admin/access_request/create", userId, domain.CreateAccessRequestRequest{
+		RoleId:        int(roleId),
+		Justification: "need",
+	}, &request)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/access_request/cancel", otherId, domain.CancelAccessRequestRequest{Id: request.Id}, nil)
+	s.requireCode(codes.PermissionDenied, err)
+
+	cancelled := domain.AccessRequest{}
+	err = s.invoke("admin/access_request/cancel", userId, domain.CancelAccessRequestRequest{Id: request.Id}, &cancelled)
+	s.Require().NoError(err)
+	s.Require().Equal(entity.AccessRequestCancelled, cancelled.Status)
+
+	err = s.invoke("admin/access_request/create", userId, domain.CreateAccessRequestRequest{
+		RoleId:        int(roleId),
+		Justification: "need again",
+	}, &request)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/access_request/create", userId, domain.CreateAccessRequestRequest{
+		RoleId:        100,
+		Justification: "need",
+	}, nil)
+	s.requireCode(codes.NotFound, err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *AccessRequestTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(req)
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
+
+func (s *AccessRequestTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
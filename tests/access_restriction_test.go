@@ -0,0 +1,269 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const accessRestrictionTimezone = "Europe/Moscow"
+
+func TestAccessRestrictionTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &AccessRestrictionTestSuite{})
+}
+
+type AccessRestrictionTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *AccessRestrictionTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	s.grpcCli = s.newClient(conf.AccessRestrictions{})
+}
+
+// newClient starts the server with restrictions in accessRestrictionTimezone checked on every authorization
+func (s *AccessRestrictionTestSuite) newClient(restrictions conf.AccessRestrictions) *client.Client {
+	restrictions.Timezone = accessRestrictionTimezone
+	restrictions.CheckOnAuthorize = true
+	remote := conf.Remote{
+		ExpireSec: 3600,
+		AntiBruteforce: conf.AntiBruteforce{
+			MaxInFlightLoginRequests: 10,
+		},
+		AccessRestrictions: restrictions,
+	}
+	cfg := assembly.NewLocator(s.test.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(s.test, cfg.Handler)
+	s.test.T().Cleanup(func() {
+		server.Shutdown()
+	})
+	return apiCli
+}
+
+func (s *AccessRestrictionTestSuite) TestLoginAllowedCidrs() {
+	userId := s.insertUserWithRole(entity.Role{
+		Name:         "vlan",
+		AllowedCidrs: entity.CidrList{"10.0.0.0/8", "fd00::/8"},
+	})
+
+	err := s.login("192.168.0.1, 10.1.2.3")
+	s.Require().NoError(err)
+
+	err = s.login("fd00::1")
+	s.Require().NoError(err)
+
+	err = s.login("192.168.0.1")
+	s.requireCode(codes.PermissionDenied, err)
+
+	err = s.login("")
+	s.requireCode(codes.PermissionDenied, err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var messages []string
+	s.db.Must().Select(&messages, "select message from audit where user_id = $1 and event = $2 order by id",
+		userId, entity.EventAccessRestricted)
+	s.Require().Len(messages, 2)
+	s.Require().Contains(messages[0], "IP-адрес: 192.168.0.1")
+	s.Require().Contains(messages[1], "IP-адрес: неизвестен")
+}
+
+func (s *AccessRestrictionTestSuite) TestLoginSpoofedForwardedFor() {
+	s.insertUserWithRole(entity.Role{
+		Name:         "vlan",
+		AllowedCidrs: entity.CidrList{"10.0.0.0/8"},
+	})
+
+	err := s.login("10.1.2.3, 192.168.0.1")
+	s.requireCode(codes.PermissionDenied, err)
+
+	err = s.loginWithMetadata("x-real-ip", "10.1.2.3", "x-forwarded-for", "192.168.0.1")
+	s.requireCode(codes.PermissionDenied, err)
+
+	s.grpcCli = s.newClient(conf.AccessRestrictions{TrustRealIp: true})
+	err = s.loginWithMetadata("x-real-ip", "192.168.0.1", "x-forwarded-for", "10.1.2.3")
+	s.requireCode(codes.PermissionDenied, err)
+
+	err = s.loginWithMetadata("x-real-ip", "10.1.2.3", "x-forwarded-for", "192.168.0.1")
+	s.Require().NoError(err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *AccessRestrictionTestSuite) TestLoginTimeWindows() {
+	location, err := time.LoadLocation(accessRestrictionTimezone)
+	s.Require().NoError(err)
+	today := isoWeekday(time.Now().In(location))
+
+	s.insertUserWithRole(entity.Role{
+		Name: "business_hours",
+		TimeWindows: entity.TimeWindowList{{
+			Weekdays: []int{today%7 + 1},
+			From:     "00:00",
+			To:       "00:00",
+		}},
+	})
+	err = s.login("10.0.0.1")
+	s.requireCode(codes.PermissionDenied, err)
+
+	s.db.Must().Exec(`update roles set time_windows = '[{"Weekdays":[],"From":"00:00","To":"00:00"}]'`)
+	err = s.login("10.0.0.1")
+	s.Require().NoError(err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *AccessRestrictionTestSuite) TestAuthorize() {
+	restrictedRoleId := InsertRole(s.db, entity.Role{
+		Name:         "restricted",
+		Permissions:  []string{"module_edit"},
+		AllowedCidrs: entity.CidrList{"10.0.0.0/8"},
+	})
+	roleId := InsertRole(s.db, entity.Role{
+		Name:        "viewer",
+		Permissions: []string{"module_view"},
+	})
+	userId := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(restrictedRoleId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(roleId)})
+
+	s.Require().True(s.authorize(int(userId), "module_edit", "10.0.0.1"))
+	s.Require().True(s.authorize(int(userId), "module_view", "192.168.0.1"))
+	s.Require().False(s.authorize(int(userId), "module_edit", "192.168.0.1"))
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var messages []string
+	s.db.Must().Select(&messages, "select message from audit where user_id = $1 and event = $2",
+		userId, entity.EventAccessRestricted)
+	s.Require().Len(messages, 1)
+	s.Require().Contains(messages[0], "module_edit")
+	s.Require().Contains(messages[0], "IP-адрес: 192.168.0.1")
+}
+
+func (s *AccessRestrictionTestSuite) TestCreateRole() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+
+	err := s.grpcCli.Invoke("admin/role/create").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(domain.CreateRoleRequest{
+			Name:         "invalid",
+			AllowedCidrs: []string{"10.0.0.1"},
+		}).
+		Do(context.Background())
+	s.requireCode(codes.InvalidArgument, err)
+
+	err = s.grpcCli.Invoke("admin/role/create").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(domain.CreateRoleRequest{
+			Name:        "invalid",
+			TimeWindows: []domain.TimeWindow{{Weekdays: []int{8}, From: "09:00", To: "18:00"}},
+		}).
+		Do(context.Background())
+	s.requireCode(codes.InvalidArgument, err)
+
+	role := domain.Role{}
+	err = s.grpcCli.Invoke("admin/role/create").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(domain.CreateRoleRequest{
+			Name:         "editor",
+			Permissions:  []string{},
+			AllowedCidrs: []string{"10.0.0.0/8"},
+			TimeWindows:  []domain.TimeWindow{{Weekdays: []int{1, 2, 3, 4, 5}, From: "09:00", To: "18:00"}},
+		}).
+		JsonResponseBody(&role).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"10.0.0.0/8"}, role.AllowedCidrs)
+	s.Require().Equal([]domain.TimeWindow{{Weekdays: []int{1, 2, 3, 4, 5}, From: "09:00", To: "18:00"}}, role.TimeWindows)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var message string
+	s.db.Must().SelectRow(&message, "select message from audit where event = $1", entity.EventRoleChanged)
+	s.Require().True(strings.Contains(message, "Разрешенные сети: [] -> [10.0.0.0/8]"), message)
+}
+
+func (s *AccessRestrictionTestSuite) insertUserWithRole(role entity.Role) int64 {
+	roleId := InsertRole(s.db, role)
+	userId := InsertUser(s.db, entity.User{
+		Email:    "a@a.ru",
+		Password: "password",
+	})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(roleId)})
+	return userId
+}
+
+func (s *AccessRestrictionTestSuite) login(forwardedFor string) error {
+	if forwardedFor == "" {
+		return s.loginWithMetadata()
+	}
+	return s.loginWithMetadata("x-forwarded-for", forwardedFor)
+}
+
+func (s *AccessRestrictionTestSuite) loginWithMetadata(keyValues ...string) error {
+	request := s.grpcCli.Invoke("admin/auth/login").
+		JsonRequestBody(domain.LoginRequest{
+			Email:    "a@a.ru",
+			Password: "password",
+		})
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		request = request.AppendMetadata(keyValues[i], keyValues[i+1])
+	}
+	return request.Do(context.Background())
+}
+
+func (s *AccessRestrictionTestSuite) authorize(adminId int, permission string, clientIp string) bool {
+	result := domain.SecureAuthzResponse{}
+	err := s.grpcCli.Invoke("admin/secure/authorize").
+		JsonRequestBody(domain.SecureAuthzRequest{
+			AdminId:    adminId,
+			Permission: permission,
+			ClientIp:   clientIp,
+		}).
+		JsonResponseBody(&result).
+		Do(context.Background())
+	s.Require().NoError(err)
+	return result.Authorized
+}
+
+func (s *AccessRestrictionTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
+
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
@@ -67,6 +67,34 @@ func (t *AuditSuite) SetupTest() {
 					Event: entity.EventUserBlocked,
 					Name:  "изменение статуса блокировки пользователя",
 				},
+				{
+					Event: entity.EventUserImpersonated,
+					Name:  "вход от имени пользователя",
+				},
+				{
+					Event: entity.EventMagicLinkRequested,
+					Name:  "запрос ссылки для входа",
+				},
+				{
+					Event: entity.EventWebauthnChanged,
+					Name:  "изменение ключей доступа",
+				},
+				{
+					Event: entity.EventAccessRestricted,
+					Name:  "отказ в доступе по ограничениям роли",
+				},
+				{
+					Event: entity.EventAccessRequestChanged,
+					Name:  "изменение запроса доступа",
+				},
+				{
+					Event: entity.EventPendingChange,
+					Name:  "изменение, требующее подтверждения",
+				},
+				{
+					Event: entity.EventRecertification,
+					Name:  "пересмотр доступа",
+				},
 			},
 			AuditTTl: conf.AuditTTlSetting{},
 		},
@@ -93,12 +121,19 @@ func (t *AuditSuite) Test_Events_DefaultEvents() {
 	t.Require().NoError(err)
 
 	expectedEventList := map[string]string{
-		entity.EventSuccessLogin:  "успешный вход",
-		entity.EventErrorLogin:    "неуспешный вход",
-		entity.EventSuccessLogout: "успешный выход",
-		entity.EventRoleChanged:   "изменение роли",
-		entity.EventUserChanged:   "изменение пользователя",
-		entity.EventUserBlocked:   "изменение статуса блокировки пользователя",
+		entity.EventSuccessLogin:         "успешный вход",
+		entity.EventErrorLogin:           "неуспешный вход",
+		entity.EventSuccessLogout:        "успешный выход",
+		entity.EventRoleChanged:          "изменение роли",
+		entity.EventUserChanged:          "изменение пользователя",
+		entity.EventUserBlocked:          "изменение статуса блокировки пользователя",
+		entity.EventUserImpersonated:     "вход от имени пользователя",
+		entity.EventMagicLinkRequested:   "запрос ссылки для входа",
+		entity.EventWebauthnChanged:      "изменение ключей доступа",
+		entity.EventAccessRestricted:     "отказ в доступе по ограничениям роли",
+		entity.EventAccessRequestChanged: "изменение запроса доступа",
+		entity.EventPendingChange:        "изменение, требующее подтверждения",
+		entity.EventRecertification:      "пересмотр доступа",
 	}
 	for _, event := range response {
 		name, found := expectedEventList[event.Event]
@@ -119,6 +154,13 @@ func (t *AuditSuite) Test_Events_SortEvents() {
 		{Event: entity.EventRoleChanged, Enable: false},
 		{Event: entity.EventUserChanged, Enable: true},
 		{Event: entity.EventUserBlocked, Enable: true},
+		{Event: entity.EventUserImpersonated, Enable: true},
+		{Event: entity.EventMagicLinkRequested, Enable: true},
+		{Event: entity.EventWebauthnChanged, Enable: true},
+		{Event: entity.EventAccessRestricted, Enable: true},
+		{Event: entity.EventAccessRequestChanged, Enable: true},
+		{Event: entity.EventPendingChange, Enable: true},
+		{Event: entity.EventRecertification, Enable: true},
 		{Event: "новый#2", Enable: false},
 	})
 	t.Require().NoError(err)
@@ -131,7 +173,7 @@ func (t *AuditSuite) Test_Events_SortEvents() {
 	t.Require().NoError(err)
 
 	expectedSort := []bool{
-		true, true, true, true, false, false, false, false,
+		true, true, true, true, true, true, true, true, true, true, true, false, false, false, false,
 	}
 	t.Require().Equal(len(expectedSort), len(response)) // nolint:testifylint
 	for i, event := range response {
@@ -150,12 +192,19 @@ func (t *AuditSuite) Test_SetEvents_HappyPath() {
 	t.Require().NoError(err)
 
 	expectedEventList := map[string]bool{
-		entity.EventSuccessLogin:  true,
-		entity.EventErrorLogin:    true,
-		entity.EventSuccessLogout: true,
-		entity.EventRoleChanged:   false,
-		entity.EventUserChanged:   true,
-		entity.EventUserBlocked:   true,
+		entity.EventSuccessLogin:         true,
+		entity.EventErrorLogin:           true,
+		entity.EventSuccessLogout:        true,
+		entity.EventRoleChanged:          false,
+		entity.EventUserChanged:          true,
+		entity.EventUserBlocked:          true,
+		entity.EventUserImpersonated:     true,
+		entity.EventMagicLinkRequested:   true,
+		entity.EventWebauthnChanged:      true,
+		entity.EventAccessRestricted:     true,
+		entity.EventAccessRequestChanged: true,
+		entity.EventPendingChange:        true,
+		entity.EventRecertification:      true,
 	}
 	eventRep := repository.NewAuditEvent(t.db)
 	eventList, err := eventRep.All(context.Background())
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -16,6 +17,7 @@ import (
 	"msp-admin-service/domain"
 	"msp-admin-service/entity"
 	"msp-admin-service/repository"
+	"msp-admin-service/service/password"
 
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
@@ -104,6 +106,111 @@ func (s *AuthTestSuite) TestLoginHappyPath() {
 	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
 }
 
+func (s *AuthTestSuite) TestLoginRehashPassword() {
+	id := InsertUser(s.db, entity.User{
+		Email:    "a@a.ru",
+		Password: "password",
+	})
+
+	for range 2 {
+		err := s.grpcCli.Invoke("admin/auth/login").
+			JsonRequestBody(domain.LoginRequest{
+				Email:    "a@a.ru",
+				Password: "password",
+			}).
+			Do(context.Background())
+		s.Require().NoError(err)
+
+		var hash string
+		s.db.Must().SelectRow(&hash, "select password from users where id = $1", id)
+		s.Require().True(strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$"), hash)
+	}
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *AuthTestSuite) TestLoginWrongPasswordArgon2id() {
+	id := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+	hash, err := password.NewHasher(conf.PasswordHashing{}).Hash("password")
+	s.Require().NoError(err)
+	s.db.Must().Exec("update users set password = $1 where id = $2", hash, id)
+
+	err = s.login(s.grpcCli, "a@a.ru", "WrongPassword")
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.Unauthenticated, st.Code())
+
+	var stored string
+	s.db.Must().SelectRow(&stored, "select password from users where id = $1", id)
+	s.Require().Equal(hash, stored)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *AuthTestSuite) TestLoginRehashOutdatedArgon2id() {
+	id := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+	hash, err := password.NewHasher(conf.PasswordHashing{
+		Argon2id: conf.Argon2id{MemoryKib: 8192},
+	}).Hash("password")
+	s.Require().NoError(err)
+	s.db.Must().Exec("update users set password = $1 where id = $2", hash, id)
+
+	err = s.login(s.grpcCli, "a@a.ru", "password")
+	s.Require().NoError(err)
+
+	s.db.Must().SelectRow(&hash, "select password from users where id = $1", id)
+	s.Require().True(strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$"), hash)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *AuthTestSuite) TestLoginRehashOutdatedBcryptCost() {
+	id := InsertUser(s.db, entity.User{
+		Email:    "a@a.ru",
+		Password: "password",
+	})
+	grpcCli := s.newClient(conf.PasswordHashing{
+		Algorithm:  password.AlgorithmBcrypt,
+		BcryptCost: 10,
+	})
+
+	err := s.login(grpcCli, "a@a.ru", "password")
+	s.Require().NoError(err)
+
+	var hash string
+	s.db.Must().SelectRow(&hash, "select password from users where id = $1", id)
+	s.Require().True(strings.HasPrefix(hash, "$2a$10$"), hash)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *AuthTestSuite) TestLoginLongPassphrase() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	passphrase := strings.Repeat("correct horse battery staple ", 4)
+	s.Require().Greater(len(passphrase), 72)
+
+	err := s.grpcCli.Invoke("admin/user/create_user").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(domain.CreateUserRequest{
+			Email:    "a@a.ru",
+			Password: passphrase,
+		}).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	err = s.login(s.grpcCli, "a@a.ru", passphrase)
+	s.Require().NoError(err)
+
+	err = s.login(s.grpcCli, "a@a.ru", passphrase[:72])
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.Unauthenticated, st.Code())
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
 func (s *AuthTestSuite) TestLoginNotFound() {
 	err := s.grpcCli.Invoke("admin/auth/login").
 		JsonRequestBody(domain.LoginRequest{
@@ -298,6 +405,34 @@ func (s *AuthTestSuite) TestBruteForceLogin() {
 	time.Sleep(2 * time.Second) // wait for go SaveAuditAsync()
 }
 
+func (s *AuthTestSuite) login(grpcCli *client.Client, email string, userPassword string) error {
+	return grpcCli.Invoke("admin/auth/login").
+		JsonRequestBody(domain.LoginRequest{
+			Email:    email,
+			Password: userPassword,
+		}).
+		Do(context.Background())
+}
+
+// newClient starts another server with password hashing settings
+func (s *AuthTestSuite) newClient(hashing conf.PasswordHashing) *client.Client {
+	remote := conf.Remote{
+		ExpireSec: 3600,
+		AntiBruteforce: conf.AntiBruteforce{
+			MaxInFlightLoginRequests: 3,
+		},
+		PasswordHashing: hashing,
+	}
+	cfg := assembly.NewLocator(s.test.Logger(), s.httpCli, s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(s.test, cfg.Handler)
+	s.test.T().Cleanup(func() {
+		server.Shutdown()
+	})
+	return apiCli
+}
+
 func (s *AuthTestSuite) initMockSudir() (*httptest.Server, string) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/blitz/oauth/te", func(writer http.ResponseWriter, request *http.Request) {
@@ -42,8 +42,8 @@ func InsertRole(db *dbt.TestDb, role entity.Role) int64 {
 	}
 	q, args, err := query.New().
 		Insert("roles").
-		Columns("name", "permissions").
-		Values(role.Name, role.Permissions).
+		Columns("name", "permissions", "allowed_cidrs", "time_windows", "approver_ids", "parent_ids", "resource_scopes").
+		Values(role.Name, role.Permissions, role.AllowedCidrs, role.TimeWindows, role.ApproverIds, role.ParentIds, role.ResourceScopes).
 		Suffix("ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name, permissions = EXCLUDED.permissions RETURNING id").
 		ToSql()
 	if err != nil {
@@ -57,8 +57,8 @@ func InsertRole(db *dbt.TestDb, role entity.Role) int64 {
 func InsertUserRole(db *dbt.TestDb, role entity.UserRole) {
 	q, args, err := query.New().
 		Insert("user_roles").
-		Columns("user_id", "role_id").
-		Values(role.UserId, role.RoleId).
+		Columns("user_id", "role_id", "valid_from", "valid_until").
+		Values(role.UserId, role.RoleId, role.ValidFrom, role.ValidUntil).
 		ToSql()
 	if err != nil {
 		panic(errors.WithMessage(err, "insert user role"))
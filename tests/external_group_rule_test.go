@@ -0,0 +1,133 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExternalGroupRuleTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &ExternalGroupRuleTestSuite{})
+}
+
+type ExternalGroupRuleTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *ExternalGroupRuleTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), conf.Remote{}, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *ExternalGroupRuleTestSuite) TestDryRun() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	exactRoleId := InsertRole(s.db, entity.Role{Name: "exact"})
+	prefixRoleId := InsertRole(s.db, entity.Role{Name: "prefix"})
+	regexRoleId := InsertRole(s.db, entity.Role{Name: "regex"})
+	defaultRoleId := InsertRole(s.db, entity.Role{Name: "default"})
+
+	s.createRule(adminId, domain.CreateExternalGroupRuleRequest{
+		Pattern:   "CN=Admins,OU=Groups,DC=example,DC=com",
+		MatchType: entity.GroupMatchExact,
+		RoleId:    int(exactRoleId),
+		Priority:  100,
+	})
+	s.createRule(adminId, domain.CreateExternalGroupRuleRequest{
+		Pattern:   "app-ops-",
+		MatchType: entity.GroupMatchPrefix,
+		RoleId:    int(prefixRoleId),
+		Priority:  50,
+	})
+	s.createRule(adminId, domain.CreateExternalGroupRuleRequest{
+		Pattern:   "^CN=.*Auditors",
+		MatchType: entity.GroupMatchRegex,
+		RoleId:    int(regexRoleId),
+		Priority:  10,
+		Terminal:  true,
+	})
+	s.createRule(adminId, domain.CreateExternalGroupRuleRequest{
+		MatchType: entity.GroupMatchDefault,
+		RoleId:    int(defaultRoleId),
+	})
+
+	response := domain.ExternalGroupDryRunResponse{}
+	err := s.grpcCli.Invoke("admin/external_group_rule/dry_run").
+		JsonRequestBody(domain.ExternalGroupDryRunRequest{
+			Groups: []string{"app-ops-moscow"},
+		}).
+		JsonResponseBody(&response).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Equal([]int{int(prefixRoleId), int(defaultRoleId)}, response.RoleIds)
+
+	response = domain.ExternalGroupDryRunResponse{}
+	err = s.grpcCli.Invoke("admin/external_group_rule/dry_run").
+		JsonRequestBody(domain.ExternalGroupDryRunRequest{
+			Groups: []string{"CN=Admins,OU=Groups,DC=example,DC=com", "CN=Security Auditors,OU=Groups"},
+		}).
+		JsonResponseBody(&response).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Equal([]int{int(exactRoleId), int(regexRoleId)}, response.RoleIds)
+	s.Require().Len(response.MatchedRules, 2)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *ExternalGroupRuleTestSuite) TestCreateInvalidRegex() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "regex"})
+
+	err := s.grpcCli.Invoke("admin/external_group_rule/create").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(domain.CreateExternalGroupRuleRequest{
+			Pattern:   "(",
+			MatchType: entity.GroupMatchRegex,
+			RoleId:    int(roleId),
+		}).
+		Do(context.Background())
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.InvalidArgument, st.Code())
+}
+
+func (s *ExternalGroupRuleTestSuite) createRule(adminId int64, req domain.CreateExternalGroupRuleRequest) {
+	err := s.grpcCli.Invoke("admin/external_group_rule/create").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(req).
+		Do(context.Background())
+	s.Require().NoError(err)
+}
@@ -0,0 +1,289 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestImpersonationTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &ImpersonationTestSuite{})
+}
+
+type ImpersonationTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *ImpersonationTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec:              3600,
+		ImpersonationExpireSec: 600,
+		Permissions: []conf.Permission{
+			{Key: "user_impersonate", Name: "Вход от имени пользователя"},
+			{Key: "user_view", Name: "Просмотр пользователей"},
+			{Key: "module_edit", Name: "Редактирование модулей"},
+			{Key: "module_delete", Name: "Удаление модулей"},
+		},
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *ImpersonationTestSuite) TestImpersonateHappyPath() {
+	adminId := s.insertUserWithRole("admin@a.ru", entity.Role{
+		Name:        "support",
+		Permissions: []string{"user_impersonate", "user_view"},
+	})
+	userId := s.insertUserWithRole("user@a.ru", entity.Role{
+		Name:        "viewer",
+		Permissions: []string{"user_view"},
+	})
+
+	response := domain.ImpersonateResponse{}
+	err := s.grpcCli.Invoke("admin/auth/impersonate").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(domain.ImpersonateRequest{UserId: userId}).
+		JsonResponseBody(&response).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Equal(userId, response.UserId)
+
+	var impersonatorId int64
+	s.db.Must().SelectRow(&impersonatorId, "select impersonator_id from tokens where token = $1", response.Token)
+	s.Require().Equal(adminId, impersonatorId)
+
+	err = s.grpcCli.Invoke("admin/auth/end_impersonation").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(userId))).
+		AppendMetadata(domain.AdminAuthHeaderName, response.Token).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	tokenInfo := SelectTokenEntityByToken(s.db, response.Token)
+	s.Require().Equal(entity.TokenStatusRevoked, tokenInfo.Status)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var events []string
+	s.db.Must().Select(&events, "select event from audit where user_id = $1 order by id", adminId)
+	s.Require().Equal([]string{entity.EventUserImpersonated, entity.EventUserImpersonated}, events)
+}
+
+func (s *ImpersonationTestSuite) TestAuditActingAs() {
+	adminId := s.insertUserWithRole("admin@a.ru", entity.Role{
+		Name:        "support",
+		Permissions: []string{"user_impersonate"},
+	})
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru"})
+
+	response := domain.ImpersonateResponse{}
+	err := s.grpcCli.Invoke("admin/auth/impersonate").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(domain.ImpersonateRequest{UserId: userId}).
+		JsonResponseBody(&response).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	err = s.grpcCli.Invoke("admin/auth/logout").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(userId))).
+		AppendMetadata(domain.AdminAuthHeaderName, response.Token).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var message string
+	s.db.Must().SelectRow(&message, "select message from audit where user_id = $1 and event = $2",
+		userId, entity.EventSuccessLogout)
+	s.Require().Contains(message, "Администратор "+strconv.Itoa(int(adminId))+" действует от имени пользователя")
+}
+
+func (s *ImpersonationTestSuite) TestTokenOfOtherUser() {
+	adminId := s.insertUserWithRole("admin@a.ru", entity.Role{
+		Name:        "support",
+		Permissions: []string{"user_impersonate"},
+	})
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru"})
+	otherId := InsertUser(s.db, entity.User{Email: "other@a.ru"})
+
+	response := domain.ImpersonateResponse{}
+	err := s.grpcCli.Invoke("admin/auth/impersonate").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(domain.ImpersonateRequest{UserId: userId}).
+		JsonResponseBody(&response).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	err = s.grpcCli.Invoke("admin/auth/logout").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(otherId))).
+		AppendMetadata(domain.AdminAuthHeaderName, response.Token).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	err = s.grpcCli.Invoke("admin/auth/login").
+		AppendMetadata(domain.AdminAuthHeaderName, "unknown").
+		JsonRequestBody(domain.LoginRequest{Email: "unknown@a.ru", Password: "password"}).
+		Do(context.Background())
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.Unauthenticated, st.Code())
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var message string
+	s.db.Must().SelectRow(&message, "select message from audit where user_id = $1 and event = $2",
+		otherId, entity.EventSuccessLogout)
+	s.Require().NotContains(message, "действует от имени пользователя")
+}
+
+func (s *ImpersonationTestSuite) TestImpersonateDenied() {
+	adminId := s.insertUserWithRole("admin@a.ru", entity.Role{
+		Name:        "support",
+		Permissions: []string{"user_impersonate"},
+	})
+	privilegedId := s.insertUserWithRole("privileged@a.ru", entity.Role{
+		Name:        "security",
+		Permissions: []string{"user_delete"},
+	})
+	immutableId := InsertUser(s.db, entity.User{Email: "immutable@a.ru"})
+	immutableRoleId := InsertRole(s.db, entity.Role{Name: "immutable"})
+	s.db.Must().Exec("update roles set immutable = true where id = $1", immutableRoleId)
+	InsertUserRole(s.db, entity.UserRole{UserId: int(immutableId), RoleId: int(immutableRoleId)})
+
+	for _, userId := range []int64{privilegedId, immutableId, adminId} {
+		err := s.grpcCli.Invoke("admin/auth/impersonate").
+			AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+			JsonRequestBody(domain.ImpersonateRequest{UserId: userId}).
+			Do(context.Background())
+		s.Require().Error(err)
+		st, ok := status.FromError(err)
+		s.Require().True(ok)
+		s.Require().Equal(codes.PermissionDenied, st.Code())
+	}
+}
+
+func (s *ImpersonationTestSuite) TestImpersonateWildcardDenied() {
+	adminId := s.insertUserWithRole("admin@a.ru", entity.Role{
+		Name:        "support",
+		Permissions: []string{"user_impersonate", "module_*", "!module_delete"},
+	})
+	wildcardUserId := s.insertUserWithRole("wildcard@a.ru", entity.Role{
+		Name:        "module_admin",
+		Permissions: []string{"module_*"},
+	})
+	editorId := s.insertUserWithRole("editor@a.ru", entity.Role{
+		Name:        "module_editor",
+		Permissions: []string{"module_*", "!module_delete"},
+	})
+
+	err := s.impersonate(adminId, wildcardUserId)
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.PermissionDenied, st.Code())
+
+	err = s.impersonate(adminId, editorId)
+	s.Require().NoError(err)
+
+	profile := domain.AdminUserShort{}
+	err = s.grpcCli.Invoke("admin/user/get_profile").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(wildcardUserId))).
+		JsonResponseBody(&profile).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().ElementsMatch([]string{"module_edit", "module_delete"}, profile.Permissions)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *ImpersonationTestSuite) TestImpersonateScoped() {
+	adminId := s.insertUserWithRole("admin@a.ru", entity.Role{
+		Name:        "team_support",
+		Permissions: []string{"user_impersonate", "application_group_view"},
+		ResourceScopes: entity.ResourceScopeList{{
+			Permission:   "application_group_view",
+			ResourceType: "application_group",
+			ResourceIds:  []string{"1", "2"},
+		}},
+	})
+	teamUserId := s.insertUserWithRole("team@a.ru", entity.Role{
+		Name:        "team",
+		Permissions: []string{"application_group_view"},
+		ResourceScopes: entity.ResourceScopeList{{
+			Permission:   "application_group_view",
+			ResourceType: "application_group",
+			ResourceIds:  []string{"2"},
+		}},
+	})
+	otherTeamUserId := s.insertUserWithRole("other@a.ru", entity.Role{
+		Name:        "other_team",
+		Permissions: []string{"application_group_view"},
+		ResourceScopes: entity.ResourceScopeList{{
+			Permission:   "application_group_view",
+			ResourceType: "application_group",
+			ResourceIds:  []string{"2", "3"},
+		}},
+	})
+	globalUserId := s.insertUserWithRole("global@a.ru", entity.Role{
+		Name:        "global",
+		Permissions: []string{"application_group_view"},
+	})
+
+	err := s.impersonate(adminId, teamUserId)
+	s.Require().NoError(err)
+	for _, userId := range []int64{otherTeamUserId, globalUserId} {
+		err = s.impersonate(adminId, userId)
+		s.Require().Error(err)
+		st, ok := status.FromError(err)
+		s.Require().True(ok)
+		s.Require().Equal(codes.PermissionDenied, st.Code())
+	}
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *ImpersonationTestSuite) impersonate(adminId int64, userId int64) error {
+	return s.grpcCli.Invoke("admin/auth/impersonate").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(domain.ImpersonateRequest{UserId: userId}).
+		Do(context.Background())
+}
+
+func (s *ImpersonationTestSuite) insertUserWithRole(email string, role entity.Role) int64 {
+	userId := InsertUser(s.db, entity.User{Email: email})
+	roleId := InsertRole(s.db, role)
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(roleId)})
+	return userId
+}
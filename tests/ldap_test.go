@@ -0,0 +1,195 @@
+package tests_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/jimlambrt/gldap"
+	"github.com/jimlambrt/gldap/testdirectory"
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLdapTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &LdapTestSuite{})
+}
+
+type LdapTestSuite struct {
+	suite.Suite
+
+	test      *test.Test
+	db        *dbt.TestDb
+	grpcCli   *client.Client
+	directory *testdirectory.Directory
+}
+
+func (s *LdapTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	s.directory = testdirectory.Start(s.T())
+	users := testdirectory.NewUsers(s.T(), []string{"alice"},
+		testdirectory.WithMembersOf(s.T(), testdirectory.NewMemberOf(s.T(), []string{"admins"})...),
+	)
+	// the directory binds by exact name, so upn of alice is a separate entry
+	users = append(users, gldap.NewEntry("alice@corp.local", map[string][]string{"password": {"password"}}))
+	s.directory.SetUsers(users...)
+
+	s.grpcCli = s.newClient("cn=%s," + testdirectory.DefaultUserDN)
+}
+
+func (s *LdapTestSuite) newClient(bindDnTemplate string) *client.Client {
+	remote := conf.Remote{
+		Ldap: &conf.Ldap{
+			Url:               fmt.Sprintf("ldaps://%s:%d", s.directory.Host(), s.directory.Port()),
+			RootCa:            s.directory.Cert(),
+			BindDnTemplate:    bindDnTemplate,
+			BaseDn:            testdirectory.DefaultUserDN,
+			UserFilter:        "(cn=%s)",
+			EmailAttribute:    "email",
+			FullNameAttribute: "name",
+		},
+		ExpireSec: 3600,
+		AntiBruteforce: conf.AntiBruteforce{
+			MaxInFlightLoginRequests: 3,
+			DelayLoginRequestInSec:   0,
+		},
+	}
+	cfg := assembly.NewLocator(s.test.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(s.test, cfg.Handler)
+	s.test.T().Cleanup(func() {
+		server.Shutdown()
+	})
+	return apiCli
+}
+
+func (s *LdapTestSuite) TestLoginHappyPath() {
+	roleId := InsertRole(s.db, entity.Role{Name: "admin"})
+	s.db.Must().Exec("update roles set external_group = $1 where id = $2",
+		"cn=admins,"+testdirectory.DefaultGroupDN, roleId)
+
+	response := domain.LoginResponse{}
+	err := s.grpcCli.Invoke("admin/auth/login_with_ldap").
+		JsonRequestBody(domain.LoginLdapRequest{
+			Login:    "alice",
+			Password: "password",
+		}).
+		JsonResponseBody(&response).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	user := entity.User{}
+	s.db.Must().SelectRow(&user, "select id, email, full_name from users where sudir_user_id = $1", "ldap:alice")
+	s.Require().Equal("alice@example.com", user.Email)
+	s.Require().Equal("alice", user.FullName)
+
+	var roleIds []int
+	s.db.Must().Select(&roleIds, "select role_id from user_roles where user_id = $1", user.Id)
+	s.Require().Equal([]int{int(roleId)}, roleIds)
+
+	tokenInfo := SelectTokenEntityByToken(s.db, response.Token)
+	s.Require().Equal(user.Id, tokenInfo.UserId)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *LdapTestSuite) TestLoginWrongPassword() {
+	userId := InsertSudirUser(s.db, entity.SudirUser{
+		SudirUserId: "ldap:alice",
+		Email:       "alice@example.com",
+	})
+
+	err := s.grpcCli.Invoke("admin/auth/login_with_ldap").
+		JsonRequestBody(domain.LoginLdapRequest{
+			Login:    "alice",
+			Password: "wrong",
+		}).
+		Do(context.Background())
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.Unauthenticated, st.Code())
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var messages []string
+	s.db.Must().Select(&messages, "select message from audit where user_id = $1 and event = $2",
+		userId, entity.EventErrorLogin)
+	s.Require().Len(messages, 1)
+}
+
+func (s *LdapTestSuite) TestLoginUpnTemplate() {
+	grpcCli := s.newClient("%s@corp.local")
+
+	err := grpcCli.Invoke("admin/auth/login_with_ldap").
+		JsonRequestBody(domain.LoginLdapRequest{
+			Login:    "alice",
+			Password: "password",
+		}).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	var userId int64
+	s.db.Must().SelectRow(&userId, "select id from users where sudir_user_id = $1", "ldap:alice")
+
+	err = grpcCli.Invoke("admin/auth/login_with_ldap").
+		JsonRequestBody(domain.LoginLdapRequest{
+			Login:    "alice",
+			Password: "wrong",
+		}).
+		Do(context.Background())
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.Unauthenticated, st.Code())
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from audit where user_id = $1 and event = $2",
+		userId, entity.EventErrorLogin)
+	s.Require().Equal(1, count)
+}
+
+func (s *LdapTestSuite) TestLoginSudirUserWithSameId() {
+	sudirUserId := InsertSudirUser(s.db, entity.SudirUser{
+		SudirUserId: "alice",
+		Email:       "sudir@example.com",
+	})
+
+	err := s.grpcCli.Invoke("admin/auth/login_with_ldap").
+		JsonRequestBody(domain.LoginLdapRequest{
+			Login:    "alice",
+			Password: "password",
+		}).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	var emails []string
+	s.db.Must().Select(&emails, "select email from users where id <> $1", sudirUserId)
+	s.Require().Equal([]string{"alice@example.com"}, emails)
+
+	email := ""
+	s.db.Must().SelectRow(&email, "select email from users where id = $1", sudirUserId)
+	s.Require().Equal("sudir@example.com", email)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
@@ -0,0 +1,265 @@
+package tests_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var magicLinkTokenRegexp = regexp.MustCompile(`magicLink=([0-9a-f]+)`)
+
+func TestMagicLinkTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &MagicLinkTestSuite{})
+}
+
+type MagicLinkTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+	mails   chan string
+}
+
+func (s *MagicLinkTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	s.mails = make(chan string, 10)
+	smtpPort := s.startSmtpServer()
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+		MagicLink: &conf.MagicLink{
+			UrlTemplate:        "https://admin.example.com/login?magicLink=%s",
+			MaxRequestsPerHour: 2,
+			Smtp: conf.Smtp{
+				Host: "127.0.0.1",
+				Port: smtpPort,
+				From: "admin@example.com",
+			},
+		},
+		AntiBruteforce: conf.AntiBruteforce{
+			MaxInFlightLoginRequests: 3,
+			DelayLoginRequestInSec:   0,
+		},
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *MagicLinkTestSuite) TestLoginHappyPath() {
+	userId := InsertUser(s.db, entity.User{Email: "user@example.com", Password: "password"})
+
+	s.requestMagicLink("user@example.com")
+	token := s.receiveToken()
+
+	response := domain.LoginResponse{}
+	err := s.grpcCli.Invoke("admin/auth/login_with_magic_link").
+		JsonRequestBody(domain.LoginMagicLinkRequest{Token: token}).
+		JsonResponseBody(&response).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	tokenInfo := SelectTokenEntityByToken(s.db, response.Token)
+	s.Require().Equal(userId, tokenInfo.UserId)
+
+	err = s.grpcCli.Invoke("admin/auth/login_with_magic_link").
+		JsonRequestBody(domain.LoginMagicLinkRequest{Token: token}).
+		Do(context.Background())
+	s.requireCode(codes.Unauthenticated, err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *MagicLinkTestSuite) TestUnknownEmail() {
+	s.requestMagicLink("unknown@example.com")
+	s.Require().Empty(s.mails)
+}
+
+func (s *MagicLinkTestSuite) TestSudirAndBlockedUsers() {
+	sudirUserId := InsertSudirUser(s.db, entity.SudirUser{SudirUserId: "sudir-1", Email: "sudir@example.com"})
+	blockedUserId := InsertUser(s.db, entity.User{Email: "blocked@example.com", Password: "password", Blocked: true})
+
+	s.requestMagicLink("sudir@example.com")
+	s.requestMagicLink("blocked@example.com")
+	s.Require().Empty(s.mails)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from audit where user_id in ($1, $2) and event = $3",
+		sudirUserId, blockedUserId, entity.EventErrorLogin)
+	s.Require().Equal(2, count)
+}
+
+func (s *MagicLinkTestSuite) TestBlockedUser() {
+	userId := InsertUser(s.db, entity.User{Email: "user@example.com", Password: "password"})
+
+	s.requestMagicLink("user@example.com")
+	token := s.receiveToken()
+
+	s.db.Must().Exec("update users set blocked = true where id = $1", userId)
+	err := s.grpcCli.Invoke("admin/auth/login_with_magic_link").
+		JsonRequestBody(domain.LoginMagicLinkRequest{Token: token}).
+		Do(context.Background())
+	s.requireCode(codes.Unauthenticated, err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *MagicLinkTestSuite) TestRateLimit() {
+	InsertUser(s.db, entity.User{Email: "user@example.com", Password: "password"})
+
+	s.requestMagicLink("user@example.com")
+	s.requestMagicLink("user@example.com")
+	err := s.grpcCli.Invoke("admin/auth/request_magic_link").
+		JsonRequestBody(domain.MagicLinkRequest{Email: "user@example.com"}).
+		Do(context.Background())
+	s.requireCode(codes.ResourceExhausted, err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *MagicLinkTestSuite) TestRateLimitUnknownEmail() {
+	s.requestMagicLink("unknown@example.com")
+	s.requestMagicLink("Unknown@example.com ")
+	err := s.grpcCli.Invoke("admin/auth/request_magic_link").
+		JsonRequestBody(domain.MagicLinkRequest{Email: "unknown@example.com"}).
+		Do(context.Background())
+	s.requireCode(codes.ResourceExhausted, err)
+}
+
+func (s *MagicLinkTestSuite) TestSendError() {
+	userId := InsertUser(s.db, entity.User{Email: "rejected@example.com", Password: "password"})
+
+	s.requestMagicLink("rejected@example.com")
+	s.Require().Empty(s.mails)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from audit where user_id = $1 and event = $2",
+		userId, entity.EventErrorLogin)
+	s.Require().Equal(1, count)
+}
+
+func (s *MagicLinkTestSuite) requestMagicLink(email string) {
+	err := s.grpcCli.Invoke("admin/auth/request_magic_link").
+		JsonRequestBody(domain.MagicLinkRequest{Email: email}).
+		Do(context.Background())
+	s.Require().NoError(err)
+}
+
+func (s *MagicLinkTestSuite) receiveToken() string {
+	select {
+	case mail := <-s.mails:
+		match := magicLinkTokenRegexp.FindStringSubmatch(mail)
+		s.Require().NotNil(match)
+		return match[1]
+	case <-time.After(5 * time.Second):
+		s.FailNow("mail is not received")
+		return ""
+	}
+}
+
+func (s *MagicLinkTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
+
+// startSmtpServer starts minimal smtp server, that passes every received message to s.mails
+// and rejects recipients with `rejected@` address
+func (s *MagicLinkTestSuite) startSmtpServer() int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	s.T().Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serveSmtp(conn)
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *MagicLinkTestSuite) serveSmtp(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	write := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	write("220 localhost")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			write("250 localhost")
+		case strings.HasPrefix(command, "RCPT") && strings.Contains(command, "REJECTED@"):
+			write("550 mailbox unavailable")
+		case command == "DATA":
+			write("354 go ahead")
+			data := strings.Builder{}
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.mails <- data.String()
+			write("250 ok")
+		case command == "QUIT":
+			write("221 bye")
+			return
+		default:
+			write("250 ok " + strconv.Itoa(len(command)))
+		}
+	}
+}
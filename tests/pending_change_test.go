@@ -0,0 +1,324 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPendingChangeTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &PendingChangeTestSuite{})
+}
+
+type PendingChangeTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *PendingChangeTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+		FourEyes: conf.FourEyes{
+			Operations:      []string{entity.ChangeRoleUpdate, entity.ChangeUserDelete, "privileged_role_grant"},
+			PrivilegedRoles: []string{"admin"},
+		},
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *PendingChangeTestSuite) TestApproveRoleUpdate() {
+	authorId := InsertUser(s.db, entity.User{Email: "author@a.ru"})
+	reviewerId := InsertUser(s.db, entity.User{Email: "reviewer@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "editor", Permissions: []string{"user_view"}})
+
+	err := s.invoke("admin/role/update", authorId, domain.UpdateRoleRequest{
+		Id:            int(roleId),
+		Name:          "editor",
+		ChangeMessage: "more rights",
+		Permissions:   []string{"user_view", "user_update"},
+	}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+
+	role := entity.Role{}
+	s.db.Must().SelectRow(&role, "select id, name, permissions from roles where id = $1", roleId)
+	s.Require().Equal(entity.PermList{"user_view"}, role.Permissions)
+
+	change := s.singlePending()
+	s.Require().Equal(entity.ChangeRoleUpdate, change.Operation)
+	s.Require().Equal(authorId, change.CreatedBy)
+	s.Require().Contains(change.Diff, "user_update")
+
+	err = s.invoke("admin/pending_change/approve", authorId, domain.ReviewPendingChangeRequest{Id: change.Id}, nil)
+	s.requireCode(codes.PermissionDenied, err)
+
+	approved := domain.PendingChange{}
+	err = s.invoke("admin/pending_change/approve", reviewerId, domain.ReviewPendingChangeRequest{Id: change.Id}, &approved)
+	s.Require().NoError(err)
+	s.Require().Equal(entity.PendingChangeApproved, approved.Status)
+	s.Require().Equal(reviewerId, *approved.ReviewerId)
+
+	s.db.Must().SelectRow(&role, "select id, name, permissions from roles where id = $1", roleId)
+	s.Require().Equal(entity.PermList{"user_update", "user_view"}, role.Permissions)
+
+	err = s.invoke("admin/pending_change/reject", reviewerId, domain.ReviewPendingChangeRequest{Id: change.Id}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from audit where event = $1", entity.EventPendingChange)
+	s.Require().Equal(2, count)
+}
+
+func (s *PendingChangeTestSuite) TestApproveNotApplied() {
+	authorId := InsertUser(s.db, entity.User{Email: "author@a.ru"})
+	reviewerId := InsertUser(s.db, entity.User{Email: "reviewer@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "editor", Permissions: []string{"user_view"}})
+
+	err := s.invoke("admin/role/update", authorId, domain.UpdateRoleRequest{
+		Id:          int(roleId),
+		Name:        "editor",
+		Permissions: []string{"user_view", "user_update"},
+	}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+	change := s.singlePending()
+
+	s.db.Must().Exec("delete from roles where id = $1", roleId)
+	err = s.invoke("admin/pending_change/approve", reviewerId, domain.ReviewPendingChangeRequest{Id: change.Id}, nil)
+	s.requireCode(codes.NotFound, err)
+
+	pending := s.singlePending()
+	s.Require().Equal(change.Id, pending.Id)
+	s.Require().Nil(pending.ReviewerId)
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from role_versions where role_id = $1", roleId)
+	s.Require().Zero(count)
+}
+
+func (s *PendingChangeTestSuite) TestApproveRoleRollback() {
+	authorId := InsertUser(s.db, entity.User{Email: "author@a.ru"})
+	reviewerId := InsertUser(s.db, entity.User{Email: "reviewer@a.ru"})
+
+	role := domain.Role{}
+	err := s.invoke("admin/role/create", authorId, domain.CreateRoleRequest{
+		Name:        "editor",
+		Permissions: []string{"user_view"},
+	}, &role)
+	s.Require().NoError(err)
+	s.db.Must().Exec(`update roles set permissions = '["user_view", "user_update"]' where id = $1`, role.Id)
+
+	err = s.invoke("admin/role/rollback", authorId, domain.RollbackRoleRequest{RoleId: role.Id, Version: 1}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+
+	change := s.singlePending()
+	s.Require().Equal(entity.ChangeRoleRollback, change.Operation)
+
+	err = s.invoke("admin/pending_change/approve", reviewerId, domain.ReviewPendingChangeRequest{Id: change.Id}, nil)
+	s.Require().NoError(err)
+
+	operation := ""
+	s.db.Must().SelectRow(&operation, "select operation from role_versions where role_id = $1 order by version desc limit 1", role.Id)
+	s.Require().Equal(entity.RoleVersionRollback, operation)
+	permissions := entity.PermList{}
+	s.db.Must().SelectRow(&permissions, "select permissions from roles where id = $1", role.Id)
+	s.Require().Equal(entity.PermList{"user_view"}, permissions)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *PendingChangeTestSuite) TestRejectUserDelete() {
+	authorId := InsertUser(s.db, entity.User{Email: "author@a.ru"})
+	reviewerId := InsertUser(s.db, entity.User{Email: "reviewer@a.ru"})
+	userId := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+
+	err := s.invoke("admin/user/delete_user", authorId, domain.IdentitiesRequest{Ids: []int64{userId}}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+
+	change := s.singlePending()
+	s.Require().Equal(entity.ChangeUserDelete, change.Operation)
+
+	rejected := domain.PendingChange{}
+	err = s.invoke("admin/pending_change/reject", reviewerId, domain.ReviewPendingChangeRequest{Id: change.Id}, &rejected)
+	s.Require().NoError(err)
+	s.Require().Equal(entity.PendingChangeRejected, rejected.Status)
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from users where id = $1", userId)
+	s.Require().Equal(1, count)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *PendingChangeTestSuite) TestPrivilegedRoleGrant() {
+	authorId := InsertUser(s.db, entity.User{Email: "author@a.ru"})
+	reviewerId := InsertUser(s.db, entity.User{Email: "reviewer@a.ru"})
+	userId := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+	adminRoleId := InsertRole(s.db, entity.Role{Name: "admin"})
+	editorRoleId := InsertRole(s.db, entity.Role{Name: "editor"})
+
+	err := s.invoke("admin/user/grant_role", authorId, domain.GrantRoleRequest{
+		UserId: userId,
+		RoleId: int(editorRoleId),
+	}, nil)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/user/grant_role", authorId, domain.GrantRoleRequest{
+		UserId: userId,
+		RoleId: int(adminRoleId),
+	}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1", userId)
+	s.Require().Equal(1, count)
+
+	change := s.singlePending()
+	s.Require().Equal(entity.ChangeUserGrantRole, change.Operation)
+
+	err = s.invoke("admin/pending_change/approve", reviewerId, domain.ReviewPendingChangeRequest{Id: change.Id}, nil)
+	s.Require().NoError(err)
+
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1", userId)
+	s.Require().Equal(2, count)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *PendingChangeTestSuite) TestPrivilegedCreateUser() {
+	authorId := InsertUser(s.db, entity.User{Email: "author@a.ru"})
+	reviewerId := InsertUser(s.db, entity.User{Email: "reviewer@a.ru"})
+	adminRoleId := InsertRole(s.db, entity.Role{Name: "admin"})
+	editorRoleId := InsertRole(s.db, entity.Role{Name: "editor"})
+
+	user := domain.User{}
+	err := s.invoke("admin/user/create_user", authorId, domain.CreateUserRequest{
+		Email:    "editor@a.ru",
+		Password: "password",
+		Roles:    []int{int(editorRoleId)},
+	}, &user)
+	s.Require().NoError(err)
+	s.Require().NotZero(user.Id)
+
+	err = s.invoke("admin/user/create_user", authorId, domain.CreateUserRequest{
+		Email:    "a@a.ru",
+		Password: "password",
+		Roles:    []int{int(adminRoleId)},
+	}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from users where email = $1", "a@a.ru")
+	s.Require().Zero(count)
+
+	change := s.singlePending()
+	s.Require().Equal(entity.ChangeUserCreate, change.Operation)
+	s.Require().NotContains(string(change.Payload), "PasswordHash")
+
+	err = s.invoke("admin/pending_change/approve", reviewerId, domain.ReviewPendingChangeRequest{Id: change.Id}, nil)
+	s.Require().NoError(err)
+
+	s.db.Must().SelectRow(&count,
+		"select count(*) from users u join user_roles ur on ur.user_id = u.id where u.email = $1 and ur.role_id = $2",
+		"a@a.ru", adminRoleId,
+	)
+	s.Require().Equal(1, count)
+
+	err = s.grpcCli.Invoke("admin/auth/login").
+		JsonRequestBody(domain.LoginRequest{Email: "a@a.ru", Password: "password"}).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *PendingChangeTestSuite) TestExpire() {
+	authorId := InsertUser(s.db, entity.User{Email: "author@a.ru"})
+	reviewerId := InsertUser(s.db, entity.User{Email: "reviewer@a.ru"})
+	userId := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+
+	err := s.invoke("admin/user/delete_user", authorId, domain.IdentitiesRequest{Ids: []int64{userId}}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+
+	change := s.singlePending()
+	s.db.Must().Exec("update pending_changes set expired_at = $1 where id = $2", time.Now().Add(-time.Minute), change.Id)
+
+	list := domain.PendingChangeResponse{}
+	err = s.invoke("admin/pending_change/all", reviewerId, domain.PendingChangePageRequest{
+		LimitOffestParams: domain.LimitOffestParams{Limit: 10},
+	}, &list)
+	s.Require().NoError(err)
+	s.Require().Equal(1, list.TotalCount)
+	s.Require().Equal(entity.PendingChangeExpired, list.Items[0].Status)
+
+	err = s.invoke("admin/pending_change/approve", reviewerId, domain.ReviewPendingChangeRequest{Id: change.Id}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from users where id = $1", userId)
+	s.Require().Equal(1, count)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *PendingChangeTestSuite) singlePending() domain.PendingChange {
+	list := domain.PendingChangeResponse{}
+	err := s.grpcCli.Invoke("admin/pending_change/all").
+		JsonRequestBody(domain.PendingChangePageRequest{
+			LimitOffestParams: domain.LimitOffestParams{Limit: 10},
+			Query:             &domain.PendingChangeQuery{Status: []string{entity.PendingChangePending}},
+		}).
+		JsonResponseBody(&list).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Equal(1, list.TotalCount)
+	return list.Items[0]
+}
+
+func (s *PendingChangeTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(req)
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
+
+func (s *PendingChangeTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
@@ -0,0 +1,153 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPermissionCatalogTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &PermissionCatalogTestSuite{})
+}
+
+type PermissionCatalogTestSuite struct {
+	suite.Suite
+
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *PermissionCatalogTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+		Permissions: []conf.Permission{
+			{Key: "user_view", Name: "Просмотр пользователей"},
+			{Key: "user_update", Name: "Редактирование пользователей"},
+			{Key: "module_configuration_edit", Name: "Редактирование конфигурации"},
+		},
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *PermissionCatalogTestSuite) TestValidateRolePermissions() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+
+	err := s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+		Name:        "typo",
+		Permissions: []string{"user_view", "user_updat", "!app_*"},
+	}, nil)
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.InvalidArgument, st.Code())
+	s.Require().Contains(st.Message(), "user_updat, !app_*")
+
+	role := domain.Role{}
+	err = s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+		Name:        "valid",
+		Permissions: []string{"user_*", "!user_update", "module_configuration_edit"},
+	}, &role)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/role/update", adminId, domain.UpdateRoleRequest{
+		Id:          role.Id,
+		Name:        "valid",
+		Permissions: []string{"user_view"},
+		ResourceScopes: []domain.ResourceScope{{
+			Permission:   "application_group_app_edit",
+			ResourceType: "application_group",
+			ResourceIds:  []string{"1"},
+		}},
+	}, nil)
+	s.Require().Error(err)
+	st, ok = status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.InvalidArgument, st.Code())
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *PermissionCatalogTestSuite) TestRemoveUnknownPermissions() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	staleId := InsertRole(s.db, entity.Role{Name: "stale", Permissions: []string{"user_view", "old_feature"}})
+	otherStaleId := InsertRole(s.db, entity.Role{Name: "other_stale", Permissions: []string{"!removed_*"}})
+	InsertRole(s.db, entity.Role{Name: "actual", Permissions: []string{"user_view"}})
+
+	report := s.unknownPermissions(adminId)
+	s.Require().Equal(domain.RoleUnknownPermissions{
+		RoleId: int(staleId), RoleName: "stale", Permissions: []string{"old_feature"},
+	}, report[int(staleId)])
+	s.Require().Equal(domain.RoleUnknownPermissions{
+		RoleId: int(otherStaleId), RoleName: "other_stale", Permissions: []string{"!removed_*"},
+	}, report[int(otherStaleId)])
+
+	removed := make([]domain.RoleUnknownPermissions, 0)
+	err := s.invoke("admin/role/remove_unknown_permissions", adminId, domain.RemoveUnknownPermissionsRequest{
+		RoleIds: []int{int(staleId)},
+	}, &removed)
+	s.Require().NoError(err)
+	s.Require().Len(removed, 1)
+
+	var permissions entity.PermList
+	s.db.Must().SelectRow(&permissions, "select permissions from roles where id = $1", staleId)
+	s.Require().Equal(entity.PermList{"user_view"}, permissions)
+	s.db.Must().SelectRow(&permissions, "select permissions from roles where id = $1", otherStaleId)
+	s.Require().Equal(entity.PermList{"!removed_*"}, permissions)
+
+	report = s.unknownPermissions(adminId)
+	s.Require().NotContains(report, int(staleId))
+	s.Require().Contains(report, int(otherStaleId))
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *PermissionCatalogTestSuite) unknownPermissions(adminId int64) map[int]domain.RoleUnknownPermissions {
+	report := make([]domain.RoleUnknownPermissions, 0)
+	err := s.invoke("admin/role/unknown_permissions", adminId, nil, &report)
+	s.Require().NoError(err)
+	result := make(map[int]domain.RoleUnknownPermissions)
+	for _, role := range report {
+		result[role.RoleId] = role
+	}
+	return result
+}
+
+func (s *PermissionCatalogTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId)))
+	if req != nil {
+		request = request.JsonRequestBody(req)
+	}
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
@@ -0,0 +1,131 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPermissionMatchTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &PermissionMatchTestSuite{})
+}
+
+type PermissionMatchTestSuite struct {
+	suite.Suite
+
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *PermissionMatchTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+		Permissions: []conf.Permission{
+			{Key: "module_configuration_edit", Name: "Редактирование конфигурации"},
+			{Key: "module_delete", Name: "Удаление модулей"},
+			{Key: "module:configuration:edit", Name: "Редактирование конфигурации модуля"},
+			{Key: "user_view", Name: "Просмотр пользователей"},
+		},
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *PermissionMatchTestSuite) TestWildcardAndDeny() {
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru"})
+	moduleRoleId := InsertRole(s.db, entity.Role{
+		Name:        "module",
+		Permissions: []string{"module_*", "module:configuration:*", "user_view"},
+	})
+	denyRoleId := InsertRole(s.db, entity.Role{
+		Name:        "restricted",
+		Permissions: []string{"!module_delete", "!user_view"},
+	})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(moduleRoleId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(denyRoleId)})
+
+	for permission, expected := range map[string]bool{
+		"module_configuration_edit":  true,
+		"module:configuration:edit":  true,
+		"module:configuration":       false,
+		"module_delete":              false,
+		"user_view":                  false,
+		"application_group_app_hide": false,
+	} {
+		result := domain.SecureAuthzResponse{}
+		err := s.invoke("admin/secure/authorize", 0, domain.SecureAuthzRequest{
+			AdminId:    int(userId),
+			Permission: permission,
+		}, &result)
+		s.Require().NoError(err)
+		s.Require().Equal(expected, result.Authorized, permission)
+	}
+
+	profile := domain.AdminUserShort{}
+	err := s.invoke("admin/user/get_profile", userId, nil, &profile)
+	s.Require().NoError(err)
+	s.Require().ElementsMatch([]string{"module_configuration_edit", "module:configuration:edit"}, profile.Permissions)
+}
+
+func (s *PermissionMatchTestSuite) TestInvalidPattern() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+
+	for _, permission := range []string{"", "!", "module_*_edit", "!!module_edit"} {
+		err := s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+			Name:        "invalid",
+			Permissions: []string{permission},
+		}, nil)
+		s.Require().Error(err, permission)
+		st, ok := status.FromError(err)
+		s.Require().True(ok)
+		s.Require().Equal(codes.InvalidArgument, st.Code(), permission)
+	}
+
+	role := domain.Role{}
+	err := s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+		Name:        "valid",
+		Permissions: []string{"*", "!module_*"},
+	}, &role)
+	s.Require().NoError(err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *PermissionMatchTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId)))
+	if req != nil {
+		request = request.JsonRequestBody(req)
+	}
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
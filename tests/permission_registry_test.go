@@ -0,0 +1,143 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPermissionRegistryTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &PermissionRegistryTestSuite{})
+}
+
+type PermissionRegistryTestSuite struct {
+	suite.Suite
+
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *PermissionRegistryTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+		Permissions: []conf.Permission{
+			{Key: "user_view", Name: "Просмотр пользователей"},
+			{Key: "module_view", Name: "Просмотр модулей"},
+		},
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *PermissionRegistryTestSuite) TestRegister() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+
+	err := s.invoke("admin/permission/register", 0, domain.RegisterPermissionsRequest{
+		Module: "isp-config-service",
+		Permissions: []domain.RegisteredPermission{
+			{Key: "module_view", Name: "Просмотр модулей и конфигураций", Category: "Конфигурация"},
+			{Key: "module_configuration_edit", Name: "Редактирование конфигурации", Category: "Конфигурация"},
+			{Key: "module_delete", Name: "Удаление модуля", Category: "Конфигурация"},
+		},
+	}, nil)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/permission/register", 0, domain.RegisterPermissionsRequest{
+		Module: "other-service",
+		Permissions: []domain.RegisteredPermission{
+			{Key: "module_delete", Name: "Чужое разрешение", Category: "Другое"},
+		},
+		DeprecatedKeys: []string{"module_view"},
+	}, nil)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/permission/register", 0, domain.RegisterPermissionsRequest{
+		Module:         "isp-config-service",
+		DeprecatedKeys: []string{"module_delete"},
+	}, nil)
+	s.Require().NoError(err)
+
+	groups := make([]domain.PermissionGroup, 0)
+	err = s.invoke("admin/user/get_permissions", adminId, nil, &groups)
+	s.Require().NoError(err)
+	s.Require().Equal([]domain.PermissionGroup{{
+		Category: "",
+		Permissions: []domain.Permission{
+			{Key: "user_view", Name: "Просмотр пользователей", Module: "msp-admin-service"},
+		},
+	}, {
+		Category: "Конфигурация",
+		Permissions: []domain.Permission{
+			{Key: "module_configuration_edit", Name: "Редактирование конфигурации", Category: "Конфигурация", Module: "isp-config-service"},
+			{Key: "module_view", Name: "Просмотр модулей и конфигураций", Category: "Конфигурация", Module: "isp-config-service"},
+		},
+	}}, groups)
+
+	err = s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+		Name:        "deprecated",
+		Permissions: []string{"module_delete"},
+	}, nil)
+	s.requireCode(codes.InvalidArgument, err)
+
+	err = s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+		Name:        "config",
+		Permissions: []string{"module_*", "user_view"},
+	}, nil)
+	s.Require().NoError(err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *PermissionRegistryTestSuite) TestRegisterPattern() {
+	err := s.invoke("admin/permission/register", 0, domain.RegisterPermissionsRequest{
+		Module:      "isp-config-service",
+		Permissions: []domain.RegisteredPermission{{Key: "module_*", Name: "Все"}},
+	}, nil)
+	s.requireCode(codes.InvalidArgument, err)
+}
+
+func (s *PermissionRegistryTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId)))
+	if req != nil {
+		request = request.JsonRequestBody(req)
+	}
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
+
+func (s *PermissionRegistryTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
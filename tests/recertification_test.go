@@ -0,0 +1,261 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+	"msp-admin-service/service/recertification_worker"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/bgjobx"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecertificationTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &RecertificationTestSuite{})
+}
+
+type RecertificationTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	config  assembly.Config
+	grpcCli *client.Client
+}
+
+func (s *RecertificationTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+	}
+	s.config = assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Second)
+
+	server, apiCli := grpct.TestServer(testInstance, s.config.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *RecertificationTestSuite) TestCloseCampaign() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	reviewerId := InsertUser(s.db, entity.User{Email: "reviewer@a.ru"})
+	otherId := InsertUser(s.db, entity.User{Email: "other@a.ru"})
+	keptId := InsertUser(s.db, entity.User{Email: "kept@a.ru"})
+	revokedId := InsertUser(s.db, entity.User{Email: "revoked@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "editor"})
+	otherRoleId := InsertRole(s.db, entity.Role{Name: "viewer"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(keptId), RoleId: int(roleId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(revokedId), RoleId: int(roleId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(revokedId), RoleId: int(otherRoleId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(reviewerId), RoleId: int(roleId)})
+
+	campaign := domain.RecertificationCampaign{}
+	err := s.invoke("admin/recertification/start", adminId, domain.StartRecertificationRequest{
+		Name:        "Q3",
+		RoleIds:     []int{int(roleId)},
+		ReviewerIds: []int64{reviewerId, adminId},
+		Deadline:    time.Now().Add(time.Hour),
+	}, &campaign)
+	s.Require().NoError(err)
+	s.Require().Equal(entity.RecertificationActive, campaign.Status)
+	s.Require().Equal(3, campaign.Progress.Total)
+
+	items := domain.RecertificationItemResponse{}
+	err = s.invoke("admin/recertification/items", reviewerId, domain.RecertificationItemPageRequest{
+		LimitOffestParams: domain.LimitOffestParams{Limit: 10},
+		Query:             &domain.RecertificationItemQuery{CampaignId: []int{campaign.Id}, Undecided: true},
+	}, &items)
+	s.Require().NoError(err)
+	s.Require().Equal(3, items.TotalCount)
+	itemIds := make(map[int64]int)
+	for _, item := range items.Items {
+		s.Require().Equal("editor", item.RoleName)
+		s.Require().NotNil(item.LastActiveAt)
+		itemIds[item.UserId] = item.Id
+	}
+
+	err = s.invoke("admin/recertification/items", otherId, domain.RecertificationItemPageRequest{
+		LimitOffestParams: domain.LimitOffestParams{Limit: 10},
+	}, &items)
+	s.Require().NoError(err)
+	s.Require().Equal(0, items.TotalCount)
+
+	err = s.invoke("admin/recertification/decide", reviewerId, domain.DecideRecertificationRequest{
+		Ids:      []int{itemIds[reviewerId]},
+		Decision: entity.RecertificationKeep,
+	}, nil)
+	s.requireCode(codes.PermissionDenied, err)
+
+	err = s.invoke("admin/recertification/decide", otherId, domain.DecideRecertificationRequest{
+		Ids:      []int{itemIds[keptId]},
+		Decision: entity.RecertificationKeep,
+	}, nil)
+	s.requireCode(codes.PermissionDenied, err)
+
+	err = s.invoke("admin/recertification/decide", reviewerId, domain.DecideRecertificationRequest{
+		Ids:      []int{itemIds[keptId]},
+		Decision: entity.RecertificationKeep,
+	}, nil)
+	s.Require().NoError(err)
+	err = s.invoke("admin/recertification/decide", reviewerId, domain.DecideRecertificationRequest{
+		Ids:      []int{itemIds[revokedId]},
+		Decision: entity.RecertificationRevoke,
+		Comment:  "left the team",
+	}, nil)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/recertification/close", adminId, domain.RecertificationIdRequest{Id: campaign.Id}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+
+	err = s.invoke("admin/recertification/decide", adminId, domain.DecideRecertificationRequest{
+		Ids:      []int{itemIds[reviewerId]},
+		Decision: entity.RecertificationKeep,
+	}, nil)
+	s.Require().NoError(err)
+
+	closed := domain.RecertificationCampaign{}
+	err = s.invoke("admin/recertification/close", adminId, domain.RecertificationIdRequest{Id: campaign.Id}, &closed)
+	s.Require().NoError(err)
+	s.Require().Equal(entity.RecertificationClosed, closed.Status)
+	s.Require().Equal(domain.RecertificationProgress{Total: 3, Reviewed: 3, Kept: 2, Revoked: 1}, closed.Progress)
+
+	var roleIds []int
+	s.db.Must().Select(&roleIds, "select role_id from user_roles where user_id = $1", revokedId)
+	s.Require().Equal([]int{int(otherRoleId)}, roleIds)
+	s.db.Must().Select(&roleIds, "select role_id from user_roles where user_id = $1", keptId)
+	s.Require().Equal([]int{int(roleId)}, roleIds)
+
+	err = s.invoke("admin/recertification/decide", reviewerId, domain.DecideRecertificationRequest{
+		Ids:      []int{itemIds[keptId]},
+		Decision: entity.RecertificationRevoke,
+	}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+
+	report := domain.RecertificationReport{}
+	err = s.invoke("admin/recertification/report", adminId, domain.RecertificationIdRequest{Id: campaign.Id}, &report)
+	s.Require().NoError(err)
+	lines := strings.Split(strings.TrimSpace(report.Content), "\n")
+	s.Require().Len(lines, 4)
+	s.Require().Contains(report.Content, "revoked@a.ru,"+strconv.Itoa(int(roleId))+",editor,")
+	s.Require().Contains(report.Content, "REVOKE,left the team,"+strconv.Itoa(int(reviewerId)))
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *RecertificationTestSuite) TestStartValidation() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "editor"})
+
+	err := s.invoke("admin/recertification/start", adminId, domain.StartRecertificationRequest{
+		Name:        "empty",
+		RoleIds:     []int{int(roleId)},
+		ReviewerIds: []int64{adminId},
+		Deadline:    time.Now().Add(time.Hour),
+	}, nil)
+	s.requireCode(codes.InvalidArgument, err)
+
+	err = s.invoke("admin/recertification/start", adminId, domain.StartRecertificationRequest{
+		Name:        "past",
+		RoleIds:     []int{int(roleId)},
+		ReviewerIds: []int64{adminId},
+		Deadline:    time.Now().Add(-time.Hour),
+	}, nil)
+	s.requireCode(codes.InvalidArgument, err)
+}
+
+func (s *RecertificationTestSuite) TestAutoRevokeAfterDeadline() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	reviewerId := InsertUser(s.db, entity.User{Email: "reviewer@a.ru"})
+	keptId := InsertUser(s.db, entity.User{Email: "kept@a.ru"})
+	forgottenId := InsertUser(s.db, entity.User{Email: "forgotten@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "editor"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(keptId), RoleId: int(roleId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(forgottenId), RoleId: int(roleId)})
+
+	campaign := domain.RecertificationCampaign{}
+	err := s.invoke("admin/recertification/start", adminId, domain.StartRecertificationRequest{
+		Name:        "Q3",
+		UserIds:     []int64{keptId, forgottenId},
+		ReviewerIds: []int64{reviewerId},
+		Deadline:    time.Now().Add(time.Hour),
+	}, &campaign)
+	s.Require().NoError(err)
+
+	items := domain.RecertificationItemResponse{}
+	err = s.invoke("admin/recertification/items", reviewerId, domain.RecertificationItemPageRequest{
+		LimitOffestParams: domain.LimitOffestParams{Limit: 10},
+		Query:             &domain.RecertificationItemQuery{UserId: []int64{keptId}},
+	}, &items)
+	s.Require().NoError(err)
+	s.Require().Equal(1, items.TotalCount)
+	err = s.invoke("admin/recertification/decide", reviewerId, domain.DecideRecertificationRequest{
+		Ids:      []int{items.Items[0].Id},
+		Decision: entity.RecertificationKeep,
+	}, nil)
+	s.Require().NoError(err)
+
+	s.db.Must().Exec("update recertification_campaigns set deadline = $1 where id = $2", time.Now().UTC().Add(-time.Minute), campaign.Id)
+
+	bgjobCli := bgjobx.NewClient(s.db, s.test.Logger())
+	err = recertification_worker.EnqueueSeedJob(s.T().Context(), bgjobCli)
+	s.Require().NoError(err)
+	err = bgjobCli.Upgrade(s.T().Context(), s.config.BgJobCfg)
+	s.Require().NoError(err)
+
+	time.Sleep(2 * time.Second)
+
+	closed := domain.RecertificationCampaign{}
+	err = s.invoke("admin/recertification/get", adminId, domain.RecertificationIdRequest{Id: campaign.Id}, &closed)
+	s.Require().NoError(err)
+	s.Require().Equal(entity.RecertificationClosed, closed.Status)
+	s.Require().Equal(1, closed.Progress.Revoked)
+
+	var decision string
+	s.db.Must().SelectRow(&decision, "select decision from recertification_items where user_id = $1", forgottenId)
+	s.Require().Equal(entity.RecertificationAutoRevoke, decision)
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1", forgottenId)
+	s.Require().Equal(0, count)
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1", keptId)
+	s.Require().Equal(1, count)
+}
+
+func (s *RecertificationTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(req)
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
+
+func (s *RecertificationTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
@@ -0,0 +1,174 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestResourceScopeTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &ResourceScopeTestSuite{})
+}
+
+type ResourceScopeTestSuite struct {
+	suite.Suite
+
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *ResourceScopeTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *ResourceScopeTestSuite) TestAuthorizeScoped() {
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru"})
+	teamRoleId := InsertRole(s.db, entity.Role{
+		Name:        "team",
+		Permissions: []string{"application_group_app_edit"},
+		ResourceScopes: entity.ResourceScopeList{{
+			Permission:   "application_group_*",
+			ResourceType: "application_group",
+			ResourceIds:  []string{"1", "2"},
+		}},
+	})
+	otherTeamRoleId := InsertRole(s.db, entity.Role{
+		Name:        "other_team",
+		Permissions: []string{"application_group_app_edit", "application_group_view"},
+		ResourceScopes: entity.ResourceScopeList{{
+			Permission:   "application_group_app_edit",
+			ResourceType: "application_group",
+			ResourceIds:  []string{"2", "3"},
+		}},
+	})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(teamRoleId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(otherTeamRoleId)})
+
+	result := s.authorize(userId, "application_group_app_edit", &domain.ResourceRef{Type: "application_group", Id: "3"})
+	s.Require().True(result.Authorized)
+	result = s.authorize(userId, "application_group_app_edit", &domain.ResourceRef{Type: "application_group", Id: "4"})
+	s.Require().False(result.Authorized)
+	result = s.authorize(userId, "application_group_app_edit", &domain.ResourceRef{Type: "module", Id: "1"})
+	s.Require().False(result.Authorized)
+
+	result = s.authorize(userId, "application_group_app_edit", nil)
+	s.Require().True(result.Authorized)
+	s.Require().Equal([]domain.ResourceScope{{
+		Permission:   "application_group_*",
+		ResourceType: "application_group",
+		ResourceIds:  []string{"1", "2"},
+	}, {
+		Permission:   "application_group_app_edit",
+		ResourceType: "application_group",
+		ResourceIds:  []string{"2", "3"},
+	}}, result.Scopes)
+
+	result = s.authorize(userId, "application_group_view", &domain.ResourceRef{Type: "application_group", Id: "4"})
+	s.Require().True(result.Authorized)
+	s.Require().Empty(result.Scopes)
+
+	profile := domain.AdminUserShort{}
+	err := s.invoke("admin/user/get_profile", userId, nil, &profile)
+	s.Require().NoError(err)
+	s.Require().ElementsMatch([]domain.ResourceScope{{
+		Permission:   "application_group_*",
+		ResourceType: "application_group",
+		ResourceIds:  []string{"1", "2"},
+	}, {
+		Permission:   "application_group_app_edit",
+		ResourceType: "application_group",
+		ResourceIds:  []string{"2", "3"},
+	}}, profile.ResourceScopes)
+}
+
+func (s *ResourceScopeTestSuite) TestRoleScopes() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+
+	role := domain.Role{}
+	err := s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+		Name:        "team",
+		Permissions: []string{"module_edit"},
+		ResourceScopes: []domain.ResourceScope{{
+			Permission:   "module_edit",
+			ResourceType: "module",
+			ResourceIds:  []string{"10"},
+		}},
+	}, &role)
+	s.Require().NoError(err)
+	s.Require().Equal([]domain.ResourceScope{{
+		Permission:   "module_edit",
+		ResourceType: "module",
+		ResourceIds:  []string{"10"},
+	}}, role.ResourceScopes)
+
+	for _, scope := range []domain.ResourceScope{
+		{Permission: "!module_edit", ResourceType: "module", ResourceIds: []string{"10"}},
+		{Permission: "module_edit", ResourceType: "module"},
+	} {
+		err = s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+			Name:           "invalid",
+			Permissions:    []string{"module_edit"},
+			ResourceScopes: []domain.ResourceScope{scope},
+		}, nil)
+		s.Require().Error(err)
+		st, ok := status.FromError(err)
+		s.Require().True(ok)
+		s.Require().Equal(codes.InvalidArgument, st.Code())
+	}
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *ResourceScopeTestSuite) authorize(userId int64, permission string, resource *domain.ResourceRef) domain.SecureAuthzResponse {
+	result := domain.SecureAuthzResponse{}
+	err := s.invoke("admin/secure/authorize", 0, domain.SecureAuthzRequest{
+		AdminId:    int(userId),
+		Permission: permission,
+		Resource:   resource,
+	}, &result)
+	s.Require().NoError(err)
+	return result
+}
+
+func (s *ResourceScopeTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId)))
+	if req != nil {
+		request = request.JsonRequestBody(req)
+	}
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
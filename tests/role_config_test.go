@@ -0,0 +1,132 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRoleConfigTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &RoleConfigTestSuite{})
+}
+
+type RoleConfigTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *RoleConfigTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+}
+
+func (s *RoleConfigTestSuite) TestReconcile() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	auditorId := InsertRole(s.db, entity.Role{Name: "auditor", Permissions: []string{"audit_view"}})
+
+	s.reconcile([]conf.Role{
+		{Name: "auditor", Permissions: []string{"audit_view", "user_view"}},
+		{Name: "operator", ExternalGroup: "operators", Permissions: []string{"session_view"}},
+		{Name: "broken", Permissions: []string{"user_*_view"}},
+		{Name: "operator", Permissions: []string{"user_update"}},
+	})
+
+	auditor := s.role("auditor")
+	s.Require().Equal(int(auditorId), auditor.Id)
+	s.Require().True(auditor.ConfigManaged)
+	s.Require().Equal(entity.PermList{"audit_view", "user_view"}, auditor.Permissions)
+	operator := s.role("operator")
+	s.Require().True(operator.ConfigManaged)
+	s.Require().Equal("operators", operator.ExternalGroup)
+	s.Require().Equal(entity.PermList{"session_view"}, operator.Permissions)
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from roles where name = 'broken'")
+	s.Require().Zero(count)
+	s.db.Must().SelectRow(&count, "select count(*) from role_versions where role_id = $1", auditorId)
+	s.Require().Equal(1, count)
+
+	s.reconcile([]conf.Role{
+		{Name: "auditor", Permissions: []string{"user_view", "audit_view"}},
+		{Name: "operator", ExternalGroup: "operators", Permissions: []string{"session_view"}},
+	})
+	s.db.Must().SelectRow(&count, "select count(*) from role_versions where role_id = $1", auditorId)
+	s.Require().Equal(1, count)
+
+	err := s.invoke("admin/role/update", adminId, domain.UpdateRoleRequest{
+		Id:          auditor.Id,
+		Name:        "auditor",
+		Permissions: []string{"audit_view"},
+	})
+	s.requireCode(codes.FailedPrecondition, err)
+	err = s.invoke("admin/role/delete", adminId, domain.DeleteRoleRequest{Id: operator.Id})
+	s.requireCode(codes.FailedPrecondition, err)
+
+	s.reconcile([]conf.Role{
+		{Name: "operator", ExternalGroup: "operators", Permissions: []string{"session_view"}},
+	})
+	auditor = s.role("auditor")
+	s.Require().False(auditor.ConfigManaged)
+	s.Require().Equal(entity.PermList{"audit_view", "user_view"}, auditor.Permissions)
+	err = s.invoke("admin/role/update", adminId, domain.UpdateRoleRequest{
+		Id:          auditor.Id,
+		Name:        "auditor",
+		Permissions: []string{"audit_view"},
+	})
+	s.Require().NoError(err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *RoleConfigTestSuite) reconcile(roles []conf.Role) {
+	cfg := assembly.NewLocator(s.test.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), conf.Remote{ExpireSec: 3600, Roles: roles}, time.Minute)
+	err := cfg.RoleConfig.Reconcile(context.Background())
+	s.Require().NoError(err)
+
+	server, apiCli := grpct.TestServer(s.test, cfg.Handler)
+	s.grpcCli = apiCli
+	s.test.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *RoleConfigTestSuite) role(name string) entity.Role {
+	role := entity.Role{}
+	s.db.Must().SelectRow(&role, "select id, name, external_group, permissions, config_managed from roles where name = $1", name)
+	return role
+}
+
+func (s *RoleConfigTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
+
+func (s *RoleConfigTestSuite) invoke(endpoint string, adminId int64, req any) error {
+	return s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(req).
+		Do(context.Background())
+}
@@ -0,0 +1,182 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRoleDeleteTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &RoleDeleteTestSuite{})
+}
+
+type RoleDeleteTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *RoleDeleteTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+		FourEyes: conf.FourEyes{
+			Operations:      []string{"privileged_role_grant"},
+			PrivilegedRoles: []string{"admin"},
+		},
+		SeparationOfDuties: []conf.SodConstraint{{Name: "payments", Roles: []string{"payment_create", "payment_approve"}}},
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+	s.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *RoleDeleteTestSuite) TestReassign() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	operatorId := InsertRole(s.db, entity.Role{Name: "operator"})
+	supportId := InsertRole(s.db, entity.Role{Name: "support"})
+	firstId := InsertUser(s.db, entity.User{Email: "first@a.ru", FullName: "Иванов Иван"})
+	secondId := InsertUser(s.db, entity.User{Email: "second@a.ru"})
+	validUntil := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	InsertUserRole(s.db, entity.UserRole{UserId: int(firstId), RoleId: int(operatorId), ValidUntil: &validUntil})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(secondId), RoleId: int(operatorId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(secondId), RoleId: int(supportId)})
+
+	preview := domain.RoleDeletePreview{}
+	err := s.invoke("admin/role/delete_preview", adminId, domain.RoleDeletePreviewRequest{Id: int(operatorId)}, &preview)
+	s.Require().NoError(err)
+	s.Require().Equal(domain.RoleDeletePreview{
+		RoleId:     int(operatorId),
+		RoleName:   "operator",
+		UsersCount: 2,
+		Users: []domain.RoleHolder{
+			{Id: firstId, Email: "first@a.ru", FullName: "Иванов Иван"},
+			{Id: secondId, Email: "second@a.ru"},
+		},
+	}, preview)
+
+	err = s.invoke("admin/role/delete", adminId, domain.DeleteRoleRequest{Id: int(operatorId)}, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+	err = s.invoke("admin/role/delete", adminId, domain.DeleteRoleRequest{Id: int(operatorId), ReassignToRoleId: 100}, nil)
+	s.requireCode(codes.InvalidArgument, err)
+
+	err = s.invoke("admin/role/delete", adminId, domain.DeleteRoleRequest{Id: int(operatorId), ReassignToRoleId: int(supportId)}, nil)
+	s.Require().NoError(err)
+	links := make([]entity.UserRole, 0)
+	s.db.Must().Select(&links, "select user_id, role_id, valid_until from user_roles where role_id = $1 order by user_id", supportId)
+	s.Require().Len(links, 2)
+	s.Require().Equal(int(firstId), links[0].UserId)
+	s.Require().Equal(int(supportId), links[0].RoleId)
+	s.Require().Equal(validUntil, links[0].ValidUntil.UTC())
+	s.Require().Equal(int(secondId), links[1].UserId)
+	s.Require().Nil(links[1].ValidUntil)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+	var message string
+	s.db.Must().SelectRow(&message, "select message from audit where event = $1", entity.EventRoleChanged)
+	s.Require().Contains(message, "["+strconv.Itoa(int(firstId))+" "+strconv.Itoa(int(secondId))+"]")
+}
+
+func (s *RoleDeleteTestSuite) TestForce() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	operatorId := InsertRole(s.db, entity.Role{Name: "operator"})
+	unusedId := InsertRole(s.db, entity.Role{Name: "unused"})
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(operatorId)})
+
+	err := s.invoke("admin/role/delete", adminId, domain.DeleteRoleRequest{Id: int(unusedId)}, nil)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/role/delete", adminId, domain.DeleteRoleRequest{Id: int(operatorId), Force: true}, nil)
+	s.Require().NoError(err)
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1", userId)
+	s.Require().Zero(count)
+	s.db.Must().SelectRow(&count, "select count(*) from roles where id in ($1, $2)", operatorId, unusedId)
+	s.Require().Zero(count)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *RoleDeleteTestSuite) TestReassignSodViolation() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	operatorId := InsertRole(s.db, entity.Role{Name: "operator"})
+	createId := InsertRole(s.db, entity.Role{Name: "payment_create"})
+	approveId := InsertRole(s.db, entity.Role{Name: "payment_approve"})
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(operatorId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(createId)})
+
+	req := domain.DeleteRoleRequest{Id: int(operatorId), ReassignToRoleId: int(approveId)}
+	err := s.invoke("admin/role/delete", adminId, req, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from roles where id = $1", operatorId)
+	s.Require().Equal(1, count)
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where role_id = $1", approveId)
+	s.Require().Zero(count)
+}
+
+func (s *RoleDeleteTestSuite) TestReassignPrivileged() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	operatorId := InsertRole(s.db, entity.Role{Name: "operator"})
+	privilegedId := InsertRole(s.db, entity.Role{Name: "admin"})
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(operatorId)})
+
+	req := domain.DeleteRoleRequest{Id: int(operatorId), ReassignToRoleId: int(privilegedId)}
+	err := s.invoke("admin/role/delete", adminId, req, nil)
+	s.requireCode(codes.FailedPrecondition, err)
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from pending_changes where operation = $1", entity.ChangeRoleDelete)
+	s.Require().Equal(1, count)
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1 and role_id = $2", userId, operatorId)
+	s.Require().Equal(1, count)
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where role_id = $1", privilegedId)
+	s.Require().Zero(count)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *RoleDeleteTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
+
+func (s *RoleDeleteTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(req)
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
@@ -0,0 +1,161 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRoleHierarchyTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &RoleHierarchyTestSuite{})
+}
+
+type RoleHierarchyTestSuite struct {
+	suite.Suite
+
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *RoleHierarchyTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *RoleHierarchyTestSuite) TestInheritedPermissions() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru"})
+	viewerId := InsertRole(s.db, entity.Role{Name: "viewer", Permissions: []string{"read"}})
+	editorId := InsertRole(s.db, entity.Role{
+		Name:        "editor",
+		Permissions: []string{"write"},
+		ParentIds:   entity.RoleIdList{int(viewerId)},
+	})
+
+	manager := domain.Role{}
+	err := s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+		Name:        "manager",
+		Permissions: []string{"approve", "read"},
+		ParentIds:   []int{int(editorId)},
+	}, &manager)
+	s.Require().NoError(err)
+	s.Require().Equal([]int{int(editorId)}, manager.ParentIds)
+	s.Require().Equal([]string{"write"}, manager.InheritedPermissions)
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: manager.Id})
+
+	roles := make([]domain.Role, 0)
+	err = s.invoke("admin/role/all", adminId, nil, &roles)
+	s.Require().NoError(err)
+	inherited := make(map[string][]string)
+	for _, role := range roles {
+		inherited[role.Name] = role.InheritedPermissions
+	}
+	s.Require().Equal([]string{}, inherited["viewer"])
+	s.Require().Equal([]string{"read"}, inherited["editor"])
+	s.Require().Equal([]string{"write"}, inherited["manager"])
+
+	profile := domain.AdminUserShort{}
+	err = s.invoke("admin/user/get_profile", userId, nil, &profile)
+	s.Require().NoError(err)
+	s.Require().ElementsMatch([]string{"approve", "read", "write"}, profile.Permissions)
+
+	for permission, expected := range map[string]bool{
+		"approve": true,
+		"write":   true,
+		"read":    true,
+		"delete":  false,
+	} {
+		result := domain.SecureAuthzResponse{}
+		err = s.invoke("admin/secure/authorize", 0, domain.SecureAuthzRequest{
+			AdminId:    int(userId),
+			Permission: permission,
+		}, &result)
+		s.Require().NoError(err)
+		s.Require().Equal(expected, result.Authorized, permission)
+	}
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *RoleHierarchyTestSuite) TestInvalidParents() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	viewerId := InsertRole(s.db, entity.Role{Name: "viewer"})
+	editorId := InsertRole(s.db, entity.Role{Name: "editor", ParentIds: entity.RoleIdList{int(viewerId)}})
+
+	err := s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+		Name:      "unknown",
+		ParentIds: []int{100500},
+	}, nil)
+	s.requireCode(codes.InvalidArgument, err)
+
+	err = s.invoke("admin/role/update", adminId, domain.UpdateRoleRequest{
+		Id:        int(viewerId),
+		Name:      "viewer",
+		ParentIds: []int{int(editorId)},
+	}, nil)
+	s.requireCode(codes.InvalidArgument, err)
+
+	err = s.invoke("admin/role/update", adminId, domain.UpdateRoleRequest{
+		Id:        int(viewerId),
+		Name:      "viewer",
+		ParentIds: []int{int(viewerId)},
+	}, nil)
+	s.requireCode(codes.InvalidArgument, err)
+
+	err = s.invoke("admin/role/delete", adminId, domain.DeleteRoleRequest{Id: int(viewerId)}, nil)
+	s.Require().NoError(err)
+	var parentIds entity.RoleIdList
+	s.db.Must().SelectRow(&parentIds, "select parent_ids from roles where id = $1", editorId)
+	s.Require().Empty(parentIds)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *RoleHierarchyTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId)))
+	if req != nil {
+		request = request.JsonRequestBody(req)
+	}
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
+
+func (s *RoleHierarchyTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
@@ -0,0 +1,267 @@
+package tests_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/json"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRoleImportTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &RoleImportTestSuite{})
+}
+
+type RoleImportTestSuite struct {
+	suite.Suite
+
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *RoleImportTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), conf.Remote{ExpireSec: 3600}, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *RoleImportTestSuite) TestExportImport() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	baseId := InsertRole(s.db, entity.Role{Name: "base", Permissions: []string{"user_view"}})
+	legacyId := InsertRole(s.db, entity.Role{Name: "legacy", Permissions: []string{"session_view"}})
+
+	doc := s.export(adminId)
+	i := slices.IndexFunc(doc.Roles, func(role domain.RbacRole) bool {
+		return role.Name == "base"
+	})
+	s.Require().NotEqual(-1, i)
+	doc.Roles[i].Permissions = append(doc.Roles[i].Permissions, "user_update")
+	doc.Roles = slices.DeleteFunc(doc.Roles, func(role domain.RbacRole) bool {
+		return role.Name == "legacy"
+	})
+	doc.Roles = append(doc.Roles, domain.RbacRole{
+		Name:        "child",
+		Permissions: []string{"audit_view"},
+		ParentNames: []string{"base"},
+		Exclusive:   true,
+	})
+	content, err := json.Marshal(doc)
+	s.Require().NoError(err)
+
+	result := domain.RbacImportResponse{}
+	err = s.invoke("admin/role/import", adminId, domain.RbacImportRequest{
+		Content: string(content),
+		DryRun:  true,
+	}, &result)
+	s.Require().NoError(err)
+	s.Require().True(result.DryRun)
+	operations := make(map[string]string)
+	for _, change := range result.Changes {
+		operations[change.RoleName] = change.Operation
+	}
+	s.Require().Equal(map[string]string{
+		"child":  entity.RoleVersionCreate,
+		"base":   entity.RoleVersionUpdate,
+		"legacy": entity.RoleVersionDelete,
+	}, operations)
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from roles where id = $1", legacyId)
+	s.Require().Equal(1, count)
+
+	err = s.invoke("admin/role/import", adminId, domain.RbacImportRequest{
+		Content:       string(content),
+		ChangeMessage: "перенос со стенда тестирования",
+	}, &result)
+	s.Require().NoError(err)
+	s.Require().False(result.DryRun)
+	s.Require().Len(result.Changes, 3)
+
+	s.db.Must().SelectRow(&count, "select count(*) from roles where id = $1", legacyId)
+	s.Require().Zero(count)
+	var permissions entity.PermList
+	s.db.Must().SelectRow(&permissions, "select permissions from roles where id = $1", baseId)
+	s.Require().Equal(entity.PermList{"user_update", "user_view"}, permissions)
+	child := entity.Role{}
+	s.db.Must().SelectRow(&child, "select id, name, parent_ids, exclusive from roles where name = 'child'")
+	s.Require().Equal(entity.RoleIdList{int(baseId)}, child.ParentIds)
+	s.Require().True(child.Exclusive)
+	var operation string
+	s.db.Must().SelectRow(&operation, "select operation from role_versions where role_id = $1 order by version desc limit 1", child.Id)
+	s.Require().Equal(entity.RoleVersionCreate, operation)
+
+	err = s.invoke("admin/role/import", adminId, domain.RbacImportRequest{Content: string(content)}, &result)
+	s.Require().NoError(err)
+	s.Require().Empty(result.Changes)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *RoleImportTestSuite) TestImportImmutableRole() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	lockedId := InsertRole(s.db, entity.Role{Name: "locked", Permissions: []string{"user_view"}})
+	s.db.Must().Exec("update roles set immutable = true where id = $1", lockedId)
+
+	doc := s.export(adminId)
+	doc.Roles = slices.DeleteFunc(doc.Roles, func(role domain.RbacRole) bool {
+		return role.Name == "locked"
+	})
+	content := s.toYaml(doc)
+
+	err := s.invoke("admin/role/import", adminId, domain.RbacImportRequest{
+		Format:  domain.RbacFormatYaml,
+		Content: content,
+	}, nil)
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.FailedPrecondition, st.Code())
+
+	result := domain.RbacImportResponse{}
+	err = s.invoke("admin/role/import", adminId, domain.RbacImportRequest{
+		Format:  domain.RbacFormatYaml,
+		Content: content,
+		Force:   true,
+	}, &result)
+	s.Require().NoError(err)
+	s.Require().Equal([]domain.RbacRoleChange{{
+		RoleName:  "locked",
+		Operation: entity.RoleVersionDelete,
+		Immutable: true,
+		Fields: []domain.RoleFieldChange{
+			{Field: "permissions", From: []any{"user_view"}, To: []any{}},
+			{Field: "immutable", From: true, To: false},
+		},
+	}}, result.Changes)
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from roles where id = $1", lockedId)
+	s.Require().Zero(count)
+
+	doc.Roles = append(doc.Roles, domain.RbacRole{Name: "orphan", ParentNames: []string{"missing"}})
+	err = s.invoke("admin/role/import", adminId, domain.RbacImportRequest{
+		Format:  domain.RbacFormatYaml,
+		Content: s.toYaml(doc),
+	}, nil)
+	s.Require().Error(err)
+	st, ok = status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.InvalidArgument, st.Code())
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *RoleImportTestSuite) TestImportHeldRole() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru", FullName: "Пользователь"})
+	heldId := InsertRole(s.db, entity.Role{Name: "held", Permissions: []string{"user_view"}})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(heldId)})
+
+	doc := s.export(adminId)
+	doc.Roles = slices.DeleteFunc(doc.Roles, func(role domain.RbacRole) bool {
+		return role.Name == "held"
+	})
+	content := s.toYaml(doc)
+
+	result := domain.RbacImportResponse{}
+	err := s.invoke("admin/role/import", adminId, domain.RbacImportRequest{
+		Format:  domain.RbacFormatYaml,
+		Content: content,
+		DryRun:  true,
+	}, &result)
+	s.Require().NoError(err)
+	s.Require().Len(result.Changes, 1)
+	holders := []domain.RoleHolder{{Id: userId, Email: "user@a.ru", FullName: "Пользователь"}}
+	s.Require().Equal(holders, result.Changes[0].Users)
+
+	err = s.invoke("admin/role/import", adminId, domain.RbacImportRequest{
+		Format:  domain.RbacFormatYaml,
+		Content: content,
+	}, nil)
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.FailedPrecondition, st.Code())
+
+	err = s.invoke("admin/role/import", adminId, domain.RbacImportRequest{
+		Format:  domain.RbacFormatYaml,
+		Content: content,
+		Force:   true,
+	}, &result)
+	s.Require().NoError(err)
+	s.Require().Equal(holders, result.Changes[0].Users)
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1", userId)
+	s.Require().Zero(count)
+	s.db.Must().SelectRow(&count, "select count(*) from roles where id = $1", heldId)
+	s.Require().Zero(count)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	message := ""
+	s.db.Must().SelectRow(&message, "select message from audit where event = $1 and message like '%удаление роли held%'",
+		entity.EventRoleChanged)
+	s.Require().Contains(message, fmt.Sprintf("Роль отозвана у пользователей [%d]", userId))
+}
+
+func (s *RoleImportTestSuite) export(adminId int64) domain.RbacDocument {
+	exported := domain.RbacExportResponse{}
+	err := s.invoke("admin/role/export", adminId, domain.RbacExportRequest{}, &exported)
+	s.Require().NoError(err)
+	s.Require().Equal(domain.RbacFormatJson, exported.Format)
+
+	doc := domain.RbacDocument{}
+	err = json.Unmarshal([]byte(exported.Content), &doc)
+	s.Require().NoError(err)
+	s.Require().Equal(domain.RbacDocumentVersion, doc.Version)
+	return doc
+}
+
+func (s *RoleImportTestSuite) toYaml(doc domain.RbacDocument) string {
+	data, err := json.Marshal(doc)
+	s.Require().NoError(err)
+	var value any
+	err = yaml.Unmarshal(data, &value)
+	s.Require().NoError(err)
+	data, err = yaml.Marshal(value)
+	s.Require().NoError(err)
+	return string(data)
+}
+
+func (s *RoleImportTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId)))
+	if req != nil {
+		request = request.JsonRequestBody(req)
+	}
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
@@ -0,0 +1,190 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRoleTemplateTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &RoleTemplateTestSuite{})
+}
+
+type RoleTemplateTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *RoleTemplateTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+}
+
+func (s *RoleTemplateTestSuite) TestClone() {
+	s.startServer(conf.RoleTemplates{})
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	operatorId := InsertRole(s.db, entity.Role{
+		Name:         "operator",
+		Permissions:  []string{"user_view", "user_update", "!user_delete"},
+		AllowedCidrs: entity.CidrList{"10.0.0.0/8"},
+	})
+	s.db.Must().Exec("update roles set external_group = 'operators' where id = $1", operatorId)
+
+	clone := domain.Role{}
+	err := s.invoke("admin/role/clone", adminId, domain.CloneRoleRequest{
+		Id:          int(operatorId),
+		Name:        "senior operator",
+		Permissions: []string{"user_block", "user_view"},
+	}, &clone)
+	s.Require().NoError(err)
+	s.Require().Equal("senior operator", clone.Name)
+	s.Require().Empty(clone.ExternalGroup)
+	s.Require().ElementsMatch([]string{"user_view", "user_update", "!user_delete", "user_block"}, clone.Permissions)
+	s.Require().Equal([]string{"10.0.0.0/8"}, clone.AllowedCidrs)
+
+	withGroup := domain.Role{}
+	err = s.invoke("admin/role/clone", adminId, domain.CloneRoleRequest{
+		Id:                int(operatorId),
+		Name:              "operator copy",
+		CopyExternalGroup: true,
+	}, &withGroup)
+	s.Require().NoError(err)
+	s.Require().Equal("operators", withGroup.ExternalGroup)
+
+	readOnly := domain.Role{}
+	err = s.invoke("admin/role/clone", adminId, domain.CloneRoleRequest{
+		Id:       int(operatorId),
+		Name:     "operator viewer",
+		Template: domain.RoleTemplateReadOnly,
+	}, &readOnly)
+	s.Require().NoError(err)
+	s.Require().ElementsMatch([]string{"user_view", "!user_delete"}, readOnly.Permissions)
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from role_versions where role_id = $1", clone.Id)
+	s.Require().Equal(1, count)
+
+	err = s.invoke("admin/role/clone", adminId, domain.CloneRoleRequest{Id: int(operatorId), Name: "operator"}, nil)
+	s.requireCode(codes.AlreadyExists, err)
+	err = s.invoke("admin/role/clone", adminId, domain.CloneRoleRequest{Id: 100, Name: "unknown"}, nil)
+	s.requireCode(codes.NotFound, err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+	s.db.Must().SelectRow(&count, "select count(*) from audit where event = $1", entity.EventRoleChanged)
+	s.Require().Equal(3, count)
+}
+
+func (s *RoleTemplateTestSuite) TestCloneReadOnlyInherited() {
+	s.startServer(conf.RoleTemplates{})
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	parentId := InsertRole(s.db, entity.Role{
+		Name:        "writer",
+		Permissions: []string{"report_view", "report_update", "!report_delete"},
+		ResourceScopes: entity.ResourceScopeList{{
+			Permission:   "report_update",
+			ResourceType: "report",
+			ResourceIds:  []string{"1"},
+		}, {
+			Permission:   "report_view",
+			ResourceType: "report",
+			ResourceIds:  []string{"1", "2"},
+		}},
+	})
+	operatorId := InsertRole(s.db, entity.Role{
+		Name:        "operator",
+		Permissions: []string{"user_view", "user_update"},
+		ParentIds:   entity.RoleIdList{int(parentId)},
+	})
+
+	readOnly := domain.Role{}
+	err := s.invoke("admin/role/clone", adminId, domain.CloneRoleRequest{
+		Id:       int(operatorId),
+		Name:     "operator viewer",
+		Template: domain.RoleTemplateReadOnly,
+	}, &readOnly)
+	s.Require().NoError(err)
+	s.Require().ElementsMatch([]string{"user_view", "report_view", "!report_delete"}, readOnly.Permissions)
+	s.Require().Empty(readOnly.ParentIds)
+	s.Require().Empty(readOnly.InheritedPermissions)
+	s.Require().Equal([]domain.ResourceScope{{
+		Permission:   "report_view",
+		ResourceType: "report",
+		ResourceIds:  []string{"1", "2"},
+	}}, readOnly.ResourceScopes)
+
+	templates := make([]domain.RoleTemplate, 0)
+	err = s.invoke("admin/role/templates", adminId, nil, &templates)
+	s.Require().NoError(err)
+	s.Require().Len(templates, 2)
+	s.Require().Equal("operator", templates[1].SourceRoleName)
+	s.Require().ElementsMatch([]string{"user_view", "report_view", "!report_delete"}, templates[1].Permissions)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *RoleTemplateTestSuite) TestTemplates() {
+	s.startServer(conf.RoleTemplates{ReadOnlySuffixes: []string{"_view", "_read"}})
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	operatorId := InsertRole(s.db, entity.Role{Name: "operator", Permissions: []string{"user_view", "log_read", "user_update"}})
+	InsertRole(s.db, entity.Role{Name: "viewer", Permissions: []string{"user_view"}})
+	InsertRole(s.db, entity.Role{Name: "writer", Permissions: []string{"user_update", "!user_delete"}})
+
+	templates := make([]domain.RoleTemplate, 0)
+	err := s.invoke("admin/role/templates", adminId, nil, &templates)
+	s.Require().NoError(err)
+	s.Require().Equal([]domain.RoleTemplate{{
+		Template:       domain.RoleTemplateReadOnly,
+		SourceRoleId:   int(operatorId),
+		SourceRoleName: "operator",
+		Name:           "operator (только чтение)",
+		Permissions:    []string{"user_view", "log_read"},
+	}}, templates)
+}
+
+func (s *RoleTemplateTestSuite) startServer(templates conf.RoleTemplates) {
+	cfg := assembly.NewLocator(s.test.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), conf.Remote{ExpireSec: 3600, RoleTemplates: templates}, time.Minute)
+	server, apiCli := grpct.TestServer(s.test, cfg.Handler)
+	s.grpcCli = apiCli
+	s.test.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *RoleTemplateTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
+
+func (s *RoleTemplateTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(req)
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
@@ -0,0 +1,173 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRoleVersionTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &RoleVersionTestSuite{})
+}
+
+type RoleVersionTestSuite struct {
+	suite.Suite
+
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *RoleVersionTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), conf.Remote{ExpireSec: 3600}, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *RoleVersionTestSuite) TestVersionsAndDiff() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+
+	role := domain.Role{}
+	err := s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+		Name:          "operator",
+		ChangeMessage: "новая роль",
+		Permissions:   []string{"user_view", "session_view"},
+	}, &role)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/role/update", adminId, domain.UpdateRoleRequest{
+		Id:            role.Id,
+		Name:          "operator",
+		ExternalGroup: "operators",
+		ChangeMessage: "расширение прав",
+		Permissions:   []string{"user_view", "user_update"},
+	}, nil)
+	s.Require().NoError(err)
+
+	versions := s.versions(adminId, role.Id)
+	s.Require().Len(versions, 2)
+	s.Require().Equal(2, versions[0].Version)
+	s.Require().Equal(entity.RoleVersionUpdate, versions[0].Operation)
+	s.Require().Equal("расширение прав", versions[0].ChangeMessage)
+	s.Require().Equal(&adminId, versions[0].ChangedBy)
+	s.Require().Equal([]string{"user_view", "user_update"}, versions[0].Snapshot.Permissions)
+	s.Require().Equal(1, versions[1].Version)
+	s.Require().Equal(entity.RoleVersionCreate, versions[1].Operation)
+
+	diff := domain.RoleVersionDiff{}
+	err = s.invoke("admin/role/version_diff", adminId, domain.RoleVersionDiffRequest{
+		RoleId: role.Id, FromVersion: 1, ToVersion: 2,
+	}, &diff)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"user_update"}, diff.AddedPermissions)
+	s.Require().Equal([]string{"session_view"}, diff.RemovedPermissions)
+	fields := make([]string, 0)
+	for _, change := range diff.Changes {
+		fields = append(fields, change.Field)
+	}
+	s.Require().Equal([]string{"externalGroup", "permissions"}, fields)
+
+	err = s.invoke("admin/role/version_diff", adminId, domain.RoleVersionDiffRequest{
+		RoleId: role.Id, FromVersion: 1, ToVersion: 5,
+	}, &diff)
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.NotFound, st.Code())
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *RoleVersionTestSuite) TestRollbackAndDelete() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+
+	role := domain.Role{}
+	err := s.invoke("admin/role/create", adminId, domain.CreateRoleRequest{
+		Name:        "auditor",
+		Permissions: []string{"audit_view"},
+	}, &role)
+	s.Require().NoError(err)
+	err = s.invoke("admin/role/update", adminId, domain.UpdateRoleRequest{
+		Id:          role.Id,
+		Name:        "auditor",
+		Permissions: []string{"audit_view", "user_update"},
+	}, nil)
+	s.Require().NoError(err)
+
+	restored := domain.Role{}
+	err = s.invoke("admin/role/rollback", adminId, domain.RollbackRoleRequest{
+		RoleId: role.Id, Version: 1, ChangeMessage: "ошибочная выдача",
+	}, &restored)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"audit_view"}, restored.Permissions)
+
+	versions := s.versions(adminId, role.Id)
+	s.Require().Len(versions, 3)
+	s.Require().Equal(entity.RoleVersionRollback, versions[0].Operation)
+	s.Require().Equal("Откат к версии 1. ошибочная выдача", versions[0].ChangeMessage)
+
+	err = s.invoke("admin/role/delete", adminId, domain.DeleteRoleRequest{Id: role.Id}, nil)
+	s.Require().NoError(err)
+
+	versions = s.versions(adminId, role.Id)
+	s.Require().Len(versions, 4)
+	s.Require().Equal(entity.RoleVersionDelete, versions[0].Operation)
+
+	err = s.invoke("admin/role/rollback", adminId, domain.RollbackRoleRequest{RoleId: role.Id, Version: 1}, nil)
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.NotFound, st.Code())
+
+	err = s.invoke("admin/role/delete", adminId, domain.DeleteRoleRequest{Id: role.Id}, nil)
+	s.Require().Error(err)
+	st, ok = status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.NotFound, st.Code())
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *RoleVersionTestSuite) versions(adminId int64, roleId int) []domain.RoleVersion {
+	versions := make([]domain.RoleVersion, 0)
+	err := s.invoke("admin/role/versions", adminId, domain.RoleVersionsRequest{RoleId: roleId}, &versions)
+	s.Require().NoError(err)
+	return versions
+}
+
+func (s *RoleVersionTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId)))
+	if req != nil {
+		request = request.JsonRequestBody(req)
+	}
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
@@ -0,0 +1,189 @@
+package tests_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/json"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+)
+
+const scimTestToken = "scim-token"
+
+func TestScimTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &ScimTestSuite{})
+}
+
+type ScimTestSuite struct {
+	suite.Suite
+
+	test *test.Test
+	db   *dbt.TestDb
+	url  string
+	cli  *httpcli.Client
+}
+
+func (s *ScimTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		Scim: &conf.Scim{BearerToken: scimTestToken},
+		FourEyes: conf.FourEyes{
+			Operations:      []string{"privileged_role_grant"},
+			PrivilegedRoles: []string{"admin"},
+		},
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server := httptest.NewServer(cfg.ScimHandler)
+	s.url = server.URL + "/scim/v2"
+	s.cli = httpcli.New()
+
+	testInstance.T().Cleanup(func() {
+		server.Close()
+	})
+}
+
+func (s *ScimTestSuite) TestUnauthorized() {
+	resp, err := s.cli.Get(s.url+"/Users").
+		Header("Authorization", "Bearer wrong").
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusUnauthorized, resp.StatusCode())
+}
+
+func (s *ScimTestSuite) TestUserLifecycle() {
+	created := domain.ScimUser{}
+	s.do(http.MethodPost, "/Users", domain.ScimUser{
+		Schemas:  []string{domain.ScimSchemaUser},
+		UserName: "scim@example.com",
+		Name:     &domain.ScimName{GivenName: "Ivan", FamilyName: "Ivanov"},
+		Active:   new(true),
+	}, http.StatusCreated, &created)
+	s.Require().Equal("scim@example.com", created.UserName)
+	s.Require().True(*created.Active)
+
+	list := domain.ScimListResponse{}
+	s.do(http.MethodGet, "/Users?filter="+url.QueryEscape(`userName eq "scim@example.com"`), nil, http.StatusOK, &list)
+	s.Require().Equal(1, list.TotalResults)
+
+	patched := domain.ScimUser{}
+	s.do(http.MethodPatch, "/Users/"+created.Id, domain.ScimPatchRequest{
+		Schemas: []string{domain.ScimSchemaPatchOp},
+		Operations: []domain.ScimPatchOperation{{
+			Op:    "replace",
+			Value: json.RawMessage(`{"active":"False","name.familyName":"Petrov"}`),
+		}},
+	}, http.StatusOK, &patched)
+	s.Require().False(*patched.Active)
+	s.Require().Equal("Petrov", patched.Name.FamilyName)
+
+	user := entity.User{}
+	s.db.Must().SelectRow(&user, "select blocked, full_name from users where id = $1", created.Id)
+	s.Require().True(user.Blocked)
+	s.Require().Equal("Ivan Petrov", user.FullName)
+
+	s.do(http.MethodDelete, "/Users/"+created.Id, nil, http.StatusNoContent, nil)
+	s.do(http.MethodGet, "/Users/"+created.Id, nil, http.StatusNotFound, nil)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *ScimTestSuite) TestGroupMembers() {
+	userId := InsertUser(s.db, entity.User{Email: "member@example.com"})
+	userIdStr := strconv.FormatInt(userId, 10)
+
+	group := domain.ScimGroup{}
+	s.do(http.MethodPost, "/Groups", domain.ScimGroup{
+		Schemas:     []string{domain.ScimSchemaGroup},
+		DisplayName: "operators",
+		Members:     []domain.ScimMultiValue{{Value: userIdStr}},
+	}, http.StatusCreated, &group)
+	s.Require().Len(group.Members, 1)
+
+	var roleIds []int
+	s.db.Must().Select(&roleIds, "select role_id from user_roles where user_id = $1", userId)
+	s.Require().Len(roleIds, 1)
+	s.Require().Equal(group.Id, strconv.Itoa(roleIds[0]))
+
+	s.do(http.MethodPatch, "/Groups/"+group.Id, domain.ScimPatchRequest{
+		Schemas: []string{domain.ScimSchemaPatchOp},
+		Operations: []domain.ScimPatchOperation{{
+			Op:   "remove",
+			Path: `members[value eq "` + userIdStr + `"]`,
+		}, {
+			Op:    "replace",
+			Path:  "displayName",
+			Value: json.RawMessage(`"operators-renamed"`),
+		}},
+	}, http.StatusOK, &group)
+	s.Require().Empty(group.Members)
+	s.Require().Equal("operators-renamed", group.DisplayName)
+
+	roleIds = nil
+	s.db.Must().Select(&roleIds, "select role_id from user_roles where user_id = $1", userId)
+	s.Require().Empty(roleIds)
+
+	s.do(http.MethodPost, "/Groups", domain.ScimGroup{
+		Schemas:     []string{domain.ScimSchemaGroup},
+		DisplayName: "operators-renamed",
+	}, http.StatusConflict, nil)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *ScimTestSuite) TestPrivilegedGroupMembers() {
+	userId := InsertUser(s.db, entity.User{Email: "member@example.com"})
+	adminRoleId := InsertRole(s.db, entity.Role{Name: "admin"})
+
+	response := domain.ScimErrorResponse{}
+	s.do(http.MethodPatch, "/Groups/"+strconv.FormatInt(adminRoleId, 10), domain.ScimPatchRequest{
+		Schemas: []string{domain.ScimSchemaPatchOp},
+		Operations: []domain.ScimPatchOperation{{
+			Op:    "add",
+			Path:  "members",
+			Value: json.RawMessage(`[{"value": "` + strconv.FormatInt(userId, 10) + `"}]`),
+		}},
+	}, http.StatusAccepted, &response)
+	s.Require().Contains(response.Detail, "pending change")
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1", userId)
+	s.Require().Zero(count)
+	s.db.Must().SelectRow(&count, "select count(*) from pending_changes where operation = $1", entity.ChangeUserUpdate)
+	s.Require().Equal(1, count)
+}
+
+func (s *ScimTestSuite) do(method string, path string, body any, expectedStatus int, response any) {
+	req := s.cli.Get(s.url+path).
+		Method(method).
+		Header("Authorization", "Bearer "+scimTestToken)
+	if body != nil {
+		req = req.JsonRequestBody(body)
+	}
+	if response != nil {
+		req = req.JsonResponseBody(response)
+	}
+
+	resp, err := req.Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Equal(expectedStatus, resp.StatusCode())
+}
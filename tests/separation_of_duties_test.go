@@ -0,0 +1,195 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSeparationOfDutiesTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &SeparationOfDutiesTestSuite{})
+}
+
+type SeparationOfDutiesTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *SeparationOfDutiesTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+}
+
+func (s *SeparationOfDutiesTestSuite) TestUserRoles() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	officerId := InsertRole(s.db, entity.Role{Name: "security officer"})
+	administratorId := InsertRole(s.db, entity.Role{Name: "administrator"})
+	viewerId := InsertRole(s.db, entity.Role{Name: "viewer"})
+	s.startServer([]conf.SodConstraint{{Name: "officer-admin", Roles: []string{"security officer", "administrator"}}})
+
+	err := s.invoke("admin/user/create_user", adminId, domain.CreateUserRequest{
+		Email:    "user@a.ru",
+		Password: "password",
+		Roles:    []int{int(officerId), int(administratorId)},
+	}, nil)
+	s.requireSodViolation(err)
+
+	user := domain.User{}
+	err = s.invoke("admin/user/create_user", adminId, domain.CreateUserRequest{
+		Email:    "user@a.ru",
+		Password: "password",
+		Roles:    []int{int(officerId), int(viewerId)},
+	}, &user)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/user/update_user", adminId, domain.UpdateUserRequest{
+		Id:    user.Id,
+		Email: "user@a.ru",
+		Roles: []int{int(administratorId), int(officerId)},
+	}, nil)
+	s.requireSodViolation(err)
+
+	err = s.invoke("admin/user/grant_role", adminId, domain.GrantRoleRequest{
+		UserId: user.Id,
+		RoleId: int(administratorId),
+	}, nil)
+	s.requireSodViolation(err)
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1", user.Id)
+	s.Require().Equal(2, count)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *SeparationOfDutiesTestSuite) TestViolations() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	officerId := InsertRole(s.db, entity.Role{Name: "security officer"})
+	administratorId := InsertRole(s.db, entity.Role{Name: "administrator"})
+	managerId := InsertRole(s.db, entity.Role{Name: "role manager"})
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru", FullName: "Иванов Иван"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(officerId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(administratorId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(managerId)})
+	otherId := InsertUser(s.db, entity.User{Email: "other@a.ru"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(otherId), RoleId: int(officerId)})
+	s.startServer([]conf.SodConstraint{
+		{Name: "officer-admin", Roles: []string{"security officer", "administrator"}},
+		{Name: "manager-officer", Roles: []string{"role manager", "security officer"}},
+	})
+
+	result := domain.SodViolationsResponse{}
+	err := s.invoke("admin/separation_of_duties/violations", adminId, domain.SodViolationsRequest{}, &result)
+	s.Require().NoError(err)
+	s.Require().Equal([]domain.SodViolation{{
+		UserId:     userId,
+		Email:      "user@a.ru",
+		FullName:   "Иванов Иван",
+		Constraint: "officer-admin",
+		Roles:      []string{"security officer", "administrator"},
+	}, {
+		UserId:     userId,
+		Email:      "user@a.ru",
+		FullName:   "Иванов Иван",
+		Constraint: "manager-officer",
+		Roles:      []string{"role manager", "security officer"},
+	}}, result.Items)
+
+	err = s.invoke("admin/separation_of_duties/violations", adminId, domain.SodViolationsRequest{Constraint: "manager-officer"}, &result)
+	s.Require().NoError(err)
+	s.Require().Len(result.Items, 1)
+	s.Require().Equal("manager-officer", result.Items[0].Constraint)
+}
+
+func (s *SeparationOfDutiesTestSuite) TestPermissionConstraint() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	creatorId := InsertRole(s.db, entity.Role{Name: "payment creator", Permissions: []string{"payment_create"}})
+	approverId := InsertRole(s.db, entity.Role{Name: "payment approver", Permissions: []string{"payment_approve"}})
+	seniorId := InsertRole(s.db, entity.Role{Name: "senior", ParentIds: entity.RoleIdList{int(approverId)}})
+	paymentsId := InsertRole(s.db, entity.Role{Name: "payments", Permissions: []string{"payment_*", "!payment_approve"}})
+	allPaymentsId := InsertRole(s.db, entity.Role{Name: "all payments", Permissions: []string{"payment_*"}})
+	s.startServer([]conf.SodConstraint{{Name: "create-approve", Permissions: []string{"payment_create", "payment_approve"}}})
+
+	for _, roleIds := range [][]int{{int(creatorId), int(seniorId)}, {int(allPaymentsId)}} {
+		err := s.invoke("admin/user/create_user", adminId, domain.CreateUserRequest{
+			Email:    "user@a.ru",
+			Password: "password",
+			Roles:    roleIds,
+		}, nil)
+		s.requireSodViolation(err)
+	}
+
+	err := s.invoke("admin/user/create_user", adminId, domain.CreateUserRequest{
+		Email:    "user@a.ru",
+		Password: "password",
+		Roles:    []int{int(paymentsId), int(creatorId)},
+	}, nil)
+	s.Require().NoError(err)
+
+	userId := InsertUser(s.db, entity.User{Email: "violator@a.ru"})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(creatorId)})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(seniorId)})
+
+	result := domain.SodViolationsResponse{}
+	err = s.invoke("admin/separation_of_duties/violations", adminId, domain.SodViolationsRequest{}, &result)
+	s.Require().NoError(err)
+	s.Require().Equal([]domain.SodViolation{{
+		UserId:      userId,
+		Email:       "violator@a.ru",
+		Constraint:  "create-approve",
+		Roles:       []string{"payment creator", "senior"},
+		Permissions: []string{"payment_create", "payment_approve"},
+	}}, result.Items)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *SeparationOfDutiesTestSuite) startServer(constraints []conf.SodConstraint) {
+	cfg := assembly.NewLocator(s.test.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), conf.Remote{ExpireSec: 3600, SeparationOfDuties: constraints}, time.Minute)
+	server, apiCli := grpct.TestServer(s.test, cfg.Handler)
+	s.grpcCli = apiCli
+	s.test.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *SeparationOfDutiesTestSuite) requireSodViolation(err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.FailedPrecondition, st.Code())
+	s.Require().Contains(apierrors.FromError(err).Error(), strconv.Itoa(domain.ErrCodeSodViolation))
+}
+
+func (s *SeparationOfDutiesTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(req)
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
@@ -0,0 +1,219 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+	"msp-admin-service/service/expired_role_worker"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/bgjobx"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTemporaryRoleTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &TemporaryRoleTestSuite{})
+}
+
+type TemporaryRoleTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	config  assembly.Config
+	grpcCli *client.Client
+}
+
+func (s *TemporaryRoleTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+	}
+	s.config = assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Second)
+
+	server, apiCli := grpct.TestServer(testInstance, s.config.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *TemporaryRoleTestSuite) TestGrantRole() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	userId := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "weekend"})
+	validUntil := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Second)
+
+	user := domain.User{}
+	err := s.grantRole(adminId, domain.GrantRoleRequest{
+		UserId:     userId,
+		RoleId:     int(roleId),
+		ValidUntil: &validUntil,
+	}, &user)
+	s.Require().NoError(err)
+	s.Require().Equal([]int{int(roleId)}, user.Roles)
+	s.Require().Len(user.TemporaryRoles, 1)
+	s.Require().Nil(user.TemporaryRoles[0].ValidFrom)
+	s.Require().True(validUntil.Equal(*user.TemporaryRoles[0].ValidUntil))
+
+	err = s.grantRole(adminId, domain.GrantRoleRequest{UserId: userId, RoleId: int(roleId)}, &user)
+	s.Require().NoError(err)
+	s.Require().Equal([]int{int(roleId)}, user.Roles)
+	s.Require().Empty(user.TemporaryRoles)
+
+	err = s.grantRole(adminId, domain.GrantRoleRequest{
+		UserId:     userId,
+		RoleId:     int(roleId),
+		ValidUntil: new(time.Now().Add(-time.Hour)),
+	}, &user)
+	s.requireCode(codes.InvalidArgument, err)
+
+	err = s.grantRole(adminId, domain.GrantRoleRequest{UserId: userId, RoleId: 100}, &user)
+	s.requireCode(codes.NotFound, err)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *TemporaryRoleTestSuite) TestInactiveRolesIgnored() {
+	userId := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+	permanentRoleId := InsertRole(s.db, entity.Role{Name: "permanent", Permissions: []string{"perm_permanent"}})
+	expiredRoleId := InsertRole(s.db, entity.Role{Name: "expired", Permissions: []string{"perm_expired"}})
+	pendingRoleId := InsertRole(s.db, entity.Role{Name: "pending", Permissions: []string{"perm_pending"}})
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(permanentRoleId)})
+	InsertUserRole(s.db, entity.UserRole{
+		UserId:     int(userId),
+		RoleId:     int(expiredRoleId),
+		ValidUntil: new(time.Now().UTC().Add(-time.Hour)),
+	})
+	InsertUserRole(s.db, entity.UserRole{
+		UserId:    int(userId),
+		RoleId:    int(pendingRoleId),
+		ValidFrom: new(time.Now().UTC().Add(time.Hour)),
+	})
+
+	profile := domain.AdminUserShort{}
+	err := s.grpcCli.Invoke("admin/user/get_profile").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(userId))).
+		JsonResponseBody(&profile).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Equal([]int{int(permanentRoleId)}, profile.Roles)
+	s.Require().Equal([]string{"perm_permanent"}, profile.Permissions)
+
+	for permission, expected := range map[string]bool{
+		"perm_permanent": true,
+		"perm_expired":   false,
+		"perm_pending":   false,
+	} {
+		result := domain.SecureAuthzResponse{}
+		err = s.grpcCli.Invoke("admin/secure/authorize").
+			JsonRequestBody(domain.SecureAuthzRequest{AdminId: int(userId), Permission: permission}).
+			JsonResponseBody(&result).
+			Do(context.Background())
+		s.Require().NoError(err)
+		s.Require().Equal(expected, result.Authorized, permission)
+	}
+}
+
+func (s *TemporaryRoleTestSuite) TestUpdateUserKeepsPeriods() {
+	userId := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "weekend"})
+	validUntil := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	InsertUserRole(s.db, entity.UserRole{UserId: int(userId), RoleId: int(roleId), ValidUntil: &validUntil})
+
+	user := domain.User{}
+	err := s.grpcCli.Invoke("admin/user/update_user").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(userId))).
+		JsonRequestBody(domain.UpdateUserRequest{
+			Id:    userId,
+			Roles: []int{int(roleId)},
+			Email: "a@a.ru",
+		}).
+		JsonResponseBody(&user).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Len(user.TemporaryRoles, 1)
+	s.Require().True(validUntil.Equal(*user.TemporaryRoles[0].ValidUntil))
+
+	err = s.grpcCli.Invoke("admin/user/update_user").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(userId))).
+		JsonRequestBody(domain.UpdateUserRequest{
+			Id:             userId,
+			Roles:          []int{int(roleId)},
+			TemporaryRoles: []domain.TemporaryRole{},
+			Email:          "a@a.ru",
+		}).
+		JsonResponseBody(&user).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Equal([]int{int(roleId)}, user.Roles)
+	s.Require().Empty(user.TemporaryRoles)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *TemporaryRoleTestSuite) TestExpiredRoleWorker() {
+	userId := InsertUser(s.db, entity.User{Email: "a@a.ru"})
+	expiredRoleId := InsertRole(s.db, entity.Role{Name: "expired"})
+	activeRoleId := InsertRole(s.db, entity.Role{Name: "active"})
+	InsertUserRole(s.db, entity.UserRole{
+		UserId:     int(userId),
+		RoleId:     int(expiredRoleId),
+		ValidUntil: new(time.Now().UTC().Add(-time.Minute)),
+	})
+	InsertUserRole(s.db, entity.UserRole{
+		UserId:     int(userId),
+		RoleId:     int(activeRoleId),
+		ValidUntil: new(time.Now().UTC().Add(time.Hour)),
+	})
+
+	bgjobCli := bgjobx.NewClient(s.db, s.test.Logger())
+	err := expired_role_worker.EnqueueSeedJob(s.T().Context(), bgjobCli)
+	s.Require().NoError(err)
+	err = bgjobCli.Upgrade(s.T().Context(), s.config.BgJobCfg)
+	s.Require().NoError(err)
+
+	time.Sleep(2 * time.Second)
+
+	var roleIds []int
+	s.db.Must().Select(&roleIds, "select role_id from user_roles where user_id = $1", userId)
+	s.Require().Equal([]int{int(activeRoleId)}, roleIds)
+
+	var events []string
+	s.db.Must().Select(&events, "select event from audit where user_id = $1", userId)
+	s.Require().Equal([]string{entity.EventUserChanged}, events)
+}
+
+func (s *TemporaryRoleTestSuite) grantRole(adminId int64, req domain.GrantRoleRequest, user *domain.User) error {
+	return s.grpcCli.Invoke("admin/user/grant_role").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(req).
+		JsonResponseBody(user).
+		Do(context.Background())
+}
+
+func (s *TemporaryRoleTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
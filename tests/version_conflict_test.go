@@ -0,0 +1,168 @@
+package tests_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestVersionConflictTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &VersionConflictTestSuite{})
+}
+
+type VersionConflictTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *VersionConflictTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), conf.Remote{ExpireSec: 3600}, time.Minute)
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+	s.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *VersionConflictTestSuite) TestRole() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "operator", Permissions: []string{"user_view"}})
+
+	role := domain.Role{}
+	err := s.invoke("admin/role/update", adminId, domain.UpdateRoleRequest{
+		Id:          int(roleId),
+		Name:        "operator",
+		Permissions: []string{"user_view", "user_update"},
+		Version:     1,
+	}, &role)
+	s.Require().NoError(err)
+	s.Require().Equal(2, role.Version)
+
+	err = s.invoke("admin/role/update", adminId, domain.UpdateRoleRequest{
+		Id:          int(roleId),
+		Name:        "operator",
+		Permissions: []string{"user_block"},
+		Version:     1,
+	}, nil)
+	current := s.requireVersionConflict(err)
+	s.Require().EqualValues(2, current["version"])
+	s.Require().ElementsMatch([]any{"user_view", "user_update"}, current["permissions"])
+
+	err = s.invoke("admin/role/update", adminId, domain.UpdateRoleRequest{
+		Id:          int(roleId),
+		Name:        "operator",
+		Permissions: []string{"user_block"},
+	}, &role)
+	s.Require().NoError(err)
+	s.Require().Equal(3, role.Version)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *VersionConflictTestSuite) TestUser() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "operator"})
+
+	user := domain.User{}
+	err := s.invoke("admin/user/update_user", adminId, domain.UpdateUserRequest{
+		Id:        userId,
+		Email:     "user@a.ru",
+		FirstName: "Иван",
+		Roles:     []int{int(roleId)},
+		Version:   1,
+	}, &user)
+	s.Require().NoError(err)
+	s.Require().Greater(user.Version, 1)
+
+	err = s.invoke("admin/user/update_user", adminId, domain.UpdateUserRequest{
+		Id:      userId,
+		Email:   "user@a.ru",
+		Version: 1,
+	}, nil)
+	current := s.requireVersionConflict(err)
+	s.Require().EqualValues(user.Version, current["version"])
+	s.Require().Equal("Иван", current["firstName"])
+	s.Require().Equal([]any{float64(roleId)}, current["roles"])
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1", userId)
+	s.Require().Equal(1, count)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *VersionConflictTestSuite) TestUserGrantRole() {
+	adminId := InsertUser(s.db, entity.User{Email: "admin@a.ru"})
+	userId := InsertUser(s.db, entity.User{Email: "user@a.ru"})
+	roleId := InsertRole(s.db, entity.Role{Name: "operator"})
+
+	err := s.invoke("admin/user/grant_role", adminId, domain.GrantRoleRequest{
+		UserId: userId,
+		RoleId: int(roleId),
+	}, nil)
+	s.Require().NoError(err)
+
+	err = s.invoke("admin/user/update_user", adminId, domain.UpdateUserRequest{
+		Id:      userId,
+		Email:   "user@a.ru",
+		Version: 1,
+	}, nil)
+	current := s.requireVersionConflict(err)
+	s.Require().Equal([]any{float64(roleId)}, current["roles"])
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from user_roles where user_id = $1", userId)
+	s.Require().Equal(1, count)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *VersionConflictTestSuite) requireVersionConflict(err error) map[string]any {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(codes.Aborted, st.Code())
+	apiErr := apierrors.FromError(err)
+	s.Require().NotNil(apiErr)
+	s.Require().Equal(domain.ErrCodeVersionConflict, apiErr.ErrorCode)
+	current, ok := apiErr.Details["current"].(map[string]any)
+	s.Require().True(ok)
+	return current
+}
+
+func (s *VersionConflictTestSuite) invoke(endpoint string, adminId int64, req any, resp any) error {
+	request := s.grpcCli.Invoke(endpoint).
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(adminId))).
+		JsonRequestBody(req)
+	if resp != nil {
+		request = request.JsonResponseBody(resp)
+	}
+	return request.Do(context.Background())
+}
@@ -0,0 +1,369 @@
+package tests_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"msp-admin-service/assembly"
+	"msp-admin-service/conf"
+	"msp-admin-service/domain"
+	"msp-admin-service/entity"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/http/httpcli"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	webauthnRpId   = "admin.example.com"
+	webauthnOrigin = "https://admin.example.com"
+)
+
+func TestWebauthnTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &WebauthnTestSuite{})
+}
+
+type WebauthnTestSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	db      *dbt.TestDb
+	grpcCli *client.Client
+}
+
+func (s *WebauthnTestSuite) SetupTest() {
+	testInstance, _ := test.New(s.T())
+	s.test = testInstance
+	s.db = dbt.New(testInstance, dbx.WithMigrationRunner("../migrations", testInstance.Logger()))
+
+	remote := conf.Remote{
+		ExpireSec: 3600,
+		Webauthn: &conf.Webauthn{
+			RpId:      webauthnRpId,
+			RpOrigins: []string{webauthnOrigin},
+		},
+		AntiBruteforce: conf.AntiBruteforce{
+			MaxInFlightLoginRequests: 3,
+			DelayLoginRequestInSec:   0,
+		},
+	}
+	cfg := assembly.NewLocator(testInstance.Logger(), httpcli.New(), s.db).
+		Config(context.Background(), remote, time.Minute)
+
+	server, apiCli := grpct.TestServer(testInstance, cfg.Handler)
+	s.grpcCli = apiCli
+
+	testInstance.T().Cleanup(func() {
+		server.Shutdown()
+	})
+}
+
+func (s *WebauthnTestSuite) TestPasskeyLogin() {
+	userId := InsertUser(s.db, entity.User{Email: "user@example.com", Password: "password"})
+	authenticator := s.register(userId)
+
+	challenge := domain.WebauthnChallenge{}
+	err := s.grpcCli.Invoke("admin/auth/webauthn/begin").
+		JsonRequestBody(domain.WebauthnBeginRequest{}).
+		JsonResponseBody(&challenge).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	request := domain.WebauthnFinishRequest{
+		SessionId:  challenge.SessionId,
+		Credential: authenticator.get(s.T(), challenge.Options),
+	}
+	response := domain.LoginResponse{}
+	err = s.grpcCli.Invoke("admin/auth/webauthn/finish").
+		JsonRequestBody(request).
+		JsonResponseBody(&response).
+		Do(context.Background())
+	s.Require().NoError(err)
+	tokenInfo := SelectTokenEntityByToken(s.db, response.Token)
+	s.Require().Equal(userId, tokenInfo.UserId)
+
+	err = s.grpcCli.Invoke("admin/auth/webauthn/finish").
+		JsonRequestBody(request).
+		Do(context.Background())
+	s.requireCode(codes.Unauthenticated, err)
+
+	credentials := s.credentials(userId)
+	s.Require().Len(credentials, 1)
+	s.Require().NotNil(credentials[0].LastUsedAt)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+}
+
+func (s *WebauthnTestSuite) TestPasswordSecondFactor() {
+	userId := InsertUser(s.db, entity.User{Email: "user@example.com", Password: "password"})
+	authenticator := s.register(userId)
+
+	loginResponse := domain.LoginResponse{}
+	err := s.grpcCli.Invoke("admin/auth/login").
+		JsonRequestBody(domain.LoginRequest{Email: "user@example.com", Password: "password"}).
+		JsonResponseBody(&loginResponse).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Empty(loginResponse.Token)
+	s.Require().NotNil(loginResponse.Webauthn)
+
+	otherAuthenticator := newSoftAuthenticator(s.T(), []byte("other"))
+	err = s.grpcCli.Invoke("admin/auth/webauthn/finish").
+		JsonRequestBody(domain.WebauthnFinishRequest{
+			SessionId:  loginResponse.Webauthn.SessionId,
+			Credential: otherAuthenticator.get(s.T(), loginResponse.Webauthn.Options),
+		}).
+		Do(context.Background())
+	s.requireCode(codes.Unauthenticated, err)
+
+	response := domain.LoginResponse{}
+	err = s.grpcCli.Invoke("admin/auth/webauthn/finish").
+		JsonRequestBody(domain.WebauthnFinishRequest{
+			SessionId:  loginResponse.Webauthn.SessionId,
+			Credential: authenticator.get(s.T(), loginResponse.Webauthn.Options),
+		}).
+		JsonResponseBody(&response).
+		Do(context.Background())
+	s.Require().NoError(err)
+	tokenInfo := SelectTokenEntityByToken(s.db, response.Token)
+	s.Require().Equal(userId, tokenInfo.UserId)
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var messages []string
+	s.db.Must().Select(&messages, "select message from audit where user_id = $1 and event = $2",
+		userId, entity.EventSuccessLogin)
+	s.Require().Equal([]string{"Успешный вход с подтверждением ключом доступа"}, messages)
+}
+
+func (s *WebauthnTestSuite) TestManageCredentials() {
+	userId := InsertUser(s.db, entity.User{Email: "user@example.com"})
+	s.register(userId)
+	credentialId := s.credentials(userId)[0].Id
+
+	credential := domain.WebauthnCredential{}
+	err := s.grpcCli.Invoke("admin/user/webauthn/rename_credential").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(userId))).
+		JsonRequestBody(domain.RenameWebauthnCredentialRequest{Id: credentialId, Name: "yubikey"}).
+		JsonResponseBody(&credential).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Equal("yubikey", credential.Name)
+
+	otherUserId := InsertUser(s.db, entity.User{Email: "other@example.com"})
+	err = s.grpcCli.Invoke("admin/user/webauthn/delete_credential").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(otherUserId))).
+		JsonRequestBody(domain.DeleteWebauthnCredentialRequest{Id: credentialId}).
+		Do(context.Background())
+	s.requireCode(codes.NotFound, err)
+
+	err = s.grpcCli.Invoke("admin/user/webauthn/delete_credential").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(userId))).
+		JsonRequestBody(domain.DeleteWebauthnCredentialRequest{Id: credentialId}).
+		Do(context.Background())
+	s.Require().NoError(err)
+	s.Require().Empty(s.credentials(userId))
+
+	time.Sleep(1 * time.Second) // wait for go SaveAuditAsync()
+
+	var count int
+	s.db.Must().SelectRow(&count, "select count(*) from audit where user_id = $1 and event = $2",
+		userId, entity.EventWebauthnChanged)
+	s.Require().Equal(3, count)
+}
+
+func (s *WebauthnTestSuite) register(userId int64) *softAuthenticator {
+	challenge := domain.WebauthnChallenge{}
+	err := s.grpcCli.Invoke("admin/user/webauthn/begin_registration").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(userId))).
+		JsonResponseBody(&challenge).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	authenticator := newSoftAuthenticator(s.T(), []byte(strconv.Itoa(int(userId))))
+	err = s.grpcCli.Invoke("admin/user/webauthn/finish_registration").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(userId))).
+		JsonRequestBody(domain.WebauthnFinishRegistrationRequest{
+			SessionId:  challenge.SessionId,
+			Name:       "laptop",
+			Credential: authenticator.create(s.T(), challenge.Options),
+		}).
+		Do(context.Background())
+	s.Require().NoError(err)
+
+	return authenticator
+}
+
+func (s *WebauthnTestSuite) credentials(userId int64) []domain.WebauthnCredential {
+	credentials := make([]domain.WebauthnCredential, 0)
+	err := s.grpcCli.Invoke("admin/user/webauthn/credentials").
+		AppendMetadata(domain.AdminAuthIdHeader, strconv.Itoa(int(userId))).
+		JsonResponseBody(&credentials).
+		Do(context.Background())
+	s.Require().NoError(err)
+	return credentials
+}
+
+func (s *WebauthnTestSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	st, ok := status.FromError(err)
+	s.Require().True(ok)
+	s.Require().Equal(code, st.Code())
+}
+
+// softAuthenticator emulates platform authenticator with ES256 key and "none" attestation
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialId []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, userHandle []byte) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialId := make([]byte, 16)
+	_, _ = rand.Read(credentialId)
+
+	return &softAuthenticator{
+		key:          key,
+		credentialId: credentialId,
+		userHandle:   userHandle,
+	}
+}
+
+// create answers navigator.credentials.create() options
+func (a *softAuthenticator) create(t *testing.T, options json.RawMessage) json.RawMessage {
+	t.Helper()
+
+	clientData := a.clientData(t, "webauthn.create", options)
+
+	publicKey, err := a.key.PublicKey.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	coseKey, err := cbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: publicKey[1:33],
+		-3: publicKey[33:],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authData := a.authData(0x45) // UP, UV, AT
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialId)))
+	authData = append(authData, a.credentialId...)
+	authData = append(authData, coseKey...)
+
+	attestation, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encodeBase64(clientData),
+		"attestationObject": encodeBase64(attestation),
+	})
+}
+
+// get answers navigator.credentials.get() options
+func (a *softAuthenticator) get(t *testing.T, options json.RawMessage) json.RawMessage {
+	t.Helper()
+
+	clientData := a.clientData(t, "webauthn.get", options)
+	a.signCount++
+	authData := a.authData(0x05) // UP, UV
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encodeBase64(clientData),
+		"authenticatorData": encodeBase64(authData),
+		"signature":         encodeBase64(signature),
+		"userHandle":        encodeBase64(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony string, options json.RawMessage) []byte {
+	t.Helper()
+
+	publicKeyOptions := struct {
+		PublicKey struct {
+			Challenge string
+		}
+	}{}
+	err := json.Unmarshal(options, &publicKeyOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientData, err := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   publicKeyOptions.PublicKey.Challenge,
+		"origin":      webauthnOrigin,
+		"crossOrigin": false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientData
+}
+
+func (a *softAuthenticator) authData(flags byte) []byte {
+	rpIdHash := sha256.Sum256([]byte(webauthnRpId))
+	authData := append(rpIdHash[:], flags)
+	return binary.BigEndian.AppendUint32(authData, a.signCount)
+}
+
+func (a *softAuthenticator) credential(t *testing.T, response map[string]string) json.RawMessage {
+	t.Helper()
+
+	credential, err := json.Marshal(map[string]any{
+		"id":       encodeBase64(a.credentialId),
+		"rawId":    encodeBase64(a.credentialId),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credential
+}
+
+func encodeBase64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -10,6 +10,9 @@ import (
 	"github.com/txix-open/isp-kit/db"
 )
 
+// txKey is the context key of the transaction, which nested transactions join
+type txKey struct{}
+
 type Manager struct {
 	db db.Transactional
 }
@@ -25,35 +28,130 @@ type userTx struct {
 	repository.Role
 	repository.UserRole
 	repository.Token
+	repository.ExternalGroupRule
+	repository.MagicLink
+	repository.Webauthn
+}
+
+type accessRequestTx struct {
+	repository.AccessRequest
+	repository.UserRole
+}
+
+type recertificationTx struct {
+	repository.Recertification
+	repository.UserRole
+}
+
+type roleTx struct {
+	repository.Role
+	repository.RoleVersion
+	repository.UserRole
 }
 
 type tokenTx struct {
 	repository.Token
 }
 
+type pendingChangeTx struct {
+	repository.PendingChange
+}
+
 func (m Manager) UserTransaction(ctx context.Context, msgTx func(ctx context.Context, tx service.UserTransaction) error) error {
-	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+	return m.runInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
 		user := repository.NewUser(tx)
 		role := repository.NewRole(tx)
 		userRole := repository.NewUserRole(tx)
 		token := repository.NewToken(tx)
-		return msgTx(ctx, userTx{user, role, userRole, token})
+		externalGroupRule := repository.NewExternalGroupRule(tx)
+		magicLink := repository.NewMagicLink(tx)
+		webauthn := repository.NewWebauthn(tx)
+		return msgTx(ctx, userTx{user, role, userRole, token, externalGroupRule, magicLink, webauthn})
 	})
 }
 
 func (m Manager) AuthTransaction(ctx context.Context, msgTx func(ctx context.Context, tx service.AuthTransaction) error) error {
-	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+	return m.runInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
 		user := repository.NewUser(tx)
 		role := repository.NewRole(tx)
 		userRole := repository.NewUserRole(tx)
 		token := repository.NewToken(tx)
-		return msgTx(ctx, userTx{user, role, userRole, token})
+		externalGroupRule := repository.NewExternalGroupRule(tx)
+		magicLink := repository.NewMagicLink(tx)
+		webauthn := repository.NewWebauthn(tx)
+		return msgTx(ctx, userTx{user, role, userRole, token, externalGroupRule, magicLink, webauthn})
+	})
+}
+
+func (m Manager) AccessRequestTransaction(
+	ctx context.Context,
+	msgTx func(ctx context.Context, tx service.AccessRequestTransaction) error,
+) error {
+	return m.runInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		accessRequest := repository.NewAccessRequest(tx)
+		userRole := repository.NewUserRole(tx)
+		return msgTx(ctx, accessRequestTx{accessRequest, userRole})
+	})
+}
+
+func (m Manager) RecertificationTransaction(
+	ctx context.Context,
+	msgTx func(ctx context.Context, tx service.RecertificationTransaction) error,
+) error {
+	return m.runInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		recertification := repository.NewRecertification(tx)
+		userRole := repository.NewUserRole(tx)
+		return msgTx(ctx, recertificationTx{recertification, userRole})
+	})
+}
+
+func (m Manager) RoleTransaction(ctx context.Context, msgTx func(ctx context.Context, tx service.RoleTransaction) error) error {
+	return m.runInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		role := repository.NewRole(tx)
+		roleVersion := repository.NewRoleVersion(tx)
+		userRole := repository.NewUserRole(tx)
+		return msgTx(ctx, roleTx{role, roleVersion, userRole})
+	})
+}
+
+func (m Manager) RoleConfigTransaction(
+	ctx context.Context,
+	msgTx func(ctx context.Context, tx service.RoleConfigTransaction) error,
+) error {
+	return m.runInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		role := repository.NewRole(tx)
+		roleVersion := repository.NewRoleVersion(tx)
+		userRole := repository.NewUserRole(tx)
+		return msgTx(ctx, roleTx{role, roleVersion, userRole})
 	})
 }
 
 func (m Manager) TokenTransaction(ctx context.Context, msgTx func(ctx context.Context, tx session_worker.TokenTransaction) error) error {
-	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+	return m.runInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
 		token := repository.NewToken(tx)
 		return msgTx(ctx, tokenTx{token})
 	})
 }
+
+// PendingChangeTransaction runs msgTx in the transaction, which transactions started inside msgTx join,
+// so the change is resolved and applied atomically
+func (m Manager) PendingChangeTransaction(
+	ctx context.Context,
+	msgTx func(ctx context.Context, tx service.PendingChangeTransaction) error,
+) error {
+	return m.runInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		pendingChange := repository.NewPendingChange(tx)
+		return msgTx(ctx, pendingChangeTx{pendingChange})
+	})
+}
+
+// runInTransaction joins the transaction from the context or starts the new one
+func (m Manager) runInTransaction(ctx context.Context, txFunc db.TxFunc) error {
+	tx, ok := ctx.Value(txKey{}).(*db.Tx)
+	if ok {
+		return txFunc(ctx, tx)
+	}
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		return txFunc(context.WithValue(ctx, txKey{}, tx), tx)
+	})
+}